@@ -2,6 +2,7 @@ package cfmysql
 
 import (
 	"encoding/json"
+	"errors"
 	"fmt"
 
 	"bytes"
@@ -16,9 +17,20 @@ import (
 //go:generate counterfeiter . ApiClient
 type ApiClient interface {
 	GetStartedApps(cliConnection plugin.CliConnection) ([]sdkModels.GetAppsModel, error)
+	GetSpaceApps(cliConnection plugin.CliConnection, spaceGuid string) ([]sdkModels.GetAppsModel, error)
+	GetSpace(cliConnection plugin.CliConnection, orgName string, spaceName string) (pluginModels.Space, error)
 	GetService(cliConnection plugin.CliConnection, spaceGuid string, name string) (pluginModels.ServiceInstance, error)
+	GetSharedServices(cliConnection plugin.CliConnection, spaceGuid string, name string) ([]pluginModels.ServiceInstance, error)
+	GetServiceInstances(cliConnection plugin.CliConnection, spaceGuid string) ([]pluginModels.ServiceInstance, error)
+	GetOrgServiceInstances(cliConnection plugin.CliConnection, orgGuid string) ([]pluginModels.ServiceInstance, error)
 	GetServiceKey(cliConnection plugin.CliConnection, serviceInstanceGuid string, keyName string) (key pluginModels.ServiceKey, found bool, err error)
+	FindServiceKeys(cliConnection plugin.CliConnection, serviceInstanceGuids []string) ([]pluginModels.ServiceKey, error)
 	CreateServiceKey(cliConnection plugin.CliConnection, serviceInstanceGuid string, keyName string) (pluginModels.ServiceKey, error)
+	DeleteServiceKey(cliConnection plugin.CliConnection, serviceKeyGuid string) error
+	IsSshEnabled(cliConnection plugin.CliConnection, appGuid string) (bool, error)
+	GetSshInfo(cliConnection plugin.CliConnection) (pluginModels.SshInfo, error)
+	GetSshCode(cliConnection plugin.CliConnection, sshInfo pluginModels.SshInfo) (string, error)
+	GetCredhubCredentials(cliConnection plugin.CliConnection, credhubRef string) (pluginModels.ServiceKey, error)
 }
 
 func NewApiClient(httpClient HttpWrapper) *apiClient {
@@ -34,28 +46,185 @@ type apiClient struct {
 
 func (self *apiClient) GetService(cliConnection plugin.CliConnection, spaceGuid string, name string) (pluginModels.ServiceInstance, error) {
 	path := fmt.Sprintf(
-		"/v2/spaces/%s/service_instances?return_user_provided_service_instances=true&q=name%%3A%s",
+		"/v2/spaces/%s/service_instances?return_user_provided_service_instances=true&inline-relations-depth=1&q=name%%3A%s",
 		spaceGuid,
 		url.QueryEscape(name),
 	)
 
-	instanceResponse, err := self.getFromCfApi(path, cliConnection)
+	var instances []pluginModels.ServiceInstance
+	err := self.getAllPages(cliConnection, path, "service instances", func(response []byte) (string, error) {
+		nextUrl, page, err := deserializeInstances(response)
+		instances = append(instances, page...)
+		return nextUrl, err
+	})
 	if err != nil {
-		return pluginModels.ServiceInstance{}, fmt.Errorf("error retrieving service instance: %s", err)
-	}
-
-	_, instances, err := deserializeInstances(instanceResponse)
-	if err != nil {
-		return pluginModels.ServiceInstance{}, fmt.Errorf("error deserializing service instances: %s", err)
+		return pluginModels.ServiceInstance{}, err
 	}
 
 	if len(instances) == 0 {
 		return pluginModels.ServiceInstance{}, fmt.Errorf("%s not found in current space", name)
 	}
 
+	// the label and tags of the service select the format of its credentials
+	instances, err = addPlanDetails(cliConnection, instances[:1], self.getServicePlanDetails)
+	if err != nil {
+		return pluginModels.ServiceInstance{}, err
+	}
+
 	return instances[0], nil
 }
 
+// GetSharedServices returns the instances with the name that other spaces share into the space. The space listing
+// includes them next to the instances of the space itself, which are skipped.
+func (self *apiClient) GetSharedServices(cliConnection plugin.CliConnection, spaceGuid string, name string) ([]pluginModels.ServiceInstance, error) {
+	path := fmt.Sprintf("/v2/spaces/%s/service_instances?return_shared_service_instances=true&q=name%%3A%s", spaceGuid, url.QueryEscape(name))
+
+	var instances []pluginModels.ServiceInstance
+	err := self.getAllPages(cliConnection, path, "service instances", func(response []byte) (string, error) {
+		nextUrl, page, err := deserializeInstances(response)
+		instances = append(instances, page...)
+		return nextUrl, err
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	var sharedInstances []pluginModels.ServiceInstance
+	for _, instance := range instances {
+		if instance.SpaceGuid == spaceGuid {
+			continue
+		}
+
+		sharedFromResponse, err := self.getFromCfApi("/v2/service_instances/"+instance.Guid+"/shared_from", cliConnection)
+		if err != nil {
+			return nil, fmt.Errorf("error retrieving source space of service instance: %s", err)
+		}
+
+		if len(bytes.TrimSpace(sharedFromResponse)) == 0 {
+			continue
+		}
+
+		sharedFrom := new(resources.SharedFromResource)
+		err = json.Unmarshal(sharedFromResponse, sharedFrom)
+		if err != nil {
+			return nil, fmt.Errorf("error deserializing source space of service instance: %s", err)
+		}
+
+		instance.SharedFromSpace = sharedFrom.SpaceName
+		sharedInstances = append(sharedInstances, instance)
+	}
+
+	return sharedInstances, nil
+}
+
+func (self *apiClient) GetServiceInstances(cliConnection plugin.CliConnection, spaceGuid string) ([]pluginModels.ServiceInstance, error) {
+	path := fmt.Sprintf("/v2/spaces/%s/service_instances?return_user_provided_service_instances=true", spaceGuid)
+
+	return self.getServiceInstances(cliConnection, path)
+}
+
+func (self *apiClient) GetOrgServiceInstances(cliConnection plugin.CliConnection, orgGuid string) ([]pluginModels.ServiceInstance, error) {
+	path := fmt.Sprintf("/v2/service_instances?q=organization_guid%%3A%s", orgGuid)
+
+	return self.getServiceInstances(cliConnection, path)
+}
+
+func (self *apiClient) getServiceInstances(cliConnection plugin.CliConnection, path string) ([]pluginModels.ServiceInstance, error) {
+	var instances []pluginModels.ServiceInstance
+	err := self.getAllPages(cliConnection, path, "service instances", func(response []byte) (string, error) {
+		nextUrl, page, err := deserializeInstances(response)
+		instances = append(instances, page...)
+		return nextUrl, err
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return addPlanDetails(cliConnection, instances, self.getServicePlanDetails)
+}
+
+// addPlanDetails sets the plan name, the service label and the service tags of the instances, looking up each plan
+// once
+func addPlanDetails(cliConnection plugin.CliConnection, instances []pluginModels.ServiceInstance, getPlanDetails func(plugin.CliConnection, string) (servicePlanDetails, error)) ([]pluginModels.ServiceInstance, error) {
+	plans := make(map[string]servicePlanDetails)
+	for i, instance := range instances {
+		if instance.UserProvided {
+			instances[i].Label = "user-provided"
+			continue
+		}
+
+		plan, found := plans[instance.PlanGuid]
+		if !found {
+			var err error
+			plan, err = getPlanDetails(cliConnection, instance.PlanGuid)
+			if err != nil {
+				return nil, err
+			}
+			plans[instance.PlanGuid] = plan
+		}
+
+		instances[i].Plan = plan.Name
+		instances[i].Label = plan.Label
+		instances[i].Tags = appendMissing(instance.Tags, plan.Tags...)
+	}
+
+	return instances, nil
+}
+
+type servicePlanDetails struct {
+	Name  string
+	Label string
+	Tags  []string
+}
+
+func (self *apiClient) getServicePlanDetails(cliConnection plugin.CliConnection, planGuid string) (servicePlanDetails, error) {
+	planResponse, err := self.getFromCfApi("/v2/service_plans/"+planGuid, cliConnection)
+	if err != nil {
+		return servicePlanDetails{}, fmt.Errorf("error retrieving service plan: %s", err)
+	}
+
+	plan := new(resources.ServicePlanResource)
+	err = json.Unmarshal(planResponse, plan)
+	if err != nil {
+		return servicePlanDetails{}, fmt.Errorf("error deserializing service plan: %s", err)
+	}
+
+	serviceResponse, err := self.getFromCfApi("/v2/services/"+plan.Entity.ServiceGuid, cliConnection)
+	if err != nil {
+		return servicePlanDetails{}, fmt.Errorf("error retrieving service: %s", err)
+	}
+
+	service := new(resources.ServiceResource)
+	err = json.Unmarshal(serviceResponse, service)
+	if err != nil {
+		return servicePlanDetails{}, fmt.Errorf("error deserializing service: %s", err)
+	}
+
+	return servicePlanDetails{
+		Name:  plan.Entity.Name,
+		Label: service.Entity.Label,
+		Tags:  service.Entity.Tags,
+	}, nil
+}
+
+func appendMissing(list []string, items ...string) []string {
+	for _, item := range items {
+		found := false
+		for _, existing := range list {
+			if existing == item {
+				found = true
+				break
+			}
+		}
+
+		if !found {
+			list = append(list, item)
+		}
+	}
+
+	return list
+}
+
 func (self *apiClient) GetServiceKey(cliConnection plugin.CliConnection, serviceInstanceGuid string, keyName string) (pluginModels.ServiceKey, bool, error) {
 	path := fmt.Sprintf(
 		"/v2/service_instances/%s/service_keys?q=name%%3A%s",
@@ -63,14 +232,9 @@ func (self *apiClient) GetServiceKey(cliConnection plugin.CliConnection, service
 		url.QueryEscape(keyName),
 	)
 
-	keyResponse, err := self.getFromCfApi(path, cliConnection)
+	serviceKeys, err := self.getServiceKeys(cliConnection, path)
 	if err != nil {
-		return pluginModels.ServiceKey{}, false, fmt.Errorf("error retrieving service key: %s", err)
-	}
-
-	serviceKeys, err := deserializeServiceKeys(keyResponse)
-	if err != nil {
-		return pluginModels.ServiceKey{}, false, fmt.Errorf("error deserializing service key response: %s", err)
+		return pluginModels.ServiceKey{}, false, err
 	}
 
 	if len(serviceKeys) == 0 {
@@ -80,6 +244,34 @@ func (self *apiClient) GetServiceKey(cliConnection plugin.CliConnection, service
 	return serviceKeys[0], true, nil
 }
 
+// FindServiceKeys returns all keys of the service instances, looking them up instance by instance
+func (self *apiClient) FindServiceKeys(cliConnection plugin.CliConnection, serviceInstanceGuids []string) ([]pluginModels.ServiceKey, error) {
+	var serviceKeys []pluginModels.ServiceKey
+	for _, serviceInstanceGuid := range serviceInstanceGuids {
+		instanceKeys, err := self.getServiceKeys(cliConnection, "/v2/service_instances/"+serviceInstanceGuid+"/service_keys")
+		if err != nil {
+			return nil, err
+		}
+		serviceKeys = append(serviceKeys, instanceKeys...)
+	}
+
+	return serviceKeys, nil
+}
+
+func (self *apiClient) getServiceKeys(cliConnection plugin.CliConnection, path string) ([]pluginModels.ServiceKey, error) {
+	var serviceKeys []pluginModels.ServiceKey
+	err := self.getAllPages(cliConnection, path, "service keys", func(response []byte) (string, error) {
+		nextUrl, page, err := deserializeServiceKeys(response)
+		serviceKeys = append(serviceKeys, page...)
+		return nextUrl, err
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return serviceKeys, nil
+}
+
 func (self *apiClient) CreateServiceKey(cliConnection plugin.CliConnection, serviceInstanceGuid string, keyName string) (pluginModels.ServiceKey, error) {
 	content := ServiceKeyRequest{
 		Name:                keyName,
@@ -121,6 +313,196 @@ func (self *apiClient) GetStartedApps(cliConnection plugin.CliConnection) ([]sdk
 	return startedApps, nil
 }
 
+// GetSpaceApps returns the apps of a space, which need not be the space targeted in the CLI
+func (self *apiClient) GetSpaceApps(cliConnection plugin.CliConnection, spaceGuid string) ([]sdkModels.GetAppsModel, error) {
+	var apps []sdkModels.GetAppsModel
+	err := self.getAllPages(cliConnection, "/v2/spaces/"+spaceGuid+"/apps", "apps", func(response []byte) (string, error) {
+		paginatedResources := new(resources.PaginatedAppResources)
+		err := json.Unmarshal(response, paginatedResources)
+		if err != nil {
+			return "", fmt.Errorf("unable to deserialize apps: %s", err)
+		}
+
+		apps = append(apps, paginatedResources.ToModel()...)
+		return paginatedResources.NextUrl, nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return apps, nil
+}
+
+// GetSpace looks up a space by the names of its organization and itself
+func (self *apiClient) GetSpace(cliConnection plugin.CliConnection, orgName string, spaceName string) (pluginModels.Space, error) {
+	orgs, err := self.getNamedResources(cliConnection, "/v2/organizations?q=name%3A"+url.QueryEscape(orgName), "organizations")
+	if err != nil {
+		return pluginModels.Space{}, err
+	}
+
+	if len(orgs) == 0 {
+		return pluginModels.Space{}, fmt.Errorf("organization %s not found", orgName)
+	}
+
+	spaces, err := self.getNamedResources(cliConnection, "/v2/organizations/"+orgs[0].Metadata.GUID+"/spaces?q=name%3A"+url.QueryEscape(spaceName), "spaces")
+	if err != nil {
+		return pluginModels.Space{}, err
+	}
+
+	if len(spaces) == 0 {
+		return pluginModels.Space{}, fmt.Errorf("space %s not found in organization %s", spaceName, orgName)
+	}
+
+	return pluginModels.Space{
+		Guid:    spaces[0].Metadata.GUID,
+		Name:    spaces[0].Entity.Name,
+		OrgGuid: orgs[0].Metadata.GUID,
+		OrgName: orgs[0].Entity.Name,
+	}, nil
+}
+
+func (self *apiClient) getNamedResources(cliConnection plugin.CliConnection, path string, description string) ([]resources.NamedResource, error) {
+	var namedResources []resources.NamedResource
+	err := self.getAllPages(cliConnection, path, description, func(response []byte) (string, error) {
+		paginatedResources := new(resources.PaginatedNamedResources)
+		err := json.Unmarshal(response, paginatedResources)
+		if err != nil {
+			return "", fmt.Errorf("unable to deserialize %s: %s", description, err)
+		}
+
+		namedResources = append(namedResources, paginatedResources.Resources...)
+		return paginatedResources.NextUrl, nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return namedResources, nil
+}
+
+func (self *apiClient) IsSshEnabled(cliConnection plugin.CliConnection, appGuid string) (bool, error) {
+	appResponse, err := self.getFromCfApi("/v2/apps/"+appGuid, cliConnection)
+	if err != nil {
+		return false, fmt.Errorf("error retrieving app: %s", err)
+	}
+
+	app := new(resources.AppResource)
+	err = json.Unmarshal(appResponse, app)
+	if err != nil {
+		return false, fmt.Errorf("error deserializing app: %s", err)
+	}
+
+	return app.Entity.EnableSsh, nil
+}
+
+func (self *apiClient) DeleteServiceKey(cliConnection plugin.CliConnection, serviceKeyGuid string) error {
+	err := self.deleteFromCfApi("/v2/service_keys/"+serviceKeyGuid, cliConnection)
+	if err != nil {
+		return fmt.Errorf("error deleting service key: %s", err)
+	}
+
+	return nil
+}
+
+func (self *apiClient) GetSshInfo(cliConnection plugin.CliConnection) (pluginModels.SshInfo, error) {
+	infoResponse, err := self.getFromCfApi("/v2/info", cliConnection)
+	if err != nil {
+		return pluginModels.SshInfo{}, fmt.Errorf("error retrieving API info: %s", err)
+	}
+
+	info := new(resources.InfoResource)
+	err = json.Unmarshal(infoResponse, info)
+	if err != nil {
+		return pluginModels.SshInfo{}, fmt.Errorf("error deserializing API info: %s", err)
+	}
+
+	if info.AppSshEndpoint == "" {
+		return pluginModels.SshInfo{}, errors.New("the API does not advertise an SSH endpoint")
+	}
+
+	return info.ToSshInfo(), nil
+}
+
+// GetSshCode requests a one-time code from UAA, which the SSH proxy accepts as a password
+func (self *apiClient) GetSshCode(cliConnection plugin.CliConnection, sshInfo pluginModels.SshInfo) (string, error) {
+	config, err := self.getCliConfig(cliConnection)
+	if err != nil {
+		return "", err
+	}
+
+	// the cached token may have expired when a tunnel is reopened, the CLI refreshes it if necessary
+	accessToken, err := cliConnection.AccessToken()
+	if err != nil {
+		return "", fmt.Errorf("unable to get access token: %s", err)
+	}
+
+	query := url.Values{}
+	query.Set("response_type", "code")
+	query.Set("client_id", sshInfo.OauthClient)
+
+	location, err := self.httpClient.GetRedirect(sshInfo.TokenEndpoint+"/oauth/authorize?"+query.Encode(), accessToken, config.SslDisabled)
+	if err != nil {
+		return "", fmt.Errorf("error requesting one-time SSH code: %s", err)
+	}
+
+	redirectUrl, err := url.Parse(location)
+	if err != nil {
+		return "", fmt.Errorf("error parsing redirect location: %s", err)
+	}
+
+	code := redirectUrl.Query().Get("code")
+	if code == "" {
+		return "", errors.New("authorization server did not return a one-time SSH code")
+	}
+
+	return code, nil
+}
+
+// GetCredhubCredentials retrieves credentials that a broker stored in CredHub, from the CredHub server linked in the
+// API root. The key's guid and name are not set.
+func (self *apiClient) GetCredhubCredentials(cliConnection plugin.CliConnection, credhubRef string) (pluginModels.ServiceKey, error) {
+	rootResponse, err := self.getFromCfApi("/", cliConnection)
+	if err != nil {
+		return pluginModels.ServiceKey{}, fmt.Errorf("error retrieving API root: %s", err)
+	}
+
+	root := new(resources.RootResource)
+	err = json.Unmarshal(rootResponse, root)
+	if err != nil {
+		return pluginModels.ServiceKey{}, fmt.Errorf("error deserializing API root: %s", err)
+	}
+
+	if root.Links.Credhub.Href == "" {
+		return pluginModels.ServiceKey{}, errors.New("the API does not advertise a CredHub server")
+	}
+
+	config, err := self.getCliConfig(cliConnection)
+	if err != nil {
+		return pluginModels.ServiceKey{}, err
+	}
+
+	query := url.Values{}
+	query.Set("name", credhubRef)
+	query.Set("current", "true")
+
+	credentialResponse, err := self.httpClient.Get(root.Links.Credhub.Href+"/api/v1/data?"+query.Encode(), config.AccessToken, config.SslDisabled)
+	if err != nil {
+		return pluginModels.ServiceKey{}, fmt.Errorf("error retrieving credentials from CredHub: %s", err)
+	}
+
+	credential := new(resources.CredhubDataResource)
+	err = json.Unmarshal(credentialResponse, credential)
+	if err != nil {
+		return pluginModels.ServiceKey{}, fmt.Errorf("error deserializing credentials from CredHub: %s", err)
+	}
+
+	if len(credential.Data) == 0 {
+		return pluginModels.ServiceKey{}, fmt.Errorf("CredHub has no credentials named %s", credhubRef)
+	}
+
+	return credential.Data[0].Value.ToModel(), nil
+}
+
 func (self *apiClient) getFromCfApi(path string, cliConnection plugin.CliConnection) ([]byte, error) {
 	config, err := self.getCliConfig(cliConnection)
 	if err != nil {
@@ -130,6 +512,44 @@ func (self *apiClient) getFromCfApi(path string, cliConnection plugin.CliConnect
 	return self.httpClient.Get(config.ApiEndpoint+path, config.AccessToken, config.SslDisabled)
 }
 
+// getAllPages requests a paginated list and follows the links to the next pages until the last one. handlePage
+// deserializes a page, keeps its results and returns the link to the next page, or an empty string on the last page.
+// The links may be paths, as in v2, or absolute URLs, as in v3.
+func (self *apiClient) getAllPages(cliConnection plugin.CliConnection, path string, description string, handlePage func(response []byte) (string, error)) error {
+	for path != "" {
+		response, err := self.getFromCfApi(path, cliConnection)
+		if err != nil {
+			return fmt.Errorf("error retrieving %s: %s", description, err)
+		}
+
+		nextUrl, err := handlePage(response)
+		if err != nil {
+			return fmt.Errorf("error deserializing %s: %s", description, err)
+		}
+
+		if nextUrl == "" {
+			return nil
+		}
+
+		path, err = pathOf(nextUrl)
+		if err != nil {
+			return fmt.Errorf("error retrieving %s: %s", description, err)
+		}
+	}
+
+	return nil
+}
+
+// pathOf strips the scheme and host from a link, as the requests go to the API endpoint configured in the CLI
+func pathOf(href string) (string, error) {
+	parsedUrl, err := url.Parse(href)
+	if err != nil {
+		return "", fmt.Errorf("unable to parse URL %s: %s", href, err)
+	}
+
+	return parsedUrl.RequestURI(), nil
+}
+
 func (self *apiClient) postToCfApi(path string, body io.Reader, cliConnection plugin.CliConnection) ([]byte, error) {
 	config, err := self.getCliConfig(cliConnection)
 	if err != nil {
@@ -139,6 +559,15 @@ func (self *apiClient) postToCfApi(path string, body io.Reader, cliConnection pl
 	return self.httpClient.Post(config.ApiEndpoint+path, body, config.AccessToken, config.SslDisabled)
 }
 
+func (self *apiClient) deleteFromCfApi(path string, cliConnection plugin.CliConnection) error {
+	config, err := self.getCliConfig(cliConnection)
+	if err != nil {
+		return err
+	}
+
+	return self.httpClient.Delete(config.ApiEndpoint+path, config.AccessToken, config.SslDisabled)
+}
+
 func (self *apiClient) getCliConfig(cliConnection plugin.CliConnection) (*CliConfig, error) {
 	if self.cliConfig == nil {
 		endpoint, err := cliConnection.ApiEndpoint()
@@ -177,19 +606,14 @@ func deserializeInstances(jsonResponse []byte) (string, []pluginModels.ServiceIn
 	return paginatedResources.NextUrl, paginatedResources.ToModel(), nil
 }
 
-func deserializeServiceKeys(keyResponse []byte) ([]pluginModels.ServiceKey, error) {
+func deserializeServiceKeys(keyResponse []byte) (string, []pluginModels.ServiceKey, error) {
 	paginatedResources := new(resources.PaginatedServiceKeyResources)
 	err := json.Unmarshal(keyResponse, paginatedResources)
 	if err != nil {
-		return nil, fmt.Errorf("error deserializing service key response: %s", err)
-	}
-
-	serviceKeys, err := paginatedResources.ToModel()
-	if err != nil {
-		return nil, fmt.Errorf("error converting service key response: %s", err)
+		return "", nil, fmt.Errorf("unable to deserialize service keys: %s", err)
 	}
 
-	return serviceKeys, nil
+	return paginatedResources.NextUrl, paginatedResources.ToModel(), nil
 }
 
 func deserializeServiceKey(keyResponse []byte) (pluginModels.ServiceKey, error) {
@@ -199,12 +623,7 @@ func deserializeServiceKey(keyResponse []byte) (pluginModels.ServiceKey, error)
 		return pluginModels.ServiceKey{}, fmt.Errorf("error deserializing service key response: %s", err)
 	}
 
-	serviceKey, err := resource.ToModel()
-	if err != nil {
-		return pluginModels.ServiceKey{}, fmt.Errorf("error converting service key response: %s", err)
-	}
-
-	return serviceKey, nil
+	return resource.ToModel(), nil
 }
 
 type ServiceKeyRequest struct {
@@ -0,0 +1,440 @@
+package cfmysql
+
+import (
+	"bytes"
+	"code.cloudfoundry.org/cli/plugin"
+	sdkModels "code.cloudfoundry.org/cli/plugin/models"
+	"encoding/json"
+	"errors"
+	"fmt"
+	pluginModels "github.com/andreasf/cf-mysql-plugin/cfmysql/models"
+	"github.com/andreasf/cf-mysql-plugin/cfmysql/resources"
+	"net/url"
+	"strings"
+	"time"
+)
+
+const ServiceKeyJobTimeout = 2 * time.Minute
+const ServiceKeyJobPollInterval = 500 * time.Millisecond
+const ServiceInstanceGuidsPerRequest = 50
+
+// apiClientV3 implements ApiClient with the v3 endpoints of the Cloud Controller, for foundations that have turned off
+// v2. Started apps, one-time SSH codes and the HTTP requests are handled like in the v2 client.
+type apiClientV3 struct {
+	*apiClient
+}
+
+func NewApiClientV3(httpClient HttpWrapper) *apiClientV3 {
+	return &apiClientV3{
+		apiClient: NewApiClient(httpClient),
+	}
+}
+
+func (self *apiClientV3) GetService(cliConnection plugin.CliConnection, spaceGuid string, name string) (pluginModels.ServiceInstance, error) {
+	path := fmt.Sprintf("/v3/service_instances?space_guids=%s&names=%s", spaceGuid, url.QueryEscape(name))
+
+	var instances []pluginModels.ServiceInstance
+	err := self.getAllPages(cliConnection, path, "service instances", func(response []byte) (string, error) {
+		nextUrl, page, err := deserializeInstancesV3(response)
+		instances = append(instances, page...)
+		return nextUrl, err
+	})
+	if err != nil {
+		return pluginModels.ServiceInstance{}, err
+	}
+
+	if len(instances) == 0 {
+		return pluginModels.ServiceInstance{}, fmt.Errorf("%s not found in current space", name)
+	}
+
+	// the label and tags of the service select the format of its credentials
+	instances, err = addPlanDetails(cliConnection, instances[:1], self.getServicePlanDetails)
+	if err != nil {
+		return pluginModels.ServiceInstance{}, err
+	}
+
+	instance := instances[0]
+	path = fmt.Sprintf("/v3/service_credential_bindings?type=app&service_instance_guids=%s", instance.Guid)
+	bindings, err := self.getCredentialBindings(cliConnection, path, "service bindings")
+	if err != nil {
+		return pluginModels.ServiceInstance{}, err
+	}
+
+	for _, binding := range bindings {
+		instance.BoundAppGuids = append(instance.BoundAppGuids, binding.Relationships.App.Data.Guid)
+	}
+
+	return instance, nil
+}
+
+// GetSharedServices returns the instances with the name that other spaces share into the space. Filtering by space
+// lists the instances of the space and those shared into it, so the shared ones are those owned by another space.
+func (self *apiClientV3) GetSharedServices(cliConnection plugin.CliConnection, spaceGuid string, name string) ([]pluginModels.ServiceInstance, error) {
+	path := fmt.Sprintf("/v3/service_instances?space_guids=%s&names=%s&fields%%5Bspace%%5D=guid,name", spaceGuid, url.QueryEscape(name))
+
+	var sharedInstances []pluginModels.ServiceInstance
+	err := self.getAllPages(cliConnection, path, "service instances", func(response []byte) (string, error) {
+		paginatedResources := new(resources.PaginatedServiceInstanceResourcesV3)
+		err := json.Unmarshal(response, paginatedResources)
+		if err != nil {
+			return "", fmt.Errorf("unable to deserialize service instances: %s", err)
+		}
+
+		spaceNames := paginatedResources.SpaceNames()
+		for _, instance := range paginatedResources.ToModel() {
+			if instance.SpaceGuid == spaceGuid {
+				continue
+			}
+
+			instance.SharedFromSpace = spaceNames[instance.SpaceGuid]
+			sharedInstances = append(sharedInstances, instance)
+		}
+
+		return paginatedResources.Pagination.NextUrl(), nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return sharedInstances, nil
+}
+
+func (self *apiClientV3) GetServiceInstances(cliConnection plugin.CliConnection, spaceGuid string) ([]pluginModels.ServiceInstance, error) {
+	return self.getServiceInstances(cliConnection, "/v3/service_instances?space_guids="+spaceGuid)
+}
+
+func (self *apiClientV3) GetOrgServiceInstances(cliConnection plugin.CliConnection, orgGuid string) ([]pluginModels.ServiceInstance, error) {
+	return self.getServiceInstances(cliConnection, "/v3/service_instances?organization_guids="+orgGuid)
+}
+
+func (self *apiClientV3) getServiceInstances(cliConnection plugin.CliConnection, path string) ([]pluginModels.ServiceInstance, error) {
+	var instances []pluginModels.ServiceInstance
+	err := self.getAllPages(cliConnection, path, "service instances", func(response []byte) (string, error) {
+		nextUrl, page, err := deserializeInstancesV3(response)
+		instances = append(instances, page...)
+		return nextUrl, err
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return addPlanDetails(cliConnection, instances, self.getServicePlanDetails)
+}
+
+func (self *apiClientV3) getServicePlanDetails(cliConnection plugin.CliConnection, planGuid string) (servicePlanDetails, error) {
+	planResponse, err := self.getFromCfApi("/v3/service_plans/"+planGuid, cliConnection)
+	if err != nil {
+		return servicePlanDetails{}, fmt.Errorf("error retrieving service plan: %s", err)
+	}
+
+	plan := new(resources.ServicePlanResourceV3)
+	err = json.Unmarshal(planResponse, plan)
+	if err != nil {
+		return servicePlanDetails{}, fmt.Errorf("error deserializing service plan: %s", err)
+	}
+
+	offeringResponse, err := self.getFromCfApi("/v3/service_offerings/"+plan.Relationships.ServiceOffering.Data.Guid, cliConnection)
+	if err != nil {
+		return servicePlanDetails{}, fmt.Errorf("error retrieving service offering: %s", err)
+	}
+
+	offering := new(resources.ServiceOfferingResourceV3)
+	err = json.Unmarshal(offeringResponse, offering)
+	if err != nil {
+		return servicePlanDetails{}, fmt.Errorf("error deserializing service offering: %s", err)
+	}
+
+	return servicePlanDetails{
+		Name:  plan.Name,
+		Label: offering.Name,
+		Tags:  offering.Tags,
+	}, nil
+}
+
+// GetServiceKey looks up the key by name, and then retrieves its credentials, which v3 does not include in the list
+func (self *apiClientV3) GetServiceKey(cliConnection plugin.CliConnection, serviceInstanceGuid string, keyName string) (pluginModels.ServiceKey, bool, error) {
+	path := fmt.Sprintf(
+		"/v3/service_credential_bindings?type=key&service_instance_guids=%s&names=%s",
+		serviceInstanceGuid,
+		url.QueryEscape(keyName),
+	)
+
+	bindings, err := self.getCredentialBindings(cliConnection, path, "service keys")
+	if err != nil {
+		return pluginModels.ServiceKey{}, false, err
+	}
+
+	if len(bindings) == 0 {
+		return pluginModels.ServiceKey{}, false, nil
+	}
+
+	detailsResponse, err := self.getFromCfApi("/v3/service_credential_bindings/"+bindings[0].Guid+"/details", cliConnection)
+	if err != nil {
+		return pluginModels.ServiceKey{}, false, fmt.Errorf("error retrieving service key details: %s", err)
+	}
+
+	details := new(resources.CredentialBindingDetailsResourceV3)
+	err = json.Unmarshal(detailsResponse, details)
+	if err != nil {
+		return pluginModels.ServiceKey{}, false, fmt.Errorf("error deserializing service key details: %s", err)
+	}
+
+	return details.ToModel(bindings[0]), true, nil
+}
+
+// FindServiceKeys returns all keys of the service instances, without their credentials. The instances are listed in
+// batches, to keep the URLs short.
+func (self *apiClientV3) FindServiceKeys(cliConnection plugin.CliConnection, serviceInstanceGuids []string) ([]pluginModels.ServiceKey, error) {
+	var serviceKeys []pluginModels.ServiceKey
+	for start := 0; start < len(serviceInstanceGuids); start += ServiceInstanceGuidsPerRequest {
+		end := start + ServiceInstanceGuidsPerRequest
+		if end > len(serviceInstanceGuids) {
+			end = len(serviceInstanceGuids)
+		}
+
+		path := "/v3/service_credential_bindings?type=key&service_instance_guids=" + strings.Join(serviceInstanceGuids[start:end], ",")
+		bindings, err := self.getCredentialBindings(cliConnection, path, "service keys")
+		if err != nil {
+			return nil, err
+		}
+
+		for _, binding := range bindings {
+			serviceKeys = append(serviceKeys, binding.ToModel())
+		}
+	}
+
+	return serviceKeys, nil
+}
+
+func (self *apiClientV3) getCredentialBindings(cliConnection plugin.CliConnection, path string, description string) ([]resources.CredentialBindingResourceV3, error) {
+	var bindings []resources.CredentialBindingResourceV3
+	err := self.getAllPages(cliConnection, path, description, func(response []byte) (string, error) {
+		nextUrl, page, err := deserializeCredentialBindingsV3(response)
+		bindings = append(bindings, page...)
+		return nextUrl, err
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return bindings, nil
+}
+
+// CreateServiceKey creates the key and, for managed services, waits for the broker to finish. The key is then
+// retrieved with its credentials.
+func (self *apiClientV3) CreateServiceKey(cliConnection plugin.CliConnection, serviceInstanceGuid string, keyName string) (pluginModels.ServiceKey, error) {
+	content := ServiceKeyRequestV3{
+		Type: "key",
+		Name: keyName,
+	}
+	content.Relationships.ServiceInstance.Data.Guid = serviceInstanceGuid
+
+	body, err := json.Marshal(content)
+	if err != nil {
+		return pluginModels.ServiceKey{}, fmt.Errorf("error serializing request body: %s", err)
+	}
+
+	config, err := self.getCliConfig(cliConnection)
+	if err != nil {
+		return pluginModels.ServiceKey{}, err
+	}
+
+	_, jobUrl, err := self.httpClient.PostWithLocation(config.ApiEndpoint+"/v3/service_credential_bindings", bytes.NewBuffer(body), config.AccessToken, config.SslDisabled)
+	if err != nil {
+		return pluginModels.ServiceKey{}, fmt.Errorf("error creating service key: %s", err)
+	}
+
+	if jobUrl != "" {
+		err = self.waitForJob(cliConnection, jobUrl)
+		if err != nil {
+			return pluginModels.ServiceKey{}, fmt.Errorf("error creating service key: %s", err)
+		}
+	}
+
+	serviceKey, found, err := self.GetServiceKey(cliConnection, serviceInstanceGuid, keyName)
+	if err != nil {
+		return pluginModels.ServiceKey{}, err
+	}
+
+	if !found {
+		return pluginModels.ServiceKey{}, fmt.Errorf("service key %s not found after creating it", keyName)
+	}
+
+	return serviceKey, nil
+}
+
+// waitForJob polls an asynchronous operation until it completes, fails, or ServiceKeyJobTimeout has passed
+func (self *apiClientV3) waitForJob(cliConnection plugin.CliConnection, jobUrl string) error {
+	path, err := pathOf(jobUrl)
+	if err != nil {
+		return err
+	}
+
+	deadline := time.Now().Add(ServiceKeyJobTimeout)
+	for {
+		jobResponse, err := self.getFromCfApi(path, cliConnection)
+		if err != nil {
+			return fmt.Errorf("error retrieving job: %s", err)
+		}
+
+		job := new(resources.JobResourceV3)
+		err = json.Unmarshal(jobResponse, job)
+		if err != nil {
+			return fmt.Errorf("error deserializing job: %s", err)
+		}
+
+		switch job.State {
+		case "COMPLETE":
+			return nil
+		case "FAILED":
+			return fmt.Errorf("job failed: %s", job.Error())
+		}
+
+		if time.Now().After(deadline) {
+			return fmt.Errorf("job did not complete within %s", ServiceKeyJobTimeout)
+		}
+		time.Sleep(ServiceKeyJobPollInterval)
+	}
+}
+
+func (self *apiClientV3) DeleteServiceKey(cliConnection plugin.CliConnection, serviceKeyGuid string) error {
+	err := self.deleteFromCfApi("/v3/service_credential_bindings/"+serviceKeyGuid, cliConnection)
+	if err != nil {
+		return fmt.Errorf("error deleting service key: %s", err)
+	}
+
+	return nil
+}
+
+func (self *apiClientV3) GetSpaceApps(cliConnection plugin.CliConnection, spaceGuid string) ([]sdkModels.GetAppsModel, error) {
+	var apps []sdkModels.GetAppsModel
+	err := self.getAllPages(cliConnection, "/v3/apps?space_guids="+spaceGuid, "apps", func(response []byte) (string, error) {
+		paginatedResources := new(resources.PaginatedAppResourcesV3)
+		err := json.Unmarshal(response, paginatedResources)
+		if err != nil {
+			return "", fmt.Errorf("unable to deserialize apps: %s", err)
+		}
+
+		apps = append(apps, paginatedResources.ToModel()...)
+		return paginatedResources.Pagination.NextUrl(), nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return apps, nil
+}
+
+func (self *apiClientV3) GetSpace(cliConnection plugin.CliConnection, orgName string, spaceName string) (pluginModels.Space, error) {
+	orgs, err := self.getNamedResourcesV3(cliConnection, "/v3/organizations?names="+url.QueryEscape(orgName), "organizations")
+	if err != nil {
+		return pluginModels.Space{}, err
+	}
+
+	if len(orgs) == 0 {
+		return pluginModels.Space{}, fmt.Errorf("organization %s not found", orgName)
+	}
+
+	path := fmt.Sprintf("/v3/spaces?organization_guids=%s&names=%s", orgs[0].Guid, url.QueryEscape(spaceName))
+	spaces, err := self.getNamedResourcesV3(cliConnection, path, "spaces")
+	if err != nil {
+		return pluginModels.Space{}, err
+	}
+
+	if len(spaces) == 0 {
+		return pluginModels.Space{}, fmt.Errorf("space %s not found in organization %s", spaceName, orgName)
+	}
+
+	return pluginModels.Space{
+		Guid:    spaces[0].Guid,
+		Name:    spaces[0].Name,
+		OrgGuid: orgs[0].Guid,
+		OrgName: orgs[0].Name,
+	}, nil
+}
+
+func (self *apiClientV3) getNamedResourcesV3(cliConnection plugin.CliConnection, path string, description string) ([]resources.NamedResourceV3, error) {
+	var namedResources []resources.NamedResourceV3
+	err := self.getAllPages(cliConnection, path, description, func(response []byte) (string, error) {
+		paginatedResources := new(resources.PaginatedNamedResourcesV3)
+		err := json.Unmarshal(response, paginatedResources)
+		if err != nil {
+			return "", fmt.Errorf("unable to deserialize %s: %s", description, err)
+		}
+
+		namedResources = append(namedResources, paginatedResources.Resources...)
+		return paginatedResources.Pagination.NextUrl(), nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return namedResources, nil
+}
+
+func (self *apiClientV3) IsSshEnabled(cliConnection plugin.CliConnection, appGuid string) (bool, error) {
+	sshResponse, err := self.getFromCfApi("/v3/apps/"+appGuid+"/ssh_enabled", cliConnection)
+	if err != nil {
+		return false, fmt.Errorf("error retrieving app: %s", err)
+	}
+
+	sshEnabled := new(resources.AppSshEnabledResourceV3)
+	err = json.Unmarshal(sshResponse, sshEnabled)
+	if err != nil {
+		return false, fmt.Errorf("error deserializing app: %s", err)
+	}
+
+	return sshEnabled.Enabled, nil
+}
+
+// GetSshInfo reads the SSH settings from the links of the API root, as /v2/info may be turned off
+func (self *apiClientV3) GetSshInfo(cliConnection plugin.CliConnection) (pluginModels.SshInfo, error) {
+	rootResponse, err := self.getFromCfApi("/", cliConnection)
+	if err != nil {
+		return pluginModels.SshInfo{}, fmt.Errorf("error retrieving API root: %s", err)
+	}
+
+	root := new(resources.RootResource)
+	err = json.Unmarshal(rootResponse, root)
+	if err != nil {
+		return pluginModels.SshInfo{}, fmt.Errorf("error deserializing API root: %s", err)
+	}
+
+	if root.Links.AppSsh.Href == "" {
+		return pluginModels.SshInfo{}, errors.New("the API does not advertise an SSH endpoint")
+	}
+
+	return root.ToSshInfo(), nil
+}
+
+func deserializeInstancesV3(jsonResponse []byte) (string, []pluginModels.ServiceInstance, error) {
+	paginatedResources := new(resources.PaginatedServiceInstanceResourcesV3)
+	err := json.Unmarshal(jsonResponse, paginatedResources)
+	if err != nil {
+		return "", nil, fmt.Errorf("unable to deserialize service instances: %s", err)
+	}
+
+	return paginatedResources.Pagination.NextUrl(), paginatedResources.ToModel(), nil
+}
+
+func deserializeCredentialBindingsV3(jsonResponse []byte) (string, []resources.CredentialBindingResourceV3, error) {
+	paginatedResources := new(resources.PaginatedCredentialBindingResourcesV3)
+	err := json.Unmarshal(jsonResponse, paginatedResources)
+	if err != nil {
+		return "", nil, fmt.Errorf("unable to deserialize service credential bindings: %s", err)
+	}
+
+	return paginatedResources.Pagination.NextUrl(), paginatedResources.Resources, nil
+}
+
+type ServiceKeyRequestV3 struct {
+	Type          string `json:"type"`
+	Name          string `json:"name"`
+	Relationships struct {
+		ServiceInstance struct {
+			Data struct {
+				Guid string `json:"guid"`
+			} `json:"data"`
+		} `json:"service_instance"`
+	} `json:"relationships"`
+}
@@ -0,0 +1,519 @@
+package cfmysql_test
+
+import (
+	. "github.com/andreasf/cf-mysql-plugin/cfmysql"
+
+	"errors"
+	"fmt"
+
+	"code.cloudfoundry.org/cli/plugin/models"
+	"code.cloudfoundry.org/cli/plugin/pluginfakes"
+	"github.com/andreasf/cf-mysql-plugin/cfmysql/cfmysqlfakes"
+	"github.com/andreasf/cf-mysql-plugin/cfmysql/models"
+	"github.com/andreasf/cf-mysql-plugin/cfmysql/test_resources"
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+	"io"
+	"io/ioutil"
+	"strings"
+)
+
+var _ = Describe("ApiClientV3", func() {
+	var cliConnection *pluginfakes.FakeCliConnection
+	var apiClient ApiClient
+	var mockHttp *cfmysqlfakes.FakeHttpWrapper
+	var jobResponses []string
+
+	BeforeEach(func() {
+		cliConnection = new(pluginfakes.FakeCliConnection)
+		mockHttp = new(cfmysqlfakes.FakeHttpWrapper)
+		jobResponses = []string{"test_resources/v3_job_complete.json"}
+
+		mockHttp.GetStub = func(url string, accessToken string, skipSsl bool) ([]byte, error) {
+			switch url {
+			case "https://cf.api.url/":
+				return test_resources.LoadResource("test_resources/v3_root.json"), nil
+			case "https://cf.api.url/v3/service_instances?space_guids=space-guid&names=service-name-a":
+				return test_resources.LoadResource("test_resources/v3_service_instance.json"), nil
+			case "https://cf.api.url/v3/service_instances?space_guids=space-guid&names=no-such-service":
+				return test_resources.LoadResource("test_resources/v3_service_instance_empty.json"), nil
+			case "https://cf.api.url/v3/service_credential_bindings?type=app&service_instance_guids=service-instance-guid-a":
+				return test_resources.LoadResource("test_resources/v3_app_bindings.json"), nil
+			case "https://cf.api.url/v3/service_instances?space_guids=space-guid":
+				return test_resources.LoadResource("test_resources/v3_service_instances.json"), nil
+			case "https://cf.api.url/v3/service_instances?page=2&per_page=2&space_guids=space-guid":
+				return test_resources.LoadResource("test_resources/v3_service_instances_page2.json"), nil
+			case "https://cf.api.url/v3/service_instances?organization_guids=org-guid":
+				return test_resources.LoadResource("test_resources/v3_service_instance.json"), nil
+			case "https://cf.api.url/v3/service_instances?space_guids=space-guid&names=shared-db&fields%5Bspace%5D=guid,name":
+				return test_resources.LoadResource("test_resources/v3_shared_service_instances.json"), nil
+			case "https://cf.api.url/v3/service_plans/service-plan-guid":
+				return test_resources.LoadResource("test_resources/v3_service_plan.json"), nil
+			case "https://cf.api.url/v3/service_plans/redis-service-plan-guid":
+				return test_resources.LoadResource("test_resources/v3_redis_service_plan.json"), nil
+			case "https://cf.api.url/v3/service_offerings/service-offering-guid":
+				return test_resources.LoadResource("test_resources/v3_service_offering.json"), nil
+			case "https://cf.api.url/v3/service_offerings/redis-service-offering-guid":
+				return test_resources.LoadResource("test_resources/v3_redis_service_offering.json"), nil
+			case "https://cf.api.url/v3/service_credential_bindings?type=key&service_instance_guids=service-instance-guid&names=service-key-name":
+				return test_resources.LoadResource("test_resources/v3_service_key.json"), nil
+			case "https://cf.api.url/v3/service_credential_bindings?type=key&service_instance_guids=service-instance-guid&names=no-such-key":
+				return test_resources.LoadResource("test_resources/v3_service_instance_empty.json"), nil
+			case "https://cf.api.url/v3/service_credential_bindings/service-key-guid/details":
+				return test_resources.LoadResource("test_resources/v3_service_key_details.json"), nil
+			case "https://cf.api.url/v3/service_credential_bindings?type=key&service_instance_guids=service-instance-guid-a,service-instance-guid-f":
+				return test_resources.LoadResource("test_resources/v3_service_keys.json"), nil
+			case "https://cf.api.url/v3/service_credential_bindings?page=2&per_page=1&service_instance_guids=service-instance-guid-a%2Cservice-instance-guid-f&type=key":
+				return test_resources.LoadResource("test_resources/v3_service_keys_page2.json"), nil
+			case "https://cf.api.url/v3/apps/app-guid/ssh_enabled":
+				return []byte(`{"enabled": true, "reason": ""}`), nil
+			case "https://cf.api.url/v3/jobs/job-guid":
+				response := jobResponses[0]
+				if len(jobResponses) > 1 {
+					jobResponses = jobResponses[1:]
+				}
+				return test_resources.LoadResource(response), nil
+			case "https://cf.api.url/v3/organizations?names=other-org":
+				return test_resources.LoadResource("test_resources/v3_organizations.json"), nil
+			case "https://cf.api.url/v3/spaces?organization_guids=other-org-guid&names=staging":
+				return test_resources.LoadResource("test_resources/v3_spaces.json"), nil
+			case "https://cf.api.url/v3/spaces?organization_guids=other-org-guid&names=no-such-space":
+				return test_resources.LoadResource("test_resources/v3_service_instance_empty.json"), nil
+			case "https://cf.api.url/v3/apps?space_guids=staging-space-guid":
+				return test_resources.LoadResource("test_resources/v3_space_apps.json"), nil
+			default:
+				return nil, fmt.Errorf("URL not handled in mock: %s", url)
+			}
+		}
+
+		mockHttp.PostWithLocationStub = func(url string, body io.Reader, accessToken string, skipSsl bool) ([]byte, string, error) {
+			switch url {
+			case "https://cf.api.url/v3/service_credential_bindings":
+				return []byte{}, "https://cf.api.url/v3/jobs/job-guid", nil
+			default:
+				return nil, "", fmt.Errorf("URL not handled in mock: %s", url)
+			}
+		}
+
+		apiClient = NewApiClientV3(mockHttp)
+
+		cliConnection.ApiEndpointReturns("https://cf.api.url", nil)
+		cliConnection.AccessTokenReturns("bearer my-secret-token", nil)
+		cliConnection.IsSSLDisabledReturns(true, nil)
+	})
+
+	Describe("GetService", func() {
+		Context("When the API returns a matching service", func() {
+			It("Returns service info with the bound apps and the service label", func() {
+				instance, err := apiClient.GetService(cliConnection, "space-guid", "service-name-a")
+
+				Expect(err).To(BeNil())
+				Expect(instance).To(Equal(models.ServiceInstance{
+					Name:          "service-name-a",
+					Guid:          "service-instance-guid-a",
+					SpaceGuid:     "space-guid",
+					PlanGuid:      "service-plan-guid",
+					Plan:          "db-small",
+					Label:         "p-mysql",
+					Tags:          []string{"mysql", "relational"},
+					LastOperation: "create succeeded",
+					BoundAppGuids: []string{"app-guid-a", "app-guid-b"},
+				}))
+			})
+		})
+
+		Context("When no matching service instance is returned", func() {
+			It("Returns an error", func() {
+				instance, err := apiClient.GetService(cliConnection, "space-guid", "no-such-service")
+
+				Expect(err).To(Equal(errors.New("no-such-service not found in current space")))
+				Expect(instance).To(Equal(models.ServiceInstance{}))
+			})
+		})
+	})
+
+	Describe("GetSharedServices", func() {
+		It("Returns the instances from other spaces that are shared into the space", func() {
+			instances, err := apiClient.GetSharedServices(cliConnection, "space-guid", "shared-db")
+
+			Expect(err).To(BeNil())
+			Expect(instances).To(Equal([]models.ServiceInstance{
+				{
+					Name:            "shared-db",
+					Guid:            "shared-instance-guid",
+					SpaceGuid:       "platform-space-guid",
+					PlanGuid:        "service-plan-guid",
+					Tags:            []string{},
+					LastOperation:   "create succeeded",
+					SharedFromSpace: "platform",
+				},
+			}))
+		})
+
+		It("Only lists the instances of the space, in one request", func() {
+			apiClient.GetSharedServices(cliConnection, "space-guid", "shared-db")
+
+			Expect(mockHttp.GetCallCount()).To(Equal(1))
+			url, _, _ := mockHttp.GetArgsForCall(0)
+			Expect(url).To(ContainSubstring("space_guids=space-guid"))
+		})
+	})
+
+	Describe("GetServiceInstances", func() {
+		Context("When the API returns several pages of instances", func() {
+			It("Returns all instances with plan and service offering details", func() {
+				instances, err := apiClient.GetServiceInstances(cliConnection, "space-guid")
+
+				Expect(err).To(BeNil())
+				Expect(instances).To(HaveLen(3))
+				Expect(instances[0]).To(Equal(models.ServiceInstance{
+					Name:          "database-a",
+					Guid:          "service-instance-guid-a",
+					SpaceGuid:     "space-guid",
+					PlanGuid:      "service-plan-guid",
+					Plan:          "db-small",
+					Label:         "p-mysql",
+					Tags:          []string{"mysql", "relational"},
+					LastOperation: "create succeeded",
+				}))
+				Expect(instances[1].Name).To(Equal("external-db-b"))
+				Expect(instances[1].UserProvided).To(BeTrue())
+				Expect(instances[2].Name).To(Equal("redis-c"))
+				Expect(instances[2].Label).To(Equal("p-redis"))
+				Expect(instances[2].LastOperation).To(Equal("update in progress"))
+			})
+		})
+
+		Context("When a later page cannot be retrieved", func() {
+			It("Returns the error", func() {
+				getStub := mockHttp.GetStub
+				mockHttp.GetStub = func(url string, accessToken string, skipSsl bool) ([]byte, error) {
+					if url == "https://cf.api.url/v3/service_instances?page=2&per_page=2&space_guids=space-guid" {
+						return nil, errors.New("HTTP status 500")
+					}
+					return getStub(url, accessToken, skipSsl)
+				}
+
+				instances, err := apiClient.GetServiceInstances(cliConnection, "space-guid")
+
+				Expect(instances).To(BeNil())
+				Expect(err).To(Equal(errors.New("error retrieving service instances: HTTP status 500")))
+			})
+		})
+
+		Context("When the API returns an error", func() {
+			It("Returns the error", func() {
+				instances, err := apiClient.GetServiceInstances(cliConnection, "unknown-space-guid")
+
+				Expect(instances).To(BeNil())
+				Expect(err).To(MatchError(ContainSubstring("error retrieving service instances")))
+			})
+		})
+	})
+
+	Describe("GetOrgServiceInstances", func() {
+		It("Returns the instances of all spaces in the org", func() {
+			instances, err := apiClient.GetOrgServiceInstances(cliConnection, "org-guid")
+
+			Expect(err).To(BeNil())
+			Expect(instances).To(HaveLen(1))
+			Expect(instances[0].Name).To(Equal("service-name-a"))
+			Expect(instances[0].Plan).To(Equal("db-small"))
+		})
+	})
+
+	Describe("FindServiceKeys", func() {
+		Context("When the API returns several pages of keys", func() {
+			It("Returns all keys of the given instances", func() {
+				serviceKeys, err := apiClient.FindServiceKeys(cliConnection, []string{"service-instance-guid-a", "service-instance-guid-f"})
+
+				Expect(err).To(BeNil())
+				Expect(serviceKeys).To(Equal([]models.ServiceKey{
+					{
+						Guid:                "service-key-guid-a",
+						Name:                "cf-mysql",
+						ServiceInstanceGuid: "service-instance-guid-a",
+					},
+					{
+						Guid:                "service-key-guid-f",
+						Name:                "cf-mysql",
+						ServiceInstanceGuid: "service-instance-guid-f",
+					},
+				}))
+			})
+		})
+
+		Context("When there are many instances", func() {
+			It("Lists them in batches", func() {
+				mockHttp.GetReturns(test_resources.LoadResource("test_resources/v3_service_instance_empty.json"), nil)
+
+				var guids []string
+				for i := 0; i < ServiceInstanceGuidsPerRequest+10; i++ {
+					guids = append(guids, fmt.Sprintf("guid-%d", i))
+				}
+
+				_, err := apiClient.FindServiceKeys(cliConnection, guids)
+
+				Expect(err).To(BeNil())
+				Expect(mockHttp.GetCallCount()).To(Equal(2))
+				firstUrl, _, _ := mockHttp.GetArgsForCall(0)
+				Expect(firstUrl).To(HaveSuffix("," + guids[ServiceInstanceGuidsPerRequest-1]))
+				secondUrl, _, _ := mockHttp.GetArgsForCall(1)
+				Expect(secondUrl).To(Equal("https://cf.api.url/v3/service_credential_bindings?type=key&service_instance_guids=" + strings.Join(guids[ServiceInstanceGuidsPerRequest:], ",")))
+			})
+		})
+	})
+
+	Describe("GetServiceKey", func() {
+		Context("When the API returns a key", func() {
+			It("Returns the key with the credentials from its details", func() {
+				serviceKey, found, err := apiClient.GetServiceKey(cliConnection, "service-instance-guid", "service-key-name")
+
+				Expect(found).To(BeTrue())
+				Expect(err).To(BeNil())
+				Expect(serviceKey).To(Equal(models.ServiceKey{
+					Guid:                "service-key-guid",
+					Name:                "service-key-name",
+					ServiceInstanceGuid: "service-instance-guid",
+					Credentials: map[string]interface{}{
+						"jdbcUrl":  "jdbc-url",
+						"uri":      "uri",
+						"name":     "db-name",
+						"hostname": "hostname",
+						"port":     float64(3306),
+						"username": "username",
+						"password": "password",
+						"tls": map[string]interface{}{
+							"cert": map[string]interface{}{
+								"ca": "ca-certificate",
+							},
+						},
+					},
+				}))
+			})
+		})
+
+		Context("When no key was found for the given service guid and key name", func() {
+			It("Returns not found", func() {
+				serviceKey, found, err := apiClient.GetServiceKey(cliConnection, "service-instance-guid", "no-such-key")
+
+				Expect(found).To(BeFalse())
+				Expect(err).To(BeNil())
+				Expect(serviceKey).To(Equal(models.ServiceKey{}))
+			})
+		})
+	})
+
+	Describe("CreateServiceKey", func() {
+		Context("When the key is created asynchronously", func() {
+			It("Waits for the job and returns the key", func() {
+				jobResponses = []string{
+					"test_resources/v3_job_processing.json",
+					"test_resources/v3_job_complete.json",
+				}
+
+				serviceKey, err := apiClient.CreateServiceKey(cliConnection, "service-instance-guid", "service-key-name")
+
+				Expect(err).To(BeNil())
+				Expect(serviceKey.Guid).To(Equal("service-key-guid"))
+				Expect(serviceKey.Credentials).To(HaveKeyWithValue("username", "username"))
+
+				Expect(mockHttp.PostWithLocationCallCount()).To(Equal(1))
+				url, body, accessToken, sslDisabled := mockHttp.PostWithLocationArgsForCall(0)
+				Expect(url).To(Equal("https://cf.api.url/v3/service_credential_bindings"))
+				Expect(ioutil.ReadAll(body)).To(MatchJSON(`{
+					"type": "key",
+					"name": "service-key-name",
+					"relationships": {"service_instance": {"data": {"guid": "service-instance-guid"}}}
+				}`))
+				Expect(accessToken).To(Equal("bearer my-secret-token"))
+				Expect(sslDisabled).To(BeTrue())
+
+				var jobRequests int
+				for i := 0; i < mockHttp.GetCallCount(); i++ {
+					url, _, _ := mockHttp.GetArgsForCall(i)
+					if url == "https://cf.api.url/v3/jobs/job-guid" {
+						jobRequests++
+					}
+				}
+				Expect(jobRequests).To(Equal(2))
+			})
+		})
+
+		Context("When the key is created synchronously", func() {
+			It("Returns the key without polling", func() {
+				mockHttp.PostWithLocationReturns([]byte("{}"), "", nil)
+				mockHttp.PostWithLocationStub = nil
+
+				serviceKey, err := apiClient.CreateServiceKey(cliConnection, "service-instance-guid", "service-key-name")
+
+				Expect(err).To(BeNil())
+				Expect(serviceKey.Guid).To(Equal("service-key-guid"))
+			})
+		})
+
+		Context("When the job fails", func() {
+			It("Returns the errors of the job", func() {
+				jobResponses = []string{"test_resources/v3_job_failed.json"}
+
+				_, err := apiClient.CreateServiceKey(cliConnection, "service-instance-guid", "service-key-name")
+
+				Expect(err).To(Equal(errors.New("error creating service key: job failed: Service broker error: quota exceeded")))
+			})
+		})
+	})
+
+	Describe("DeleteServiceKey", func() {
+		It("Deletes the key by its guid", func() {
+			err := apiClient.DeleteServiceKey(cliConnection, "service-key-guid")
+
+			Expect(err).To(BeNil())
+			url, accessToken, sslDisabled := mockHttp.DeleteArgsForCall(0)
+			Expect(url).To(Equal("https://cf.api.url/v3/service_credential_bindings/service-key-guid"))
+			Expect(accessToken).To(Equal("bearer my-secret-token"))
+			Expect(sslDisabled).To(BeTrue())
+		})
+	})
+
+	Describe("GetSpace", func() {
+		It("Looks up the space by org and space name", func() {
+			space, err := apiClient.GetSpace(cliConnection, "other-org", "staging")
+
+			Expect(err).To(BeNil())
+			Expect(space).To(Equal(models.Space{
+				Guid:    "staging-space-guid",
+				Name:    "staging",
+				OrgGuid: "other-org-guid",
+				OrgName: "other-org",
+			}))
+		})
+
+		Context("When the space does not exist", func() {
+			It("Returns an error", func() {
+				_, err := apiClient.GetSpace(cliConnection, "other-org", "no-such-space")
+
+				Expect(err).To(Equal(errors.New("space no-such-space not found in organization other-org")))
+			})
+		})
+	})
+
+	Describe("GetSpaceApps", func() {
+		It("Returns the apps of the space", func() {
+			apps, err := apiClient.GetSpaceApps(cliConnection, "staging-space-guid")
+
+			Expect(err).To(BeNil())
+			Expect(apps).To(Equal([]plugin_models.GetAppsModel{
+				{Name: "staging-app", Guid: "staging-app-guid", State: "started"},
+				{Name: "stopped-app", Guid: "stopped-app-guid", State: "stopped"},
+			}))
+		})
+	})
+
+	Describe("IsSshEnabled", func() {
+		It("Returns the SSH setting of the app", func() {
+			enabled, err := apiClient.IsSshEnabled(cliConnection, "app-guid")
+
+			Expect(err).To(BeNil())
+			Expect(enabled).To(BeTrue())
+		})
+	})
+
+	Describe("GetSshInfo", func() {
+		It("Returns the SSH settings linked from the API root", func() {
+			sshInfo, err := apiClient.GetSshInfo(cliConnection)
+
+			Expect(err).To(BeNil())
+			Expect(sshInfo).To(Equal(models.SshInfo{
+				Endpoint:           "ssh.cf.api.url:2222",
+				HostKeyFingerprint: "a6:d1:08:0b:b0:cb:9b:5f:c4:ba:44:2a:97:26:19:8a",
+				OauthClient:        "ssh-proxy",
+				TokenEndpoint:      "https://uaa.cf.api.url",
+			}))
+		})
+
+		Context("When the API does not advertise SSH", func() {
+			It("Returns an error", func() {
+				mockHttp.GetStub = nil
+				mockHttp.GetReturns([]byte(`{"links": {"uaa": {"href": "https://uaa.cf.api.url"}}}`), nil)
+
+				_, err := apiClient.GetSshInfo(cliConnection)
+
+				Expect(err).To(Equal(errors.New("the API does not advertise an SSH endpoint")))
+			})
+		})
+	})
+})
+
+var _ = Describe("VersionedApiClient", func() {
+	var cliConnection *pluginfakes.FakeCliConnection
+	var apiClient ApiClient
+	var mockHttp *cfmysqlfakes.FakeHttpWrapper
+	var root string
+
+	BeforeEach(func() {
+		cliConnection = new(pluginfakes.FakeCliConnection)
+		mockHttp = new(cfmysqlfakes.FakeHttpWrapper)
+		mockHttp.GetStub = func(url string, accessToken string, skipSsl bool) ([]byte, error) {
+			switch url {
+			case "https://cf.api.url/":
+				return test_resources.LoadResource(root), nil
+			case "https://cf.api.url/v2/apps/app-guid":
+				return test_resources.LoadResource("test_resources/app.json"), nil
+			case "https://cf.api.url/v3/apps/app-guid/ssh_enabled":
+				return []byte(`{"enabled": false, "reason": "Disabled globally"}`), nil
+			default:
+				return nil, fmt.Errorf("URL not handled in mock: %s", url)
+			}
+		}
+
+		apiClient = NewVersionedApiClient(mockHttp)
+
+		cliConnection.ApiEndpointReturns("https://cf.api.url", nil)
+		cliConnection.AccessTokenReturns("bearer my-secret-token", nil)
+		cliConnection.IsSSLDisabledReturns(true, nil)
+	})
+
+	Context("When the API root advertises v2", func() {
+		It("Uses the v2 API", func() {
+			root = "test_resources/v2_root.json"
+
+			enabled, err := apiClient.IsSshEnabled(cliConnection, "app-guid")
+
+			Expect(err).To(BeNil())
+			Expect(enabled).To(BeTrue())
+		})
+	})
+
+	Context("When the API root advertises only v3", func() {
+		It("Uses the v3 API", func() {
+			root = "test_resources/v3_root.json"
+
+			enabled, err := apiClient.IsSshEnabled(cliConnection, "app-guid")
+
+			Expect(err).To(BeNil())
+			Expect(enabled).To(BeFalse())
+		})
+	})
+
+	It("Requests the API root only once", func() {
+		root = "test_resources/v3_root.json"
+
+		apiClient.IsSshEnabled(cliConnection, "app-guid")
+		apiClient.IsSshEnabled(cliConnection, "app-guid")
+
+		Expect(mockHttp.GetCallCount()).To(Equal(3))
+		url, _, _ := mockHttp.GetArgsForCall(0)
+		Expect(url).To(Equal("https://cf.api.url/"))
+	})
+
+	Context("When the API root cannot be retrieved", func() {
+		It("Returns an error", func() {
+			mockHttp.GetStub = nil
+			mockHttp.GetReturns(nil, errors.New("HTTP status 502"))
+
+			_, err := apiClient.IsSshEnabled(cliConnection, "app-guid")
+
+			Expect(err).To(Equal(errors.New("unable to determine API version: HTTP status 502")))
+		})
+	})
+})
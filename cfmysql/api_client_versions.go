@@ -0,0 +1,186 @@
+package cfmysql
+
+import (
+	"code.cloudfoundry.org/cli/plugin"
+	sdkModels "code.cloudfoundry.org/cli/plugin/models"
+	"encoding/json"
+	"errors"
+	"fmt"
+	pluginModels "github.com/andreasf/cf-mysql-plugin/cfmysql/models"
+	"github.com/andreasf/cf-mysql-plugin/cfmysql/resources"
+	"sync"
+)
+
+// NewVersionedApiClient returns an ApiClient that uses the v2 API if the API root advertises it, and the v3 API
+// otherwise. The API root is requested once, on first use.
+func NewVersionedApiClient(httpClient HttpWrapper) ApiClient {
+	return &versionedApiClient{
+		v2: NewApiClient(httpClient),
+		v3: NewApiClientV3(httpClient),
+	}
+}
+
+type versionedApiClient struct {
+	v2       *apiClient
+	v3       *apiClientV3
+	selected ApiClient
+	mutex    sync.Mutex
+}
+
+func (self *versionedApiClient) client(cliConnection plugin.CliConnection) (ApiClient, error) {
+	self.mutex.Lock()
+	defer self.mutex.Unlock()
+
+	if self.selected != nil {
+		return self.selected, nil
+	}
+
+	rootResponse, err := self.v2.getFromCfApi("/", cliConnection)
+	if err != nil {
+		return nil, fmt.Errorf("unable to determine API version: %s", err)
+	}
+
+	root := new(resources.RootResource)
+	err = json.Unmarshal(rootResponse, root)
+	if err != nil {
+		return nil, fmt.Errorf("unable to determine API version: %s", err)
+	}
+
+	switch {
+	case root.Links.CloudControllerV2 != nil:
+		self.selected = self.v2
+	case root.Links.CloudControllerV3 != nil:
+		self.selected = self.v3
+	default:
+		return nil, errors.New("unable to determine API version: the API root advertises neither v2 nor v3")
+	}
+
+	return self.selected, nil
+}
+
+// GetStartedApps uses the CLI, which handles the API version itself
+func (self *versionedApiClient) GetStartedApps(cliConnection plugin.CliConnection) ([]sdkModels.GetAppsModel, error) {
+	return self.v2.GetStartedApps(cliConnection)
+}
+
+func (self *versionedApiClient) GetSpaceApps(cliConnection plugin.CliConnection, spaceGuid string) ([]sdkModels.GetAppsModel, error) {
+	client, err := self.client(cliConnection)
+	if err != nil {
+		return nil, err
+	}
+
+	return client.GetSpaceApps(cliConnection, spaceGuid)
+}
+
+func (self *versionedApiClient) GetSpace(cliConnection plugin.CliConnection, orgName string, spaceName string) (pluginModels.Space, error) {
+	client, err := self.client(cliConnection)
+	if err != nil {
+		return pluginModels.Space{}, err
+	}
+
+	return client.GetSpace(cliConnection, orgName, spaceName)
+}
+
+func (self *versionedApiClient) GetService(cliConnection plugin.CliConnection, spaceGuid string, name string) (pluginModels.ServiceInstance, error) {
+	client, err := self.client(cliConnection)
+	if err != nil {
+		return pluginModels.ServiceInstance{}, err
+	}
+
+	return client.GetService(cliConnection, spaceGuid, name)
+}
+
+func (self *versionedApiClient) GetSharedServices(cliConnection plugin.CliConnection, spaceGuid string, name string) ([]pluginModels.ServiceInstance, error) {
+	client, err := self.client(cliConnection)
+	if err != nil {
+		return nil, err
+	}
+
+	return client.GetSharedServices(cliConnection, spaceGuid, name)
+}
+
+func (self *versionedApiClient) GetServiceInstances(cliConnection plugin.CliConnection, spaceGuid string) ([]pluginModels.ServiceInstance, error) {
+	client, err := self.client(cliConnection)
+	if err != nil {
+		return nil, err
+	}
+
+	return client.GetServiceInstances(cliConnection, spaceGuid)
+}
+
+func (self *versionedApiClient) GetOrgServiceInstances(cliConnection plugin.CliConnection, orgGuid string) ([]pluginModels.ServiceInstance, error) {
+	client, err := self.client(cliConnection)
+	if err != nil {
+		return nil, err
+	}
+
+	return client.GetOrgServiceInstances(cliConnection, orgGuid)
+}
+
+func (self *versionedApiClient) GetServiceKey(cliConnection plugin.CliConnection, serviceInstanceGuid string, keyName string) (pluginModels.ServiceKey, bool, error) {
+	client, err := self.client(cliConnection)
+	if err != nil {
+		return pluginModels.ServiceKey{}, false, err
+	}
+
+	return client.GetServiceKey(cliConnection, serviceInstanceGuid, keyName)
+}
+
+func (self *versionedApiClient) FindServiceKeys(cliConnection plugin.CliConnection, serviceInstanceGuids []string) ([]pluginModels.ServiceKey, error) {
+	client, err := self.client(cliConnection)
+	if err != nil {
+		return nil, err
+	}
+
+	return client.FindServiceKeys(cliConnection, serviceInstanceGuids)
+}
+
+func (self *versionedApiClient) CreateServiceKey(cliConnection plugin.CliConnection, serviceInstanceGuid string, keyName string) (pluginModels.ServiceKey, error) {
+	client, err := self.client(cliConnection)
+	if err != nil {
+		return pluginModels.ServiceKey{}, err
+	}
+
+	return client.CreateServiceKey(cliConnection, serviceInstanceGuid, keyName)
+}
+
+func (self *versionedApiClient) DeleteServiceKey(cliConnection plugin.CliConnection, serviceKeyGuid string) error {
+	client, err := self.client(cliConnection)
+	if err != nil {
+		return err
+	}
+
+	return client.DeleteServiceKey(cliConnection, serviceKeyGuid)
+}
+
+func (self *versionedApiClient) IsSshEnabled(cliConnection plugin.CliConnection, appGuid string) (bool, error) {
+	client, err := self.client(cliConnection)
+	if err != nil {
+		return false, err
+	}
+
+	return client.IsSshEnabled(cliConnection, appGuid)
+}
+
+func (self *versionedApiClient) GetSshInfo(cliConnection plugin.CliConnection) (pluginModels.SshInfo, error) {
+	client, err := self.client(cliConnection)
+	if err != nil {
+		return pluginModels.SshInfo{}, err
+	}
+
+	return client.GetSshInfo(cliConnection)
+}
+
+// GetCredhubCredentials does not depend on the API version
+func (self *versionedApiClient) GetCredhubCredentials(cliConnection plugin.CliConnection, credhubRef string) (pluginModels.ServiceKey, error) {
+	return self.v2.GetCredhubCredentials(cliConnection, credhubRef)
+}
+
+func (self *versionedApiClient) GetSshCode(cliConnection plugin.CliConnection, sshInfo pluginModels.SshInfo) (string, error) {
+	client, err := self.client(cliConnection)
+	if err != nil {
+		return "", err
+	}
+
+	return client.GetSshCode(cliConnection, sshInfo)
+}
@@ -0,0 +1,224 @@
+package cfmysql
+
+import (
+	"flag"
+	"fmt"
+	"io/ioutil"
+	"strconv"
+	"text/tabwriter"
+	"time"
+)
+
+// startBackgroundTunnel runs 'cf mysql-tunnel' in a detached process on a port chosen up front, and records it in the
+// state file
+func (self *MysqlPlugin) startBackgroundTunnel(args []string) {
+	flags := flag.NewFlagSet("mysql-tunnel", flag.ContinueOnError)
+	flags.SetOutput(ioutil.Discard)
+	localAddress := addLocalAddressFlags(flags)
+	app := addAppChoiceFlags(flags)
+	target := addSpaceTargetFlags(flags)
+
+	err := flags.Parse(args)
+	if err != nil || flags.NArg() != 1 || !localAddress.normalize() || !app.valid() || !target.valid(*app) {
+		fmt.Fprint(self.Err, self.FormatUsage())
+		self.setErrorExit()
+		return
+	}
+
+	dbName := flags.Arg(0)
+	localAddresses, ok := self.localAddressesFor(1, *localAddress)
+	if !ok {
+		return
+	}
+	*localAddress = localAddresses[0]
+
+	cfArgs := []string{"mysql-tunnel", "--local-host", localAddress.Host, "--local-port", strconv.Itoa(localAddress.Port)}
+	if localAddress.Socket != "" {
+		cfArgs = []string{"mysql-tunnel", "--local-socket", localAddress.Socket}
+	}
+	if app.Name != "" {
+		cfArgs = append(cfArgs, "--app", app.Name)
+	}
+	if app.Push {
+		cfArgs = append(cfArgs, "--push-app")
+	}
+	if target.Org != "" {
+		cfArgs = append(cfArgs, "--org", target.Org)
+	}
+	if target.Space != "" {
+		cfArgs = append(cfArgs, "--space", target.Space)
+	}
+	cfArgs = append(cfArgs, dbName)
+
+	fmt.Fprintf(self.Out, "Starting SSH tunnel to %s in the background...\n", dbName)
+	tunnel, err := self.TunnelDaemon.Start(dbName, *localAddress, cfArgs)
+	if err != nil {
+		fmt.Fprintf(self.Err, "FAILED\nUnable to start SSH tunnel to '%s': %s\n", dbName, err)
+		self.setErrorExit()
+		return
+	}
+
+	unlock, err := self.TunnelStateStore.Lock()
+	if err == nil {
+		var tunnels []BackgroundTunnel
+		tunnels, err = self.TunnelStateStore.Load()
+		if err == nil {
+			err = self.TunnelStateStore.Save(append(tunnels, tunnel))
+		}
+		unlock()
+	}
+	if err != nil {
+		fmt.Fprintf(self.Err, "FAILED\nUnable to record the tunnel, stopping it: %s\n", err)
+		self.TunnelDaemon.Stop(tunnel.Pid)
+		self.setErrorExit()
+		return
+	}
+
+	fmt.Fprintf(self.Out, "SSH tunnel to %s is open.\n\n", dbName)
+	if tunnel.Socket != "" {
+		fmt.Fprintf(self.Out, "Socket:   %s\n", tunnel.Socket)
+	} else {
+		fmt.Fprintf(self.Out, "Host:     %s\n", tunnel.Host)
+		fmt.Fprintf(self.Out, "Port:     %d\n", tunnel.Port)
+	}
+	fmt.Fprintf(self.Out, "PID:      %d\n", tunnel.Pid)
+	fmt.Fprintf(self.Out, "Log:      %s\n\n", tunnel.LogFile)
+	if tunnel.Socket != "" {
+		fmt.Fprintf(self.Out, "Stop it with 'cf mysql-tunnel stop %s'.\n", dbName)
+	} else {
+		fmt.Fprintf(self.Out, "Print the credentials with 'cf mysql-env --local-port %d %s', stop it with 'cf mysql-tunnel stop %s'.\n", tunnel.Port, dbName, dbName)
+	}
+}
+
+func (self *MysqlPlugin) listBackgroundTunnels(args []string) {
+	if len(args) > 0 {
+		fmt.Fprint(self.Err, self.FormatUsage())
+		self.setErrorExit()
+		return
+	}
+
+	unlock, ok := self.lockTunnelState()
+	if !ok {
+		return
+	}
+	tunnels, ok := self.loadRunningTunnels()
+	unlock()
+	if !ok {
+		return
+	}
+
+	if len(tunnels) == 0 {
+		fmt.Fprintln(self.Out, "No background SSH tunnels found.")
+		return
+	}
+
+	table := tabwriter.NewWriter(self.Out, 0, 0, 3, ' ', 0)
+	fmt.Fprintln(table, "service\taddress\tpid\tstarted")
+	for _, tunnel := range tunnels {
+		fmt.Fprintf(table, "%s\t%s\t%d\t%s\n", tunnel.Service, tunnel.LocalAddress(), tunnel.Pid, tunnel.StartedAt.Local().Format(time.RFC3339))
+	}
+	table.Flush()
+}
+
+func (self *MysqlPlugin) stopBackgroundTunnels(args []string) {
+	flags := flag.NewFlagSet("mysql-tunnel", flag.ContinueOnError)
+	flags.SetOutput(ioutil.Discard)
+	all := flags.Bool("all", false, "")
+
+	err := flags.Parse(args)
+	if err != nil || *all == (flags.NArg() == 1) || flags.NArg() > 1 {
+		fmt.Fprint(self.Err, self.FormatUsage())
+		self.setErrorExit()
+		return
+	}
+
+	unlock, ok := self.lockTunnelState()
+	if !ok {
+		return
+	}
+	defer unlock()
+
+	tunnels, ok := self.loadRunningTunnels()
+	if !ok {
+		return
+	}
+
+	var remaining []BackgroundTunnel
+	stopped := 0
+	failed := false
+	for _, tunnel := range tunnels {
+		if !*all && tunnel.Service != flags.Arg(0) {
+			remaining = append(remaining, tunnel)
+			continue
+		}
+
+		fmt.Fprintf(self.Out, "Stopping SSH tunnel to %s on %s...\n", tunnel.Service, tunnel.LocalAddress())
+		err := self.TunnelDaemon.Stop(tunnel.Pid)
+		if err != nil {
+			fmt.Fprintf(self.Err, "FAILED\nUnable to stop process %d: %s\n", tunnel.Pid, err)
+			remaining = append(remaining, tunnel)
+			failed = true
+			continue
+		}
+		stopped++
+	}
+
+	if stopped == 0 && !failed {
+		if *all {
+			fmt.Fprintln(self.Out, "No background SSH tunnels found.")
+		} else {
+			fmt.Fprintf(self.Err, "FAILED\nNo background SSH tunnel to '%s' found\n", flags.Arg(0))
+			self.setErrorExit()
+		}
+		return
+	}
+
+	err = self.TunnelStateStore.Save(remaining)
+	if err != nil {
+		fmt.Fprintf(self.Err, "FAILED\nUnable to update the tunnel list: %s\n", err)
+		failed = true
+	}
+
+	if failed {
+		self.setErrorExit()
+		return
+	}
+
+	fmt.Fprintln(self.Out, "OK")
+}
+
+// lockTunnelState locks the state file, so that commands running at the same time don't overwrite each other's changes
+func (self *MysqlPlugin) lockTunnelState() (func(), bool) {
+	unlock, err := self.TunnelStateStore.Lock()
+	if err != nil {
+		fmt.Fprintf(self.Err, "FAILED\nUnable to load the tunnel list: %s\n", err)
+		self.setErrorExit()
+		return nil, false
+	}
+
+	return unlock, true
+}
+
+// loadRunningTunnels returns the tunnels in the state file whose process is still running. Tunnels that closed on their
+// own are removed from the state file. The caller holds the lock on the state file.
+func (self *MysqlPlugin) loadRunningTunnels() ([]BackgroundTunnel, bool) {
+	tunnels, err := self.TunnelStateStore.Load()
+	if err != nil {
+		fmt.Fprintf(self.Err, "FAILED\nUnable to load the tunnel list: %s\n", err)
+		self.setErrorExit()
+		return nil, false
+	}
+
+	var running []BackgroundTunnel
+	for _, tunnel := range tunnels {
+		if self.TunnelDaemon.IsRunning(tunnel) {
+			running = append(running, tunnel)
+		}
+	}
+
+	if len(running) < len(tunnels) {
+		self.TunnelStateStore.Save(running)
+	}
+
+	return running, true
+}
@@ -0,0 +1,315 @@
+package cfmysql_test
+
+import (
+	"errors"
+	. "github.com/andreasf/cf-mysql-plugin/cfmysql"
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+	"github.com/onsi/gomega/gbytes"
+	"time"
+)
+
+var _ = Describe("Background tunnels", func() {
+	var tunnelA BackgroundTunnel
+	var tunnelB BackgroundTunnel
+
+	BeforeEach(func() {
+		tunnelA = BackgroundTunnel{
+			Service:   "database-a",
+			Host:      "127.0.0.1",
+			Port:      13306,
+			Pid:       4242,
+			StartedAt: time.Date(2026, 10, 18, 9, 30, 0, 0, time.Local),
+			LogFile:   "/home/user/.cf/mysql-plugin/tunnel-database-a-13306.log",
+		}
+		tunnelB = BackgroundTunnel{
+			Service:   "database-b",
+			Host:      "::1",
+			Port:      13307,
+			Pid:       4343,
+			StartedAt: time.Date(2026, 10, 18, 10, 0, 0, 0, time.Local),
+			LogFile:   "/home/user/.cf/mysql-plugin/tunnel-database-b-13307.log",
+		}
+	})
+
+	Context("When calling 'cf mysql-tunnel start'", func() {
+		It("Starts the tunnel on a free port in the background and records it", func() {
+			mysqlPlugin, mocks := NewPluginAndMocks()
+			mocks.PortFinder.GetPortReturns(13306, nil)
+			mocks.TunnelDaemon.StartReturns(tunnelA, nil)
+			mocks.TunnelStateStore.LoadReturns([]BackgroundTunnel{tunnelB}, nil)
+
+			mysqlPlugin.Run(mocks.CliConnection, []string{"mysql-tunnel", "start", "database-a"})
+
+			Expect(mocks.TunnelDaemon.StartCallCount()).To(Equal(1))
+			serviceName, localAddress, cfArgs := mocks.TunnelDaemon.StartArgsForCall(0)
+			Expect(serviceName).To(Equal("database-a"))
+			Expect(localAddress).To(Equal(LocalAddress{Host: "127.0.0.1", Port: 13306}))
+			Expect(cfArgs).To(Equal([]string{"mysql-tunnel", "--local-host", "127.0.0.1", "--local-port", "13306", "database-a"}))
+
+			Expect(mocks.TunnelStateStore.SaveCallCount()).To(Equal(1))
+			Expect(mocks.TunnelStateStore.SaveArgsForCall(0)).To(Equal([]BackgroundTunnel{tunnelB, tunnelA}))
+
+			Expect(mocks.Out).To(gbytes.Say("Starting SSH tunnel to database-a in the background...\n"))
+			Expect(mocks.Out).To(gbytes.Say("SSH tunnel to database-a is open.\n\n"))
+			Expect(mocks.Out).To(gbytes.Say("Host:     127.0.0.1\n"))
+			Expect(mocks.Out).To(gbytes.Say("Port:     13306\n"))
+			Expect(mocks.Out).To(gbytes.Say("PID:      4242\n"))
+			Expect(mocks.Out).To(gbytes.Say("Log:      /home/user/.cf/mysql-plugin/tunnel-database-a-13306.log\n"))
+			Expect(mocks.Out).To(gbytes.Say("'cf mysql-tunnel stop database-a'"))
+			Expect(mysqlPlugin.GetExitCode()).To(Equal(0))
+		})
+
+		It("Passes the app flags on to the background process", func() {
+			mysqlPlugin, mocks := NewPluginAndMocks()
+			mocks.PortFinder.CheckPortReturns(nil)
+			mocks.TunnelDaemon.StartReturns(tunnelB, nil)
+
+			mysqlPlugin.Run(mocks.CliConnection, []string{"mysql-tunnel", "start", "--local-host", "::1", "--local-port", "13307", "--push-app", "database-b"})
+
+			Expect(mocks.PortFinder.GetPortCallCount()).To(Equal(0))
+			Expect(mocks.PortFinder.CheckPortArgsForCall(0)).To(Equal(LocalAddress{Host: "::1", Port: 13307}))
+			_, _, cfArgs := mocks.TunnelDaemon.StartArgsForCall(0)
+			Expect(cfArgs).To(Equal([]string{"mysql-tunnel", "--local-host", "::1", "--local-port", "13307", "--push-app", "database-b"}))
+		})
+
+		It("Passes the org and space on to the background process", func() {
+			mysqlPlugin, mocks := NewPluginAndMocks()
+			mocks.PortFinder.GetPortReturns(13306, nil)
+			mocks.TunnelDaemon.StartReturns(tunnelA, nil)
+
+			mysqlPlugin.Run(mocks.CliConnection, []string{"mysql-tunnel", "start", "--org", "other-org", "--space", "staging", "database-a"})
+
+			Expect(mocks.CfService.TargetSpaceCallCount()).To(Equal(0))
+			_, _, cfArgs := mocks.TunnelDaemon.StartArgsForCall(0)
+			Expect(cfArgs).To(Equal([]string{"mysql-tunnel", "--local-host", "127.0.0.1", "--local-port", "13306", "--org", "other-org", "--space", "staging", "database-a"}))
+		})
+
+		It("Starts the tunnel on a Unix socket", func() {
+			mysqlPlugin, mocks := NewPluginAndMocks()
+			tunnelA.Host = ""
+			tunnelA.Port = 0
+			tunnelA.Socket = "/tmp/mysql.sock"
+			mocks.TunnelDaemon.StartReturns(tunnelA, nil)
+
+			mysqlPlugin.Run(mocks.CliConnection, []string{"mysql-tunnel", "start", "--local-socket", "/tmp/mysql.sock", "database-a"})
+
+			Expect(mocks.PortFinder.GetPortCallCount()).To(Equal(0))
+			Expect(mocks.PortFinder.CheckPortArgsForCall(0).Socket).To(Equal("/tmp/mysql.sock"))
+			_, localAddress, cfArgs := mocks.TunnelDaemon.StartArgsForCall(0)
+			Expect(localAddress.Socket).To(Equal("/tmp/mysql.sock"))
+			Expect(cfArgs).To(Equal([]string{"mysql-tunnel", "--local-socket", "/tmp/mysql.sock", "database-a"}))
+
+			Expect(mocks.Out).To(gbytes.Say("Socket:   /tmp/mysql.sock\n"))
+			Expect(mocks.Out).To(gbytes.Say("PID:      4242\n"))
+			Expect(mocks.Out).To(gbytes.Say("Stop it with 'cf mysql-tunnel stop database-a'.\n"))
+		})
+
+		Context("When the local port is taken", func() {
+			It("Shows an error message and exits with 1", func() {
+				mysqlPlugin, mocks := NewPluginAndMocks()
+				mocks.PortFinder.CheckPortReturns(errors.New("address already in use"))
+
+				mysqlPlugin.Run(mocks.CliConnection, []string{"mysql-tunnel", "start", "--local-port", "13306", "database-a"})
+
+				Expect(mocks.TunnelDaemon.StartCallCount()).To(Equal(0))
+				Expect(mocks.Err).To(gbytes.Say("^FAILED\nLocal port 13306 is not available: address already in use\n$"))
+				Expect(mysqlPlugin.GetExitCode()).To(Equal(1))
+			})
+		})
+
+		Context("When the tunnel does not start", func() {
+			It("Shows an error message and exits with 1", func() {
+				mysqlPlugin, mocks := NewPluginAndMocks()
+				mocks.TunnelDaemon.StartReturns(BackgroundTunnel{}, errors.New("the tunnel process exited, see tunnel.log"))
+
+				mysqlPlugin.Run(mocks.CliConnection, []string{"mysql-tunnel", "start", "database-a"})
+
+				Expect(mocks.TunnelStateStore.SaveCallCount()).To(Equal(0))
+				Expect(mocks.Err).To(gbytes.Say("^FAILED\nUnable to start SSH tunnel to 'database-a': the tunnel process exited, see tunnel.log\n$"))
+				Expect(mysqlPlugin.GetExitCode()).To(Equal(1))
+			})
+		})
+
+		It("Holds the lock on the state file while recording the tunnel, but not while starting it", func() {
+			mysqlPlugin, mocks := NewPluginAndMocks()
+			var calls []string
+			mocks.TunnelDaemon.StartStub = func(string, LocalAddress, []string) (BackgroundTunnel, error) {
+				calls = append(calls, "start")
+				return tunnelA, nil
+			}
+			mocks.TunnelStateStore.LockStub = func() (func(), error) {
+				calls = append(calls, "lock")
+				return func() { calls = append(calls, "unlock") }, nil
+			}
+			mocks.TunnelStateStore.LoadStub = func() ([]BackgroundTunnel, error) {
+				calls = append(calls, "load")
+				return nil, nil
+			}
+			mocks.TunnelStateStore.SaveStub = func([]BackgroundTunnel) error {
+				calls = append(calls, "save")
+				return nil
+			}
+
+			mysqlPlugin.Run(mocks.CliConnection, []string{"mysql-tunnel", "start", "database-a"})
+
+			Expect(calls).To(Equal([]string{"start", "lock", "load", "save", "unlock"}))
+			Expect(mysqlPlugin.GetExitCode()).To(Equal(0))
+		})
+
+		Context("When the tunnel cannot be recorded", func() {
+			It("Stops the tunnel and exits with 1", func() {
+				mysqlPlugin, mocks := NewPluginAndMocks()
+				mocks.TunnelDaemon.StartReturns(tunnelA, nil)
+				mocks.TunnelStateStore.SaveReturns(errors.New("read-only file system"))
+
+				mysqlPlugin.Run(mocks.CliConnection, []string{"mysql-tunnel", "start", "database-a"})
+
+				Expect(mocks.TunnelDaemon.StopCallCount()).To(Equal(1))
+				Expect(mocks.TunnelDaemon.StopArgsForCall(0)).To(Equal(4242))
+				Expect(mocks.Err).To(gbytes.Say("^FAILED\nUnable to record the tunnel, stopping it: read-only file system\n$"))
+				Expect(mysqlPlugin.GetExitCode()).To(Equal(1))
+			})
+		})
+
+		Context("When no service name is passed", func() {
+			It("Prints usage information and exits with 1", func() {
+				mysqlPlugin, mocks := NewPluginAndMocks()
+
+				mysqlPlugin.Run(mocks.CliConnection, []string{"mysql-tunnel", "start"})
+
+				Expect(mocks.TunnelDaemon.StartCallCount()).To(Equal(0))
+				Expect(mocks.Err).To(gbytes.Say("cf mysql-tunnel start"))
+				Expect(mysqlPlugin.GetExitCode()).To(Equal(1))
+			})
+		})
+	})
+
+	Context("When calling 'cf mysql-tunnel list'", func() {
+		It("Lists the running tunnels and forgets the others", func() {
+			mysqlPlugin, mocks := NewPluginAndMocks()
+			mocks.TunnelStateStore.LoadReturns([]BackgroundTunnel{tunnelA, tunnelB}, nil)
+			mocks.TunnelDaemon.IsRunningStub = func(tunnel BackgroundTunnel) bool {
+				return tunnel.Pid == 4242
+			}
+
+			mysqlPlugin.Run(mocks.CliConnection, []string{"mysql-tunnel", "list"})
+
+			Expect(string(mocks.Out.Contents())).To(Equal("service      address           pid    started\n" +
+				"database-a   127.0.0.1:13306   4242   " + tunnelA.StartedAt.Format(time.RFC3339) + "\n"))
+			Expect(mocks.TunnelStateStore.SaveCallCount()).To(Equal(1))
+			Expect(mocks.TunnelStateStore.SaveArgsForCall(0)).To(Equal([]BackgroundTunnel{tunnelA}))
+			Expect(mysqlPlugin.GetExitCode()).To(Equal(0))
+		})
+
+		It("Says so if there are no tunnels", func() {
+			mysqlPlugin, mocks := NewPluginAndMocks()
+
+			mysqlPlugin.Run(mocks.CliConnection, []string{"mysql-tunnel", "list"})
+
+			Expect(string(mocks.Out.Contents())).To(Equal("No background SSH tunnels found.\n"))
+			Expect(mocks.TunnelStateStore.SaveCallCount()).To(Equal(0))
+		})
+
+		It("Shows an error message if the state file cannot be locked", func() {
+			mysqlPlugin, mocks := NewPluginAndMocks()
+			mocks.TunnelStateStore.LockReturns(nil, errors.New("unable to open lock file: permission denied"))
+
+			mysqlPlugin.Run(mocks.CliConnection, []string{"mysql-tunnel", "list"})
+
+			Expect(mocks.TunnelStateStore.LoadCallCount()).To(Equal(0))
+			Expect(mocks.Err).To(gbytes.Say("^FAILED\nUnable to load the tunnel list: unable to open lock file: permission denied\n$"))
+			Expect(mysqlPlugin.GetExitCode()).To(Equal(1))
+		})
+
+		It("Shows an error message if the state file cannot be read", func() {
+			mysqlPlugin, mocks := NewPluginAndMocks()
+			mocks.TunnelStateStore.LoadReturns(nil, errors.New("permission denied"))
+
+			mysqlPlugin.Run(mocks.CliConnection, []string{"mysql-tunnel", "list"})
+
+			Expect(mocks.Err).To(gbytes.Say("^FAILED\nUnable to load the tunnel list: permission denied\n$"))
+			Expect(mysqlPlugin.GetExitCode()).To(Equal(1))
+		})
+	})
+
+	Context("When calling 'cf mysql-tunnel stop'", func() {
+		It("Stops the tunnels to the service", func() {
+			mysqlPlugin, mocks := NewPluginAndMocks()
+			mocks.TunnelStateStore.LoadReturns([]BackgroundTunnel{tunnelA, tunnelB}, nil)
+			mocks.TunnelDaemon.IsRunningReturns(true)
+
+			mysqlPlugin.Run(mocks.CliConnection, []string{"mysql-tunnel", "stop", "database-b"})
+
+			Expect(mocks.TunnelDaemon.StopCallCount()).To(Equal(1))
+			Expect(mocks.TunnelDaemon.StopArgsForCall(0)).To(Equal(4343))
+			Expect(mocks.TunnelStateStore.SaveArgsForCall(0)).To(Equal([]BackgroundTunnel{tunnelA}))
+			Expect(string(mocks.Out.Contents())).To(Equal("Stopping SSH tunnel to database-b on [::1]:13307...\nOK\n"))
+			Expect(mysqlPlugin.GetExitCode()).To(Equal(0))
+		})
+
+		It("Stops all tunnels with --all", func() {
+			mysqlPlugin, mocks := NewPluginAndMocks()
+			mocks.TunnelStateStore.LoadReturns([]BackgroundTunnel{tunnelA, tunnelB}, nil)
+			mocks.TunnelDaemon.IsRunningReturns(true)
+
+			mysqlPlugin.Run(mocks.CliConnection, []string{"mysql-tunnel", "stop", "--all"})
+
+			Expect(mocks.TunnelDaemon.StopCallCount()).To(Equal(2))
+			Expect(mocks.TunnelStateStore.SaveArgsForCall(0)).To(BeEmpty())
+			Expect(mysqlPlugin.GetExitCode()).To(Equal(0))
+		})
+
+		It("Holds the lock on the state file until the remaining tunnels are saved", func() {
+			mysqlPlugin, mocks := NewPluginAndMocks()
+			mocks.TunnelStateStore.LoadReturns([]BackgroundTunnel{tunnelA}, nil)
+			mocks.TunnelDaemon.IsRunningReturns(true)
+			unlocked := false
+			mocks.TunnelStateStore.LockReturns(func() { unlocked = true }, nil)
+			mocks.TunnelStateStore.SaveStub = func([]BackgroundTunnel) error {
+				Expect(unlocked).To(BeFalse())
+				return nil
+			}
+
+			mysqlPlugin.Run(mocks.CliConnection, []string{"mysql-tunnel", "stop", "database-a"})
+
+			Expect(mocks.TunnelStateStore.SaveCallCount()).To(Equal(1))
+			Expect(unlocked).To(BeTrue())
+		})
+
+		It("Keeps tunnels that cannot be stopped and exits with 1", func() {
+			mysqlPlugin, mocks := NewPluginAndMocks()
+			mocks.TunnelStateStore.LoadReturns([]BackgroundTunnel{tunnelA}, nil)
+			mocks.TunnelDaemon.IsRunningReturns(true)
+			mocks.TunnelDaemon.StopReturns(errors.New("operation not permitted"))
+
+			mysqlPlugin.Run(mocks.CliConnection, []string{"mysql-tunnel", "stop", "database-a"})
+
+			Expect(mocks.Err).To(gbytes.Say("^FAILED\nUnable to stop process 4242: operation not permitted\n$"))
+			Expect(mocks.TunnelStateStore.SaveArgsForCall(0)).To(Equal([]BackgroundTunnel{tunnelA}))
+			Expect(mysqlPlugin.GetExitCode()).To(Equal(1))
+		})
+
+		It("Shows an error message if there is no tunnel to the service", func() {
+			mysqlPlugin, mocks := NewPluginAndMocks()
+			mocks.TunnelStateStore.LoadReturns([]BackgroundTunnel{tunnelA}, nil)
+			mocks.TunnelDaemon.IsRunningReturns(true)
+
+			mysqlPlugin.Run(mocks.CliConnection, []string{"mysql-tunnel", "stop", "database-c"})
+
+			Expect(mocks.TunnelDaemon.StopCallCount()).To(Equal(0))
+			Expect(mocks.Err).To(gbytes.Say("^FAILED\nNo background SSH tunnel to 'database-c' found\n$"))
+			Expect(mysqlPlugin.GetExitCode()).To(Equal(1))
+		})
+
+		It("Prints usage information when passing both a service and --all", func() {
+			mysqlPlugin, mocks := NewPluginAndMocks()
+
+			mysqlPlugin.Run(mocks.CliConnection, []string{"mysql-tunnel", "stop", "--all", "database-a"})
+
+			Expect(mocks.TunnelStateStore.LoadCallCount()).To(Equal(0))
+			Expect(mocks.Err).To(gbytes.Say("cf mysql-tunnel stop <service-name> | --all"))
+			Expect(mysqlPlugin.GetExitCode()).To(Equal(1))
+		})
+	})
+})
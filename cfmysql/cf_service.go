@@ -3,23 +3,33 @@ package cfmysql
 import (
 	"code.cloudfoundry.org/cli/plugin"
 	sdkModels "code.cloudfoundry.org/cli/plugin/models"
+	"errors"
 	"fmt"
 	pluginModels "github.com/andreasf/cf-mysql-plugin/cfmysql/models"
+	"github.com/andreasf/cf-mysql-plugin/cfmysql/resources"
 	"io"
+	"math"
+	"sort"
+	"strconv"
+	"strings"
 )
 
 //go:generate counterfeiter . CfService
 type CfService interface {
 	GetStartedApps(cliConnection plugin.CliConnection) ([]sdkModels.GetAppsModel, error)
-	OpenSshTunnel(cliConnection plugin.CliConnection, toService MysqlService, apps []sdkModels.GetAppsModel, localPort int)
+	OpenSshTunnel(cliConnection plugin.CliConnection, forwards []TunnelForward, apps []sdkModels.GetAppsModel) (SshTunnel, error)
 	GetService(connection plugin.CliConnection, name string) (MysqlService, error)
+	GetEphemeralService(connection plugin.CliConnection, name string) (MysqlService, error)
+	GetServices(connection plugin.CliConnection) ([]ServiceSummary, error)
+	GetServiceKeys(connection plugin.CliConnection, wholeOrg bool) ([]ServiceKeySummary, error)
+	DeleteServiceKey(connection plugin.CliConnection, serviceKeyGuid string) error
+	TargetSpace(connection plugin.CliConnection, orgName string, spaceName string) (plugin.CliConnection, error)
 }
 
-func NewCfService(apiClient ApiClient, runner SshRunner, waiter PortWaiter, httpClient HttpWrapper, randWrapper RandWrapper, logWriter io.Writer) *cfService {
+func NewCfService(apiClient ApiClient, runner SshRunner, httpClient HttpWrapper, randWrapper RandWrapper, logWriter io.Writer) *cfService {
 	return &cfService{
 		apiClient:   apiClient,
 		sshRunner:   runner,
-		portWaiter:  waiter,
 		httpClient:  httpClient,
 		randWrapper: randWrapper,
 		logWriter:   logWriter,
@@ -29,19 +39,43 @@ func NewCfService(apiClient ApiClient, runner SshRunner, waiter PortWaiter, http
 const ServiceKeyName = "cf-mysql"
 
 type MysqlService struct {
-	Name     string
-	Hostname string
-	Port     string
-	DbName   string
-	Username string
-	Password string
-	CaCert   string
+	Name           string
+	Hostname       string
+	Port           string
+	DbName         string
+	Username       string
+	Password       string
+	CaCert         string
+	ServiceKeyGuid string
+	ServiceKeyName string
+	BoundAppGuids  []string
+	// ClientOptions are the client options found in the key's connection URLs, e.g. ssl-mode
+	ClientOptions map[string]string
+	// ClientArgs are passed to the client programs before the user's arguments, made from the ClientOptions
+	ClientArgs []string
 }
 
+type ServiceSummary struct {
+	Name            string
+	Service         string
+	Plan            string
+	LastOperation   string
+	HasServiceKey   bool
+	MysqlCompatible bool
+}
+
+type ServiceKeySummary struct {
+	Guid        string
+	Name        string
+	ServiceName string
+	SpaceName   string
+}
+
+var mysqlKeywords = []string{"mysql", "mariadb", "aurora", "percona", "cleardb"}
+
 type cfService struct {
 	apiClient   ApiClient
 	httpClient  HttpWrapper
-	portWaiter  PortWaiter
 	sshRunner   SshRunner
 	randWrapper RandWrapper
 	logWriter   io.Writer
@@ -51,23 +85,82 @@ func (self *cfService) GetStartedApps(cliConnection plugin.CliConnection) ([]sdk
 	return self.apiClient.GetStartedApps(cliConnection)
 }
 
-func (self *cfService) OpenSshTunnel(cliConnection plugin.CliConnection, toService MysqlService, apps []sdkModels.GetAppsModel, localPort int) {
-	throughAppIndex := self.randWrapper.Intn(len(apps))
-	throughApp := apps[throughAppIndex].Name
-	go self.sshRunner.OpenSshTunnel(cliConnection, toService, throughApp, localPort)
+// OpenSshTunnel opens a tunnel with all forwards through one of the apps. Apps bound to the most services are tried
+// first, as they are the most likely to be allowed to reach them. Apps with SSH disabled are skipped, and if the
+// tunnel fails, the next app is tried. The tunnel is monitored, and reopened through the same apps if it drops.
+func (self *cfService) OpenSshTunnel(cliConnection plugin.CliConnection, forwards []TunnelForward, apps []sdkModels.GetAppsModel) (SshTunnel, error) {
+	candidates := self.sshEnabledApps(cliConnection, orderByBindings(apps, forwards))
+	if len(candidates) == 0 {
+		return nil, errors.New("SSH is disabled for all started apps, enable it with 'cf enable-ssh <app>'")
+	}
+
+	var lastErr error
+	for appIndex, app := range candidates {
+		tunnel, err := self.openSshTunnelThrough(cliConnection, forwards, app)
+		if err != nil {
+			if appIndex < len(candidates)-1 {
+				fmt.Fprintf(self.logWriter, "%s, trying the next app\n", err)
+			}
+			lastErr = err
+			continue
+		}
+
+		return newReconnectingTunnel(cliConnection, self.sshRunner, forwards, candidates, appIndex, self.logWriter, tunnel), nil
+	}
+
+	return nil, lastErr
+}
+
+// sshEnabledApps drops the apps with SSH disabled, keeping their order. Apps whose setting cannot be checked are kept.
+func (self *cfService) sshEnabledApps(cliConnection plugin.CliConnection, apps []sdkModels.GetAppsModel) []sdkModels.GetAppsModel {
+	var enabled []sdkModels.GetAppsModel
+	for _, app := range apps {
+		sshEnabled, err := self.apiClient.IsSshEnabled(cliConnection, app.Guid)
+		if err != nil {
+			fmt.Fprintf(self.logWriter, "Unable to check whether SSH is enabled for app %s: %s\n", app.Name, err)
+		} else if !sshEnabled {
+			fmt.Fprintf(self.logWriter, "Skipping app %s: SSH is disabled\n", app.Name)
+			continue
+		}
+
+		enabled = append(enabled, app)
+	}
 
-	self.portWaiter.WaitUntilOpen(localPort)
+	return enabled
 }
 
-func (self *cfService) GetService(connection plugin.CliConnection, name string) (MysqlService, error) {
-	space, err := connection.GetCurrentSpace()
+func (self *cfService) openSshTunnelThrough(cliConnection plugin.CliConnection, forwards []TunnelForward, throughApp sdkModels.GetAppsModel) (SshTunnel, error) {
+	tunnel, err := self.sshRunner.OpenSshTunnel(cliConnection, forwards, throughApp)
 	if err != nil {
-		return MysqlService{}, fmt.Errorf("unable to retrieve current space: %s", err)
+		return nil, fmt.Errorf("unable to open SSH tunnel through app %s: %s", throughApp.Name, err)
 	}
 
-	instance, err := self.apiClient.GetService(connection, space.Guid, name)
+	return tunnel, nil
+}
+
+// orderByBindings sorts the apps by the number of forwarded services they are bound to, keeping the order of apps
+// with the same number
+func orderByBindings(apps []sdkModels.GetAppsModel, forwards []TunnelForward) []sdkModels.GetAppsModel {
+	bindings := make(map[string]int)
+	for _, forward := range forwards {
+		for _, guid := range forward.Service.BoundAppGuids {
+			bindings[guid]++
+		}
+	}
+
+	ordered := make([]sdkModels.GetAppsModel, len(apps))
+	copy(ordered, apps)
+	sort.SliceStable(ordered, func(i, j int) bool {
+		return bindings[ordered[i].Guid] > bindings[ordered[j].Guid]
+	})
+
+	return ordered
+}
+
+func (self *cfService) GetService(connection plugin.CliConnection, name string) (MysqlService, error) {
+	instance, err := self.getInstance(connection, name)
 	if err != nil {
-		return MysqlService{}, fmt.Errorf("unable to retrieve metadata for service %s: %s", name, err)
+		return MysqlService{}, err
 	}
 
 	serviceKey, found, err := self.apiClient.GetServiceKey(connection, instance.Guid, ServiceKeyName)
@@ -75,27 +168,347 @@ func (self *cfService) GetService(connection plugin.CliConnection, name string)
 		return MysqlService{}, fmt.Errorf("unable to retrieve service key: %s", err)
 	}
 
-	if found {
-		return toServiceModel(name, serviceKey), nil
+	if !found {
+		fmt.Fprintf(self.logWriter, "Creating new service key %s for %s...\n", ServiceKeyName, name)
+		serviceKey, err = self.apiClient.CreateServiceKey(connection, instance.Guid, ServiceKeyName)
+		if err != nil {
+			return MysqlService{}, fmt.Errorf("unable to create service key: %s", err)
+		}
 	}
 
-	fmt.Fprintf(self.logWriter, "Creating new service key %s for %s...\n", ServiceKeyName, name)
-	serviceKey, err = self.apiClient.CreateServiceKey(connection, instance.Guid, ServiceKeyName)
+	serviceKey, err = self.resolveCredentials(connection, instance, serviceKey)
+	if err != nil {
+		return MysqlService{}, err
+	}
+
+	return toServiceModel(name, instance, serviceKey), nil
+}
+
+// GetEphemeralService always creates a new service key with a unique name, which the caller needs to delete
+func (self *cfService) GetEphemeralService(connection plugin.CliConnection, name string) (MysqlService, error) {
+	instance, err := self.getInstance(connection, name)
+	if err != nil {
+		return MysqlService{}, err
+	}
+
+	keyName := fmt.Sprintf("%s-%08x", ServiceKeyName, self.randWrapper.Intn(math.MaxInt32))
+
+	fmt.Fprintf(self.logWriter, "Creating ephemeral service key %s for %s...\n", keyName, name)
+	serviceKey, err := self.apiClient.CreateServiceKey(connection, instance.Guid, keyName)
 	if err != nil {
 		return MysqlService{}, fmt.Errorf("unable to create service key: %s", err)
 	}
 
-	return toServiceModel(name, serviceKey), nil
+	resolvedKey, err := self.resolveCredentials(connection, instance, serviceKey)
+	if err != nil {
+		// the caller only deletes keys it gets back
+		deleteErr := self.apiClient.DeleteServiceKey(connection, serviceKey.Guid)
+		if deleteErr != nil {
+			return MysqlService{}, fmt.Errorf("%s\nUnable to delete service key %s for %s: %s\nDelete it with: cf delete-service-key -f %s %s",
+				err, keyName, instance.Name, deleteErr, instance.Name, keyName)
+		}
+		return MysqlService{}, err
+	}
+
+	return toServiceModel(name, instance, resolvedKey), nil
 }
 
-func toServiceModel(name string, serviceKey pluginModels.ServiceKey) MysqlService {
+// resolveCredentials replaces a CredHub reference in the key with the credentials it refers to, and reads the
+// credentials in the format of the instance's broker
+func (self *cfService) resolveCredentials(connection plugin.CliConnection, instance pluginModels.ServiceInstance, serviceKey pluginModels.ServiceKey) (pluginModels.ServiceKey, error) {
+	credentials := serviceKey
+	if serviceKey.CredhubRef != "" {
+		var err error
+		credentials, err = self.apiClient.GetCredhubCredentials(connection, serviceKey.CredhubRef)
+		if err != nil {
+			return pluginModels.ServiceKey{}, fmt.Errorf("unable to resolve CredHub reference %s: %s", serviceKey.CredhubRef, err)
+		}
+	}
+
+	if credentials.Credentials != nil {
+		parsedKey, err := resources.ParseCredentials(credentials.Credentials, instance.Label, instance.Tags)
+		if err != nil {
+			return pluginModels.ServiceKey{}, fmt.Errorf("unable to read credentials of service key %s: %s", serviceKey.Name, err)
+		}
+		parsedKey.Credentials = credentials.Credentials
+		credentials = parsedKey
+	}
+
+	credentials.Guid = serviceKey.Guid
+	credentials.Name = serviceKey.Name
+	credentials.ServiceInstanceGuid = serviceKey.ServiceInstanceGuid
+
+	return credentials, nil
+}
+
+func (self *cfService) getInstance(connection plugin.CliConnection, name string) (pluginModels.ServiceInstance, error) {
+	space, err := connection.GetCurrentSpace()
+	if err != nil {
+		return pluginModels.ServiceInstance{}, fmt.Errorf("unable to retrieve current space: %s", err)
+	}
+
+	sourceSpace, instanceName := splitSharedServiceName(name)
+
+	// instances of the current space take precedence over shared instances with the same name
+	var ownErr error
+	if sourceSpace == "" || sourceSpace == space.Name {
+		instance, err := self.apiClient.GetService(connection, space.Guid, instanceName)
+		if err == nil {
+			return instance, nil
+		}
+		ownErr = err
+	}
+
+	sharedInstances, err := self.apiClient.GetSharedServices(connection, space.Guid, instanceName)
+	if err != nil {
+		return pluginModels.ServiceInstance{}, fmt.Errorf("unable to retrieve metadata for service %s: %s", name, err)
+	}
+
+	var candidates []pluginModels.ServiceInstance
+	for _, instance := range sharedInstances {
+		if sourceSpace == "" || instance.SharedFromSpace == sourceSpace {
+			candidates = append(candidates, instance)
+		}
+	}
+
+	switch {
+	case len(candidates) == 1:
+		return candidates[0], nil
+	case len(candidates) > 1:
+		var names []string
+		for _, instance := range candidates {
+			names = append(names, instance.SharedFromSpace+"/"+instance.Name)
+		}
+		return pluginModels.ServiceInstance{}, fmt.Errorf("several services named %s are shared into the current space, choose one of: %s", instanceName, strings.Join(names, ", "))
+	case ownErr != nil:
+		return pluginModels.ServiceInstance{}, fmt.Errorf("unable to retrieve metadata for service %s: %s", name, ownErr)
+	default:
+		return pluginModels.ServiceInstance{}, fmt.Errorf("unable to retrieve metadata for service %s: %s is not shared from space %s", name, instanceName, sourceSpace)
+	}
+}
+
+// splitSharedServiceName splits a name like source-space/service-name, which selects an instance shared from another
+// space. The source space is empty for plain names.
+func splitSharedServiceName(name string) (string, string) {
+	parts := strings.SplitN(name, "/", 2)
+	if len(parts) < 2 {
+		return "", name
+	}
+
+	return parts[0], parts[1]
+}
+
+func (self *cfService) GetServices(connection plugin.CliConnection) ([]ServiceSummary, error) {
+	space, err := connection.GetCurrentSpace()
+	if err != nil {
+		return nil, fmt.Errorf("unable to retrieve current space: %s", err)
+	}
+
+	instances, err := self.apiClient.GetServiceInstances(connection, space.Guid)
+	if err != nil {
+		return nil, fmt.Errorf("unable to retrieve service instances: %s", err)
+	}
+
+	serviceKeys, err := self.apiClient.FindServiceKeys(connection, keyableInstanceGuids(instances))
+	if err != nil {
+		return nil, fmt.Errorf("unable to retrieve service keys: %s", err)
+	}
+
+	instancesWithKey := make(map[string]bool)
+	for _, serviceKey := range serviceKeys {
+		if serviceKey.Name == ServiceKeyName {
+			instancesWithKey[serviceKey.ServiceInstanceGuid] = true
+		}
+	}
+
+	summaries := make([]ServiceSummary, 0, len(instances))
+	for _, instance := range instances {
+		summaries = append(summaries, ServiceSummary{
+			Name:            instance.Name,
+			Service:         instance.Label,
+			Plan:            instance.Plan,
+			LastOperation:   instance.LastOperation,
+			HasServiceKey:   instancesWithKey[instance.Guid],
+			MysqlCompatible: isMysqlCompatible(instance),
+		})
+	}
+
+	return summaries, nil
+}
+
+func (self *cfService) GetServiceKeys(connection plugin.CliConnection, wholeOrg bool) ([]ServiceKeySummary, error) {
+	instances, spaceNames, err := self.getInstancesAndSpaces(connection, wholeOrg)
+	if err != nil {
+		return nil, err
+	}
+
+	serviceKeys, err := self.apiClient.FindServiceKeys(connection, keyableInstanceGuids(instances))
+	if err != nil {
+		return nil, fmt.Errorf("unable to retrieve service keys: %s", err)
+	}
+
+	instancesByGuid := make(map[string]pluginModels.ServiceInstance)
+	for _, instance := range instances {
+		instancesByGuid[instance.Guid] = instance
+	}
+
+	var summaries []ServiceKeySummary
+	for _, serviceKey := range serviceKeys {
+		instance, found := instancesByGuid[serviceKey.ServiceInstanceGuid]
+		if !found || !isPluginServiceKey(serviceKey.Name) {
+			continue
+		}
+
+		summaries = append(summaries, ServiceKeySummary{
+			Guid:        serviceKey.Guid,
+			Name:        serviceKey.Name,
+			ServiceName: instance.Name,
+			SpaceName:   spaceNames[instance.SpaceGuid],
+		})
+	}
+
+	return summaries, nil
+}
+
+// isPluginServiceKey tells whether the plugin created the key, either as the shared 'cf-mysql' key or as an ephemeral
+// key named by GetEphemeralService
+func isPluginServiceKey(name string) bool {
+	if name == ServiceKeyName {
+		return true
+	}
+
+	suffix := strings.TrimPrefix(name, ServiceKeyName+"-")
+	if suffix == name || len(suffix) != 8 {
+		return false
+	}
+	_, err := strconv.ParseUint(suffix, 16, 32)
+	return err == nil
+}
+
+// keyableInstanceGuids returns the GUIDs of the instances that can have service keys, which user-provided instances
+// cannot
+func keyableInstanceGuids(instances []pluginModels.ServiceInstance) []string {
+	var guids []string
+	for _, instance := range instances {
+		if !instance.UserProvided {
+			guids = append(guids, instance.Guid)
+		}
+	}
+
+	return guids
+}
+
+func (self *cfService) getInstancesAndSpaces(connection plugin.CliConnection, wholeOrg bool) ([]pluginModels.ServiceInstance, map[string]string, error) {
+	spaceNames := make(map[string]string)
+
+	if !wholeOrg {
+		space, err := connection.GetCurrentSpace()
+		if err != nil {
+			return nil, nil, fmt.Errorf("unable to retrieve current space: %s", err)
+		}
+		spaceNames[space.Guid] = space.Name
+
+		instances, err := self.apiClient.GetServiceInstances(connection, space.Guid)
+		if err != nil {
+			return nil, nil, fmt.Errorf("unable to retrieve service instances: %s", err)
+		}
+
+		return instances, spaceNames, nil
+	}
+
+	org, err := connection.GetCurrentOrg()
+	if err != nil {
+		return nil, nil, fmt.Errorf("unable to retrieve current org: %s", err)
+	}
+
+	spaces, err := connection.GetSpaces()
+	if err != nil {
+		return nil, nil, fmt.Errorf("unable to retrieve spaces: %s", err)
+	}
+	for _, space := range spaces {
+		spaceNames[space.Guid] = space.Name
+	}
+
+	instances, err := self.apiClient.GetOrgServiceInstances(connection, org.Guid)
+	if err != nil {
+		return nil, nil, fmt.Errorf("unable to retrieve service instances: %s", err)
+	}
+
+	return instances, spaceNames, nil
+}
+
+func (self *cfService) DeleteServiceKey(connection plugin.CliConnection, serviceKeyGuid string) error {
+	return self.apiClient.DeleteServiceKey(connection, serviceKeyGuid)
+}
+
+func isMysqlCompatible(instance pluginModels.ServiceInstance) bool {
+	candidates := append([]string{instance.Label}, instance.Tags...)
+
+	for _, candidate := range candidates {
+		candidate = strings.ToLower(candidate)
+
+		for _, keyword := range mysqlKeywords {
+			if strings.Contains(candidate, keyword) {
+				return true
+			}
+		}
+	}
+
+	return false
+}
+
+func toServiceModel(name string, instance pluginModels.ServiceInstance, serviceKey pluginModels.ServiceKey) MysqlService {
 	return MysqlService{
-		Name:     name,
-		Hostname: serviceKey.Hostname,
-		Port:     serviceKey.Port,
-		DbName:   serviceKey.DbName,
-		Username: serviceKey.Username,
-		Password: serviceKey.Password,
-		CaCert:   serviceKey.CaCert,
+		Name:           name,
+		Hostname:       serviceKey.Hostname,
+		Port:           serviceKey.Port,
+		DbName:         serviceKey.DbName,
+		Username:       serviceKey.Username,
+		Password:       serviceKey.Password,
+		CaCert:         serviceKey.CaCert,
+		ServiceKeyGuid: serviceKey.Guid,
+		ServiceKeyName: serviceKey.Name,
+		BoundAppGuids:  instance.BoundAppGuids,
+		ClientOptions:  serviceKey.ClientOptions,
+		ClientArgs:     toClientArgs(serviceKey.ClientOptions),
 	}
 }
+
+func toClientArgs(options map[string]string) []string {
+	var args []string
+	for _, name := range sortedOptionNames(options) {
+		args = append(args, fmt.Sprintf("--%s=%s", name, options[name]))
+	}
+
+	return args
+}
+
+func sortedOptionNames(options map[string]string) []string {
+	var names []string
+	for name := range options {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	return names
+}
+
+// withClientArgs inserts the service's client arguments before the first option of the user's arguments, so that
+// leading arguments such as table names keep their place and the user's options take precedence
+func (self MysqlService) withClientArgs(toolArgs []string) []string {
+	if len(self.ClientArgs) == 0 {
+		return toolArgs
+	}
+
+	position := len(toolArgs)
+	for i, argument := range toolArgs {
+		if strings.HasPrefix(argument, "-") {
+			position = i
+			break
+		}
+	}
+
+	args := append([]string{}, toolArgs[:position]...)
+	args = append(args, self.ClientArgs...)
+	return append(args, toolArgs[position:]...)
+}
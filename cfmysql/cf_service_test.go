@@ -2,7 +2,6 @@ package cfmysql_test
 
 import (
 	"code.cloudfoundry.org/cli/cf/errors"
-	"code.cloudfoundry.org/cli/plugin"
 	"code.cloudfoundry.org/cli/plugin/models"
 	"code.cloudfoundry.org/cli/plugin/pluginfakes"
 	. "github.com/andreasf/cf-mysql-plugin/cfmysql"
@@ -19,7 +18,6 @@ var _ = Describe("CfService", func() {
 	var service CfService
 	var cliConnection *pluginfakes.FakeCliConnection
 	var sshRunner *cfmysqlfakes.FakeSshRunner
-	var portWaiter *cfmysqlfakes.FakePortWaiter
 	var mockHttp *cfmysqlfakes.FakeHttpWrapper
 	var mockRand *cfmysqlfakes.FakeRandWrapper
 	var appList []plugin_models.GetAppsModel
@@ -38,21 +36,23 @@ var _ = Describe("CfService", func() {
 
 		apiClient = new(cfmysqlfakes.FakeApiClient)
 		sshRunner = new(cfmysqlfakes.FakeSshRunner)
-		portWaiter = new(cfmysqlfakes.FakePortWaiter)
 		mockHttp = new(cfmysqlfakes.FakeHttpWrapper)
 		mockRand = new(cfmysqlfakes.FakeRandWrapper)
 		logWriter = gbytes.NewBuffer()
 
-		service = NewCfService(apiClient, sshRunner, portWaiter, mockHttp, mockRand, logWriter)
+		service = NewCfService(apiClient, sshRunner, mockHttp, mockRand, logWriter)
 
 		appList = []plugin_models.GetAppsModel{
 			{
 				Name: "app-name-1",
+				Guid: "app-guid-1",
 			},
 			{
 				Name: "app-name-2",
+				Guid: "app-guid-2",
 			},
 		}
+		apiClient.IsSshEnabledReturns(true, nil)
 
 		serviceKey = models.ServiceKey{
 			ServiceInstanceGuid: "service-instance-guid",
@@ -73,6 +73,8 @@ var _ = Describe("CfService", func() {
 			Username: "username",
 			Password: "password",
 			CaCert:   "ca-cert",
+
+			BoundAppGuids: []string{"app-guid-2"},
 		}
 	})
 
@@ -109,53 +111,270 @@ var _ = Describe("CfService", func() {
 			Username: "username-a",
 			Password: "password-a",
 		}
-		openSshTunnelCalled := make(chan bool, 0)
+		localAddress := LocalAddress{Host: "127.0.0.1", Port: 4242}
+		forwards := []TunnelForward{{Service: mysqlService, LocalAddress: localAddress}}
 
 		Context("When opening the tunnel", func() {
-			notifyWhenGoroutineCalled := func(cliConnection plugin.CliConnection, toService MysqlService, throughApp string, localPort int) {
-				openSshTunnelCalled <- true
-			}
+			It("Opens the tunnel through the first app with SSH enabled", func() {
+				tunnel, _ := NewFakeSshTunnel()
+				sshRunner.OpenSshTunnelReturns(tunnel, nil)
+
+				openedTunnel, err := service.OpenSshTunnel(cliConnection, forwards, appList)
+
+				Expect(err).To(BeNil())
+				Expect(openedTunnel.Close()).To(Succeed())
+				Expect(tunnel.CloseCallCount()).To(Equal(1))
+				Expect(openedTunnel.Wait()).To(Succeed())
 
-			It("Runs the SSH runner in a goroutine", func() {
-				cliConnection := new(pluginfakes.FakeCliConnection)
-				sshRunner := new(cfmysqlfakes.FakeSshRunner)
-				portWaiter := new(cfmysqlfakes.FakePortWaiter)
-				mockRand := new(cfmysqlfakes.FakeRandWrapper)
-				logWriter := gbytes.NewBuffer()
+				Expect(apiClient.IsSshEnabledCallCount()).To(Equal(2))
+				calledCliConnection, calledGuid := apiClient.IsSshEnabledArgsForCall(0)
+				Expect(calledCliConnection).To(Equal(cliConnection))
+				Expect(calledGuid).To(Equal("app-guid-1"))
+				_, calledGuid = apiClient.IsSshEnabledArgsForCall(1)
+				Expect(calledGuid).To(Equal("app-guid-2"))
 
-				mockRand.IntnReturns(1)
+				Expect(sshRunner.OpenSshTunnelCallCount()).To(Equal(1))
+				calledCliConnection, calledForwards, calledApp := sshRunner.OpenSshTunnelArgsForCall(0)
+				Expect(calledCliConnection).To(Equal(cliConnection))
+				Expect(calledForwards).To(Equal(forwards))
+				Expect(calledApp).To(Equal(appList[0]))
+			})
 
-				service := NewCfService(apiClient, sshRunner, portWaiter, mockHttp, mockRand, logWriter)
+			It("Prefers apps bound to the service", func() {
+				tunnel, _ := NewFakeSshTunnel()
+				sshRunner.OpenSshTunnelReturns(tunnel, nil)
+				boundService := mysqlService
+				boundService.BoundAppGuids = []string{"app-guid-2"}
 
-				sshRunner.OpenSshTunnelStub = notifyWhenGoroutineCalled
+				openedTunnel, _ := service.OpenSshTunnel(cliConnection, []TunnelForward{{Service: boundService, LocalAddress: localAddress}}, appList)
+				defer openedTunnel.Close()
 
-				service.OpenSshTunnel(cliConnection, mysqlService, appList, 4242)
+				_, _, calledApp := sshRunner.OpenSshTunnelArgsForCall(0)
+				Expect(calledApp).To(Equal(appList[1]))
+			})
 
-				select {
-				case <-openSshTunnelCalled:
-					break
-				case <-time.After(1 * time.Second):
-					Fail("timed out waiting for OpenSSH tunnel to be called")
+			It("Prefers apps bound to most of the services", func() {
+				tunnel, _ := NewFakeSshTunnel()
+				sshRunner.OpenSshTunnelReturns(tunnel, nil)
+				serviceA := mysqlService
+				serviceA.BoundAppGuids = []string{"app-guid-1", "app-guid-2"}
+				serviceB := mysqlService
+				serviceB.Name = "database-b"
+				serviceB.BoundAppGuids = []string{"app-guid-2"}
+				multipleForwards := []TunnelForward{
+					{Service: serviceA, LocalAddress: localAddress},
+					{Service: serviceB, LocalAddress: LocalAddress{Host: "127.0.0.1", Port: 4243}},
 				}
 
+				openedTunnel, _ := service.OpenSshTunnel(cliConnection, multipleForwards, appList)
+				defer openedTunnel.Close()
+
 				Expect(sshRunner.OpenSshTunnelCallCount()).To(Equal(1))
+				_, calledForwards, calledApp := sshRunner.OpenSshTunnelArgsForCall(0)
+				Expect(calledForwards).To(Equal(multipleForwards))
+				Expect(calledApp).To(Equal(appList[1]))
+			})
 
-				calledCliConnection, calledService, calledAppName, calledPort := sshRunner.OpenSshTunnelArgsForCall(0)
-				Expect(mockRand.IntnCallCount()).To(Equal(1))
-				Expect(mockRand.IntnArgsForCall(0)).To(Equal(2))
+			It("Skips apps with SSH disabled", func() {
+				tunnel, _ := NewFakeSshTunnel()
+				sshRunner.OpenSshTunnelReturns(tunnel, nil)
+				apiClient.IsSshEnabledReturnsOnCall(0, false, nil)
 
-				Expect(calledCliConnection).To(Equal(cliConnection))
-				Expect(calledService).To(Equal(mysqlService))
-				Expect(calledAppName).To(Equal("app-name-2"))
-				Expect(calledPort).To(Equal(4242))
+				openedTunnel, _ := service.OpenSshTunnel(cliConnection, forwards, appList)
+				defer openedTunnel.Close()
+
+				Expect(logWriter).To(gbytes.Say("Skipping app app-name-1: SSH is disabled\n"))
+				Expect(sshRunner.OpenSshTunnelCallCount()).To(Equal(1))
+				_, _, calledApp := sshRunner.OpenSshTunnelArgsForCall(0)
+				Expect(calledApp).To(Equal(appList[1]))
+			})
+
+			It("Tries the app anyway if the SSH setting cannot be checked", func() {
+				tunnel, _ := NewFakeSshTunnel()
+				sshRunner.OpenSshTunnelReturns(tunnel, nil)
+				apiClient.IsSshEnabledReturns(false, errors.New("PC LOAD LETTER"))
+
+				openedTunnel, err := service.OpenSshTunnel(cliConnection, forwards, appList)
+				defer openedTunnel.Close()
+
+				Expect(err).To(BeNil())
+				Expect(logWriter).To(gbytes.Say("Unable to check whether SSH is enabled for app app-name-1: PC LOAD LETTER\n"))
+				_, _, calledApp := sshRunner.OpenSshTunnelArgsForCall(0)
+				Expect(calledApp).To(Equal(appList[0]))
+			})
+
+		})
+
+		Context("When forwarding several services", func() {
+			It("Names all services and addresses when reconnecting", func() {
+				firstTunnel, dropFirstTunnel := NewFakeSshTunnel()
+				secondTunnel, _ := NewFakeSshTunnel()
+				sshRunner.OpenSshTunnelReturnsOnCall(0, firstTunnel, nil)
+				sshRunner.OpenSshTunnelReturnsOnCall(1, secondTunnel, nil)
+				serviceB := mysqlService
+				serviceB.Name = "database-b"
+				multipleForwards := append(forwards, TunnelForward{Service: serviceB, LocalAddress: LocalAddress{Host: "127.0.0.1", Port: 4243}})
+
+				openedTunnel, _ := service.OpenSshTunnel(cliConnection, multipleForwards, appList)
+				defer openedTunnel.Close()
+
+				dropFirstTunnel(errors.New("connection reset by peer"))
+
+				Eventually(logWriter).Should(gbytes.Say("SSH tunnel to database-a, database-b dropped: connection reset by peer\n"))
+				Eventually(logWriter).Should(gbytes.Say("Reconnected SSH tunnel to database-a, database-b on 127.0.0.1:4242, 127.0.0.1:4243\n"))
+				_, calledForwards, _ := sshRunner.OpenSshTunnelArgsForCall(1)
+				Expect(calledForwards).To(Equal(multipleForwards))
 			})
+		})
+
+		Context("When the tunnel drops", func() {
+			var firstTunnel *cfmysqlfakes.FakeSshTunnel
+			var dropFirstTunnel func(error)
+
+			BeforeEach(func() {
+				firstTunnel, dropFirstTunnel = NewFakeSshTunnel()
+				sshRunner.OpenSshTunnelReturnsOnCall(0, firstTunnel, nil)
+			})
+
+			It("Reopens it on the same port and logs the reconnect", func() {
+				secondTunnel, _ := NewFakeSshTunnel()
+				sshRunner.OpenSshTunnelReturnsOnCall(1, secondTunnel, nil)
+
+				openedTunnel, err := service.OpenSshTunnel(cliConnection, forwards, appList)
+				Expect(err).To(BeNil())
+
+				dropFirstTunnel(errors.New("connection reset by peer"))
+
+				Eventually(logWriter).Should(gbytes.Say("SSH tunnel to database-a dropped: connection reset by peer\n"))
+				Eventually(logWriter).Should(gbytes.Say("Reconnecting SSH tunnel to database-a through app app-name-1...\n"))
+				Eventually(logWriter).Should(gbytes.Say("Reconnected SSH tunnel to database-a on 127.0.0.1:4242\n"))
+
+				Expect(sshRunner.OpenSshTunnelCallCount()).To(Equal(2))
+				_, calledForwards, calledApp := sshRunner.OpenSshTunnelArgsForCall(1)
+				Expect(calledForwards).To(Equal(forwards))
+				Expect(calledApp).To(Equal(appList[0]))
+
+				Expect(openedTunnel.Close()).To(Succeed())
+				Expect(secondTunnel.CloseCallCount()).To(Equal(1))
+			})
+
+			It("Moves on to another app if the tunnel cannot be reopened", func() {
+				secondTunnel, _ := NewFakeSshTunnel()
+				sshRunner.OpenSshTunnelReturnsOnCall(1, nil, errors.New("instance not found"))
+				sshRunner.OpenSshTunnelReturnsOnCall(2, secondTunnel, nil)
+
+				openedTunnel, _ := service.OpenSshTunnel(cliConnection, forwards, appList)
+				defer openedTunnel.Close()
+
+				dropFirstTunnel(errors.New("connection reset by peer"))
+
+				Eventually(logWriter, 2*time.Second).Should(gbytes.Say("Unable to reconnect: instance not found\n"))
+				Eventually(logWriter, 2*time.Second).Should(gbytes.Say("Reconnecting SSH tunnel to database-a through app app-name-2...\n"))
+				Eventually(logWriter, 2*time.Second).Should(gbytes.Say("Reconnected SSH tunnel to database-a on 127.0.0.1:4242\n"))
+
+				_, _, calledApp := sshRunner.OpenSshTunnelArgsForCall(2)
+				Expect(calledApp).To(Equal(appList[1]))
+			})
+
+			It("Does not try apps with SSH disabled", func() {
+				apiClient.IsSshEnabledReturnsOnCall(1, false, nil)
+				sshRunner.OpenSshTunnelReturns(nil, errors.New("instance not found"))
+
+				openedTunnel, _ := service.OpenSshTunnel(cliConnection, forwards, appList)
+
+				dropFirstTunnel(errors.New("connection reset by peer"))
+
+				Expect(openedTunnel.Wait()).To(MatchError("SSH tunnel dropped and could not be reopened: instance not found"))
+				Expect(sshRunner.OpenSshTunnelCallCount()).To(Equal(1 + TunnelReconnectAttempts))
+				for i := 0; i < sshRunner.OpenSshTunnelCallCount(); i++ {
+					_, _, calledApp := sshRunner.OpenSshTunnelArgsForCall(i)
+					Expect(calledApp).To(Equal(appList[0]))
+				}
+			})
+
+			It("Moves on to the next bound app with SSH enabled", func() {
+				secondTunnel, _ := NewFakeSshTunnel()
+				sshRunner.OpenSshTunnelReturnsOnCall(1, nil, errors.New("instance not found"))
+				sshRunner.OpenSshTunnelReturnsOnCall(2, secondTunnel, nil)
+				apiClient.IsSshEnabledReturnsOnCall(1, false, nil)
+				apps := append(appList, plugin_models.GetAppsModel{Name: "app-name-3", Guid: "app-guid-3"})
+				serviceA := mysqlService
+				serviceA.BoundAppGuids = []string{"app-guid-2", "app-guid-3"}
+				serviceB := mysqlService
+				serviceB.Name = "database-b"
+				serviceB.BoundAppGuids = []string{"app-guid-2"}
+				multipleForwards := []TunnelForward{
+					{Service: serviceA, LocalAddress: localAddress},
+					{Service: serviceB, LocalAddress: LocalAddress{Host: "127.0.0.1", Port: 4243}},
+				}
+
+				openedTunnel, _ := service.OpenSshTunnel(cliConnection, multipleForwards, apps)
+				defer openedTunnel.Close()
+
+				Expect(logWriter).To(gbytes.Say("Skipping app app-name-3: SSH is disabled\n"))
+				_, _, calledApp := sshRunner.OpenSshTunnelArgsForCall(0)
+				Expect(calledApp).To(Equal(appList[1]))
+
+				dropFirstTunnel(errors.New("connection reset by peer"))
+
+				Eventually(logWriter, 2*time.Second).Should(gbytes.Say("Reconnecting SSH tunnel to database-a, database-b through app app-name-2...\n"))
+				Eventually(logWriter, 2*time.Second).Should(gbytes.Say("Reconnecting SSH tunnel to database-a, database-b through app app-name-1...\n"))
+				Eventually(logWriter, 2*time.Second).Should(gbytes.Say("Reconnected SSH tunnel to database-a, database-b on 127.0.0.1:4242, 127.0.0.1:4243\n"))
+				Expect(sshRunner.OpenSshTunnelCallCount()).To(Equal(3))
+				_, _, calledApp = sshRunner.OpenSshTunnelArgsForCall(2)
+				Expect(calledApp).To(Equal(appList[0]))
+			})
+
+			It("Gives up after several attempts", func() {
+				sshRunner.OpenSshTunnelReturns(nil, errors.New("SSH disabled"))
+
+				openedTunnel, _ := service.OpenSshTunnel(cliConnection, forwards, appList)
+
+				dropFirstTunnel(errors.New("connection reset by peer"))
+
+				Expect(openedTunnel.Wait()).To(MatchError("SSH tunnel dropped and could not be reopened: SSH disabled"))
+				Expect(sshRunner.OpenSshTunnelCallCount()).To(Equal(1 + TunnelReconnectAttempts))
+				Expect(logWriter).To(gbytes.Say("Giving up on SSH tunnel to database-a after 3 attempts\n"))
+			})
+		})
+
+		Context("When the tunnel cannot be opened through an app", func() {
+			It("Moves on to the next app", func() {
+				tunnel, _ := NewFakeSshTunnel()
+				sshRunner.OpenSshTunnelReturnsOnCall(0, nil, errors.New("connection refused"))
+				sshRunner.OpenSshTunnelReturnsOnCall(1, tunnel, nil)
+
+				openedTunnel, err := service.OpenSshTunnel(cliConnection, forwards, appList)
+				defer openedTunnel.Close()
+
+				Expect(err).To(BeNil())
+				Expect(logWriter).To(gbytes.Say("unable to open SSH tunnel through app app-name-1: connection refused, trying the next app\n"))
+				_, _, calledApp := sshRunner.OpenSshTunnelArgsForCall(1)
+				Expect(calledApp).To(Equal(appList[1]))
+			})
+		})
+
+		Context("When the tunnel cannot be opened through any app", func() {
+			It("Returns the last error, naming the app", func() {
+				sshRunner.OpenSshTunnelReturns(nil, errors.New("connection refused"))
+
+				openedTunnel, err := service.OpenSshTunnel(cliConnection, forwards, appList)
+
+				Expect(openedTunnel).To(BeNil())
+				Expect(err).To(MatchError("unable to open SSH tunnel through app app-name-2: connection refused"))
+				Expect(sshRunner.OpenSshTunnelCallCount()).To(Equal(2))
+			})
+		})
 
-			It("Blocks until the tunnel is open", func() {
-				cliConnection.CliCommandWithoutTerminalOutputStub = nil
-				service.OpenSshTunnel(cliConnection, mysqlService, appList, 4242)
+		Context("When SSH is disabled for all apps", func() {
+			It("Returns an error", func() {
+				apiClient.IsSshEnabledReturns(false, nil)
+
+				openedTunnel, err := service.OpenSshTunnel(cliConnection, forwards, appList)
 
-				Expect(portWaiter.WaitUntilOpenCallCount()).To(Equal(1))
-				Expect(portWaiter.WaitUntilOpenArgsForCall(0)).To(Equal(4242))
+				Expect(openedTunnel).To(BeNil())
+				Expect(err).To(MatchError("SSH is disabled for all started apps, enable it with 'cf enable-ssh <app>'"))
+				Expect(sshRunner.OpenSshTunnelCallCount()).To(Equal(0))
 			})
 		})
 	})
@@ -164,9 +383,10 @@ var _ = Describe("CfService", func() {
 		var instance models.ServiceInstance
 		BeforeEach(func() {
 			instance = models.ServiceInstance{
-				Name:      "service-instance-name",
-				Guid:      "service-instance-guid",
-				SpaceGuid: "space-guid",
+				Name:          "service-instance-name",
+				Guid:          "service-instance-guid",
+				SpaceGuid:     "space-guid",
+				BoundAppGuids: []string{"app-guid-2"},
 			}
 		})
 
@@ -187,6 +407,88 @@ var _ = Describe("CfService", func() {
 			})
 		})
 
+		Context("When the service is shared into the current space", func() {
+			var sharedInstance models.ServiceInstance
+
+			BeforeEach(func() {
+				sharedInstance = models.ServiceInstance{
+					Name:            "service-instance-name",
+					Guid:            "shared-instance-guid",
+					SpaceGuid:       "platform-space-guid",
+					SharedFromSpace: "platform",
+				}
+				apiClient.GetServiceReturns(models.ServiceInstance{}, errors.New("service-instance-name not found in current space"))
+				apiClient.GetServiceKeyReturns(serviceKey, true, nil)
+			})
+
+			It("Returns the shared instance", func() {
+				apiClient.GetSharedServicesReturns([]models.ServiceInstance{sharedInstance}, nil)
+
+				mysqlService, err := service.GetService(cliConnection, "service-instance-name")
+
+				Expect(err).To(BeNil())
+				Expect(mysqlService.Name).To(Equal("service-instance-name"))
+
+				calledConnection, calledSpaceGuid, calledName := apiClient.GetSharedServicesArgsForCall(0)
+				Expect(calledConnection).To(Equal(cliConnection))
+				Expect(calledSpaceGuid).To(Equal("space-guid-a"))
+				Expect(calledName).To(Equal("service-instance-name"))
+
+				_, calledInstanceGuid, _ := apiClient.GetServiceKeyArgsForCall(0)
+				Expect(calledInstanceGuid).To(Equal("shared-instance-guid"))
+			})
+
+			It("Selects the instance by source space", func() {
+				otherInstance := sharedInstance
+				otherInstance.Guid = "other-instance-guid"
+				otherInstance.SharedFromSpace = "other"
+				apiClient.GetSharedServicesReturns([]models.ServiceInstance{otherInstance, sharedInstance}, nil)
+
+				mysqlService, err := service.GetService(cliConnection, "platform/service-instance-name")
+
+				Expect(err).To(BeNil())
+				Expect(mysqlService.Name).To(Equal("platform/service-instance-name"))
+				Expect(apiClient.GetServiceCallCount()).To(Equal(0))
+
+				_, _, calledName := apiClient.GetSharedServicesArgsForCall(0)
+				Expect(calledName).To(Equal("service-instance-name"))
+
+				_, calledInstanceGuid, _ := apiClient.GetServiceKeyArgsForCall(0)
+				Expect(calledInstanceGuid).To(Equal("shared-instance-guid"))
+			})
+
+			It("Returns an error when several instances have the name", func() {
+				otherInstance := sharedInstance
+				otherInstance.SharedFromSpace = "other"
+				apiClient.GetSharedServicesReturns([]models.ServiceInstance{otherInstance, sharedInstance}, nil)
+
+				_, err := service.GetService(cliConnection, "service-instance-name")
+
+				Expect(err).To(Equal(errors.New("several services named service-instance-name are shared into the current space, choose one of: other/service-instance-name, platform/service-instance-name")))
+			})
+
+			It("Returns an error when no instance is shared from the given space", func() {
+				apiClient.GetSharedServicesReturns([]models.ServiceInstance{sharedInstance}, nil)
+
+				_, err := service.GetService(cliConnection, "other/service-instance-name")
+
+				Expect(err).To(Equal(errors.New("unable to retrieve metadata for service other/service-instance-name: service-instance-name is not shared from space other")))
+			})
+
+			It("Prefers the instance of the current space", func() {
+				apiClient.GetServiceReturns(instance, nil)
+				apiClient.GetSharedServicesReturns([]models.ServiceInstance{sharedInstance}, nil)
+
+				_, err := service.GetService(cliConnection, "service-instance-name")
+
+				Expect(err).To(BeNil())
+				Expect(apiClient.GetSharedServicesCallCount()).To(Equal(0))
+
+				_, calledInstanceGuid, _ := apiClient.GetServiceKeyArgsForCall(0)
+				Expect(calledInstanceGuid).To(Equal("service-instance-guid"))
+			})
+		})
+
 		Context("When service and key are found", func() {
 			It("Returns credentials", func() {
 				apiClient.GetServiceReturns(instance, nil)
@@ -209,6 +511,134 @@ var _ = Describe("CfService", func() {
 			})
 		})
 
+		It("Turns the key's client options into arguments", func() {
+			serviceKey.ClientOptions = map[string]string{
+				"tls-version": "TLSv1.2",
+				"ssl-mode":    "REQUIRED",
+			}
+			apiClient.GetServiceReturns(instance, nil)
+			apiClient.GetServiceKeyReturns(serviceKey, true, nil)
+
+			mysqlService, err := service.GetService(cliConnection, "service-instance-name")
+
+			Expect(err).To(BeNil())
+			Expect(mysqlService.ClientArgs).To(Equal([]string{"--ssl-mode=REQUIRED", "--tls-version=TLSv1.2"}))
+		})
+
+		Context("When the key has credentials in another broker format", func() {
+			BeforeEach(func() {
+				instance.Label = "azure-mysql-5-7"
+				serviceKey = models.ServiceKey{
+					Guid: "service-key-guid",
+					Name: "cf-mysql",
+					Credentials: map[string]interface{}{
+						"host":     "hostname",
+						"port":     float64(2342),
+						"database": "db-name",
+						"username": "username",
+						"password": "password",
+					},
+				}
+				apiClient.GetServiceReturns(instance, nil)
+			})
+
+			It("Reads them in the format selected by the service label", func() {
+				apiClient.GetServiceKeyReturns(serviceKey, true, nil)
+
+				mysqlService, err := service.GetService(cliConnection, "service-instance-name")
+
+				Expect(err).To(BeNil())
+				Expect(mysqlService.Hostname).To(Equal("hostname"))
+				Expect(mysqlService.Port).To(Equal("2342"))
+				Expect(mysqlService.DbName).To(Equal("db-name"))
+				Expect(mysqlService.Username).To(Equal("username"))
+				Expect(mysqlService.Password).To(Equal("password"))
+				Expect(mysqlService.ServiceKeyGuid).To(Equal("service-key-guid"))
+			})
+
+			It("Returns an error listing the missing fields", func() {
+				delete(serviceKey.Credentials, "password")
+				apiClient.GetServiceKeyReturns(serviceKey, true, nil)
+
+				mysqlService, err := service.GetService(cliConnection, "service-instance-name")
+
+				Expect(mysqlService).To(Equal(MysqlService{}))
+				Expect(err).To(Equal(errors.New("unable to read credentials of service key cf-mysql: credentials do not match the azure-mysql format, missing password")))
+			})
+		})
+
+		Context("When the key refers to credentials in CredHub", func() {
+			var credhubKey models.ServiceKey
+
+			BeforeEach(func() {
+				credhubKey = models.ServiceKey{
+					Guid:                "service-key-guid",
+					Name:                "cf-mysql",
+					ServiceInstanceGuid: "service-instance-guid",
+					CredhubRef:          "/c/p-mysql/service-instance-guid/service-key-guid/credentials",
+				}
+				apiClient.GetServiceReturns(instance, nil)
+			})
+
+			It("Resolves the reference", func() {
+				apiClient.GetServiceKeyReturns(credhubKey, true, nil)
+				apiClient.GetCredhubCredentialsReturns(models.ServiceKey{
+					DbName:   serviceKey.DbName,
+					Hostname: serviceKey.Hostname,
+					Port:     serviceKey.Port,
+					Username: serviceKey.Username,
+					Password: serviceKey.Password,
+					CaCert:   serviceKey.CaCert,
+				}, nil)
+
+				mysqlService, err := service.GetService(cliConnection, "service-instance-name")
+
+				expectedMysqlService.ServiceKeyGuid = "service-key-guid"
+				expectedMysqlService.ServiceKeyName = "cf-mysql"
+				Expect(err).To(BeNil())
+				Expect(mysqlService).To(Equal(expectedMysqlService))
+
+				calledConnection, calledRef := apiClient.GetCredhubCredentialsArgsForCall(0)
+				Expect(calledConnection).To(Equal(cliConnection))
+				Expect(calledRef).To(Equal("/c/p-mysql/service-instance-guid/service-key-guid/credentials"))
+			})
+
+			It("Returns an error if the reference cannot be resolved", func() {
+				apiClient.GetServiceKeyReturns(credhubKey, true, nil)
+				apiClient.GetCredhubCredentialsReturns(models.ServiceKey{}, errors.New("HTTP status 403"))
+
+				mysqlService, err := service.GetService(cliConnection, "service-instance-name")
+
+				Expect(mysqlService).To(Equal(MysqlService{}))
+				Expect(err).To(Equal(errors.New("unable to resolve CredHub reference /c/p-mysql/service-instance-guid/service-key-guid/credentials: HTTP status 403")))
+			})
+
+			It("Deletes an ephemeral key if the reference cannot be resolved", func() {
+				apiClient.CreateServiceKeyReturns(credhubKey, nil)
+				apiClient.GetCredhubCredentialsReturns(models.ServiceKey{}, errors.New("HTTP status 403"))
+
+				_, err := service.GetEphemeralService(cliConnection, "service-instance-name")
+
+				Expect(err).To(MatchError(ContainSubstring("unable to resolve CredHub reference")))
+				Expect(apiClient.DeleteServiceKeyCallCount()).To(Equal(1))
+				_, calledKeyGuid := apiClient.DeleteServiceKeyArgsForCall(0)
+				Expect(calledKeyGuid).To(Equal("service-key-guid"))
+			})
+
+			It("Names the ephemeral key if it cannot be deleted either", func() {
+				apiClient.CreateServiceKeyReturns(credhubKey, nil)
+				apiClient.GetCredhubCredentialsReturns(models.ServiceKey{}, errors.New("HTTP status 403"))
+				apiClient.DeleteServiceKeyReturns(errors.New("HTTP status 502"))
+				mockRand.IntnReturns(42)
+
+				_, err := service.GetEphemeralService(cliConnection, "service-instance-name")
+
+				Expect(err).To(Equal(errors.New("unable to resolve CredHub reference /c/p-mysql/service-instance-guid/service-key-guid/credentials: HTTP status 403\n" +
+					"Unable to delete service key cf-mysql-0000002a for service-instance-name: HTTP status 502\n" +
+					"Delete it with: cf delete-service-key -f service-instance-name cf-mysql-0000002a")))
+			})
+		})
+
 		Context("When the service key does not yet exist", func() {
 			It("Creates the key and returns credentials", func() {
 				apiClient.GetServiceReturns(instance, nil)
@@ -286,4 +716,323 @@ var _ = Describe("CfService", func() {
 			})
 		})
 	})
+
+	Context("GetServices", func() {
+		BeforeEach(func() {
+			apiClient.GetServiceInstancesReturns([]models.ServiceInstance{
+				{
+					Name:          "database-a",
+					Guid:          "service-instance-guid-a",
+					Plan:          "db-small",
+					Label:         "p-mysql",
+					LastOperation: "create succeeded",
+				},
+				{
+					Name:          "database-b",
+					Guid:          "service-instance-guid-b",
+					Plan:          "aws-db",
+					Label:         "rds",
+					Tags:          []string{"relational", "MariaDB"},
+					LastOperation: "update in progress",
+				},
+				{
+					Name:          "redis-c",
+					Guid:          "service-instance-guid-c",
+					Plan:          "shared-vm",
+					Label:         "p-redis",
+					Tags:          []string{"redis"},
+					LastOperation: "create succeeded",
+				},
+				{
+					Name:          "config-d",
+					Guid:          "service-instance-guid-d",
+					LastOperation: "create succeeded",
+					UserProvided:  true,
+				},
+			}, nil)
+			apiClient.FindServiceKeysReturns([]models.ServiceKey{
+				{Name: "cf-mysql", ServiceInstanceGuid: "service-instance-guid-a"},
+				{Name: "other-key", ServiceInstanceGuid: "service-instance-guid-b"},
+			}, nil)
+		})
+
+		Context("When instances and keys are found", func() {
+			It("Returns summaries with key and compatibility information", func() {
+				summaries, err := service.GetServices(cliConnection)
+
+				Expect(err).To(BeNil())
+				Expect(summaries).To(Equal([]ServiceSummary{
+					{
+						Name:            "database-a",
+						Service:         "p-mysql",
+						Plan:            "db-small",
+						LastOperation:   "create succeeded",
+						HasServiceKey:   true,
+						MysqlCompatible: true,
+					},
+					{
+						Name:            "database-b",
+						Service:         "rds",
+						Plan:            "aws-db",
+						LastOperation:   "update in progress",
+						HasServiceKey:   false,
+						MysqlCompatible: true,
+					},
+					{
+						Name:            "redis-c",
+						Service:         "p-redis",
+						Plan:            "shared-vm",
+						LastOperation:   "create succeeded",
+						HasServiceKey:   false,
+						MysqlCompatible: false,
+					},
+					{
+						Name:            "config-d",
+						LastOperation:   "create succeeded",
+						HasServiceKey:   false,
+						MysqlCompatible: false,
+					},
+				}))
+
+				calledConnection, calledSpaceGuid := apiClient.GetServiceInstancesArgsForCall(0)
+				Expect(calledConnection).To(Equal(cliConnection))
+				Expect(calledSpaceGuid).To(Equal("space-guid-a"))
+
+				calledConnection, calledInstanceGuids := apiClient.FindServiceKeysArgsForCall(0)
+				Expect(calledConnection).To(Equal(cliConnection))
+				Expect(calledInstanceGuids).To(Equal([]string{"service-instance-guid-a", "service-instance-guid-b", "service-instance-guid-c"}))
+			})
+		})
+
+		Context("When the instances cannot be retrieved", func() {
+			It("Returns an error", func() {
+				apiClient.GetServiceInstancesReturns(nil, errors.New("PC LOAD LETTER"))
+
+				summaries, err := service.GetServices(cliConnection)
+
+				Expect(err).To(Equal(errors.New("unable to retrieve service instances: PC LOAD LETTER")))
+				Expect(summaries).To(BeNil())
+			})
+		})
+
+		Context("When the service keys cannot be retrieved", func() {
+			It("Returns an error", func() {
+				apiClient.FindServiceKeysReturns(nil, errors.New("PC LOAD LETTER"))
+
+				summaries, err := service.GetServices(cliConnection)
+
+				Expect(err).To(Equal(errors.New("unable to retrieve service keys: PC LOAD LETTER")))
+				Expect(summaries).To(BeNil())
+			})
+		})
+
+		Context("When the current space cannot be retrieved", func() {
+			It("Returns an error", func() {
+				cliConnection.GetCurrentSpaceReturns(plugin_models.Space{}, errors.New("PC LOAD LETTER"))
+
+				summaries, err := service.GetServices(cliConnection)
+
+				Expect(err).To(Equal(errors.New("unable to retrieve current space: PC LOAD LETTER")))
+				Expect(summaries).To(BeNil())
+			})
+		})
+	})
+
+	Context("GetServiceKeys", func() {
+		BeforeEach(func() {
+			instances := []models.ServiceInstance{
+				{Name: "database-a", Guid: "service-instance-guid-a", SpaceGuid: "space-guid-a"},
+				{Name: "database-b", Guid: "service-instance-guid-b", SpaceGuid: "space-guid-b"},
+			}
+			apiClient.GetServiceInstancesReturns(instances[:1], nil)
+			apiClient.GetOrgServiceInstancesReturns(instances, nil)
+			apiClient.FindServiceKeysReturns([]models.ServiceKey{
+				{Guid: "service-key-guid-a", Name: "cf-mysql", ServiceInstanceGuid: "service-instance-guid-a"},
+				{Guid: "service-key-guid-e", Name: "cf-mysql-0000002a", ServiceInstanceGuid: "service-instance-guid-a"},
+				{Guid: "service-key-guid-u", Name: "cf-mysql-readonly", ServiceInstanceGuid: "service-instance-guid-a"},
+				{Guid: "service-key-guid-b", Name: "cf-mysql", ServiceInstanceGuid: "service-instance-guid-b"},
+			}, nil)
+			cliConnection.GetCurrentOrgReturns(plugin_models.Organization{
+				OrganizationFields: plugin_models.OrganizationFields{Guid: "org-guid"},
+			}, nil)
+			cliConnection.GetSpacesReturns([]plugin_models.GetSpaces_Model{
+				{Guid: "space-guid-a", Name: "space is the place"},
+				{Guid: "space-guid-b", Name: "space-b"},
+			}, nil)
+		})
+
+		Context("When looking in the current space", func() {
+			It("Returns the keys and ephemeral keys of the instances in the space", func() {
+				serviceKeys, err := service.GetServiceKeys(cliConnection, false)
+
+				Expect(err).To(BeNil())
+				Expect(serviceKeys).To(Equal([]ServiceKeySummary{
+					{Guid: "service-key-guid-a", Name: "cf-mysql", ServiceName: "database-a", SpaceName: "space is the place"},
+					{Guid: "service-key-guid-e", Name: "cf-mysql-0000002a", ServiceName: "database-a", SpaceName: "space is the place"},
+				}))
+
+				_, calledSpaceGuid := apiClient.GetServiceInstancesArgsForCall(0)
+				Expect(calledSpaceGuid).To(Equal("space-guid-a"))
+				_, calledInstanceGuids := apiClient.FindServiceKeysArgsForCall(0)
+				Expect(calledInstanceGuids).To(Equal([]string{"service-instance-guid-a"}))
+			})
+		})
+
+		Context("When looking in the whole org", func() {
+			It("Returns the keys of the instances in all spaces", func() {
+				serviceKeys, err := service.GetServiceKeys(cliConnection, true)
+
+				Expect(err).To(BeNil())
+				Expect(serviceKeys).To(Equal([]ServiceKeySummary{
+					{Guid: "service-key-guid-a", Name: "cf-mysql", ServiceName: "database-a", SpaceName: "space is the place"},
+					{Guid: "service-key-guid-e", Name: "cf-mysql-0000002a", ServiceName: "database-a", SpaceName: "space is the place"},
+					{Guid: "service-key-guid-b", Name: "cf-mysql", ServiceName: "database-b", SpaceName: "space-b"},
+				}))
+
+				_, calledOrgGuid := apiClient.GetOrgServiceInstancesArgsForCall(0)
+				Expect(calledOrgGuid).To(Equal("org-guid"))
+			})
+		})
+
+		Context("When the service keys cannot be retrieved", func() {
+			It("Returns an error", func() {
+				apiClient.FindServiceKeysReturns(nil, errors.New("PC LOAD LETTER"))
+
+				serviceKeys, err := service.GetServiceKeys(cliConnection, false)
+
+				Expect(err).To(Equal(errors.New("unable to retrieve service keys: PC LOAD LETTER")))
+				Expect(serviceKeys).To(BeNil())
+			})
+		})
+	})
+
+	Context("DeleteServiceKey", func() {
+		It("Delegates the call to ApiClient", func() {
+			apiClient.DeleteServiceKeyReturns(errors.New("PC LOAD LETTER"))
+
+			err := service.DeleteServiceKey(cliConnection, "service-key-guid")
+
+			Expect(err).To(Equal(errors.New("PC LOAD LETTER")))
+			calledConnection, calledGuid := apiClient.DeleteServiceKeyArgsForCall(0)
+			Expect(calledConnection).To(Equal(cliConnection))
+			Expect(calledGuid).To(Equal("service-key-guid"))
+		})
+	})
+
+	Context("GetEphemeralService", func() {
+		var instance models.ServiceInstance
+
+		BeforeEach(func() {
+			instance = models.ServiceInstance{
+				Name:      "service-instance-name",
+				Guid:      "service-instance-guid",
+				SpaceGuid: "space-guid",
+			}
+		})
+
+		Context("When the service instance exists", func() {
+			It("Creates a key with a unique name and returns credentials", func() {
+				serviceKey.Guid = "service-key-guid"
+				serviceKey.Name = "cf-mysql-0000002a"
+				apiClient.GetServiceReturns(instance, nil)
+				apiClient.CreateServiceKeyReturns(serviceKey, nil)
+				mockRand.IntnReturns(42)
+
+				mysqlService, err := service.GetEphemeralService(cliConnection, "service-instance-name")
+
+				Expect(err).To(BeNil())
+				Expect(mysqlService.ServiceKeyGuid).To(Equal("service-key-guid"))
+				Expect(mysqlService.ServiceKeyName).To(Equal("cf-mysql-0000002a"))
+				Expect(mysqlService.Username).To(Equal(serviceKey.Username))
+				Expect(apiClient.GetServiceKeyCallCount()).To(Equal(0))
+
+				calledConnection, calledInstanceGuid, calledKeyName := apiClient.CreateServiceKeyArgsForCall(0)
+				Expect(calledConnection).To(Equal(cliConnection))
+				Expect(calledInstanceGuid).To(Equal("service-instance-guid"))
+				Expect(calledKeyName).To(Equal("cf-mysql-0000002a"))
+				Expect(logWriter).To(gbytes.Say("Creating ephemeral service key cf-mysql-0000002a for service-instance-name...\n"))
+			})
+		})
+
+		Context("When the key cannot be created", func() {
+			It("Returns an error", func() {
+				apiClient.GetServiceReturns(instance, nil)
+				apiClient.CreateServiceKeyReturns(models.ServiceKey{}, errors.New("PC LOAD LETTER"))
+
+				mysqlService, err := service.GetEphemeralService(cliConnection, "service-instance-name")
+
+				Expect(err).To(Equal(errors.New("unable to create service key: PC LOAD LETTER")))
+				Expect(mysqlService).To(Equal(MysqlService{}))
+			})
+		})
+	})
+
+	Context("TargetSpace", func() {
+		space := models.Space{
+			Guid:    "staging-space-guid",
+			Name:    "staging",
+			OrgGuid: "other-org-guid",
+			OrgName: "other-org",
+		}
+
+		It("Returns a connection that reports the space as the current one", func() {
+			apiClient.GetSpaceReturns(space, nil)
+
+			connection, err := service.TargetSpace(cliConnection, "other-org", "staging")
+
+			Expect(err).To(BeNil())
+			_, calledOrg, calledSpace := apiClient.GetSpaceArgsForCall(0)
+			Expect(calledOrg).To(Equal("other-org"))
+			Expect(calledSpace).To(Equal("staging"))
+
+			currentSpace, err := connection.GetCurrentSpace()
+			Expect(err).To(BeNil())
+			Expect(currentSpace.Guid).To(Equal("staging-space-guid"))
+			Expect(currentSpace.Name).To(Equal("staging"))
+
+			currentOrg, err := connection.GetCurrentOrg()
+			Expect(err).To(BeNil())
+			Expect(currentOrg.Guid).To(Equal("other-org-guid"))
+			Expect(currentOrg.Name).To(Equal("other-org"))
+			Expect(cliConnection.GetCurrentSpaceCallCount()).To(Equal(0))
+		})
+
+		It("Lists the apps of the space", func() {
+			apps := []plugin_models.GetAppsModel{{Name: "staging-app", Guid: "staging-app-guid", State: "started"}}
+			apiClient.GetSpaceReturns(space, nil)
+			apiClient.GetSpaceAppsReturns(apps, nil)
+
+			connection, _ := service.TargetSpace(cliConnection, "other-org", "staging")
+			calledApps, err := connection.GetApps()
+
+			Expect(err).To(BeNil())
+			Expect(calledApps).To(Equal(apps))
+			calledConnection, calledSpaceGuid := apiClient.GetSpaceAppsArgsForCall(0)
+			Expect(calledConnection).To(Equal(cliConnection))
+			Expect(calledSpaceGuid).To(Equal("staging-space-guid"))
+			Expect(cliConnection.GetAppsCallCount()).To(Equal(0))
+		})
+
+		It("Looks up the space in the current org without an org name", func() {
+			cliConnection.GetCurrentOrgReturns(plugin_models.Organization{
+				OrganizationFields: plugin_models.OrganizationFields{Name: "current-org"},
+			}, nil)
+			apiClient.GetSpaceReturns(space, nil)
+
+			_, err := service.TargetSpace(cliConnection, "", "staging")
+
+			Expect(err).To(BeNil())
+			_, calledOrg, _ := apiClient.GetSpaceArgsForCall(0)
+			Expect(calledOrg).To(Equal("current-org"))
+		})
+
+		It("Returns an error if the space cannot be found", func() {
+			apiClient.GetSpaceReturns(models.Space{}, errors.New("space staging not found in organization other-org"))
+
+			connection, err := service.TargetSpace(cliConnection, "other-org", "staging")
+
+			Expect(connection).To(BeNil())
+			Expect(err).To(Equal(errors.New("space staging not found in organization other-org")))
+		})
+	})
 })
@@ -24,6 +24,35 @@ type FakeApiClient struct {
 		result1 []sdkModels.GetAppsModel
 		result2 error
 	}
+	GetSpaceAppsStub        func(cliConnection plugin.CliConnection, spaceGuid string) ([]sdkModels.GetAppsModel, error)
+	getSpaceAppsMutex       sync.RWMutex
+	getSpaceAppsArgsForCall []struct {
+		cliConnection plugin.CliConnection
+		spaceGuid     string
+	}
+	getSpaceAppsReturns struct {
+		result1 []sdkModels.GetAppsModel
+		result2 error
+	}
+	getSpaceAppsReturnsOnCall map[int]struct {
+		result1 []sdkModels.GetAppsModel
+		result2 error
+	}
+	GetSpaceStub        func(cliConnection plugin.CliConnection, orgName string, spaceName string) (pluginModels.Space, error)
+	getSpaceMutex       sync.RWMutex
+	getSpaceArgsForCall []struct {
+		cliConnection plugin.CliConnection
+		orgName       string
+		spaceName     string
+	}
+	getSpaceReturns struct {
+		result1 pluginModels.Space
+		result2 error
+	}
+	getSpaceReturnsOnCall map[int]struct {
+		result1 pluginModels.Space
+		result2 error
+	}
 	GetServiceStub        func(cliConnection plugin.CliConnection, spaceGuid string, name string) (pluginModels.ServiceInstance, error)
 	getServiceMutex       sync.RWMutex
 	getServiceArgsForCall []struct {
@@ -39,6 +68,49 @@ type FakeApiClient struct {
 		result1 pluginModels.ServiceInstance
 		result2 error
 	}
+	GetSharedServicesStub        func(cliConnection plugin.CliConnection, spaceGuid string, name string) ([]pluginModels.ServiceInstance, error)
+	getSharedServicesMutex       sync.RWMutex
+	getSharedServicesArgsForCall []struct {
+		cliConnection plugin.CliConnection
+		spaceGuid     string
+		name          string
+	}
+	getSharedServicesReturns struct {
+		result1 []pluginModels.ServiceInstance
+		result2 error
+	}
+	getSharedServicesReturnsOnCall map[int]struct {
+		result1 []pluginModels.ServiceInstance
+		result2 error
+	}
+	GetServiceInstancesStub        func(cliConnection plugin.CliConnection, spaceGuid string) ([]pluginModels.ServiceInstance, error)
+	getServiceInstancesMutex       sync.RWMutex
+	getServiceInstancesArgsForCall []struct {
+		cliConnection plugin.CliConnection
+		spaceGuid     string
+	}
+	getServiceInstancesReturns struct {
+		result1 []pluginModels.ServiceInstance
+		result2 error
+	}
+	getServiceInstancesReturnsOnCall map[int]struct {
+		result1 []pluginModels.ServiceInstance
+		result2 error
+	}
+	GetOrgServiceInstancesStub        func(cliConnection plugin.CliConnection, orgGuid string) ([]pluginModels.ServiceInstance, error)
+	getOrgServiceInstancesMutex       sync.RWMutex
+	getOrgServiceInstancesArgsForCall []struct {
+		cliConnection plugin.CliConnection
+		orgGuid       string
+	}
+	getOrgServiceInstancesReturns struct {
+		result1 []pluginModels.ServiceInstance
+		result2 error
+	}
+	getOrgServiceInstancesReturnsOnCall map[int]struct {
+		result1 []pluginModels.ServiceInstance
+		result2 error
+	}
 	GetServiceKeyStub        func(cliConnection plugin.CliConnection, serviceInstanceGuid string, keyName string) (key pluginModels.ServiceKey, found bool, err error)
 	getServiceKeyMutex       sync.RWMutex
 	getServiceKeyArgsForCall []struct {
@@ -56,6 +128,20 @@ type FakeApiClient struct {
 		result2 bool
 		result3 error
 	}
+	FindServiceKeysStub        func(cliConnection plugin.CliConnection, serviceInstanceGuids []string) ([]pluginModels.ServiceKey, error)
+	findServiceKeysMutex       sync.RWMutex
+	findServiceKeysArgsForCall []struct {
+		cliConnection        plugin.CliConnection
+		serviceInstanceGuids []string
+	}
+	findServiceKeysReturns struct {
+		result1 []pluginModels.ServiceKey
+		result2 error
+	}
+	findServiceKeysReturnsOnCall map[int]struct {
+		result1 []pluginModels.ServiceKey
+		result2 error
+	}
 	CreateServiceKeyStub        func(cliConnection plugin.CliConnection, serviceInstanceGuid string, keyName string) (pluginModels.ServiceKey, error)
 	createServiceKeyMutex       sync.RWMutex
 	createServiceKeyArgsForCall []struct {
@@ -71,6 +157,73 @@ type FakeApiClient struct {
 		result1 pluginModels.ServiceKey
 		result2 error
 	}
+	DeleteServiceKeyStub        func(cliConnection plugin.CliConnection, serviceKeyGuid string) error
+	deleteServiceKeyMutex       sync.RWMutex
+	deleteServiceKeyArgsForCall []struct {
+		cliConnection  plugin.CliConnection
+		serviceKeyGuid string
+	}
+	deleteServiceKeyReturns struct {
+		result1 error
+	}
+	deleteServiceKeyReturnsOnCall map[int]struct {
+		result1 error
+	}
+	IsSshEnabledStub        func(cliConnection plugin.CliConnection, appGuid string) (bool, error)
+	isSshEnabledMutex       sync.RWMutex
+	isSshEnabledArgsForCall []struct {
+		cliConnection plugin.CliConnection
+		appGuid       string
+	}
+	isSshEnabledReturns struct {
+		result1 bool
+		result2 error
+	}
+	isSshEnabledReturnsOnCall map[int]struct {
+		result1 bool
+		result2 error
+	}
+	GetSshInfoStub        func(cliConnection plugin.CliConnection) (pluginModels.SshInfo, error)
+	getSshInfoMutex       sync.RWMutex
+	getSshInfoArgsForCall []struct {
+		cliConnection plugin.CliConnection
+	}
+	getSshInfoReturns struct {
+		result1 pluginModels.SshInfo
+		result2 error
+	}
+	getSshInfoReturnsOnCall map[int]struct {
+		result1 pluginModels.SshInfo
+		result2 error
+	}
+	GetSshCodeStub        func(cliConnection plugin.CliConnection, sshInfo pluginModels.SshInfo) (string, error)
+	getSshCodeMutex       sync.RWMutex
+	getSshCodeArgsForCall []struct {
+		cliConnection plugin.CliConnection
+		sshInfo       pluginModels.SshInfo
+	}
+	getSshCodeReturns struct {
+		result1 string
+		result2 error
+	}
+	getSshCodeReturnsOnCall map[int]struct {
+		result1 string
+		result2 error
+	}
+	GetCredhubCredentialsStub        func(cliConnection plugin.CliConnection, credhubRef string) (pluginModels.ServiceKey, error)
+	getCredhubCredentialsMutex       sync.RWMutex
+	getCredhubCredentialsArgsForCall []struct {
+		cliConnection plugin.CliConnection
+		credhubRef    string
+	}
+	getCredhubCredentialsReturns struct {
+		result1 pluginModels.ServiceKey
+		result2 error
+	}
+	getCredhubCredentialsReturnsOnCall map[int]struct {
+		result1 pluginModels.ServiceKey
+		result2 error
+	}
 	invocations      map[string][][]interface{}
 	invocationsMutex sync.RWMutex
 }
@@ -126,6 +279,111 @@ func (fake *FakeApiClient) GetStartedAppsReturnsOnCall(i int, result1 []sdkModel
 	}{result1, result2}
 }
 
+func (fake *FakeApiClient) GetSpaceApps(cliConnection plugin.CliConnection, spaceGuid string) ([]sdkModels.GetAppsModel, error) {
+	fake.getSpaceAppsMutex.Lock()
+	ret, specificReturn := fake.getSpaceAppsReturnsOnCall[len(fake.getSpaceAppsArgsForCall)]
+	fake.getSpaceAppsArgsForCall = append(fake.getSpaceAppsArgsForCall, struct {
+		cliConnection plugin.CliConnection
+		spaceGuid     string
+	}{cliConnection, spaceGuid})
+	fake.recordInvocation("GetSpaceApps", []interface{}{cliConnection, spaceGuid})
+	fake.getSpaceAppsMutex.Unlock()
+	if fake.GetSpaceAppsStub != nil {
+		return fake.GetSpaceAppsStub(cliConnection, spaceGuid)
+	}
+	if specificReturn {
+		return ret.result1, ret.result2
+	}
+	return fake.getSpaceAppsReturns.result1, fake.getSpaceAppsReturns.result2
+}
+
+func (fake *FakeApiClient) GetSpaceAppsCallCount() int {
+	fake.getSpaceAppsMutex.RLock()
+	defer fake.getSpaceAppsMutex.RUnlock()
+	return len(fake.getSpaceAppsArgsForCall)
+}
+
+func (fake *FakeApiClient) GetSpaceAppsArgsForCall(i int) (plugin.CliConnection, string) {
+	fake.getSpaceAppsMutex.RLock()
+	defer fake.getSpaceAppsMutex.RUnlock()
+	return fake.getSpaceAppsArgsForCall[i].cliConnection, fake.getSpaceAppsArgsForCall[i].spaceGuid
+}
+
+func (fake *FakeApiClient) GetSpaceAppsReturns(result1 []sdkModels.GetAppsModel, result2 error) {
+	fake.GetSpaceAppsStub = nil
+	fake.getSpaceAppsReturns = struct {
+		result1 []sdkModels.GetAppsModel
+		result2 error
+	}{result1, result2}
+}
+
+func (fake *FakeApiClient) GetSpaceAppsReturnsOnCall(i int, result1 []sdkModels.GetAppsModel, result2 error) {
+	fake.GetSpaceAppsStub = nil
+	if fake.getSpaceAppsReturnsOnCall == nil {
+		fake.getSpaceAppsReturnsOnCall = make(map[int]struct {
+			result1 []sdkModels.GetAppsModel
+			result2 error
+		})
+	}
+	fake.getSpaceAppsReturnsOnCall[i] = struct {
+		result1 []sdkModels.GetAppsModel
+		result2 error
+	}{result1, result2}
+}
+
+func (fake *FakeApiClient) GetSpace(cliConnection plugin.CliConnection, orgName string, spaceName string) (pluginModels.Space, error) {
+	fake.getSpaceMutex.Lock()
+	ret, specificReturn := fake.getSpaceReturnsOnCall[len(fake.getSpaceArgsForCall)]
+	fake.getSpaceArgsForCall = append(fake.getSpaceArgsForCall, struct {
+		cliConnection plugin.CliConnection
+		orgName       string
+		spaceName     string
+	}{cliConnection, orgName, spaceName})
+	fake.recordInvocation("GetSpace", []interface{}{cliConnection, orgName, spaceName})
+	fake.getSpaceMutex.Unlock()
+	if fake.GetSpaceStub != nil {
+		return fake.GetSpaceStub(cliConnection, orgName, spaceName)
+	}
+	if specificReturn {
+		return ret.result1, ret.result2
+	}
+	return fake.getSpaceReturns.result1, fake.getSpaceReturns.result2
+}
+
+func (fake *FakeApiClient) GetSpaceCallCount() int {
+	fake.getSpaceMutex.RLock()
+	defer fake.getSpaceMutex.RUnlock()
+	return len(fake.getSpaceArgsForCall)
+}
+
+func (fake *FakeApiClient) GetSpaceArgsForCall(i int) (plugin.CliConnection, string, string) {
+	fake.getSpaceMutex.RLock()
+	defer fake.getSpaceMutex.RUnlock()
+	return fake.getSpaceArgsForCall[i].cliConnection, fake.getSpaceArgsForCall[i].orgName, fake.getSpaceArgsForCall[i].spaceName
+}
+
+func (fake *FakeApiClient) GetSpaceReturns(result1 pluginModels.Space, result2 error) {
+	fake.GetSpaceStub = nil
+	fake.getSpaceReturns = struct {
+		result1 pluginModels.Space
+		result2 error
+	}{result1, result2}
+}
+
+func (fake *FakeApiClient) GetSpaceReturnsOnCall(i int, result1 pluginModels.Space, result2 error) {
+	fake.GetSpaceStub = nil
+	if fake.getSpaceReturnsOnCall == nil {
+		fake.getSpaceReturnsOnCall = make(map[int]struct {
+			result1 pluginModels.Space
+			result2 error
+		})
+	}
+	fake.getSpaceReturnsOnCall[i] = struct {
+		result1 pluginModels.Space
+		result2 error
+	}{result1, result2}
+}
+
 func (fake *FakeApiClient) GetService(cliConnection plugin.CliConnection, spaceGuid string, name string) (pluginModels.ServiceInstance, error) {
 	fake.getServiceMutex.Lock()
 	ret, specificReturn := fake.getServiceReturnsOnCall[len(fake.getServiceArgsForCall)]
@@ -179,6 +437,163 @@ func (fake *FakeApiClient) GetServiceReturnsOnCall(i int, result1 pluginModels.S
 	}{result1, result2}
 }
 
+func (fake *FakeApiClient) GetSharedServices(cliConnection plugin.CliConnection, spaceGuid string, name string) ([]pluginModels.ServiceInstance, error) {
+	fake.getSharedServicesMutex.Lock()
+	ret, specificReturn := fake.getSharedServicesReturnsOnCall[len(fake.getSharedServicesArgsForCall)]
+	fake.getSharedServicesArgsForCall = append(fake.getSharedServicesArgsForCall, struct {
+		cliConnection plugin.CliConnection
+		spaceGuid     string
+		name          string
+	}{cliConnection, spaceGuid, name})
+	fake.recordInvocation("GetSharedServices", []interface{}{cliConnection, spaceGuid, name})
+	fake.getSharedServicesMutex.Unlock()
+	if fake.GetSharedServicesStub != nil {
+		return fake.GetSharedServicesStub(cliConnection, spaceGuid, name)
+	}
+	if specificReturn {
+		return ret.result1, ret.result2
+	}
+	return fake.getSharedServicesReturns.result1, fake.getSharedServicesReturns.result2
+}
+
+func (fake *FakeApiClient) GetSharedServicesCallCount() int {
+	fake.getSharedServicesMutex.RLock()
+	defer fake.getSharedServicesMutex.RUnlock()
+	return len(fake.getSharedServicesArgsForCall)
+}
+
+func (fake *FakeApiClient) GetSharedServicesArgsForCall(i int) (plugin.CliConnection, string, string) {
+	fake.getSharedServicesMutex.RLock()
+	defer fake.getSharedServicesMutex.RUnlock()
+	return fake.getSharedServicesArgsForCall[i].cliConnection, fake.getSharedServicesArgsForCall[i].spaceGuid, fake.getSharedServicesArgsForCall[i].name
+}
+
+func (fake *FakeApiClient) GetSharedServicesReturns(result1 []pluginModels.ServiceInstance, result2 error) {
+	fake.GetSharedServicesStub = nil
+	fake.getSharedServicesReturns = struct {
+		result1 []pluginModels.ServiceInstance
+		result2 error
+	}{result1, result2}
+}
+
+func (fake *FakeApiClient) GetSharedServicesReturnsOnCall(i int, result1 []pluginModels.ServiceInstance, result2 error) {
+	fake.GetSharedServicesStub = nil
+	if fake.getSharedServicesReturnsOnCall == nil {
+		fake.getSharedServicesReturnsOnCall = make(map[int]struct {
+			result1 []pluginModels.ServiceInstance
+			result2 error
+		})
+	}
+	fake.getSharedServicesReturnsOnCall[i] = struct {
+		result1 []pluginModels.ServiceInstance
+		result2 error
+	}{result1, result2}
+}
+
+func (fake *FakeApiClient) GetServiceInstances(cliConnection plugin.CliConnection, spaceGuid string) ([]pluginModels.ServiceInstance, error) {
+	fake.getServiceInstancesMutex.Lock()
+	ret, specificReturn := fake.getServiceInstancesReturnsOnCall[len(fake.getServiceInstancesArgsForCall)]
+	fake.getServiceInstancesArgsForCall = append(fake.getServiceInstancesArgsForCall, struct {
+		cliConnection plugin.CliConnection
+		spaceGuid     string
+	}{cliConnection, spaceGuid})
+	fake.recordInvocation("GetServiceInstances", []interface{}{cliConnection, spaceGuid})
+	fake.getServiceInstancesMutex.Unlock()
+	if fake.GetServiceInstancesStub != nil {
+		return fake.GetServiceInstancesStub(cliConnection, spaceGuid)
+	}
+	if specificReturn {
+		return ret.result1, ret.result2
+	}
+	return fake.getServiceInstancesReturns.result1, fake.getServiceInstancesReturns.result2
+}
+
+func (fake *FakeApiClient) GetServiceInstancesCallCount() int {
+	fake.getServiceInstancesMutex.RLock()
+	defer fake.getServiceInstancesMutex.RUnlock()
+	return len(fake.getServiceInstancesArgsForCall)
+}
+
+func (fake *FakeApiClient) GetServiceInstancesArgsForCall(i int) (plugin.CliConnection, string) {
+	fake.getServiceInstancesMutex.RLock()
+	defer fake.getServiceInstancesMutex.RUnlock()
+	return fake.getServiceInstancesArgsForCall[i].cliConnection, fake.getServiceInstancesArgsForCall[i].spaceGuid
+}
+
+func (fake *FakeApiClient) GetServiceInstancesReturns(result1 []pluginModels.ServiceInstance, result2 error) {
+	fake.GetServiceInstancesStub = nil
+	fake.getServiceInstancesReturns = struct {
+		result1 []pluginModels.ServiceInstance
+		result2 error
+	}{result1, result2}
+}
+
+func (fake *FakeApiClient) GetServiceInstancesReturnsOnCall(i int, result1 []pluginModels.ServiceInstance, result2 error) {
+	fake.GetServiceInstancesStub = nil
+	if fake.getServiceInstancesReturnsOnCall == nil {
+		fake.getServiceInstancesReturnsOnCall = make(map[int]struct {
+			result1 []pluginModels.ServiceInstance
+			result2 error
+		})
+	}
+	fake.getServiceInstancesReturnsOnCall[i] = struct {
+		result1 []pluginModels.ServiceInstance
+		result2 error
+	}{result1, result2}
+}
+
+func (fake *FakeApiClient) GetOrgServiceInstances(cliConnection plugin.CliConnection, orgGuid string) ([]pluginModels.ServiceInstance, error) {
+	fake.getOrgServiceInstancesMutex.Lock()
+	ret, specificReturn := fake.getOrgServiceInstancesReturnsOnCall[len(fake.getOrgServiceInstancesArgsForCall)]
+	fake.getOrgServiceInstancesArgsForCall = append(fake.getOrgServiceInstancesArgsForCall, struct {
+		cliConnection plugin.CliConnection
+		orgGuid       string
+	}{cliConnection, orgGuid})
+	fake.recordInvocation("GetOrgServiceInstances", []interface{}{cliConnection, orgGuid})
+	fake.getOrgServiceInstancesMutex.Unlock()
+	if fake.GetOrgServiceInstancesStub != nil {
+		return fake.GetOrgServiceInstancesStub(cliConnection, orgGuid)
+	}
+	if specificReturn {
+		return ret.result1, ret.result2
+	}
+	return fake.getOrgServiceInstancesReturns.result1, fake.getOrgServiceInstancesReturns.result2
+}
+
+func (fake *FakeApiClient) GetOrgServiceInstancesCallCount() int {
+	fake.getOrgServiceInstancesMutex.RLock()
+	defer fake.getOrgServiceInstancesMutex.RUnlock()
+	return len(fake.getOrgServiceInstancesArgsForCall)
+}
+
+func (fake *FakeApiClient) GetOrgServiceInstancesArgsForCall(i int) (plugin.CliConnection, string) {
+	fake.getOrgServiceInstancesMutex.RLock()
+	defer fake.getOrgServiceInstancesMutex.RUnlock()
+	return fake.getOrgServiceInstancesArgsForCall[i].cliConnection, fake.getOrgServiceInstancesArgsForCall[i].orgGuid
+}
+
+func (fake *FakeApiClient) GetOrgServiceInstancesReturns(result1 []pluginModels.ServiceInstance, result2 error) {
+	fake.GetOrgServiceInstancesStub = nil
+	fake.getOrgServiceInstancesReturns = struct {
+		result1 []pluginModels.ServiceInstance
+		result2 error
+	}{result1, result2}
+}
+
+func (fake *FakeApiClient) GetOrgServiceInstancesReturnsOnCall(i int, result1 []pluginModels.ServiceInstance, result2 error) {
+	fake.GetOrgServiceInstancesStub = nil
+	if fake.getOrgServiceInstancesReturnsOnCall == nil {
+		fake.getOrgServiceInstancesReturnsOnCall = make(map[int]struct {
+			result1 []pluginModels.ServiceInstance
+			result2 error
+		})
+	}
+	fake.getOrgServiceInstancesReturnsOnCall[i] = struct {
+		result1 []pluginModels.ServiceInstance
+		result2 error
+	}{result1, result2}
+}
+
 func (fake *FakeApiClient) GetServiceKey(cliConnection plugin.CliConnection, serviceInstanceGuid string, keyName string) (key pluginModels.ServiceKey, found bool, err error) {
 	fake.getServiceKeyMutex.Lock()
 	ret, specificReturn := fake.getServiceKeyReturnsOnCall[len(fake.getServiceKeyArgsForCall)]
@@ -235,6 +650,63 @@ func (fake *FakeApiClient) GetServiceKeyReturnsOnCall(i int, result1 pluginModel
 	}{result1, result2, result3}
 }
 
+func (fake *FakeApiClient) FindServiceKeys(cliConnection plugin.CliConnection, serviceInstanceGuids []string) ([]pluginModels.ServiceKey, error) {
+	var serviceInstanceGuidsCopy []string
+	if serviceInstanceGuids != nil {
+		serviceInstanceGuidsCopy = make([]string, len(serviceInstanceGuids))
+		copy(serviceInstanceGuidsCopy, serviceInstanceGuids)
+	}
+	fake.findServiceKeysMutex.Lock()
+	ret, specificReturn := fake.findServiceKeysReturnsOnCall[len(fake.findServiceKeysArgsForCall)]
+	fake.findServiceKeysArgsForCall = append(fake.findServiceKeysArgsForCall, struct {
+		cliConnection        plugin.CliConnection
+		serviceInstanceGuids []string
+	}{cliConnection, serviceInstanceGuidsCopy})
+	fake.recordInvocation("FindServiceKeys", []interface{}{cliConnection, serviceInstanceGuidsCopy})
+	fake.findServiceKeysMutex.Unlock()
+	if fake.FindServiceKeysStub != nil {
+		return fake.FindServiceKeysStub(cliConnection, serviceInstanceGuids)
+	}
+	if specificReturn {
+		return ret.result1, ret.result2
+	}
+	return fake.findServiceKeysReturns.result1, fake.findServiceKeysReturns.result2
+}
+
+func (fake *FakeApiClient) FindServiceKeysCallCount() int {
+	fake.findServiceKeysMutex.RLock()
+	defer fake.findServiceKeysMutex.RUnlock()
+	return len(fake.findServiceKeysArgsForCall)
+}
+
+func (fake *FakeApiClient) FindServiceKeysArgsForCall(i int) (plugin.CliConnection, []string) {
+	fake.findServiceKeysMutex.RLock()
+	defer fake.findServiceKeysMutex.RUnlock()
+	return fake.findServiceKeysArgsForCall[i].cliConnection, fake.findServiceKeysArgsForCall[i].serviceInstanceGuids
+}
+
+func (fake *FakeApiClient) FindServiceKeysReturns(result1 []pluginModels.ServiceKey, result2 error) {
+	fake.FindServiceKeysStub = nil
+	fake.findServiceKeysReturns = struct {
+		result1 []pluginModels.ServiceKey
+		result2 error
+	}{result1, result2}
+}
+
+func (fake *FakeApiClient) FindServiceKeysReturnsOnCall(i int, result1 []pluginModels.ServiceKey, result2 error) {
+	fake.FindServiceKeysStub = nil
+	if fake.findServiceKeysReturnsOnCall == nil {
+		fake.findServiceKeysReturnsOnCall = make(map[int]struct {
+			result1 []pluginModels.ServiceKey
+			result2 error
+		})
+	}
+	fake.findServiceKeysReturnsOnCall[i] = struct {
+		result1 []pluginModels.ServiceKey
+		result2 error
+	}{result1, result2}
+}
+
 func (fake *FakeApiClient) CreateServiceKey(cliConnection plugin.CliConnection, serviceInstanceGuid string, keyName string) (pluginModels.ServiceKey, error) {
 	fake.createServiceKeyMutex.Lock()
 	ret, specificReturn := fake.createServiceKeyReturnsOnCall[len(fake.createServiceKeyArgsForCall)]
@@ -288,17 +760,295 @@ func (fake *FakeApiClient) CreateServiceKeyReturnsOnCall(i int, result1 pluginMo
 	}{result1, result2}
 }
 
+func (fake *FakeApiClient) DeleteServiceKey(cliConnection plugin.CliConnection, serviceKeyGuid string) error {
+	fake.deleteServiceKeyMutex.Lock()
+	ret, specificReturn := fake.deleteServiceKeyReturnsOnCall[len(fake.deleteServiceKeyArgsForCall)]
+	fake.deleteServiceKeyArgsForCall = append(fake.deleteServiceKeyArgsForCall, struct {
+		cliConnection  plugin.CliConnection
+		serviceKeyGuid string
+	}{cliConnection, serviceKeyGuid})
+	fake.recordInvocation("DeleteServiceKey", []interface{}{cliConnection, serviceKeyGuid})
+	fake.deleteServiceKeyMutex.Unlock()
+	if fake.DeleteServiceKeyStub != nil {
+		return fake.DeleteServiceKeyStub(cliConnection, serviceKeyGuid)
+	}
+	if specificReturn {
+		return ret.result1
+	}
+	return fake.deleteServiceKeyReturns.result1
+}
+
+func (fake *FakeApiClient) DeleteServiceKeyCallCount() int {
+	fake.deleteServiceKeyMutex.RLock()
+	defer fake.deleteServiceKeyMutex.RUnlock()
+	return len(fake.deleteServiceKeyArgsForCall)
+}
+
+func (fake *FakeApiClient) DeleteServiceKeyArgsForCall(i int) (plugin.CliConnection, string) {
+	fake.deleteServiceKeyMutex.RLock()
+	defer fake.deleteServiceKeyMutex.RUnlock()
+	return fake.deleteServiceKeyArgsForCall[i].cliConnection, fake.deleteServiceKeyArgsForCall[i].serviceKeyGuid
+}
+
+func (fake *FakeApiClient) DeleteServiceKeyReturns(result1 error) {
+	fake.DeleteServiceKeyStub = nil
+	fake.deleteServiceKeyReturns = struct {
+		result1 error
+	}{result1}
+}
+
+func (fake *FakeApiClient) DeleteServiceKeyReturnsOnCall(i int, result1 error) {
+	fake.DeleteServiceKeyStub = nil
+	if fake.deleteServiceKeyReturnsOnCall == nil {
+		fake.deleteServiceKeyReturnsOnCall = make(map[int]struct {
+			result1 error
+		})
+	}
+	fake.deleteServiceKeyReturnsOnCall[i] = struct {
+		result1 error
+	}{result1}
+}
+
+func (fake *FakeApiClient) IsSshEnabled(cliConnection plugin.CliConnection, appGuid string) (bool, error) {
+	fake.isSshEnabledMutex.Lock()
+	ret, specificReturn := fake.isSshEnabledReturnsOnCall[len(fake.isSshEnabledArgsForCall)]
+	fake.isSshEnabledArgsForCall = append(fake.isSshEnabledArgsForCall, struct {
+		cliConnection plugin.CliConnection
+		appGuid       string
+	}{cliConnection, appGuid})
+	fake.recordInvocation("IsSshEnabled", []interface{}{cliConnection, appGuid})
+	fake.isSshEnabledMutex.Unlock()
+	if fake.IsSshEnabledStub != nil {
+		return fake.IsSshEnabledStub(cliConnection, appGuid)
+	}
+	if specificReturn {
+		return ret.result1, ret.result2
+	}
+	return fake.isSshEnabledReturns.result1, fake.isSshEnabledReturns.result2
+}
+
+func (fake *FakeApiClient) IsSshEnabledCallCount() int {
+	fake.isSshEnabledMutex.RLock()
+	defer fake.isSshEnabledMutex.RUnlock()
+	return len(fake.isSshEnabledArgsForCall)
+}
+
+func (fake *FakeApiClient) IsSshEnabledArgsForCall(i int) (plugin.CliConnection, string) {
+	fake.isSshEnabledMutex.RLock()
+	defer fake.isSshEnabledMutex.RUnlock()
+	return fake.isSshEnabledArgsForCall[i].cliConnection, fake.isSshEnabledArgsForCall[i].appGuid
+}
+
+func (fake *FakeApiClient) IsSshEnabledReturns(result1 bool, result2 error) {
+	fake.IsSshEnabledStub = nil
+	fake.isSshEnabledReturns = struct {
+		result1 bool
+		result2 error
+	}{result1, result2}
+}
+
+func (fake *FakeApiClient) IsSshEnabledReturnsOnCall(i int, result1 bool, result2 error) {
+	fake.IsSshEnabledStub = nil
+	if fake.isSshEnabledReturnsOnCall == nil {
+		fake.isSshEnabledReturnsOnCall = make(map[int]struct {
+			result1 bool
+			result2 error
+		})
+	}
+	fake.isSshEnabledReturnsOnCall[i] = struct {
+		result1 bool
+		result2 error
+	}{result1, result2}
+}
+
+func (fake *FakeApiClient) GetSshInfo(cliConnection plugin.CliConnection) (pluginModels.SshInfo, error) {
+	fake.getSshInfoMutex.Lock()
+	ret, specificReturn := fake.getSshInfoReturnsOnCall[len(fake.getSshInfoArgsForCall)]
+	fake.getSshInfoArgsForCall = append(fake.getSshInfoArgsForCall, struct {
+		cliConnection plugin.CliConnection
+	}{cliConnection})
+	fake.recordInvocation("GetSshInfo", []interface{}{cliConnection})
+	fake.getSshInfoMutex.Unlock()
+	if fake.GetSshInfoStub != nil {
+		return fake.GetSshInfoStub(cliConnection)
+	}
+	if specificReturn {
+		return ret.result1, ret.result2
+	}
+	return fake.getSshInfoReturns.result1, fake.getSshInfoReturns.result2
+}
+
+func (fake *FakeApiClient) GetSshInfoCallCount() int {
+	fake.getSshInfoMutex.RLock()
+	defer fake.getSshInfoMutex.RUnlock()
+	return len(fake.getSshInfoArgsForCall)
+}
+
+func (fake *FakeApiClient) GetSshInfoArgsForCall(i int) plugin.CliConnection {
+	fake.getSshInfoMutex.RLock()
+	defer fake.getSshInfoMutex.RUnlock()
+	return fake.getSshInfoArgsForCall[i].cliConnection
+}
+
+func (fake *FakeApiClient) GetSshInfoReturns(result1 pluginModels.SshInfo, result2 error) {
+	fake.GetSshInfoStub = nil
+	fake.getSshInfoReturns = struct {
+		result1 pluginModels.SshInfo
+		result2 error
+	}{result1, result2}
+}
+
+func (fake *FakeApiClient) GetSshInfoReturnsOnCall(i int, result1 pluginModels.SshInfo, result2 error) {
+	fake.GetSshInfoStub = nil
+	if fake.getSshInfoReturnsOnCall == nil {
+		fake.getSshInfoReturnsOnCall = make(map[int]struct {
+			result1 pluginModels.SshInfo
+			result2 error
+		})
+	}
+	fake.getSshInfoReturnsOnCall[i] = struct {
+		result1 pluginModels.SshInfo
+		result2 error
+	}{result1, result2}
+}
+
+func (fake *FakeApiClient) GetSshCode(cliConnection plugin.CliConnection, sshInfo pluginModels.SshInfo) (string, error) {
+	fake.getSshCodeMutex.Lock()
+	ret, specificReturn := fake.getSshCodeReturnsOnCall[len(fake.getSshCodeArgsForCall)]
+	fake.getSshCodeArgsForCall = append(fake.getSshCodeArgsForCall, struct {
+		cliConnection plugin.CliConnection
+		sshInfo       pluginModels.SshInfo
+	}{cliConnection, sshInfo})
+	fake.recordInvocation("GetSshCode", []interface{}{cliConnection, sshInfo})
+	fake.getSshCodeMutex.Unlock()
+	if fake.GetSshCodeStub != nil {
+		return fake.GetSshCodeStub(cliConnection, sshInfo)
+	}
+	if specificReturn {
+		return ret.result1, ret.result2
+	}
+	return fake.getSshCodeReturns.result1, fake.getSshCodeReturns.result2
+}
+
+func (fake *FakeApiClient) GetSshCodeCallCount() int {
+	fake.getSshCodeMutex.RLock()
+	defer fake.getSshCodeMutex.RUnlock()
+	return len(fake.getSshCodeArgsForCall)
+}
+
+func (fake *FakeApiClient) GetSshCodeArgsForCall(i int) (plugin.CliConnection, pluginModels.SshInfo) {
+	fake.getSshCodeMutex.RLock()
+	defer fake.getSshCodeMutex.RUnlock()
+	return fake.getSshCodeArgsForCall[i].cliConnection, fake.getSshCodeArgsForCall[i].sshInfo
+}
+
+func (fake *FakeApiClient) GetSshCodeReturns(result1 string, result2 error) {
+	fake.GetSshCodeStub = nil
+	fake.getSshCodeReturns = struct {
+		result1 string
+		result2 error
+	}{result1, result2}
+}
+
+func (fake *FakeApiClient) GetSshCodeReturnsOnCall(i int, result1 string, result2 error) {
+	fake.GetSshCodeStub = nil
+	if fake.getSshCodeReturnsOnCall == nil {
+		fake.getSshCodeReturnsOnCall = make(map[int]struct {
+			result1 string
+			result2 error
+		})
+	}
+	fake.getSshCodeReturnsOnCall[i] = struct {
+		result1 string
+		result2 error
+	}{result1, result2}
+}
+
+func (fake *FakeApiClient) GetCredhubCredentials(cliConnection plugin.CliConnection, credhubRef string) (pluginModels.ServiceKey, error) {
+	fake.getCredhubCredentialsMutex.Lock()
+	ret, specificReturn := fake.getCredhubCredentialsReturnsOnCall[len(fake.getCredhubCredentialsArgsForCall)]
+	fake.getCredhubCredentialsArgsForCall = append(fake.getCredhubCredentialsArgsForCall, struct {
+		cliConnection plugin.CliConnection
+		credhubRef    string
+	}{cliConnection, credhubRef})
+	fake.recordInvocation("GetCredhubCredentials", []interface{}{cliConnection, credhubRef})
+	fake.getCredhubCredentialsMutex.Unlock()
+	if fake.GetCredhubCredentialsStub != nil {
+		return fake.GetCredhubCredentialsStub(cliConnection, credhubRef)
+	}
+	if specificReturn {
+		return ret.result1, ret.result2
+	}
+	return fake.getCredhubCredentialsReturns.result1, fake.getCredhubCredentialsReturns.result2
+}
+
+func (fake *FakeApiClient) GetCredhubCredentialsCallCount() int {
+	fake.getCredhubCredentialsMutex.RLock()
+	defer fake.getCredhubCredentialsMutex.RUnlock()
+	return len(fake.getCredhubCredentialsArgsForCall)
+}
+
+func (fake *FakeApiClient) GetCredhubCredentialsArgsForCall(i int) (plugin.CliConnection, string) {
+	fake.getCredhubCredentialsMutex.RLock()
+	defer fake.getCredhubCredentialsMutex.RUnlock()
+	return fake.getCredhubCredentialsArgsForCall[i].cliConnection, fake.getCredhubCredentialsArgsForCall[i].credhubRef
+}
+
+func (fake *FakeApiClient) GetCredhubCredentialsReturns(result1 pluginModels.ServiceKey, result2 error) {
+	fake.GetCredhubCredentialsStub = nil
+	fake.getCredhubCredentialsReturns = struct {
+		result1 pluginModels.ServiceKey
+		result2 error
+	}{result1, result2}
+}
+
+func (fake *FakeApiClient) GetCredhubCredentialsReturnsOnCall(i int, result1 pluginModels.ServiceKey, result2 error) {
+	fake.GetCredhubCredentialsStub = nil
+	if fake.getCredhubCredentialsReturnsOnCall == nil {
+		fake.getCredhubCredentialsReturnsOnCall = make(map[int]struct {
+			result1 pluginModels.ServiceKey
+			result2 error
+		})
+	}
+	fake.getCredhubCredentialsReturnsOnCall[i] = struct {
+		result1 pluginModels.ServiceKey
+		result2 error
+	}{result1, result2}
+}
+
 func (fake *FakeApiClient) Invocations() map[string][][]interface{} {
 	fake.invocationsMutex.RLock()
 	defer fake.invocationsMutex.RUnlock()
 	fake.getStartedAppsMutex.RLock()
 	defer fake.getStartedAppsMutex.RUnlock()
+	fake.getSpaceAppsMutex.RLock()
+	defer fake.getSpaceAppsMutex.RUnlock()
+	fake.getSpaceMutex.RLock()
+	defer fake.getSpaceMutex.RUnlock()
 	fake.getServiceMutex.RLock()
 	defer fake.getServiceMutex.RUnlock()
+	fake.getSharedServicesMutex.RLock()
+	defer fake.getSharedServicesMutex.RUnlock()
+	fake.getServiceInstancesMutex.RLock()
+	defer fake.getServiceInstancesMutex.RUnlock()
+	fake.getOrgServiceInstancesMutex.RLock()
+	defer fake.getOrgServiceInstancesMutex.RUnlock()
 	fake.getServiceKeyMutex.RLock()
 	defer fake.getServiceKeyMutex.RUnlock()
+	fake.findServiceKeysMutex.RLock()
+	defer fake.findServiceKeysMutex.RUnlock()
 	fake.createServiceKeyMutex.RLock()
 	defer fake.createServiceKeyMutex.RUnlock()
+	fake.deleteServiceKeyMutex.RLock()
+	defer fake.deleteServiceKeyMutex.RUnlock()
+	fake.isSshEnabledMutex.RLock()
+	defer fake.isSshEnabledMutex.RUnlock()
+	fake.getSshInfoMutex.RLock()
+	defer fake.getSshInfoMutex.RUnlock()
+	fake.getSshCodeMutex.RLock()
+	defer fake.getSshCodeMutex.RUnlock()
+	fake.getCredhubCredentialsMutex.RLock()
+	defer fake.getCredhubCredentialsMutex.RUnlock()
 	copiedInvocations := map[string][][]interface{}{}
 	for key, value := range fake.invocations {
 		copiedInvocations[key] = value
@@ -23,13 +23,20 @@ type FakeCfService struct {
 		result1 []sdkModels.GetAppsModel
 		result2 error
 	}
-	OpenSshTunnelStub        func(cliConnection plugin.CliConnection, toService cfmysql.MysqlService, apps []sdkModels.GetAppsModel, localPort int)
+	OpenSshTunnelStub        func(cliConnection plugin.CliConnection, forwards []cfmysql.TunnelForward, apps []sdkModels.GetAppsModel) (cfmysql.SshTunnel, error)
 	openSshTunnelMutex       sync.RWMutex
 	openSshTunnelArgsForCall []struct {
 		cliConnection plugin.CliConnection
-		toService     cfmysql.MysqlService
+		forwards      []cfmysql.TunnelForward
 		apps          []sdkModels.GetAppsModel
-		localPort     int
+	}
+	openSshTunnelReturns struct {
+		result1 cfmysql.SshTunnel
+		result2 error
+	}
+	openSshTunnelReturnsOnCall map[int]struct {
+		result1 cfmysql.SshTunnel
+		result2 error
 	}
 	GetServiceStub        func(connection plugin.CliConnection, name string) (cfmysql.MysqlService, error)
 	getServiceMutex       sync.RWMutex
@@ -45,6 +52,74 @@ type FakeCfService struct {
 		result1 cfmysql.MysqlService
 		result2 error
 	}
+	GetEphemeralServiceStub        func(connection plugin.CliConnection, name string) (cfmysql.MysqlService, error)
+	getEphemeralServiceMutex       sync.RWMutex
+	getEphemeralServiceArgsForCall []struct {
+		connection plugin.CliConnection
+		name       string
+	}
+	getEphemeralServiceReturns struct {
+		result1 cfmysql.MysqlService
+		result2 error
+	}
+	getEphemeralServiceReturnsOnCall map[int]struct {
+		result1 cfmysql.MysqlService
+		result2 error
+	}
+	GetServicesStub        func(connection plugin.CliConnection) ([]cfmysql.ServiceSummary, error)
+	getServicesMutex       sync.RWMutex
+	getServicesArgsForCall []struct {
+		connection plugin.CliConnection
+	}
+	getServicesReturns struct {
+		result1 []cfmysql.ServiceSummary
+		result2 error
+	}
+	getServicesReturnsOnCall map[int]struct {
+		result1 []cfmysql.ServiceSummary
+		result2 error
+	}
+	GetServiceKeysStub        func(connection plugin.CliConnection, wholeOrg bool) ([]cfmysql.ServiceKeySummary, error)
+	getServiceKeysMutex       sync.RWMutex
+	getServiceKeysArgsForCall []struct {
+		connection plugin.CliConnection
+		wholeOrg   bool
+	}
+	getServiceKeysReturns struct {
+		result1 []cfmysql.ServiceKeySummary
+		result2 error
+	}
+	getServiceKeysReturnsOnCall map[int]struct {
+		result1 []cfmysql.ServiceKeySummary
+		result2 error
+	}
+	DeleteServiceKeyStub        func(connection plugin.CliConnection, serviceKeyGuid string) error
+	deleteServiceKeyMutex       sync.RWMutex
+	deleteServiceKeyArgsForCall []struct {
+		connection     plugin.CliConnection
+		serviceKeyGuid string
+	}
+	deleteServiceKeyReturns struct {
+		result1 error
+	}
+	deleteServiceKeyReturnsOnCall map[int]struct {
+		result1 error
+	}
+	TargetSpaceStub        func(connection plugin.CliConnection, orgName string, spaceName string) (plugin.CliConnection, error)
+	targetSpaceMutex       sync.RWMutex
+	targetSpaceArgsForCall []struct {
+		connection plugin.CliConnection
+		orgName    string
+		spaceName  string
+	}
+	targetSpaceReturns struct {
+		result1 plugin.CliConnection
+		result2 error
+	}
+	targetSpaceReturnsOnCall map[int]struct {
+		result1 plugin.CliConnection
+		result2 error
+	}
 	invocations      map[string][][]interface{}
 	invocationsMutex sync.RWMutex
 }
@@ -100,24 +175,33 @@ func (fake *FakeCfService) GetStartedAppsReturnsOnCall(i int, result1 []sdkModel
 	}{result1, result2}
 }
 
-func (fake *FakeCfService) OpenSshTunnel(cliConnection plugin.CliConnection, toService cfmysql.MysqlService, apps []sdkModels.GetAppsModel, localPort int) {
+func (fake *FakeCfService) OpenSshTunnel(cliConnection plugin.CliConnection, forwards []cfmysql.TunnelForward, apps []sdkModels.GetAppsModel) (cfmysql.SshTunnel, error) {
+	var forwardsCopy []cfmysql.TunnelForward
+	if forwards != nil {
+		forwardsCopy = make([]cfmysql.TunnelForward, len(forwards))
+		copy(forwardsCopy, forwards)
+	}
 	var appsCopy []sdkModels.GetAppsModel
 	if apps != nil {
 		appsCopy = make([]sdkModels.GetAppsModel, len(apps))
 		copy(appsCopy, apps)
 	}
 	fake.openSshTunnelMutex.Lock()
+	ret, specificReturn := fake.openSshTunnelReturnsOnCall[len(fake.openSshTunnelArgsForCall)]
 	fake.openSshTunnelArgsForCall = append(fake.openSshTunnelArgsForCall, struct {
 		cliConnection plugin.CliConnection
-		toService     cfmysql.MysqlService
+		forwards      []cfmysql.TunnelForward
 		apps          []sdkModels.GetAppsModel
-		localPort     int
-	}{cliConnection, toService, appsCopy, localPort})
-	fake.recordInvocation("OpenSshTunnel", []interface{}{cliConnection, toService, appsCopy, localPort})
+	}{cliConnection, forwardsCopy, appsCopy})
+	fake.recordInvocation("OpenSshTunnel", []interface{}{cliConnection, forwardsCopy, appsCopy})
 	fake.openSshTunnelMutex.Unlock()
 	if fake.OpenSshTunnelStub != nil {
-		fake.OpenSshTunnelStub(cliConnection, toService, apps, localPort)
+		return fake.OpenSshTunnelStub(cliConnection, forwards, apps)
 	}
+	if specificReturn {
+		return ret.result1, ret.result2
+	}
+	return fake.openSshTunnelReturns.result1, fake.openSshTunnelReturns.result2
 }
 
 func (fake *FakeCfService) OpenSshTunnelCallCount() int {
@@ -126,10 +210,32 @@ func (fake *FakeCfService) OpenSshTunnelCallCount() int {
 	return len(fake.openSshTunnelArgsForCall)
 }
 
-func (fake *FakeCfService) OpenSshTunnelArgsForCall(i int) (plugin.CliConnection, cfmysql.MysqlService, []sdkModels.GetAppsModel, int) {
+func (fake *FakeCfService) OpenSshTunnelArgsForCall(i int) (plugin.CliConnection, []cfmysql.TunnelForward, []sdkModels.GetAppsModel) {
 	fake.openSshTunnelMutex.RLock()
 	defer fake.openSshTunnelMutex.RUnlock()
-	return fake.openSshTunnelArgsForCall[i].cliConnection, fake.openSshTunnelArgsForCall[i].toService, fake.openSshTunnelArgsForCall[i].apps, fake.openSshTunnelArgsForCall[i].localPort
+	return fake.openSshTunnelArgsForCall[i].cliConnection, fake.openSshTunnelArgsForCall[i].forwards, fake.openSshTunnelArgsForCall[i].apps
+}
+
+func (fake *FakeCfService) OpenSshTunnelReturns(result1 cfmysql.SshTunnel, result2 error) {
+	fake.OpenSshTunnelStub = nil
+	fake.openSshTunnelReturns = struct {
+		result1 cfmysql.SshTunnel
+		result2 error
+	}{result1, result2}
+}
+
+func (fake *FakeCfService) OpenSshTunnelReturnsOnCall(i int, result1 cfmysql.SshTunnel, result2 error) {
+	fake.OpenSshTunnelStub = nil
+	if fake.openSshTunnelReturnsOnCall == nil {
+		fake.openSshTunnelReturnsOnCall = make(map[int]struct {
+			result1 cfmysql.SshTunnel
+			result2 error
+		})
+	}
+	fake.openSshTunnelReturnsOnCall[i] = struct {
+		result1 cfmysql.SshTunnel
+		result2 error
+	}{result1, result2}
 }
 
 func (fake *FakeCfService) GetService(connection plugin.CliConnection, name string) (cfmysql.MysqlService, error) {
@@ -184,6 +290,263 @@ func (fake *FakeCfService) GetServiceReturnsOnCall(i int, result1 cfmysql.MysqlS
 	}{result1, result2}
 }
 
+func (fake *FakeCfService) GetEphemeralService(connection plugin.CliConnection, name string) (cfmysql.MysqlService, error) {
+	fake.getEphemeralServiceMutex.Lock()
+	ret, specificReturn := fake.getEphemeralServiceReturnsOnCall[len(fake.getEphemeralServiceArgsForCall)]
+	fake.getEphemeralServiceArgsForCall = append(fake.getEphemeralServiceArgsForCall, struct {
+		connection plugin.CliConnection
+		name       string
+	}{connection, name})
+	fake.recordInvocation("GetEphemeralService", []interface{}{connection, name})
+	fake.getEphemeralServiceMutex.Unlock()
+	if fake.GetEphemeralServiceStub != nil {
+		return fake.GetEphemeralServiceStub(connection, name)
+	}
+	if specificReturn {
+		return ret.result1, ret.result2
+	}
+	return fake.getEphemeralServiceReturns.result1, fake.getEphemeralServiceReturns.result2
+}
+
+func (fake *FakeCfService) GetEphemeralServiceCallCount() int {
+	fake.getEphemeralServiceMutex.RLock()
+	defer fake.getEphemeralServiceMutex.RUnlock()
+	return len(fake.getEphemeralServiceArgsForCall)
+}
+
+func (fake *FakeCfService) GetEphemeralServiceArgsForCall(i int) (plugin.CliConnection, string) {
+	fake.getEphemeralServiceMutex.RLock()
+	defer fake.getEphemeralServiceMutex.RUnlock()
+	return fake.getEphemeralServiceArgsForCall[i].connection, fake.getEphemeralServiceArgsForCall[i].name
+}
+
+func (fake *FakeCfService) GetEphemeralServiceReturns(result1 cfmysql.MysqlService, result2 error) {
+	fake.GetEphemeralServiceStub = nil
+	fake.getEphemeralServiceReturns = struct {
+		result1 cfmysql.MysqlService
+		result2 error
+	}{result1, result2}
+}
+
+func (fake *FakeCfService) GetEphemeralServiceReturnsOnCall(i int, result1 cfmysql.MysqlService, result2 error) {
+	fake.GetEphemeralServiceStub = nil
+	if fake.getEphemeralServiceReturnsOnCall == nil {
+		fake.getEphemeralServiceReturnsOnCall = make(map[int]struct {
+			result1 cfmysql.MysqlService
+			result2 error
+		})
+	}
+	fake.getEphemeralServiceReturnsOnCall[i] = struct {
+		result1 cfmysql.MysqlService
+		result2 error
+	}{result1, result2}
+}
+
+func (fake *FakeCfService) GetServices(connection plugin.CliConnection) ([]cfmysql.ServiceSummary, error) {
+	fake.getServicesMutex.Lock()
+	ret, specificReturn := fake.getServicesReturnsOnCall[len(fake.getServicesArgsForCall)]
+	fake.getServicesArgsForCall = append(fake.getServicesArgsForCall, struct {
+		connection plugin.CliConnection
+	}{connection})
+	fake.recordInvocation("GetServices", []interface{}{connection})
+	fake.getServicesMutex.Unlock()
+	if fake.GetServicesStub != nil {
+		return fake.GetServicesStub(connection)
+	}
+	if specificReturn {
+		return ret.result1, ret.result2
+	}
+	return fake.getServicesReturns.result1, fake.getServicesReturns.result2
+}
+
+func (fake *FakeCfService) GetServicesCallCount() int {
+	fake.getServicesMutex.RLock()
+	defer fake.getServicesMutex.RUnlock()
+	return len(fake.getServicesArgsForCall)
+}
+
+func (fake *FakeCfService) GetServicesArgsForCall(i int) plugin.CliConnection {
+	fake.getServicesMutex.RLock()
+	defer fake.getServicesMutex.RUnlock()
+	return fake.getServicesArgsForCall[i].connection
+}
+
+func (fake *FakeCfService) GetServicesReturns(result1 []cfmysql.ServiceSummary, result2 error) {
+	fake.GetServicesStub = nil
+	fake.getServicesReturns = struct {
+		result1 []cfmysql.ServiceSummary
+		result2 error
+	}{result1, result2}
+}
+
+func (fake *FakeCfService) GetServicesReturnsOnCall(i int, result1 []cfmysql.ServiceSummary, result2 error) {
+	fake.GetServicesStub = nil
+	if fake.getServicesReturnsOnCall == nil {
+		fake.getServicesReturnsOnCall = make(map[int]struct {
+			result1 []cfmysql.ServiceSummary
+			result2 error
+		})
+	}
+	fake.getServicesReturnsOnCall[i] = struct {
+		result1 []cfmysql.ServiceSummary
+		result2 error
+	}{result1, result2}
+}
+
+func (fake *FakeCfService) GetServiceKeys(connection plugin.CliConnection, wholeOrg bool) ([]cfmysql.ServiceKeySummary, error) {
+	fake.getServiceKeysMutex.Lock()
+	ret, specificReturn := fake.getServiceKeysReturnsOnCall[len(fake.getServiceKeysArgsForCall)]
+	fake.getServiceKeysArgsForCall = append(fake.getServiceKeysArgsForCall, struct {
+		connection plugin.CliConnection
+		wholeOrg   bool
+	}{connection, wholeOrg})
+	fake.recordInvocation("GetServiceKeys", []interface{}{connection, wholeOrg})
+	fake.getServiceKeysMutex.Unlock()
+	if fake.GetServiceKeysStub != nil {
+		return fake.GetServiceKeysStub(connection, wholeOrg)
+	}
+	if specificReturn {
+		return ret.result1, ret.result2
+	}
+	return fake.getServiceKeysReturns.result1, fake.getServiceKeysReturns.result2
+}
+
+func (fake *FakeCfService) GetServiceKeysCallCount() int {
+	fake.getServiceKeysMutex.RLock()
+	defer fake.getServiceKeysMutex.RUnlock()
+	return len(fake.getServiceKeysArgsForCall)
+}
+
+func (fake *FakeCfService) GetServiceKeysArgsForCall(i int) (plugin.CliConnection, bool) {
+	fake.getServiceKeysMutex.RLock()
+	defer fake.getServiceKeysMutex.RUnlock()
+	return fake.getServiceKeysArgsForCall[i].connection, fake.getServiceKeysArgsForCall[i].wholeOrg
+}
+
+func (fake *FakeCfService) GetServiceKeysReturns(result1 []cfmysql.ServiceKeySummary, result2 error) {
+	fake.GetServiceKeysStub = nil
+	fake.getServiceKeysReturns = struct {
+		result1 []cfmysql.ServiceKeySummary
+		result2 error
+	}{result1, result2}
+}
+
+func (fake *FakeCfService) GetServiceKeysReturnsOnCall(i int, result1 []cfmysql.ServiceKeySummary, result2 error) {
+	fake.GetServiceKeysStub = nil
+	if fake.getServiceKeysReturnsOnCall == nil {
+		fake.getServiceKeysReturnsOnCall = make(map[int]struct {
+			result1 []cfmysql.ServiceKeySummary
+			result2 error
+		})
+	}
+	fake.getServiceKeysReturnsOnCall[i] = struct {
+		result1 []cfmysql.ServiceKeySummary
+		result2 error
+	}{result1, result2}
+}
+
+func (fake *FakeCfService) DeleteServiceKey(connection plugin.CliConnection, serviceKeyGuid string) error {
+	fake.deleteServiceKeyMutex.Lock()
+	ret, specificReturn := fake.deleteServiceKeyReturnsOnCall[len(fake.deleteServiceKeyArgsForCall)]
+	fake.deleteServiceKeyArgsForCall = append(fake.deleteServiceKeyArgsForCall, struct {
+		connection     plugin.CliConnection
+		serviceKeyGuid string
+	}{connection, serviceKeyGuid})
+	fake.recordInvocation("DeleteServiceKey", []interface{}{connection, serviceKeyGuid})
+	fake.deleteServiceKeyMutex.Unlock()
+	if fake.DeleteServiceKeyStub != nil {
+		return fake.DeleteServiceKeyStub(connection, serviceKeyGuid)
+	}
+	if specificReturn {
+		return ret.result1
+	}
+	return fake.deleteServiceKeyReturns.result1
+}
+
+func (fake *FakeCfService) DeleteServiceKeyCallCount() int {
+	fake.deleteServiceKeyMutex.RLock()
+	defer fake.deleteServiceKeyMutex.RUnlock()
+	return len(fake.deleteServiceKeyArgsForCall)
+}
+
+func (fake *FakeCfService) DeleteServiceKeyArgsForCall(i int) (plugin.CliConnection, string) {
+	fake.deleteServiceKeyMutex.RLock()
+	defer fake.deleteServiceKeyMutex.RUnlock()
+	return fake.deleteServiceKeyArgsForCall[i].connection, fake.deleteServiceKeyArgsForCall[i].serviceKeyGuid
+}
+
+func (fake *FakeCfService) DeleteServiceKeyReturns(result1 error) {
+	fake.DeleteServiceKeyStub = nil
+	fake.deleteServiceKeyReturns = struct {
+		result1 error
+	}{result1}
+}
+
+func (fake *FakeCfService) DeleteServiceKeyReturnsOnCall(i int, result1 error) {
+	fake.DeleteServiceKeyStub = nil
+	if fake.deleteServiceKeyReturnsOnCall == nil {
+		fake.deleteServiceKeyReturnsOnCall = make(map[int]struct {
+			result1 error
+		})
+	}
+	fake.deleteServiceKeyReturnsOnCall[i] = struct {
+		result1 error
+	}{result1}
+}
+
+func (fake *FakeCfService) TargetSpace(connection plugin.CliConnection, orgName string, spaceName string) (plugin.CliConnection, error) {
+	fake.targetSpaceMutex.Lock()
+	ret, specificReturn := fake.targetSpaceReturnsOnCall[len(fake.targetSpaceArgsForCall)]
+	fake.targetSpaceArgsForCall = append(fake.targetSpaceArgsForCall, struct {
+		connection plugin.CliConnection
+		orgName    string
+		spaceName  string
+	}{connection, orgName, spaceName})
+	fake.recordInvocation("TargetSpace", []interface{}{connection, orgName, spaceName})
+	fake.targetSpaceMutex.Unlock()
+	if fake.TargetSpaceStub != nil {
+		return fake.TargetSpaceStub(connection, orgName, spaceName)
+	}
+	if specificReturn {
+		return ret.result1, ret.result2
+	}
+	return fake.targetSpaceReturns.result1, fake.targetSpaceReturns.result2
+}
+
+func (fake *FakeCfService) TargetSpaceCallCount() int {
+	fake.targetSpaceMutex.RLock()
+	defer fake.targetSpaceMutex.RUnlock()
+	return len(fake.targetSpaceArgsForCall)
+}
+
+func (fake *FakeCfService) TargetSpaceArgsForCall(i int) (plugin.CliConnection, string, string) {
+	fake.targetSpaceMutex.RLock()
+	defer fake.targetSpaceMutex.RUnlock()
+	return fake.targetSpaceArgsForCall[i].connection, fake.targetSpaceArgsForCall[i].orgName, fake.targetSpaceArgsForCall[i].spaceName
+}
+
+func (fake *FakeCfService) TargetSpaceReturns(result1 plugin.CliConnection, result2 error) {
+	fake.TargetSpaceStub = nil
+	fake.targetSpaceReturns = struct {
+		result1 plugin.CliConnection
+		result2 error
+	}{result1, result2}
+}
+
+func (fake *FakeCfService) TargetSpaceReturnsOnCall(i int, result1 plugin.CliConnection, result2 error) {
+	fake.TargetSpaceStub = nil
+	if fake.targetSpaceReturnsOnCall == nil {
+		fake.targetSpaceReturnsOnCall = make(map[int]struct {
+			result1 plugin.CliConnection
+			result2 error
+		})
+	}
+	fake.targetSpaceReturnsOnCall[i] = struct {
+		result1 plugin.CliConnection
+		result2 error
+	}{result1, result2}
+}
+
 func (fake *FakeCfService) Invocations() map[string][][]interface{} {
 	fake.invocationsMutex.RLock()
 	defer fake.invocationsMutex.RUnlock()
@@ -193,6 +556,16 @@ func (fake *FakeCfService) Invocations() map[string][][]interface{} {
 	defer fake.openSshTunnelMutex.RUnlock()
 	fake.getServiceMutex.RLock()
 	defer fake.getServiceMutex.RUnlock()
+	fake.getEphemeralServiceMutex.RLock()
+	defer fake.getEphemeralServiceMutex.RUnlock()
+	fake.getServicesMutex.RLock()
+	defer fake.getServicesMutex.RUnlock()
+	fake.getServiceKeysMutex.RLock()
+	defer fake.getServiceKeysMutex.RUnlock()
+	fake.deleteServiceKeyMutex.RLock()
+	defer fake.deleteServiceKeyMutex.RUnlock()
+	fake.targetSpaceMutex.RLock()
+	defer fake.targetSpaceMutex.RUnlock()
 	copiedInvocations := map[string][][]interface{}{}
 	for key, value := range fake.invocations {
 		copiedInvocations[key] = value
@@ -33,6 +33,17 @@ type FakeExecWrapper struct {
 	runReturnsOnCall map[int]struct {
 		result1 error
 	}
+	StartStub        func(*exec.Cmd) error
+	startMutex       sync.RWMutex
+	startArgsForCall []struct {
+		arg1 *exec.Cmd
+	}
+	startReturns struct {
+		result1 error
+	}
+	startReturnsOnCall map[int]struct {
+		result1 error
+	}
 	invocations      map[string][][]interface{}
 	invocationsMutex sync.RWMutex
 }
@@ -136,6 +147,54 @@ func (fake *FakeExecWrapper) RunReturnsOnCall(i int, result1 error) {
 	}{result1}
 }
 
+func (fake *FakeExecWrapper) Start(arg1 *exec.Cmd) error {
+	fake.startMutex.Lock()
+	ret, specificReturn := fake.startReturnsOnCall[len(fake.startArgsForCall)]
+	fake.startArgsForCall = append(fake.startArgsForCall, struct {
+		arg1 *exec.Cmd
+	}{arg1})
+	fake.recordInvocation("Start", []interface{}{arg1})
+	fake.startMutex.Unlock()
+	if fake.StartStub != nil {
+		return fake.StartStub(arg1)
+	}
+	if specificReturn {
+		return ret.result1
+	}
+	return fake.startReturns.result1
+}
+
+func (fake *FakeExecWrapper) StartCallCount() int {
+	fake.startMutex.RLock()
+	defer fake.startMutex.RUnlock()
+	return len(fake.startArgsForCall)
+}
+
+func (fake *FakeExecWrapper) StartArgsForCall(i int) *exec.Cmd {
+	fake.startMutex.RLock()
+	defer fake.startMutex.RUnlock()
+	return fake.startArgsForCall[i].arg1
+}
+
+func (fake *FakeExecWrapper) StartReturns(result1 error) {
+	fake.StartStub = nil
+	fake.startReturns = struct {
+		result1 error
+	}{result1}
+}
+
+func (fake *FakeExecWrapper) StartReturnsOnCall(i int, result1 error) {
+	fake.StartStub = nil
+	if fake.startReturnsOnCall == nil {
+		fake.startReturnsOnCall = make(map[int]struct {
+			result1 error
+		})
+	}
+	fake.startReturnsOnCall[i] = struct {
+		result1 error
+	}{result1}
+}
+
 func (fake *FakeExecWrapper) Invocations() map[string][][]interface{} {
 	fake.invocationsMutex.RLock()
 	defer fake.invocationsMutex.RUnlock()
@@ -143,6 +202,8 @@ func (fake *FakeExecWrapper) Invocations() map[string][][]interface{} {
 	defer fake.lookPathMutex.RUnlock()
 	fake.runMutex.RLock()
 	defer fake.runMutex.RUnlock()
+	fake.startMutex.RLock()
+	defer fake.startMutex.RUnlock()
 	copiedInvocations := map[string][][]interface{}{}
 	for key, value := range fake.invocations {
 		copiedInvocations[key] = value
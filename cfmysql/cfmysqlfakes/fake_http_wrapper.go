@@ -40,6 +40,52 @@ type FakeHttpWrapper struct {
 		result1 []byte
 		result2 error
 	}
+	PostWithLocationStub        func(url string, body io.Reader, accessToken string, sslDisabled bool) ([]byte, string, error)
+	postWithLocationMutex       sync.RWMutex
+	postWithLocationArgsForCall []struct {
+		url         string
+		body        io.Reader
+		accessToken string
+		sslDisabled bool
+	}
+	postWithLocationReturns struct {
+		result1 []byte
+		result2 string
+		result3 error
+	}
+	postWithLocationReturnsOnCall map[int]struct {
+		result1 []byte
+		result2 string
+		result3 error
+	}
+	DeleteStub        func(url string, accessToken string, sslDisabled bool) error
+	deleteMutex       sync.RWMutex
+	deleteArgsForCall []struct {
+		url         string
+		accessToken string
+		sslDisabled bool
+	}
+	deleteReturns struct {
+		result1 error
+	}
+	deleteReturnsOnCall map[int]struct {
+		result1 error
+	}
+	GetRedirectStub        func(url string, accessToken string, sslDisabled bool) (string, error)
+	getRedirectMutex       sync.RWMutex
+	getRedirectArgsForCall []struct {
+		url         string
+		accessToken string
+		sslDisabled bool
+	}
+	getRedirectReturns struct {
+		result1 string
+		result2 error
+	}
+	getRedirectReturnsOnCall map[int]struct {
+		result1 string
+		result2 error
+	}
 	invocations      map[string][][]interface{}
 	invocationsMutex sync.RWMutex
 }
@@ -151,6 +197,166 @@ func (fake *FakeHttpWrapper) PostReturnsOnCall(i int, result1 []byte, result2 er
 	}{result1, result2}
 }
 
+func (fake *FakeHttpWrapper) PostWithLocation(url string, body io.Reader, accessToken string, sslDisabled bool) ([]byte, string, error) {
+	fake.postWithLocationMutex.Lock()
+	ret, specificReturn := fake.postWithLocationReturnsOnCall[len(fake.postWithLocationArgsForCall)]
+	fake.postWithLocationArgsForCall = append(fake.postWithLocationArgsForCall, struct {
+		url         string
+		body        io.Reader
+		accessToken string
+		sslDisabled bool
+	}{url, body, accessToken, sslDisabled})
+	fake.recordInvocation("PostWithLocation", []interface{}{url, body, accessToken, sslDisabled})
+	fake.postWithLocationMutex.Unlock()
+	if fake.PostWithLocationStub != nil {
+		return fake.PostWithLocationStub(url, body, accessToken, sslDisabled)
+	}
+	if specificReturn {
+		return ret.result1, ret.result2, ret.result3
+	}
+	return fake.postWithLocationReturns.result1, fake.postWithLocationReturns.result2, fake.postWithLocationReturns.result3
+}
+
+func (fake *FakeHttpWrapper) PostWithLocationCallCount() int {
+	fake.postWithLocationMutex.RLock()
+	defer fake.postWithLocationMutex.RUnlock()
+	return len(fake.postWithLocationArgsForCall)
+}
+
+func (fake *FakeHttpWrapper) PostWithLocationArgsForCall(i int) (string, io.Reader, string, bool) {
+	fake.postWithLocationMutex.RLock()
+	defer fake.postWithLocationMutex.RUnlock()
+	return fake.postWithLocationArgsForCall[i].url, fake.postWithLocationArgsForCall[i].body, fake.postWithLocationArgsForCall[i].accessToken, fake.postWithLocationArgsForCall[i].sslDisabled
+}
+
+func (fake *FakeHttpWrapper) PostWithLocationReturns(result1 []byte, result2 string, result3 error) {
+	fake.PostWithLocationStub = nil
+	fake.postWithLocationReturns = struct {
+		result1 []byte
+		result2 string
+		result3 error
+	}{result1, result2, result3}
+}
+
+func (fake *FakeHttpWrapper) PostWithLocationReturnsOnCall(i int, result1 []byte, result2 string, result3 error) {
+	fake.PostWithLocationStub = nil
+	if fake.postWithLocationReturnsOnCall == nil {
+		fake.postWithLocationReturnsOnCall = make(map[int]struct {
+			result1 []byte
+			result2 string
+			result3 error
+		})
+	}
+	fake.postWithLocationReturnsOnCall[i] = struct {
+		result1 []byte
+		result2 string
+		result3 error
+	}{result1, result2, result3}
+}
+
+func (fake *FakeHttpWrapper) Delete(url string, accessToken string, sslDisabled bool) error {
+	fake.deleteMutex.Lock()
+	ret, specificReturn := fake.deleteReturnsOnCall[len(fake.deleteArgsForCall)]
+	fake.deleteArgsForCall = append(fake.deleteArgsForCall, struct {
+		url         string
+		accessToken string
+		sslDisabled bool
+	}{url, accessToken, sslDisabled})
+	fake.recordInvocation("Delete", []interface{}{url, accessToken, sslDisabled})
+	fake.deleteMutex.Unlock()
+	if fake.DeleteStub != nil {
+		return fake.DeleteStub(url, accessToken, sslDisabled)
+	}
+	if specificReturn {
+		return ret.result1
+	}
+	return fake.deleteReturns.result1
+}
+
+func (fake *FakeHttpWrapper) DeleteCallCount() int {
+	fake.deleteMutex.RLock()
+	defer fake.deleteMutex.RUnlock()
+	return len(fake.deleteArgsForCall)
+}
+
+func (fake *FakeHttpWrapper) DeleteArgsForCall(i int) (string, string, bool) {
+	fake.deleteMutex.RLock()
+	defer fake.deleteMutex.RUnlock()
+	return fake.deleteArgsForCall[i].url, fake.deleteArgsForCall[i].accessToken, fake.deleteArgsForCall[i].sslDisabled
+}
+
+func (fake *FakeHttpWrapper) DeleteReturns(result1 error) {
+	fake.DeleteStub = nil
+	fake.deleteReturns = struct {
+		result1 error
+	}{result1}
+}
+
+func (fake *FakeHttpWrapper) DeleteReturnsOnCall(i int, result1 error) {
+	fake.DeleteStub = nil
+	if fake.deleteReturnsOnCall == nil {
+		fake.deleteReturnsOnCall = make(map[int]struct {
+			result1 error
+		})
+	}
+	fake.deleteReturnsOnCall[i] = struct {
+		result1 error
+	}{result1}
+}
+
+func (fake *FakeHttpWrapper) GetRedirect(url string, accessToken string, sslDisabled bool) (string, error) {
+	fake.getRedirectMutex.Lock()
+	ret, specificReturn := fake.getRedirectReturnsOnCall[len(fake.getRedirectArgsForCall)]
+	fake.getRedirectArgsForCall = append(fake.getRedirectArgsForCall, struct {
+		url         string
+		accessToken string
+		sslDisabled bool
+	}{url, accessToken, sslDisabled})
+	fake.recordInvocation("GetRedirect", []interface{}{url, accessToken, sslDisabled})
+	fake.getRedirectMutex.Unlock()
+	if fake.GetRedirectStub != nil {
+		return fake.GetRedirectStub(url, accessToken, sslDisabled)
+	}
+	if specificReturn {
+		return ret.result1, ret.result2
+	}
+	return fake.getRedirectReturns.result1, fake.getRedirectReturns.result2
+}
+
+func (fake *FakeHttpWrapper) GetRedirectCallCount() int {
+	fake.getRedirectMutex.RLock()
+	defer fake.getRedirectMutex.RUnlock()
+	return len(fake.getRedirectArgsForCall)
+}
+
+func (fake *FakeHttpWrapper) GetRedirectArgsForCall(i int) (string, string, bool) {
+	fake.getRedirectMutex.RLock()
+	defer fake.getRedirectMutex.RUnlock()
+	return fake.getRedirectArgsForCall[i].url, fake.getRedirectArgsForCall[i].accessToken, fake.getRedirectArgsForCall[i].sslDisabled
+}
+
+func (fake *FakeHttpWrapper) GetRedirectReturns(result1 string, result2 error) {
+	fake.GetRedirectStub = nil
+	fake.getRedirectReturns = struct {
+		result1 string
+		result2 error
+	}{result1, result2}
+}
+
+func (fake *FakeHttpWrapper) GetRedirectReturnsOnCall(i int, result1 string, result2 error) {
+	fake.GetRedirectStub = nil
+	if fake.getRedirectReturnsOnCall == nil {
+		fake.getRedirectReturnsOnCall = make(map[int]struct {
+			result1 string
+			result2 error
+		})
+	}
+	fake.getRedirectReturnsOnCall[i] = struct {
+		result1 string
+		result2 error
+	}{result1, result2}
+}
+
 func (fake *FakeHttpWrapper) Invocations() map[string][][]interface{} {
 	fake.invocationsMutex.RLock()
 	defer fake.invocationsMutex.RUnlock()
@@ -158,6 +364,12 @@ func (fake *FakeHttpWrapper) Invocations() map[string][][]interface{} {
 	defer fake.getMutex.RUnlock()
 	fake.postMutex.RLock()
 	defer fake.postMutex.RUnlock()
+	fake.postWithLocationMutex.RLock()
+	defer fake.postWithLocationMutex.RUnlock()
+	fake.deleteMutex.RLock()
+	defer fake.deleteMutex.RUnlock()
+	fake.getRedirectMutex.RLock()
+	defer fake.getRedirectMutex.RUnlock()
 	copiedInvocations := map[string][][]interface{}{}
 	for key, value := range fake.invocations {
 		copiedInvocations[key] = value
@@ -23,6 +23,33 @@ type FakeIoUtilWrapper struct {
 		result1 *os.File
 		result2 error
 	}
+	TempDirStub        func(dir, pattern string) (name string, err error)
+	tempDirMutex       sync.RWMutex
+	tempDirArgsForCall []struct {
+		dir     string
+		pattern string
+	}
+	tempDirReturns struct {
+		result1 string
+		result2 error
+	}
+	tempDirReturnsOnCall map[int]struct {
+		result1 string
+		result2 error
+	}
+	WriteFileStub        func(filename string, data []byte, perm os.FileMode) error
+	writeFileMutex       sync.RWMutex
+	writeFileArgsForCall []struct {
+		filename string
+		data     []byte
+		perm     os.FileMode
+	}
+	writeFileReturns struct {
+		result1 error
+	}
+	writeFileReturnsOnCall map[int]struct {
+		result1 error
+	}
 	invocations      map[string][][]interface{}
 	invocationsMutex sync.RWMutex
 }
@@ -79,11 +106,122 @@ func (fake *FakeIoUtilWrapper) TempFileReturnsOnCall(i int, result1 *os.File, re
 	}{result1, result2}
 }
 
+func (fake *FakeIoUtilWrapper) TempDir(dir string, pattern string) (name string, err error) {
+	fake.tempDirMutex.Lock()
+	ret, specificReturn := fake.tempDirReturnsOnCall[len(fake.tempDirArgsForCall)]
+	fake.tempDirArgsForCall = append(fake.tempDirArgsForCall, struct {
+		dir     string
+		pattern string
+	}{dir, pattern})
+	fake.recordInvocation("TempDir", []interface{}{dir, pattern})
+	fake.tempDirMutex.Unlock()
+	if fake.TempDirStub != nil {
+		return fake.TempDirStub(dir, pattern)
+	}
+	if specificReturn {
+		return ret.result1, ret.result2
+	}
+	return fake.tempDirReturns.result1, fake.tempDirReturns.result2
+}
+
+func (fake *FakeIoUtilWrapper) TempDirCallCount() int {
+	fake.tempDirMutex.RLock()
+	defer fake.tempDirMutex.RUnlock()
+	return len(fake.tempDirArgsForCall)
+}
+
+func (fake *FakeIoUtilWrapper) TempDirArgsForCall(i int) (string, string) {
+	fake.tempDirMutex.RLock()
+	defer fake.tempDirMutex.RUnlock()
+	return fake.tempDirArgsForCall[i].dir, fake.tempDirArgsForCall[i].pattern
+}
+
+func (fake *FakeIoUtilWrapper) TempDirReturns(result1 string, result2 error) {
+	fake.TempDirStub = nil
+	fake.tempDirReturns = struct {
+		result1 string
+		result2 error
+	}{result1, result2}
+}
+
+func (fake *FakeIoUtilWrapper) TempDirReturnsOnCall(i int, result1 string, result2 error) {
+	fake.TempDirStub = nil
+	if fake.tempDirReturnsOnCall == nil {
+		fake.tempDirReturnsOnCall = make(map[int]struct {
+			result1 string
+			result2 error
+		})
+	}
+	fake.tempDirReturnsOnCall[i] = struct {
+		result1 string
+		result2 error
+	}{result1, result2}
+}
+
+func (fake *FakeIoUtilWrapper) WriteFile(filename string, data []byte, perm os.FileMode) error {
+	var dataCopy []byte
+	if data != nil {
+		dataCopy = make([]byte, len(data))
+		copy(dataCopy, data)
+	}
+	fake.writeFileMutex.Lock()
+	ret, specificReturn := fake.writeFileReturnsOnCall[len(fake.writeFileArgsForCall)]
+	fake.writeFileArgsForCall = append(fake.writeFileArgsForCall, struct {
+		filename string
+		data     []byte
+		perm     os.FileMode
+	}{filename, dataCopy, perm})
+	fake.recordInvocation("WriteFile", []interface{}{filename, dataCopy, perm})
+	fake.writeFileMutex.Unlock()
+	if fake.WriteFileStub != nil {
+		return fake.WriteFileStub(filename, data, perm)
+	}
+	if specificReturn {
+		return ret.result1
+	}
+	return fake.writeFileReturns.result1
+}
+
+func (fake *FakeIoUtilWrapper) WriteFileCallCount() int {
+	fake.writeFileMutex.RLock()
+	defer fake.writeFileMutex.RUnlock()
+	return len(fake.writeFileArgsForCall)
+}
+
+func (fake *FakeIoUtilWrapper) WriteFileArgsForCall(i int) (string, []byte, os.FileMode) {
+	fake.writeFileMutex.RLock()
+	defer fake.writeFileMutex.RUnlock()
+	return fake.writeFileArgsForCall[i].filename, fake.writeFileArgsForCall[i].data, fake.writeFileArgsForCall[i].perm
+}
+
+func (fake *FakeIoUtilWrapper) WriteFileReturns(result1 error) {
+	fake.WriteFileStub = nil
+	fake.writeFileReturns = struct {
+		result1 error
+	}{result1}
+}
+
+func (fake *FakeIoUtilWrapper) WriteFileReturnsOnCall(i int, result1 error) {
+	fake.WriteFileStub = nil
+	if fake.writeFileReturnsOnCall == nil {
+		fake.writeFileReturnsOnCall = make(map[int]struct {
+			result1 error
+		})
+	}
+	fake.writeFileReturnsOnCall[i] = struct {
+		result1 error
+	}{result1}
+}
+
 func (fake *FakeIoUtilWrapper) Invocations() map[string][][]interface{} {
 	fake.invocationsMutex.RLock()
 	defer fake.invocationsMutex.RUnlock()
 	fake.tempFileMutex.RLock()
 	defer fake.tempFileMutex.RUnlock()
+	fake.tempDirMutex.RLock()
+	defer fake.tempDirMutex.RUnlock()
+	fake.writeFileMutex.RLock()
+	defer fake.writeFileMutex.RUnlock()
 	copiedInvocations := map[string][][]interface{}{}
 	for key, value := range fake.invocations {
 		copiedInvocations[key] = value
@@ -2,154 +2,161 @@
 package cfmysqlfakes
 
 import (
+	"context"
 	"sync"
 
 	"github.com/andreasf/cf-mysql-plugin/cfmysql"
 )
 
 type FakeMysqlRunner struct {
-	RunMysqlStub        func(hostname string, port int, dbName string, username string, password string, caCert string, args ...string) error
-	runMysqlMutex       sync.RWMutex
-	runMysqlArgsForCall []struct {
-		hostname string
-		port     int
+	RunToolStub        func(tool cfmysql.ClientTool, clientIo cfmysql.ClientIo, address cfmysql.LocalAddress, dbName string, username string, password string, caCert string, args ...string) error
+	runToolMutex       sync.RWMutex
+	runToolArgsForCall []struct {
+		tool     cfmysql.ClientTool
+		clientIo cfmysql.ClientIo
+		address  cfmysql.LocalAddress
 		dbName   string
 		username string
 		password string
 		caCert   string
 		args     []string
 	}
-	runMysqlReturns struct {
+	runToolReturns struct {
 		result1 error
 	}
-	runMysqlReturnsOnCall map[int]struct {
+	runToolReturnsOnCall map[int]struct {
 		result1 error
 	}
-	RunMysqlDumpStub        func(hostname string, port int, dbName string, username string, password string, caCert string, args ...string) error
-	runMysqlDumpMutex       sync.RWMutex
-	runMysqlDumpArgsForCall []struct {
-		hostname string
-		port     int
+	RunToolWithContextStub        func(ctx context.Context, tool cfmysql.ClientTool, clientIo cfmysql.ClientIo, address cfmysql.LocalAddress, dbName string, username string, password string, caCert string, args ...string) error
+	runToolWithContextMutex       sync.RWMutex
+	runToolWithContextArgsForCall []struct {
+		ctx      context.Context
+		tool     cfmysql.ClientTool
+		clientIo cfmysql.ClientIo
+		address  cfmysql.LocalAddress
 		dbName   string
 		username string
 		password string
 		caCert   string
 		args     []string
 	}
-	runMysqlDumpReturns struct {
+	runToolWithContextReturns struct {
 		result1 error
 	}
-	runMysqlDumpReturnsOnCall map[int]struct {
+	runToolWithContextReturnsOnCall map[int]struct {
 		result1 error
 	}
 	invocations      map[string][][]interface{}
 	invocationsMutex sync.RWMutex
 }
 
-func (fake *FakeMysqlRunner) RunMysql(hostname string, port int, dbName string, username string, password string, caCert string, args ...string) error {
-	fake.runMysqlMutex.Lock()
-	ret, specificReturn := fake.runMysqlReturnsOnCall[len(fake.runMysqlArgsForCall)]
-	fake.runMysqlArgsForCall = append(fake.runMysqlArgsForCall, struct {
-		hostname string
-		port     int
+func (fake *FakeMysqlRunner) RunTool(tool cfmysql.ClientTool, clientIo cfmysql.ClientIo, address cfmysql.LocalAddress, dbName string, username string, password string, caCert string, args ...string) error {
+	fake.runToolMutex.Lock()
+	ret, specificReturn := fake.runToolReturnsOnCall[len(fake.runToolArgsForCall)]
+	fake.runToolArgsForCall = append(fake.runToolArgsForCall, struct {
+		tool     cfmysql.ClientTool
+		clientIo cfmysql.ClientIo
+		address  cfmysql.LocalAddress
 		dbName   string
 		username string
 		password string
 		caCert   string
 		args     []string
-	}{hostname, port, dbName, username, password, caCert, args})
-	fake.recordInvocation("RunMysql", []interface{}{hostname, port, dbName, username, password, caCert, args})
-	fake.runMysqlMutex.Unlock()
-	if fake.RunMysqlStub != nil {
-		return fake.RunMysqlStub(hostname, port, dbName, username, password, caCert, args...)
+	}{tool, clientIo, address, dbName, username, password, caCert, args})
+	fake.recordInvocation("RunTool", []interface{}{tool, clientIo, address, dbName, username, password, caCert, args})
+	fake.runToolMutex.Unlock()
+	if fake.RunToolStub != nil {
+		return fake.RunToolStub(tool, clientIo, address, dbName, username, password, caCert, args...)
 	}
 	if specificReturn {
 		return ret.result1
 	}
-	return fake.runMysqlReturns.result1
+	return fake.runToolReturns.result1
 }
 
-func (fake *FakeMysqlRunner) RunMysqlCallCount() int {
-	fake.runMysqlMutex.RLock()
-	defer fake.runMysqlMutex.RUnlock()
-	return len(fake.runMysqlArgsForCall)
+func (fake *FakeMysqlRunner) RunToolCallCount() int {
+	fake.runToolMutex.RLock()
+	defer fake.runToolMutex.RUnlock()
+	return len(fake.runToolArgsForCall)
 }
 
-func (fake *FakeMysqlRunner) RunMysqlArgsForCall(i int) (string, int, string, string, string, string, []string) {
-	fake.runMysqlMutex.RLock()
-	defer fake.runMysqlMutex.RUnlock()
-	return fake.runMysqlArgsForCall[i].hostname, fake.runMysqlArgsForCall[i].port, fake.runMysqlArgsForCall[i].dbName, fake.runMysqlArgsForCall[i].username, fake.runMysqlArgsForCall[i].password, fake.runMysqlArgsForCall[i].caCert, fake.runMysqlArgsForCall[i].args
+func (fake *FakeMysqlRunner) RunToolArgsForCall(i int) (cfmysql.ClientTool, cfmysql.ClientIo, cfmysql.LocalAddress, string, string, string, string, []string) {
+	fake.runToolMutex.RLock()
+	defer fake.runToolMutex.RUnlock()
+	return fake.runToolArgsForCall[i].tool, fake.runToolArgsForCall[i].clientIo, fake.runToolArgsForCall[i].address, fake.runToolArgsForCall[i].dbName, fake.runToolArgsForCall[i].username, fake.runToolArgsForCall[i].password, fake.runToolArgsForCall[i].caCert, fake.runToolArgsForCall[i].args
 }
 
-func (fake *FakeMysqlRunner) RunMysqlReturns(result1 error) {
-	fake.RunMysqlStub = nil
-	fake.runMysqlReturns = struct {
+func (fake *FakeMysqlRunner) RunToolReturns(result1 error) {
+	fake.RunToolStub = nil
+	fake.runToolReturns = struct {
 		result1 error
 	}{result1}
 }
 
-func (fake *FakeMysqlRunner) RunMysqlReturnsOnCall(i int, result1 error) {
-	fake.RunMysqlStub = nil
-	if fake.runMysqlReturnsOnCall == nil {
-		fake.runMysqlReturnsOnCall = make(map[int]struct {
+func (fake *FakeMysqlRunner) RunToolReturnsOnCall(i int, result1 error) {
+	fake.RunToolStub = nil
+	if fake.runToolReturnsOnCall == nil {
+		fake.runToolReturnsOnCall = make(map[int]struct {
 			result1 error
 		})
 	}
-	fake.runMysqlReturnsOnCall[i] = struct {
+	fake.runToolReturnsOnCall[i] = struct {
 		result1 error
 	}{result1}
 }
 
-func (fake *FakeMysqlRunner) RunMysqlDump(hostname string, port int, dbName string, username string, password string, caCert string, args ...string) error {
-	fake.runMysqlDumpMutex.Lock()
-	ret, specificReturn := fake.runMysqlDumpReturnsOnCall[len(fake.runMysqlDumpArgsForCall)]
-	fake.runMysqlDumpArgsForCall = append(fake.runMysqlDumpArgsForCall, struct {
-		hostname string
-		port     int
+func (fake *FakeMysqlRunner) RunToolWithContext(ctx context.Context, tool cfmysql.ClientTool, clientIo cfmysql.ClientIo, address cfmysql.LocalAddress, dbName string, username string, password string, caCert string, args ...string) error {
+	fake.runToolWithContextMutex.Lock()
+	ret, specificReturn := fake.runToolWithContextReturnsOnCall[len(fake.runToolWithContextArgsForCall)]
+	fake.runToolWithContextArgsForCall = append(fake.runToolWithContextArgsForCall, struct {
+		ctx      context.Context
+		tool     cfmysql.ClientTool
+		clientIo cfmysql.ClientIo
+		address  cfmysql.LocalAddress
 		dbName   string
 		username string
 		password string
 		caCert   string
 		args     []string
-	}{hostname, port, dbName, username, password, caCert, args})
-	fake.recordInvocation("RunMysqlDump", []interface{}{hostname, port, dbName, username, password, caCert, args})
-	fake.runMysqlDumpMutex.Unlock()
-	if fake.RunMysqlDumpStub != nil {
-		return fake.RunMysqlDumpStub(hostname, port, dbName, username, password, caCert, args...)
+	}{ctx, tool, clientIo, address, dbName, username, password, caCert, args})
+	fake.recordInvocation("RunToolWithContext", []interface{}{ctx, tool, clientIo, address, dbName, username, password, caCert, args})
+	fake.runToolWithContextMutex.Unlock()
+	if fake.RunToolWithContextStub != nil {
+		return fake.RunToolWithContextStub(ctx, tool, clientIo, address, dbName, username, password, caCert, args...)
 	}
 	if specificReturn {
 		return ret.result1
 	}
-	return fake.runMysqlDumpReturns.result1
+	return fake.runToolWithContextReturns.result1
 }
 
-func (fake *FakeMysqlRunner) RunMysqlDumpCallCount() int {
-	fake.runMysqlDumpMutex.RLock()
-	defer fake.runMysqlDumpMutex.RUnlock()
-	return len(fake.runMysqlDumpArgsForCall)
+func (fake *FakeMysqlRunner) RunToolWithContextCallCount() int {
+	fake.runToolWithContextMutex.RLock()
+	defer fake.runToolWithContextMutex.RUnlock()
+	return len(fake.runToolWithContextArgsForCall)
 }
 
-func (fake *FakeMysqlRunner) RunMysqlDumpArgsForCall(i int) (string, int, string, string, string, string, []string) {
-	fake.runMysqlDumpMutex.RLock()
-	defer fake.runMysqlDumpMutex.RUnlock()
-	return fake.runMysqlDumpArgsForCall[i].hostname, fake.runMysqlDumpArgsForCall[i].port, fake.runMysqlDumpArgsForCall[i].dbName, fake.runMysqlDumpArgsForCall[i].username, fake.runMysqlDumpArgsForCall[i].password, fake.runMysqlDumpArgsForCall[i].caCert, fake.runMysqlDumpArgsForCall[i].args
+func (fake *FakeMysqlRunner) RunToolWithContextArgsForCall(i int) (context.Context, cfmysql.ClientTool, cfmysql.ClientIo, cfmysql.LocalAddress, string, string, string, string, []string) {
+	fake.runToolWithContextMutex.RLock()
+	defer fake.runToolWithContextMutex.RUnlock()
+	return fake.runToolWithContextArgsForCall[i].ctx, fake.runToolWithContextArgsForCall[i].tool, fake.runToolWithContextArgsForCall[i].clientIo, fake.runToolWithContextArgsForCall[i].address, fake.runToolWithContextArgsForCall[i].dbName, fake.runToolWithContextArgsForCall[i].username, fake.runToolWithContextArgsForCall[i].password, fake.runToolWithContextArgsForCall[i].caCert, fake.runToolWithContextArgsForCall[i].args
 }
 
-func (fake *FakeMysqlRunner) RunMysqlDumpReturns(result1 error) {
-	fake.RunMysqlDumpStub = nil
-	fake.runMysqlDumpReturns = struct {
+func (fake *FakeMysqlRunner) RunToolWithContextReturns(result1 error) {
+	fake.RunToolWithContextStub = nil
+	fake.runToolWithContextReturns = struct {
 		result1 error
 	}{result1}
 }
 
-func (fake *FakeMysqlRunner) RunMysqlDumpReturnsOnCall(i int, result1 error) {
-	fake.RunMysqlDumpStub = nil
-	if fake.runMysqlDumpReturnsOnCall == nil {
-		fake.runMysqlDumpReturnsOnCall = make(map[int]struct {
+func (fake *FakeMysqlRunner) RunToolWithContextReturnsOnCall(i int, result1 error) {
+	fake.RunToolWithContextStub = nil
+	if fake.runToolWithContextReturnsOnCall == nil {
+		fake.runToolWithContextReturnsOnCall = make(map[int]struct {
 			result1 error
 		})
 	}
-	fake.runMysqlDumpReturnsOnCall[i] = struct {
+	fake.runToolWithContextReturnsOnCall[i] = struct {
 		result1 error
 	}{result1}
 }
@@ -157,10 +164,10 @@ func (fake *FakeMysqlRunner) RunMysqlDumpReturnsOnCall(i int, result1 error) {
 func (fake *FakeMysqlRunner) Invocations() map[string][][]interface{} {
 	fake.invocationsMutex.RLock()
 	defer fake.invocationsMutex.RUnlock()
-	fake.runMysqlMutex.RLock()
-	defer fake.runMysqlMutex.RUnlock()
-	fake.runMysqlDumpMutex.RLock()
-	defer fake.runMysqlDumpMutex.RUnlock()
+	fake.runToolMutex.RLock()
+	defer fake.runToolMutex.RUnlock()
+	fake.runToolWithContextMutex.RLock()
+	defer fake.runToolWithContextMutex.RUnlock()
 	copiedInvocations := map[string][][]interface{}{}
 	for key, value := range fake.invocations {
 		copiedInvocations[key] = value
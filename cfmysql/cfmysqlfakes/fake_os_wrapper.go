@@ -33,6 +33,19 @@ type FakeOsWrapper struct {
 	nameReturnsOnCall map[int]struct {
 		result1 string
 	}
+	OpenStub        func(name string) (*os.File, error)
+	openMutex       sync.RWMutex
+	openArgsForCall []struct {
+		name string
+	}
+	openReturns struct {
+		result1 *os.File
+		result2 error
+	}
+	openReturnsOnCall map[int]struct {
+		result1 *os.File
+		result2 error
+	}
 	RemoveStub        func(name string) error
 	removeMutex       sync.RWMutex
 	removeArgsForCall []struct {
@@ -44,6 +57,17 @@ type FakeOsWrapper struct {
 	removeReturnsOnCall map[int]struct {
 		result1 error
 	}
+	RemoveAllStub        func(path string) error
+	removeAllMutex       sync.RWMutex
+	removeAllArgsForCall []struct {
+		path string
+	}
+	removeAllReturns struct {
+		result1 error
+	}
+	removeAllReturnsOnCall map[int]struct {
+		result1 error
+	}
 	WriteStringStub        func(file *os.File, s string) (n int, err error)
 	writeStringMutex       sync.RWMutex
 	writeStringArgsForCall []struct {
@@ -161,6 +185,57 @@ func (fake *FakeOsWrapper) NameReturnsOnCall(i int, result1 string) {
 	}{result1}
 }
 
+func (fake *FakeOsWrapper) Open(name string) (*os.File, error) {
+	fake.openMutex.Lock()
+	ret, specificReturn := fake.openReturnsOnCall[len(fake.openArgsForCall)]
+	fake.openArgsForCall = append(fake.openArgsForCall, struct {
+		name string
+	}{name})
+	fake.recordInvocation("Open", []interface{}{name})
+	fake.openMutex.Unlock()
+	if fake.OpenStub != nil {
+		return fake.OpenStub(name)
+	}
+	if specificReturn {
+		return ret.result1, ret.result2
+	}
+	return fake.openReturns.result1, fake.openReturns.result2
+}
+
+func (fake *FakeOsWrapper) OpenCallCount() int {
+	fake.openMutex.RLock()
+	defer fake.openMutex.RUnlock()
+	return len(fake.openArgsForCall)
+}
+
+func (fake *FakeOsWrapper) OpenArgsForCall(i int) string {
+	fake.openMutex.RLock()
+	defer fake.openMutex.RUnlock()
+	return fake.openArgsForCall[i].name
+}
+
+func (fake *FakeOsWrapper) OpenReturns(result1 *os.File, result2 error) {
+	fake.OpenStub = nil
+	fake.openReturns = struct {
+		result1 *os.File
+		result2 error
+	}{result1, result2}
+}
+
+func (fake *FakeOsWrapper) OpenReturnsOnCall(i int, result1 *os.File, result2 error) {
+	fake.OpenStub = nil
+	if fake.openReturnsOnCall == nil {
+		fake.openReturnsOnCall = make(map[int]struct {
+			result1 *os.File
+			result2 error
+		})
+	}
+	fake.openReturnsOnCall[i] = struct {
+		result1 *os.File
+		result2 error
+	}{result1, result2}
+}
+
 func (fake *FakeOsWrapper) Remove(name string) error {
 	fake.removeMutex.Lock()
 	ret, specificReturn := fake.removeReturnsOnCall[len(fake.removeArgsForCall)]
@@ -209,6 +284,54 @@ func (fake *FakeOsWrapper) RemoveReturnsOnCall(i int, result1 error) {
 	}{result1}
 }
 
+func (fake *FakeOsWrapper) RemoveAll(path string) error {
+	fake.removeAllMutex.Lock()
+	ret, specificReturn := fake.removeAllReturnsOnCall[len(fake.removeAllArgsForCall)]
+	fake.removeAllArgsForCall = append(fake.removeAllArgsForCall, struct {
+		path string
+	}{path})
+	fake.recordInvocation("RemoveAll", []interface{}{path})
+	fake.removeAllMutex.Unlock()
+	if fake.RemoveAllStub != nil {
+		return fake.RemoveAllStub(path)
+	}
+	if specificReturn {
+		return ret.result1
+	}
+	return fake.removeAllReturns.result1
+}
+
+func (fake *FakeOsWrapper) RemoveAllCallCount() int {
+	fake.removeAllMutex.RLock()
+	defer fake.removeAllMutex.RUnlock()
+	return len(fake.removeAllArgsForCall)
+}
+
+func (fake *FakeOsWrapper) RemoveAllArgsForCall(i int) string {
+	fake.removeAllMutex.RLock()
+	defer fake.removeAllMutex.RUnlock()
+	return fake.removeAllArgsForCall[i].path
+}
+
+func (fake *FakeOsWrapper) RemoveAllReturns(result1 error) {
+	fake.RemoveAllStub = nil
+	fake.removeAllReturns = struct {
+		result1 error
+	}{result1}
+}
+
+func (fake *FakeOsWrapper) RemoveAllReturnsOnCall(i int, result1 error) {
+	fake.RemoveAllStub = nil
+	if fake.removeAllReturnsOnCall == nil {
+		fake.removeAllReturnsOnCall = make(map[int]struct {
+			result1 error
+		})
+	}
+	fake.removeAllReturnsOnCall[i] = struct {
+		result1 error
+	}{result1}
+}
+
 func (fake *FakeOsWrapper) WriteString(file *os.File, s string) (n int, err error) {
 	fake.writeStringMutex.Lock()
 	ret, specificReturn := fake.writeStringReturnsOnCall[len(fake.writeStringArgsForCall)]
@@ -268,8 +391,12 @@ func (fake *FakeOsWrapper) Invocations() map[string][][]interface{} {
 	defer fake.lookupEnvMutex.RUnlock()
 	fake.nameMutex.RLock()
 	defer fake.nameMutex.RUnlock()
+	fake.openMutex.RLock()
+	defer fake.openMutex.RUnlock()
 	fake.removeMutex.RLock()
 	defer fake.removeMutex.RUnlock()
+	fake.removeAllMutex.RLock()
+	defer fake.removeAllMutex.RUnlock()
 	fake.writeStringMutex.RLock()
 	defer fake.writeStringMutex.RUnlock()
 	copiedInvocations := map[string][][]interface{}{}
@@ -8,32 +8,49 @@ import (
 )
 
 type FakePortFinder struct {
-	GetPortStub        func() int
+	GetPortStub        func(host string) (int, error)
 	getPortMutex       sync.RWMutex
-	getPortArgsForCall []struct{}
-	getPortReturns     struct {
+	getPortArgsForCall []struct {
+		host string
+	}
+	getPortReturns struct {
 		result1 int
+		result2 error
 	}
 	getPortReturnsOnCall map[int]struct {
 		result1 int
+		result2 error
+	}
+	CheckPortStub        func(localAddress cfmysql.LocalAddress) error
+	checkPortMutex       sync.RWMutex
+	checkPortArgsForCall []struct {
+		localAddress cfmysql.LocalAddress
+	}
+	checkPortReturns struct {
+		result1 error
+	}
+	checkPortReturnsOnCall map[int]struct {
+		result1 error
 	}
 	invocations      map[string][][]interface{}
 	invocationsMutex sync.RWMutex
 }
 
-func (fake *FakePortFinder) GetPort() int {
+func (fake *FakePortFinder) GetPort(host string) (int, error) {
 	fake.getPortMutex.Lock()
 	ret, specificReturn := fake.getPortReturnsOnCall[len(fake.getPortArgsForCall)]
-	fake.getPortArgsForCall = append(fake.getPortArgsForCall, struct{}{})
-	fake.recordInvocation("GetPort", []interface{}{})
+	fake.getPortArgsForCall = append(fake.getPortArgsForCall, struct {
+		host string
+	}{host})
+	fake.recordInvocation("GetPort", []interface{}{host})
 	fake.getPortMutex.Unlock()
 	if fake.GetPortStub != nil {
-		return fake.GetPortStub()
+		return fake.GetPortStub(host)
 	}
 	if specificReturn {
-		return ret.result1
+		return ret.result1, ret.result2
 	}
-	return fake.getPortReturns.result1
+	return fake.getPortReturns.result1, fake.getPortReturns.result2
 }
 
 func (fake *FakePortFinder) GetPortCallCount() int {
@@ -42,22 +59,79 @@ func (fake *FakePortFinder) GetPortCallCount() int {
 	return len(fake.getPortArgsForCall)
 }
 
-func (fake *FakePortFinder) GetPortReturns(result1 int) {
+func (fake *FakePortFinder) GetPortArgsForCall(i int) string {
+	fake.getPortMutex.RLock()
+	defer fake.getPortMutex.RUnlock()
+	return fake.getPortArgsForCall[i].host
+}
+
+func (fake *FakePortFinder) GetPortReturns(result1 int, result2 error) {
 	fake.GetPortStub = nil
 	fake.getPortReturns = struct {
 		result1 int
-	}{result1}
+		result2 error
+	}{result1, result2}
 }
 
-func (fake *FakePortFinder) GetPortReturnsOnCall(i int, result1 int) {
+func (fake *FakePortFinder) GetPortReturnsOnCall(i int, result1 int, result2 error) {
 	fake.GetPortStub = nil
 	if fake.getPortReturnsOnCall == nil {
 		fake.getPortReturnsOnCall = make(map[int]struct {
 			result1 int
+			result2 error
 		})
 	}
 	fake.getPortReturnsOnCall[i] = struct {
 		result1 int
+		result2 error
+	}{result1, result2}
+}
+
+func (fake *FakePortFinder) CheckPort(localAddress cfmysql.LocalAddress) error {
+	fake.checkPortMutex.Lock()
+	ret, specificReturn := fake.checkPortReturnsOnCall[len(fake.checkPortArgsForCall)]
+	fake.checkPortArgsForCall = append(fake.checkPortArgsForCall, struct {
+		localAddress cfmysql.LocalAddress
+	}{localAddress})
+	fake.recordInvocation("CheckPort", []interface{}{localAddress})
+	fake.checkPortMutex.Unlock()
+	if fake.CheckPortStub != nil {
+		return fake.CheckPortStub(localAddress)
+	}
+	if specificReturn {
+		return ret.result1
+	}
+	return fake.checkPortReturns.result1
+}
+
+func (fake *FakePortFinder) CheckPortCallCount() int {
+	fake.checkPortMutex.RLock()
+	defer fake.checkPortMutex.RUnlock()
+	return len(fake.checkPortArgsForCall)
+}
+
+func (fake *FakePortFinder) CheckPortArgsForCall(i int) cfmysql.LocalAddress {
+	fake.checkPortMutex.RLock()
+	defer fake.checkPortMutex.RUnlock()
+	return fake.checkPortArgsForCall[i].localAddress
+}
+
+func (fake *FakePortFinder) CheckPortReturns(result1 error) {
+	fake.CheckPortStub = nil
+	fake.checkPortReturns = struct {
+		result1 error
+	}{result1}
+}
+
+func (fake *FakePortFinder) CheckPortReturnsOnCall(i int, result1 error) {
+	fake.CheckPortStub = nil
+	if fake.checkPortReturnsOnCall == nil {
+		fake.checkPortReturnsOnCall = make(map[int]struct {
+			result1 error
+		})
+	}
+	fake.checkPortReturnsOnCall[i] = struct {
+		result1 error
 	}{result1}
 }
 
@@ -66,6 +140,8 @@ func (fake *FakePortFinder) Invocations() map[string][][]interface{} {
 	defer fake.invocationsMutex.RUnlock()
 	fake.getPortMutex.RLock()
 	defer fake.getPortMutex.RUnlock()
+	fake.checkPortMutex.RLock()
+	defer fake.checkPortMutex.RUnlock()
 	copiedInvocations := map[string][][]interface{}{}
 	for key, value := range fake.invocations {
 		copiedInvocations[key] = value
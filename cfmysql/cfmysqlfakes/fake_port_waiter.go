@@ -2,31 +2,45 @@
 package cfmysqlfakes
 
 import (
+	"context"
 	"sync"
 
 	"github.com/andreasf/cf-mysql-plugin/cfmysql"
 )
 
 type FakePortWaiter struct {
-	WaitUntilOpenStub        func(localPort int)
+	WaitUntilOpenStub        func(ctx context.Context, localAddress cfmysql.LocalAddress) error
 	waitUntilOpenMutex       sync.RWMutex
 	waitUntilOpenArgsForCall []struct {
-		localPort int
+		ctx          context.Context
+		localAddress cfmysql.LocalAddress
+	}
+	waitUntilOpenReturns struct {
+		result1 error
+	}
+	waitUntilOpenReturnsOnCall map[int]struct {
+		result1 error
 	}
 	invocations      map[string][][]interface{}
 	invocationsMutex sync.RWMutex
 }
 
-func (fake *FakePortWaiter) WaitUntilOpen(localPort int) {
+func (fake *FakePortWaiter) WaitUntilOpen(ctx context.Context, localAddress cfmysql.LocalAddress) error {
 	fake.waitUntilOpenMutex.Lock()
+	ret, specificReturn := fake.waitUntilOpenReturnsOnCall[len(fake.waitUntilOpenArgsForCall)]
 	fake.waitUntilOpenArgsForCall = append(fake.waitUntilOpenArgsForCall, struct {
-		localPort int
-	}{localPort})
-	fake.recordInvocation("WaitUntilOpen", []interface{}{localPort})
+		ctx          context.Context
+		localAddress cfmysql.LocalAddress
+	}{ctx, localAddress})
+	fake.recordInvocation("WaitUntilOpen", []interface{}{ctx, localAddress})
 	fake.waitUntilOpenMutex.Unlock()
 	if fake.WaitUntilOpenStub != nil {
-		fake.WaitUntilOpenStub(localPort)
+		return fake.WaitUntilOpenStub(ctx, localAddress)
 	}
+	if specificReturn {
+		return ret.result1
+	}
+	return fake.waitUntilOpenReturns.result1
 }
 
 func (fake *FakePortWaiter) WaitUntilOpenCallCount() int {
@@ -35,10 +49,29 @@ func (fake *FakePortWaiter) WaitUntilOpenCallCount() int {
 	return len(fake.waitUntilOpenArgsForCall)
 }
 
-func (fake *FakePortWaiter) WaitUntilOpenArgsForCall(i int) int {
+func (fake *FakePortWaiter) WaitUntilOpenArgsForCall(i int) (context.Context, cfmysql.LocalAddress) {
 	fake.waitUntilOpenMutex.RLock()
 	defer fake.waitUntilOpenMutex.RUnlock()
-	return fake.waitUntilOpenArgsForCall[i].localPort
+	return fake.waitUntilOpenArgsForCall[i].ctx, fake.waitUntilOpenArgsForCall[i].localAddress
+}
+
+func (fake *FakePortWaiter) WaitUntilOpenReturns(result1 error) {
+	fake.WaitUntilOpenStub = nil
+	fake.waitUntilOpenReturns = struct {
+		result1 error
+	}{result1}
+}
+
+func (fake *FakePortWaiter) WaitUntilOpenReturnsOnCall(i int, result1 error) {
+	fake.WaitUntilOpenStub = nil
+	if fake.waitUntilOpenReturnsOnCall == nil {
+		fake.waitUntilOpenReturnsOnCall = make(map[int]struct {
+			result1 error
+		})
+	}
+	fake.waitUntilOpenReturnsOnCall[i] = struct {
+		result1 error
+	}{result1}
 }
 
 func (fake *FakePortWaiter) Invocations() map[string][][]interface{} {
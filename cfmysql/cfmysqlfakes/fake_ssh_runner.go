@@ -5,35 +5,52 @@ import (
 	"sync"
 
 	"code.cloudfoundry.org/cli/plugin"
+	sdkModels "code.cloudfoundry.org/cli/plugin/models"
 	"github.com/andreasf/cf-mysql-plugin/cfmysql"
 )
 
 type FakeSshRunner struct {
-	OpenSshTunnelStub        func(cliConnection plugin.CliConnection, toService cfmysql.MysqlService, throughApp string, localPort int)
+	OpenSshTunnelStub        func(cliConnection plugin.CliConnection, forwards []cfmysql.TunnelForward, throughApp sdkModels.GetAppsModel) (cfmysql.SshTunnel, error)
 	openSshTunnelMutex       sync.RWMutex
 	openSshTunnelArgsForCall []struct {
 		cliConnection plugin.CliConnection
-		toService     cfmysql.MysqlService
-		throughApp    string
-		localPort     int
+		forwards      []cfmysql.TunnelForward
+		throughApp    sdkModels.GetAppsModel
+	}
+	openSshTunnelReturns struct {
+		result1 cfmysql.SshTunnel
+		result2 error
+	}
+	openSshTunnelReturnsOnCall map[int]struct {
+		result1 cfmysql.SshTunnel
+		result2 error
 	}
 	invocations      map[string][][]interface{}
 	invocationsMutex sync.RWMutex
 }
 
-func (fake *FakeSshRunner) OpenSshTunnel(cliConnection plugin.CliConnection, toService cfmysql.MysqlService, throughApp string, localPort int) {
+func (fake *FakeSshRunner) OpenSshTunnel(cliConnection plugin.CliConnection, forwards []cfmysql.TunnelForward, throughApp sdkModels.GetAppsModel) (cfmysql.SshTunnel, error) {
+	var forwardsCopy []cfmysql.TunnelForward
+	if forwards != nil {
+		forwardsCopy = make([]cfmysql.TunnelForward, len(forwards))
+		copy(forwardsCopy, forwards)
+	}
 	fake.openSshTunnelMutex.Lock()
+	ret, specificReturn := fake.openSshTunnelReturnsOnCall[len(fake.openSshTunnelArgsForCall)]
 	fake.openSshTunnelArgsForCall = append(fake.openSshTunnelArgsForCall, struct {
 		cliConnection plugin.CliConnection
-		toService     cfmysql.MysqlService
-		throughApp    string
-		localPort     int
-	}{cliConnection, toService, throughApp, localPort})
-	fake.recordInvocation("OpenSshTunnel", []interface{}{cliConnection, toService, throughApp, localPort})
+		forwards      []cfmysql.TunnelForward
+		throughApp    sdkModels.GetAppsModel
+	}{cliConnection, forwardsCopy, throughApp})
+	fake.recordInvocation("OpenSshTunnel", []interface{}{cliConnection, forwardsCopy, throughApp})
 	fake.openSshTunnelMutex.Unlock()
 	if fake.OpenSshTunnelStub != nil {
-		fake.OpenSshTunnelStub(cliConnection, toService, throughApp, localPort)
+		return fake.OpenSshTunnelStub(cliConnection, forwards, throughApp)
 	}
+	if specificReturn {
+		return ret.result1, ret.result2
+	}
+	return fake.openSshTunnelReturns.result1, fake.openSshTunnelReturns.result2
 }
 
 func (fake *FakeSshRunner) OpenSshTunnelCallCount() int {
@@ -42,10 +59,32 @@ func (fake *FakeSshRunner) OpenSshTunnelCallCount() int {
 	return len(fake.openSshTunnelArgsForCall)
 }
 
-func (fake *FakeSshRunner) OpenSshTunnelArgsForCall(i int) (plugin.CliConnection, cfmysql.MysqlService, string, int) {
+func (fake *FakeSshRunner) OpenSshTunnelArgsForCall(i int) (plugin.CliConnection, []cfmysql.TunnelForward, sdkModels.GetAppsModel) {
 	fake.openSshTunnelMutex.RLock()
 	defer fake.openSshTunnelMutex.RUnlock()
-	return fake.openSshTunnelArgsForCall[i].cliConnection, fake.openSshTunnelArgsForCall[i].toService, fake.openSshTunnelArgsForCall[i].throughApp, fake.openSshTunnelArgsForCall[i].localPort
+	return fake.openSshTunnelArgsForCall[i].cliConnection, fake.openSshTunnelArgsForCall[i].forwards, fake.openSshTunnelArgsForCall[i].throughApp
+}
+
+func (fake *FakeSshRunner) OpenSshTunnelReturns(result1 cfmysql.SshTunnel, result2 error) {
+	fake.OpenSshTunnelStub = nil
+	fake.openSshTunnelReturns = struct {
+		result1 cfmysql.SshTunnel
+		result2 error
+	}{result1, result2}
+}
+
+func (fake *FakeSshRunner) OpenSshTunnelReturnsOnCall(i int, result1 cfmysql.SshTunnel, result2 error) {
+	fake.OpenSshTunnelStub = nil
+	if fake.openSshTunnelReturnsOnCall == nil {
+		fake.openSshTunnelReturnsOnCall = make(map[int]struct {
+			result1 cfmysql.SshTunnel
+			result2 error
+		})
+	}
+	fake.openSshTunnelReturnsOnCall[i] = struct {
+		result1 cfmysql.SshTunnel
+		result2 error
+	}{result1, result2}
 }
 
 func (fake *FakeSshRunner) Invocations() map[string][][]interface{} {
@@ -0,0 +1,137 @@
+package cfmysql
+
+import (
+	"bufio"
+	"code.cloudfoundry.org/cli/plugin"
+	"flag"
+	"fmt"
+	"io/ioutil"
+	"strings"
+	"text/tabwriter"
+)
+
+func (self *MysqlPlugin) cleanup(cliConnection plugin.CliConnection, args []string) {
+	flags := flag.NewFlagSet("mysql-cleanup", flag.ContinueOnError)
+	flags.SetOutput(ioutil.Discard)
+	wholeOrg := flags.Bool("org", false, "")
+	serviceName := flags.String("service", "", "")
+	force := flags.Bool("f", false, "")
+	apps := flags.Bool("apps", false, "")
+
+	err := flags.Parse(args)
+	if err != nil || flags.NArg() > 0 || *apps && (*wholeOrg || *serviceName != "") {
+		fmt.Fprint(self.Err, self.FormatUsage())
+		self.setErrorExit()
+		return
+	}
+
+	if *apps {
+		self.cleanupTunnelApps(cliConnection, *force)
+		return
+	}
+
+	serviceKeys, err := self.CfService.GetServiceKeys(cliConnection, *wholeOrg)
+	if err != nil {
+		fmt.Fprintf(self.Err, "FAILED\nUnable to find service keys: %s\n", err)
+		self.setErrorExit()
+		return
+	}
+
+	var matching []ServiceKeySummary
+	for _, serviceKey := range serviceKeys {
+		if *serviceName == "" || serviceKey.ServiceName == *serviceName {
+			matching = append(matching, serviceKey)
+		}
+	}
+
+	if len(matching) == 0 {
+		fmt.Fprintf(self.Out, "No %s service keys found.\n", ServiceKeyName)
+		return
+	}
+
+	fmt.Fprintf(self.Out, "Found %d %s service key(s):\n\n", len(matching), ServiceKeyName)
+	table := tabwriter.NewWriter(self.Out, 0, 0, 3, ' ', 0)
+	fmt.Fprintln(table, "service\tkey\tspace")
+	for _, serviceKey := range matching {
+		fmt.Fprintf(table, "%s\t%s\t%s\n", serviceKey.ServiceName, serviceKey.Name, serviceKey.SpaceName)
+	}
+	table.Flush()
+	fmt.Fprintln(self.Out)
+
+	if !*force && !self.confirm("Really delete these service keys?") {
+		fmt.Fprintln(self.Out, "Cleanup cancelled.")
+		return
+	}
+
+	failed := false
+	for _, serviceKey := range matching {
+		fmt.Fprintf(self.Out, "Deleting key %s for service instance %s...\n", serviceKey.Name, serviceKey.ServiceName)
+
+		err := self.CfService.DeleteServiceKey(cliConnection, serviceKey.Guid)
+		if err != nil {
+			fmt.Fprintf(self.Err, "FAILED\nUnable to delete service key %s for %s: %s\n", serviceKey.Name, serviceKey.ServiceName, err)
+			failed = true
+		}
+	}
+
+	if failed {
+		self.setErrorExit()
+		return
+	}
+
+	fmt.Fprintln(self.Out, "OK")
+}
+
+// cleanupTunnelApps deletes the temporary apps that sessions with --push-app did not delete, for example because the
+// plugin was killed
+func (self *MysqlPlugin) cleanupTunnelApps(cliConnection plugin.CliConnection, force bool) {
+	apps, err := self.TunnelAppPusher.FindTunnelApps(cliConnection)
+	if err != nil {
+		fmt.Fprintf(self.Err, "FAILED\nUnable to find tunnel apps: %s\n", err)
+		self.setErrorExit()
+		return
+	}
+
+	if len(apps) == 0 {
+		fmt.Fprintln(self.Out, "No temporary tunnel apps found.")
+		return
+	}
+
+	fmt.Fprintf(self.Out, "Found %d temporary tunnel app(s):\n\n", len(apps))
+	for _, app := range apps {
+		fmt.Fprintln(self.Out, app.Name)
+	}
+	fmt.Fprintln(self.Out)
+
+	if !force && !self.confirm("Really delete these apps? Other sessions may still be using them.") {
+		fmt.Fprintln(self.Out, "Cleanup cancelled.")
+		return
+	}
+
+	failed := false
+	for _, app := range apps {
+		fmt.Fprintf(self.Out, "Deleting app %s...\n", app.Name)
+
+		err := self.TunnelAppPusher.DeleteTunnelApp(cliConnection, app.Name)
+		if err != nil {
+			fmt.Fprintf(self.Err, "FAILED\nUnable to delete app %s: %s\n", app.Name, err)
+			failed = true
+		}
+	}
+
+	if failed {
+		self.setErrorExit()
+		return
+	}
+
+	fmt.Fprintln(self.Out, "OK")
+}
+
+func (self *MysqlPlugin) confirm(question string) bool {
+	fmt.Fprintf(self.Out, "%s [yN]: ", question)
+
+	answer, _ := bufio.NewReader(self.In).ReadString('\n')
+	answer = strings.ToLower(strings.TrimSpace(answer))
+
+	return answer == "y" || answer == "yes"
+}
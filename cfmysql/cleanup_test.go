@@ -0,0 +1,198 @@
+package cfmysql_test
+
+import (
+	"code.cloudfoundry.org/cli/plugin/models"
+	"errors"
+	. "github.com/andreasf/cf-mysql-plugin/cfmysql"
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("Cleanup", func() {
+	var serviceKeys []ServiceKeySummary
+
+	BeforeEach(func() {
+		serviceKeys = []ServiceKeySummary{
+			{
+				Guid:        "service-key-guid-a",
+				Name:        "cf-mysql",
+				ServiceName: "database-a",
+				SpaceName:   "space-a",
+			},
+			{
+				Guid:        "service-key-guid-b",
+				Name:        "cf-mysql-0000002a",
+				ServiceName: "database-b",
+				SpaceName:   "space-a",
+			},
+		}
+	})
+
+	Context("When calling 'cf mysql-cleanup' and confirming", func() {
+		It("Lists and deletes the keys and ephemeral keys in the current space", func() {
+			mysqlPlugin, mocks := NewPluginAndMocks()
+			mocks.CfService.GetServiceKeysReturns(serviceKeys, nil)
+			mocks.In.Write([]byte("y\n"))
+
+			mysqlPlugin.Run(mocks.CliConnection, []string{"mysql-cleanup"})
+
+			Expect(string(mocks.Out.Contents())).To(Equal("Found 2 cf-mysql service key(s):\n\n" +
+				"service      key                 space\n" +
+				"database-a   cf-mysql            space-a\n" +
+				"database-b   cf-mysql-0000002a   space-a\n\n" +
+				"Really delete these service keys? [yN]: " +
+				"Deleting key cf-mysql for service instance database-a...\n" +
+				"Deleting key cf-mysql-0000002a for service instance database-b...\n" +
+				"OK\n"))
+			Expect(mysqlPlugin.GetExitCode()).To(Equal(0))
+
+			calledConnection, wholeOrg := mocks.CfService.GetServiceKeysArgsForCall(0)
+			Expect(calledConnection).To(Equal(mocks.CliConnection))
+			Expect(wholeOrg).To(BeFalse())
+
+			Expect(mocks.CfService.DeleteServiceKeyCallCount()).To(Equal(2))
+			_, guid := mocks.CfService.DeleteServiceKeyArgsForCall(0)
+			Expect(guid).To(Equal("service-key-guid-a"))
+			_, guid = mocks.CfService.DeleteServiceKeyArgsForCall(1)
+			Expect(guid).To(Equal("service-key-guid-b"))
+		})
+	})
+
+	Context("When the confirmation is declined", func() {
+		It("Does not delete anything", func() {
+			mysqlPlugin, mocks := NewPluginAndMocks()
+			mocks.CfService.GetServiceKeysReturns(serviceKeys, nil)
+			mocks.In.Write([]byte("n\n"))
+
+			mysqlPlugin.Run(mocks.CliConnection, []string{"mysql-cleanup"})
+
+			Expect(string(mocks.Out.Contents())).To(HaveSuffix("Cleanup cancelled.\n"))
+			Expect(mysqlPlugin.GetExitCode()).To(Equal(0))
+			Expect(mocks.CfService.DeleteServiceKeyCallCount()).To(Equal(0))
+		})
+	})
+
+	Context("When calling 'cf mysql-cleanup --org --service database-b -f'", func() {
+		It("Deletes only the matching key in the org without asking", func() {
+			mysqlPlugin, mocks := NewPluginAndMocks()
+			mocks.CfService.GetServiceKeysReturns(serviceKeys, nil)
+
+			mysqlPlugin.Run(mocks.CliConnection, []string{"mysql-cleanup", "--org", "--service", "database-b", "-f"})
+
+			Expect(string(mocks.Out.Contents())).NotTo(ContainSubstring("[yN]"))
+			Expect(mysqlPlugin.GetExitCode()).To(Equal(0))
+
+			_, wholeOrg := mocks.CfService.GetServiceKeysArgsForCall(0)
+			Expect(wholeOrg).To(BeTrue())
+
+			Expect(mocks.CfService.DeleteServiceKeyCallCount()).To(Equal(1))
+			_, guid := mocks.CfService.DeleteServiceKeyArgsForCall(0)
+			Expect(guid).To(Equal("service-key-guid-b"))
+		})
+	})
+
+	Context("When no keys are found", func() {
+		It("Says so", func() {
+			mysqlPlugin, mocks := NewPluginAndMocks()
+
+			mysqlPlugin.Run(mocks.CliConnection, []string{"mysql-cleanup"})
+
+			Expect(string(mocks.Out.Contents())).To(Equal("No cf-mysql service keys found.\n"))
+			Expect(mysqlPlugin.GetExitCode()).To(Equal(0))
+		})
+	})
+
+	Context("When a key cannot be deleted", func() {
+		It("Deletes the other keys and exits with 1", func() {
+			mysqlPlugin, mocks := NewPluginAndMocks()
+			mocks.CfService.GetServiceKeysReturns(serviceKeys, nil)
+			mocks.CfService.DeleteServiceKeyReturnsOnCall(0, errors.New("PC LOAD LETTER"))
+
+			mysqlPlugin.Run(mocks.CliConnection, []string{"mysql-cleanup", "-f"})
+
+			Expect(string(mocks.Err.Contents())).To(Equal("FAILED\nUnable to delete service key cf-mysql for database-a: PC LOAD LETTER\n"))
+			Expect(mocks.CfService.DeleteServiceKeyCallCount()).To(Equal(2))
+			Expect(mysqlPlugin.GetExitCode()).To(Equal(1))
+		})
+	})
+
+	Context("When the keys cannot be retrieved", func() {
+		It("Shows an error message and exits with 1", func() {
+			mysqlPlugin, mocks := NewPluginAndMocks()
+			mocks.CfService.GetServiceKeysReturns(nil, errors.New("PC LOAD LETTER"))
+
+			mysqlPlugin.Run(mocks.CliConnection, []string{"mysql-cleanup"})
+
+			Expect(string(mocks.Err.Contents())).To(Equal("FAILED\nUnable to find service keys: PC LOAD LETTER\n"))
+			Expect(mysqlPlugin.GetExitCode()).To(Equal(1))
+		})
+	})
+
+	Context("When calling 'cf mysql-cleanup --apps' and confirming", func() {
+		It("Lists and deletes the temporary tunnel apps", func() {
+			mysqlPlugin, mocks := NewPluginAndMocks()
+			mocks.TunnelAppPusher.FindTunnelAppsReturns([]plugin_models.GetAppsModel{
+				{Name: "cf-mysql-tunnel-0000abcd"},
+				{Name: "cf-mysql-tunnel-0000beef"},
+			}, nil)
+			mocks.In.Write([]byte("y\n"))
+
+			mysqlPlugin.Run(mocks.CliConnection, []string{"mysql-cleanup", "--apps"})
+
+			Expect(string(mocks.Out.Contents())).To(Equal("Found 2 temporary tunnel app(s):\n\n" +
+				"cf-mysql-tunnel-0000abcd\n" +
+				"cf-mysql-tunnel-0000beef\n\n" +
+				"Really delete these apps? Other sessions may still be using them. [yN]: " +
+				"Deleting app cf-mysql-tunnel-0000abcd...\n" +
+				"Deleting app cf-mysql-tunnel-0000beef...\n" +
+				"OK\n"))
+			Expect(mysqlPlugin.GetExitCode()).To(Equal(0))
+
+			Expect(mocks.TunnelAppPusher.FindTunnelAppsArgsForCall(0)).To(Equal(mocks.CliConnection))
+			Expect(mocks.TunnelAppPusher.DeleteTunnelAppCallCount()).To(Equal(2))
+			_, name := mocks.TunnelAppPusher.DeleteTunnelAppArgsForCall(1)
+			Expect(name).To(Equal("cf-mysql-tunnel-0000beef"))
+			Expect(mocks.CfService.GetServiceKeysCallCount()).To(Equal(0))
+		})
+	})
+
+	Context("When no tunnel apps are found", func() {
+		It("Says so", func() {
+			mysqlPlugin, mocks := NewPluginAndMocks()
+
+			mysqlPlugin.Run(mocks.CliConnection, []string{"mysql-cleanup", "--apps", "-f"})
+
+			Expect(string(mocks.Out.Contents())).To(Equal("No temporary tunnel apps found.\n"))
+			Expect(mysqlPlugin.GetExitCode()).To(Equal(0))
+		})
+	})
+
+	Context("When a tunnel app cannot be deleted", func() {
+		It("Deletes the other apps and exits with 1", func() {
+			mysqlPlugin, mocks := NewPluginAndMocks()
+			mocks.TunnelAppPusher.FindTunnelAppsReturns([]plugin_models.GetAppsModel{
+				{Name: "cf-mysql-tunnel-0000abcd"},
+				{Name: "cf-mysql-tunnel-0000beef"},
+			}, nil)
+			mocks.TunnelAppPusher.DeleteTunnelAppReturnsOnCall(0, errors.New("PC LOAD LETTER"))
+
+			mysqlPlugin.Run(mocks.CliConnection, []string{"mysql-cleanup", "--apps", "-f"})
+
+			Expect(string(mocks.Err.Contents())).To(Equal("FAILED\nUnable to delete app cf-mysql-tunnel-0000abcd: PC LOAD LETTER\n"))
+			Expect(mocks.TunnelAppPusher.DeleteTunnelAppCallCount()).To(Equal(2))
+			Expect(mysqlPlugin.GetExitCode()).To(Equal(1))
+		})
+	})
+
+	Context("When combining --apps with --org", func() {
+		It("Prints usage information and exits with 1", func() {
+			mysqlPlugin, mocks := NewPluginAndMocks()
+
+			mysqlPlugin.Run(mocks.CliConnection, []string{"mysql-cleanup", "--apps", "--org"})
+
+			Expect(string(mocks.Err.Contents())).To(ContainSubstring("cf mysql-cleanup --apps [-f]"))
+			Expect(mocks.TunnelAppPusher.FindTunnelAppsCallCount()).To(Equal(0))
+			Expect(mysqlPlugin.GetExitCode()).To(Equal(1))
+		})
+	})
+})
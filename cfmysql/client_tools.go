@@ -0,0 +1,172 @@
+package cfmysql
+
+import "strings"
+
+// ClientTool describes a MySQL client program that is run through the tunnel by the plugin command of the same name
+type ClientTool struct {
+	Name     string
+	Label    string
+	HelpText string
+	Usage    string
+
+	// BuildArgs places the connection arguments, the database name and the user's arguments on the command line
+	BuildArgs func(connectionArgs []string, dbName string, toolArgs []string) []string
+}
+
+var MysqlTool = ClientTool{
+	Name:     "mysql",
+	Label:    "mysql client",
+	HelpText: "Connect to a MySQL database service",
+	Usage: "Open a mysql client to a database:\n   " +
+		"cf mysql [--ephemeral] [--local-port <port>] [--local-host <host>] [--local-socket <path>] [--app <app-name> | --push-app] [--org <org>] [--space <space>] <service-name> [mysql args...]",
+	BuildArgs: optionsBeforeDbName,
+}
+
+var MysqlDumpTool = ClientTool{
+	Name:     "mysqldump",
+	Label:    "mysqldump",
+	HelpText: "Dump a MySQL database",
+	Usage: "Dump all tables in a database:\n   " +
+		"cf mysqldump [--ephemeral] [--local-port <port>] [--local-host <host>] [--local-socket <path>] [--app <app-name> | --push-app] [--org <org>] [--space <space>] <service-name> [mysqldump args...]\n   " +
+		"Dump specific tables in a database:\n   " +
+		"cf mysqldump [--ephemeral] [--local-port <port>] [--local-host <host>] [--local-socket <path>] [--app <app-name> | --push-app] [--org <org>] [--space <space>] <service-name> [tables...] [mysqldump args...]",
+	BuildArgs: leadingArgsAfterDbName,
+}
+
+var MysqlAdminTool = ClientTool{
+	Name:     "mysqladmin",
+	Label:    "mysqladmin",
+	HelpText: "Run administrative commands against a MySQL database service",
+	Usage: "Run mysqladmin commands such as processlist, status or variables:\n   " +
+		"cf mysqladmin [--ephemeral] [--local-port <port>] [--local-host <host>] [--local-socket <path>] [--app <app-name> | --push-app] [--org <org>] [--space <space>] <service-name> [mysqladmin args...] <command...>",
+	BuildArgs: withoutDbName,
+}
+
+var MysqlImportTool = ClientTool{
+	Name:     "mysqlimport",
+	Label:    "mysqlimport",
+	HelpText: "Load data files into a MySQL database",
+	Usage: "Load local text files into the tables named like the files:\n   " +
+		"cf mysqlimport [--ephemeral] [--local-port <port>] [--local-host <host>] [--local-socket <path>] [--app <app-name> | --push-app] [--org <org>] [--space <space>] <service-name> [mysqlimport args...] <files...>",
+	BuildArgs: filesAfterDbName,
+}
+
+// ClientTools lists the client programs the plugin provides a command for
+var ClientTools = []ClientTool{MysqlTool, MysqlDumpTool, MysqlAdminTool, MysqlImportTool}
+
+func FindClientTool(name string) (ClientTool, bool) {
+	for _, tool := range ClientTools {
+		if tool.Name == name {
+			return tool, true
+		}
+	}
+
+	return ClientTool{}, false
+}
+
+// optionsBeforeDbName passes the database as the last argument, as mysql treats it as the default database
+func optionsBeforeDbName(connectionArgs []string, dbName string, toolArgs []string) []string {
+	args := append([]string{}, connectionArgs...)
+	args = append(args, toolArgs...)
+	return append(args, dbName)
+}
+
+// leadingArgsAfterDbName moves the arguments before the first option behind the database, as mysqldump expects
+// table names there
+func leadingArgsAfterDbName(connectionArgs []string, dbName string, toolArgs []string) []string {
+	var leadingArgs []string
+	options := toolArgs
+
+	for i, argument := range toolArgs {
+		if strings.HasPrefix(argument, "-") {
+			break
+		}
+
+		leadingArgs = append(leadingArgs, argument)
+		options = toolArgs[i+1:]
+	}
+
+	args := append([]string{}, connectionArgs...)
+	args = append(args, options...)
+	args = append(args, dbName)
+	return append(args, leadingArgs...)
+}
+
+// withoutDbName leaves out the database, as mysqladmin commands apply to the whole server
+func withoutDbName(connectionArgs []string, dbName string, toolArgs []string) []string {
+	args := append([]string{}, connectionArgs...)
+	return append(args, toolArgs...)
+}
+
+// mysqlImportValueOptions are the mysqlimport options that can take their value as the next argument
+var mysqlImportValueOptions = map[string]bool{
+	"-c":                              true,
+	"--columns":                       true,
+	"-h":                              true,
+	"--host":                          true,
+	"-P":                              true,
+	"--port":                          true,
+	"-S":                              true,
+	"--socket":                        true,
+	"-u":                              true,
+	"--user":                          true,
+	"--character-sets-dir":            true,
+	"--compression-algorithms":        true,
+	"--default-auth":                  true,
+	"--default-character-set":         true,
+	"--defaults-extra-file":           true,
+	"--defaults-file":                 true,
+	"--defaults-group-suffix":         true,
+	"--fields-enclosed-by":            true,
+	"--fields-escaped-by":             true,
+	"--fields-optionally-enclosed-by": true,
+	"--fields-terminated-by":          true,
+	"--ignore-lines":                  true,
+	"--lines-terminated-by":           true,
+	"--load-data-local-dir":           true,
+	"--login-path":                    true,
+	"--plugin-dir":                    true,
+	"--protocol":                      true,
+	"--server-public-key-path":        true,
+	"--shared-memory-base-name":       true,
+	"--ssl-ca":                        true,
+	"--ssl-capath":                    true,
+	"--ssl-cert":                      true,
+	"--ssl-cipher":                    true,
+	"--ssl-crl":                       true,
+	"--ssl-crlpath":                   true,
+	"--ssl-fips-mode":                 true,
+	"--ssl-key":                       true,
+	"--ssl-mode":                      true,
+	"--threads":                       true,
+	"--tls-ciphersuites":              true,
+	"--tls-version":                   true,
+	"--zstd-compression-level":        true,
+}
+
+// filesAfterDbName passes options before and files after the database. Options that take a separate value keep it.
+// The files are always read by the client, as the database server cannot access them.
+func filesAfterDbName(connectionArgs []string, dbName string, toolArgs []string) []string {
+	var options []string
+	var files []string
+
+	for i := 0; i < len(toolArgs); i++ {
+		argument := toolArgs[i]
+		if !strings.HasPrefix(argument, "-") {
+			files = append(files, argument)
+			continue
+		}
+
+		options = append(options, argument)
+		if mysqlImportValueOptions[argument] && i+1 < len(toolArgs) {
+			i++
+			options = append(options, toolArgs[i])
+		}
+	}
+
+	args := append([]string{}, connectionArgs...)
+	args = append(args, "--local")
+	args = append(args, options...)
+	args = append(args, dbName)
+	return append(args, files...)
+}
@@ -0,0 +1,145 @@
+package cfmysql
+
+import (
+	"code.cloudfoundry.org/cli/plugin"
+	"flag"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"sync"
+	"time"
+)
+
+const CopyProgressInterval = 500 * time.Millisecond
+
+func (self *MysqlPlugin) copyDatabase(cliConnection plugin.CliConnection, args []string) {
+	flags := flag.NewFlagSet("mysql-copy", flag.ContinueOnError)
+	flags.SetOutput(ioutil.Discard)
+	ephemeral := flags.Bool("ephemeral", false, "")
+	app := addAppChoiceFlags(flags)
+	spaceTarget := addSpaceTargetFlags(flags)
+
+	err := flags.Parse(args)
+	if err != nil || flags.NArg() < 2 || !app.valid() || !spaceTarget.valid(*app) {
+		fmt.Fprint(self.Err, self.FormatUsage())
+		self.setErrorExit()
+		return
+	}
+
+	sourceName := flags.Arg(0)
+	targetName := flags.Arg(1)
+	tables := flags.Args()[2:]
+
+	if sourceName == targetName {
+		fmt.Fprintf(self.Err, "FAILED\nSource and target are the same service: %s\n", sourceName)
+		self.setErrorExit()
+		return
+	}
+
+	cliConnection, ok := self.targetConnection(cliConnection, *spaceTarget)
+	if !ok {
+		return
+	}
+
+	if *ephemeral || app.Push {
+		defer self.ignoreInterrupts()()
+	}
+
+	source, sourceTunnel, sourceAddress, ok := self.openTunnel(cliConnection, sourceName, *ephemeral, LocalAddress{Host: DefaultLocalHost}, *app)
+	if *ephemeral && source.ServiceKeyGuid != "" {
+		defer self.deleteEphemeralKey(cliConnection, source)
+	}
+	if !ok {
+		return
+	}
+	defer sourceTunnel.Close()
+
+	// a temporary app pushed for the source is started by now, so the target's tunnel goes through it as well
+	target, targetTunnel, targetAddress, ok := self.openTunnel(cliConnection, targetName, *ephemeral, LocalAddress{Host: DefaultLocalHost}, *app)
+	if *ephemeral && target.ServiceKeyGuid != "" {
+		defer self.deleteEphemeralKey(cliConnection, target)
+	}
+	if !ok {
+		return
+	}
+	defer targetTunnel.Close()
+
+	fmt.Fprintf(self.Err, "Copying %s into %s...\n", sourceName, targetName)
+
+	pipeReader, pipeWriter := io.Pipe()
+	progress := newCopyProgress(pipeWriter)
+
+	dumpArgs := append(append([]string{}, tables...), "--single-transaction")
+	dumpIo := ClientIo{
+		Stdout: progress,
+		Stderr: self.Err,
+	}
+
+	dumpResult := make(chan error, 1)
+	go func() {
+		err := self.MysqlRunner.RunTool(MysqlDumpTool, dumpIo, sourceAddress, source.DbName, source.Username, source.Password, source.CaCert, source.withClientArgs(dumpArgs)...)
+		pipeWriter.CloseWithError(err)
+		dumpResult <- err
+	}()
+
+	mysqlIo := ClientIo{
+		Stdin:  pipeReader,
+		Stdout: self.Out,
+		Stderr: self.Err,
+	}
+
+	stopReporting := reportProgress(self.Err, progress, CopyProgressInterval)
+	mysqlErr := self.MysqlRunner.RunTool(MysqlTool, mysqlIo, targetAddress, target.DbName, target.Username, target.Password, target.CaCert, target.ClientArgs...)
+	pipeReader.Close()
+	dumpErr := <-dumpResult
+	stopReporting()
+
+	fmt.Fprintf(self.Err, "\r%s\n", progress)
+
+	// a failing mysql client breaks the pipe, so its error is reported in favor of mysqldump's
+	err = mysqlErr
+	if err == nil {
+		err = dumpErr
+	}
+
+	if err != nil {
+		fmt.Fprintf(self.Err, "FAILED\n%s\n", err)
+		self.setClientErrorExit(err)
+		return
+	}
+
+	fmt.Fprint(self.Err, "OK\n")
+}
+
+// copyProgress passes the dump on to the target client and counts the bytes and rows written
+type copyProgress struct {
+	writer  io.Writer
+	mutex   sync.Mutex
+	bytes   int64
+	scanner statementScanner
+}
+
+func newCopyProgress(writer io.Writer) *copyProgress {
+	return &copyProgress{
+		writer:  writer,
+		scanner: newStatementScanner(),
+	}
+}
+
+func (self *copyProgress) Write(p []byte) (int, error) {
+	n, err := self.writer.Write(p)
+
+	self.mutex.Lock()
+	self.bytes += int64(n)
+	self.scanner.scan(p[:n])
+	self.mutex.Unlock()
+
+	return n, err
+}
+
+func (self *copyProgress) String() string {
+	self.mutex.Lock()
+	defer self.mutex.Unlock()
+
+	return fmt.Sprintf("%d rows, %s copied", self.scanner.rows, formatBytes(self.bytes))
+}
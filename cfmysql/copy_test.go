@@ -0,0 +1,296 @@
+package cfmysql_test
+
+import (
+	"code.cloudfoundry.org/cli/plugin"
+	"code.cloudfoundry.org/cli/plugin/models"
+	"fmt"
+	. "github.com/andreasf/cf-mysql-plugin/cfmysql"
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+	"github.com/onsi/gomega/gbytes"
+	"io"
+	"io/ioutil"
+)
+
+var _ = Describe("Copy", func() {
+	var services map[string]MysqlService
+	var appList []plugin_models.GetAppsModel
+
+	dump := "-- MySQL dump\n" +
+		"/*!40101 SET NAMES utf8 */;\n" +
+		"CREATE TABLE `t` (`a` text, `b` int);\n" +
+		"INSERT INTO `t` VALUES ('(not a row)',1),('it\\'s',2),('),(',3);\n" +
+		"DELIMITER ;;\n" +
+		"CREATE TRIGGER `trg` BEFORE INSERT ON `t` FOR EACH ROW BEGIN INSERT INTO `log` VALUES (NEW.b); END ;;\n" +
+		"DELIMITER ;\n" +
+		"insert into `u` values (1),(2);\n"
+
+	BeforeEach(func() {
+		services = map[string]MysqlService{
+			"database-a": {
+				Name:     "database-a",
+				DbName:   "dbname-a",
+				Username: "username-a",
+				Password: "password-a",
+				CaCert:   "ca-cert-a",
+			},
+			"database-b": {
+				Name:     "database-b",
+				DbName:   "dbname-b",
+				Username: "username-b",
+				Password: "password-b",
+			},
+		}
+
+		appList = []plugin_models.GetAppsModel{
+			{
+				Name: "app-name-1",
+			},
+		}
+	})
+
+	setUpMocks := func(mocks Mocks) {
+		mocks.CfService.GetServiceStub = func(connection plugin.CliConnection, name string) (MysqlService, error) {
+			service, found := services[name]
+			if !found {
+				return MysqlService{}, fmt.Errorf("%s not found", name)
+			}
+			return service, nil
+		}
+		mocks.CfService.GetStartedAppsReturns(appList, nil)
+		mocks.PortFinder.GetPortReturnsOnCall(0, 2342, nil)
+		mocks.PortFinder.GetPortReturnsOnCall(1, 2343, nil)
+	}
+
+	// stubClients runs the stubs in place of mysqldump and mysql, which copy starts at the same time
+	stubClients := func(mocks Mocks, dumpStub func(ClientIo) error, mysqlStub func(ClientIo) error) {
+		mocks.MysqlRunner.RunToolStub = func(tool ClientTool, clientIo ClientIo, address LocalAddress, dbName string, username string, password string, caCert string, args ...string) error {
+			if tool.Name == MysqlDumpTool.Name {
+				return dumpStub(clientIo)
+			}
+			return mysqlStub(clientIo)
+		}
+	}
+
+	clientArgsForCall := func(mocks Mocks, tool ClientTool) (LocalAddress, string, string, string, string, []string) {
+		for i := 0; i < mocks.MysqlRunner.RunToolCallCount(); i++ {
+			calledTool, _, address, dbName, username, password, caCert, args := mocks.MysqlRunner.RunToolArgsForCall(i)
+			if calledTool.Name == tool.Name {
+				return address, dbName, username, password, caCert, args
+			}
+		}
+
+		Fail(tool.Name + " was not run")
+		return LocalAddress{}, "", "", "", "", nil
+	}
+
+	Context("When calling 'cf mysql-copy' with less than two services", func() {
+		It("Prints usage information to STDERR and exits with 1", func() {
+			mysqlPlugin, mocks := NewPluginAndMocks()
+
+			mysqlPlugin.Run(mocks.CliConnection, []string{"mysql-copy", "database-a"})
+
+			Expect(string(mocks.Err.Contents())).To(Equal(mysqlPlugin.FormatUsage()))
+			Expect(mysqlPlugin.GetExitCode()).To(Equal(1))
+			Expect(mocks.CfService.GetServiceCallCount()).To(Equal(0))
+		})
+	})
+
+	Context("When calling 'cf mysql-copy database-a database-b'", func() {
+		It("Opens a tunnel to each service on separate ports", func() {
+			mysqlPlugin, mocks := NewPluginAndMocks()
+			setUpMocks(mocks)
+
+			mysqlPlugin.Run(mocks.CliConnection, []string{"mysql-copy", "database-a", "database-b"})
+
+			Expect(mocks.CfService.OpenSshTunnelCallCount()).To(Equal(2))
+			_, forwards, calledApps := mocks.CfService.OpenSshTunnelArgsForCall(0)
+			Expect(calledApps).To(Equal(appList))
+			Expect(forwards).To(Equal([]TunnelForward{{Service: services["database-a"], LocalAddress: LocalAddress{Host: "127.0.0.1", Port: 2342}}}))
+
+			_, forwards, calledApps = mocks.CfService.OpenSshTunnelArgsForCall(1)
+			Expect(calledApps).To(Equal(appList))
+			Expect(forwards).To(Equal([]TunnelForward{{Service: services["database-b"], LocalAddress: LocalAddress{Host: "127.0.0.1", Port: 2343}}}))
+		})
+
+		It("Streams the dump of the source into the target and reports rows and bytes", func() {
+			mysqlPlugin, mocks := NewPluginAndMocks()
+			setUpMocks(mocks)
+
+			var received []byte
+			stubClients(mocks, func(clientIo ClientIo) error {
+				_, err := io.WriteString(clientIo.Stdout, dump)
+				return err
+			}, func(clientIo ClientIo) error {
+				var err error
+				received, err = ioutil.ReadAll(clientIo.Stdin)
+				return err
+			})
+
+			mysqlPlugin.Run(mocks.CliConnection, []string{"mysql-copy", "database-a", "database-b"})
+
+			Expect(string(received)).To(Equal(dump))
+
+			address, dbName, username, password, caCert, args := clientArgsForCall(mocks, MysqlDumpTool)
+			Expect(address).To(Equal(LocalAddress{Host: "127.0.0.1", Port: 2342}))
+			Expect(dbName).To(Equal("dbname-a"))
+			Expect(username).To(Equal("username-a"))
+			Expect(password).To(Equal("password-a"))
+			Expect(caCert).To(Equal("ca-cert-a"))
+			Expect(args).To(Equal([]string{"--single-transaction"}))
+
+			address, dbName, username, password, caCert, args = clientArgsForCall(mocks, MysqlTool)
+			Expect(address).To(Equal(LocalAddress{Host: "127.0.0.1", Port: 2343}))
+			Expect(dbName).To(Equal("dbname-b"))
+			Expect(username).To(Equal("username-b"))
+			Expect(password).To(Equal("password-b"))
+			Expect(caCert).To(Equal(""))
+			Expect(args).To(BeEmpty())
+
+			Expect(mocks.Err).To(gbytes.Say("Copying database-a into database-b...\n"))
+			Expect(mocks.Err).To(gbytes.Say(fmt.Sprintf("\r5 rows, %d B copied\nOK\n", len(dump))))
+			Expect(mysqlPlugin.GetExitCode()).To(Equal(0))
+		})
+
+		Context("When passing table names", func() {
+			It("Only dumps the given tables", func() {
+				mysqlPlugin, mocks := NewPluginAndMocks()
+				setUpMocks(mocks)
+
+				mysqlPlugin.Run(mocks.CliConnection, []string{"mysql-copy", "database-a", "database-b", "table1", "table2"})
+
+				_, _, _, _, _, args := clientArgsForCall(mocks, MysqlDumpTool)
+				Expect(args).To(Equal([]string{"table1", "table2", "--single-transaction"}))
+			})
+		})
+
+		Context("When the target client fails", func() {
+			It("Stops the dump and exits with the status of mysql", func() {
+				mysqlPlugin, mocks := NewPluginAndMocks()
+				setUpMocks(mocks)
+
+				stubClients(mocks, func(clientIo ClientIo) error {
+					for {
+						_, err := io.WriteString(clientIo.Stdout, dump)
+						if err != nil {
+							return &ClientExitError{Message: "error running mysqldump: exit status 2", ExitCode: 2}
+						}
+					}
+				}, func(clientIo ClientIo) error {
+					return &ClientExitError{Message: "error running mysql client: exit status 1", ExitCode: 1}
+				})
+
+				mysqlPlugin.Run(mocks.CliConnection, []string{"mysql-copy", "database-a", "database-b"})
+
+				Expect(mocks.Err).To(gbytes.Say("FAILED\nerror running mysql client: exit status 1\n"))
+				Expect(mysqlPlugin.GetExitCode()).To(Equal(1))
+			})
+		})
+
+		Context("When mysqldump fails", func() {
+			It("Exits with the status of mysqldump", func() {
+				mysqlPlugin, mocks := NewPluginAndMocks()
+				setUpMocks(mocks)
+
+				stubClients(mocks, func(clientIo ClientIo) error {
+					return &ClientExitError{Message: "error running mysqldump: exit status 2", ExitCode: 2}
+				}, func(clientIo ClientIo) error {
+					_, err := ioutil.ReadAll(clientIo.Stdin)
+					Expect(err).NotTo(BeNil())
+					return nil
+				})
+
+				mysqlPlugin.Run(mocks.CliConnection, []string{"mysql-copy", "database-a", "database-b"})
+
+				Expect(mocks.Err).To(gbytes.Say("FAILED\nerror running mysqldump: exit status 2\n"))
+				Expect(mysqlPlugin.GetExitCode()).To(Equal(2))
+			})
+		})
+	})
+
+	Context("When calling 'cf mysql-copy --ephemeral --app app-name-1'", func() {
+		It("Uses ephemeral keys for both services and deletes them after the copy", func() {
+			mysqlPlugin, mocks := NewPluginAndMocks()
+			setUpMocks(mocks)
+			mocks.CfService.GetEphemeralServiceStub = func(connection plugin.CliConnection, name string) (MysqlService, error) {
+				service := services[name]
+				service.ServiceKeyGuid = name + "-key-guid"
+				return service, nil
+			}
+			mocks.CfService.GetStartedAppsReturns(append(appList, plugin_models.GetAppsModel{Name: "app-name-2"}), nil)
+			stubClients(mocks, func(clientIo ClientIo) error {
+				Expect(mocks.CfService.DeleteServiceKeyCallCount()).To(Equal(0))
+				return nil
+			}, func(clientIo ClientIo) error {
+				_, err := ioutil.ReadAll(clientIo.Stdin)
+				return err
+			})
+
+			mysqlPlugin.Run(mocks.CliConnection, []string{"mysql-copy", "--ephemeral", "--app", "app-name-1", "database-a", "database-b", "users"})
+
+			Expect(mocks.CfService.GetServiceCallCount()).To(Equal(0))
+			Expect(mocks.CfService.GetEphemeralServiceCallCount()).To(Equal(2))
+
+			_, _, calledApps := mocks.CfService.OpenSshTunnelArgsForCall(0)
+			Expect(calledApps).To(Equal(appList))
+			_, _, calledApps = mocks.CfService.OpenSshTunnelArgsForCall(1)
+			Expect(calledApps).To(Equal(appList))
+
+			_, _, _, _, _, args := clientArgsForCall(mocks, MysqlDumpTool)
+			Expect(args).To(Equal([]string{"users", "--single-transaction"}))
+
+			Expect(mocks.CfService.DeleteServiceKeyCallCount()).To(Equal(2))
+			_, calledGuid := mocks.CfService.DeleteServiceKeyArgsForCall(0)
+			Expect(calledGuid).To(Equal("database-b-key-guid"))
+			_, calledGuid = mocks.CfService.DeleteServiceKeyArgsForCall(1)
+			Expect(calledGuid).To(Equal("database-a-key-guid"))
+			Expect(mocks.SignalWrapper.StopCallCount()).To(Equal(1))
+			Expect(mysqlPlugin.GetExitCode()).To(Equal(0))
+		})
+
+		It("Deletes the key of the source if the target cannot be found", func() {
+			mysqlPlugin, mocks := NewPluginAndMocks()
+			setUpMocks(mocks)
+			mocks.CfService.GetEphemeralServiceStub = func(connection plugin.CliConnection, name string) (MysqlService, error) {
+				if name == "database-c" {
+					return MysqlService{}, fmt.Errorf("%s not found", name)
+				}
+				service := services[name]
+				service.ServiceKeyGuid = name + "-key-guid"
+				return service, nil
+			}
+
+			mysqlPlugin.Run(mocks.CliConnection, []string{"mysql-copy", "--ephemeral", "database-a", "database-c"})
+
+			Expect(mocks.CfService.DeleteServiceKeyCallCount()).To(Equal(1))
+			_, calledGuid := mocks.CfService.DeleteServiceKeyArgsForCall(0)
+			Expect(calledGuid).To(Equal("database-a-key-guid"))
+			Expect(mocks.MysqlRunner.RunToolCallCount()).To(Equal(0))
+			Expect(mysqlPlugin.GetExitCode()).To(Equal(1))
+		})
+	})
+
+	Context("When the target service cannot be found", func() {
+		It("Shows an error message and exits with 1", func() {
+			mysqlPlugin, mocks := NewPluginAndMocks()
+			setUpMocks(mocks)
+
+			mysqlPlugin.Run(mocks.CliConnection, []string{"mysql-copy", "database-a", "database-c"})
+
+			Expect(string(mocks.Err.Contents())).To(Equal("FAILED\nUnable to retrieve service credentials: database-c not found\n"))
+			Expect(mocks.MysqlRunner.RunToolCallCount()).To(Equal(0))
+			Expect(mysqlPlugin.GetExitCode()).To(Equal(1))
+		})
+	})
+
+	Context("When source and target are the same service", func() {
+		It("Shows an error message and exits with 1", func() {
+			mysqlPlugin, mocks := NewPluginAndMocks()
+
+			mysqlPlugin.Run(mocks.CliConnection, []string{"mysql-copy", "database-a", "database-a"})
+
+			Expect(string(mocks.Err.Contents())).To(Equal("FAILED\nSource and target are the same service: database-a\n"))
+			Expect(mysqlPlugin.GetExitCode()).To(Equal(1))
+		})
+	})
+})
@@ -0,0 +1,193 @@
+package cfmysql
+
+import (
+	"code.cloudfoundry.org/cli/plugin"
+	"flag"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net"
+	"net/url"
+	"strconv"
+	"strings"
+)
+
+// EnvFormat prints the connection details in the shape one kind of tool understands. The CA certificate of the
+// service is passed as the path of a file, as the formats cannot hold its content.
+type EnvFormat struct {
+	Write func(writer io.Writer, service MysqlService, caCertPath string)
+	// CaCertNote tells how to use the CA certificate with formats that cannot refer to the file
+	CaCertNote string
+}
+
+// EnvFormats maps the formats of 'cf mysql-env' to their writers
+var EnvFormats = map[string]EnvFormat{
+	"env":   {Write: writeEnvExports},
+	"mycnf": {Write: writeMyCnf},
+	"url":   {Write: writeMysqlUrl, CaCertNote: "Pass it to the client as the CA certificate."},
+	"jdbc":  {Write: writeJdbcUrl, CaCertNote: "Import it into a trust store and pass that as trustCertificateKeyStoreUrl."},
+	"dsn":   {Write: writeGoDsn, CaCertNote: "Register it with mysql.RegisterTLSConfig and pass the name as tls."},
+}
+
+// jdbcOptionNames maps client options to the properties of MySQL Connector/J
+var jdbcOptionNames = map[string]string{
+	"ssl-mode":              "sslMode",
+	"ssl-cipher":            "enabledSSLCipherSuites",
+	"tls-version":           "enabledTLSProtocols",
+	"default-character-set": "characterEncoding",
+}
+
+// goDsnTlsValues maps the ssl-mode client option to the tls parameter of github.com/go-sql-driver/mysql
+var goDsnTlsValues = map[string]string{
+	"DISABLED":        "false",
+	"PREFERRED":       "preferred",
+	"REQUIRED":        "skip-verify",
+	"VERIFY_CA":       "true",
+	"VERIFY_IDENTITY": "true",
+}
+
+func (self *MysqlPlugin) printEnv(cliConnection plugin.CliConnection, args []string) {
+	flags := flag.NewFlagSet("mysql-env", flag.ContinueOnError)
+	flags.SetOutput(ioutil.Discard)
+	format := flags.String("format", "env", "")
+	localAddress := addLocalAddressFlags(flags)
+	target := addSpaceTargetFlags(flags)
+
+	err := flags.Parse(args)
+	envFormat, knownFormat := EnvFormats[*format]
+	if err != nil || flags.NArg() != 1 || !knownFormat || !localAddress.normalize() || localAddress.Socket != "" || !target.valid(appChoice{}) {
+		fmt.Fprint(self.Err, self.FormatUsage())
+		self.setErrorExit()
+		return
+	}
+
+	cliConnection, ok := self.targetConnection(cliConnection, *target)
+	if !ok {
+		return
+	}
+
+	service, err := self.CfService.GetService(cliConnection, flags.Arg(0))
+	if err != nil {
+		fmt.Fprintf(self.Err, "FAILED\nUnable to retrieve service credentials: %s\n", err)
+		self.setErrorExit()
+		return
+	}
+
+	if localAddress.Port > 0 {
+		service.Hostname = localAddress.Host
+		service.Port = strconv.Itoa(localAddress.Port)
+	}
+
+	caCertPath := ""
+	if service.CaCert != "" {
+		// the file is kept, as the printed details refer to it
+		caCertPath, err = writeCaCert(self.IoUtilWrapper, self.OsWrapper, service.CaCert)
+		if err != nil {
+			fmt.Fprintf(self.Err, "FAILED\nUnable to store CA certificate: %s\n", err)
+			self.setErrorExit()
+			return
+		}
+
+		if envFormat.CaCertNote != "" {
+			fmt.Fprintf(self.Err, "The CA certificate of %s was written to %s. %s\n", service.Name, caCertPath, envFormat.CaCertNote)
+		}
+	}
+
+	envFormat.Write(self.Out, service, caCertPath)
+}
+
+// writeEnvExports writes the host, port and password under the names the mysql client reads, and the user, database
+// and CA certificate as plain variables for apps and scripts
+func writeEnvExports(writer io.Writer, service MysqlService, caCertPath string) {
+	fmt.Fprintf(writer, "export MYSQL_HOST=%s\n", shellQuote(service.Hostname))
+	fmt.Fprintf(writer, "export MYSQL_TCP_PORT=%s\n", shellQuote(service.Port))
+	fmt.Fprintf(writer, "export MYSQL_DATABASE=%s\n", shellQuote(service.DbName))
+	fmt.Fprintf(writer, "export MYSQL_USER=%s\n", shellQuote(service.Username))
+	fmt.Fprintf(writer, "export MYSQL_PWD=%s\n", shellQuote(service.Password))
+
+	if caCertPath != "" {
+		fmt.Fprintf(writer, "export MYSQL_SSL_CA=%s\n", shellQuote(caCertPath))
+	}
+}
+
+func shellQuote(value string) string {
+	return "'" + strings.Replace(value, "'", `'\''`, -1) + "'"
+}
+
+func writeMyCnf(writer io.Writer, service MysqlService, caCertPath string) {
+	fmt.Fprintln(writer, "[client]")
+	fmt.Fprintf(writer, "host=%s\n", service.Hostname)
+	fmt.Fprintf(writer, "port=%s\n", service.Port)
+	fmt.Fprintf(writer, "database=%s\n", myCnfQuote(service.DbName))
+	fmt.Fprintf(writer, "user=%s\n", myCnfQuote(service.Username))
+	fmt.Fprintf(writer, "password=%s\n", myCnfQuote(service.Password))
+
+	if caCertPath != "" {
+		fmt.Fprintf(writer, "ssl-ca=%s\n", myCnfQuote(caCertPath))
+	}
+	for _, name := range sortedOptionNames(service.ClientOptions) {
+		fmt.Fprintf(writer, "%s=%s\n", name, service.ClientOptions[name])
+	}
+}
+
+var myCnfEscaper = strings.NewReplacer(`\`, `\\`, `"`, `\"`)
+
+func myCnfQuote(value string) string {
+	return `"` + myCnfEscaper.Replace(value) + `"`
+}
+
+func writeMysqlUrl(writer io.Writer, service MysqlService, caCertPath string) {
+	query := url.Values{}
+	for name, value := range service.ClientOptions {
+		query.Set(name, value)
+	}
+
+	mysqlUrl := url.URL{
+		Scheme:   "mysql",
+		User:     url.UserPassword(service.Username, service.Password),
+		Host:     net.JoinHostPort(service.Hostname, service.Port),
+		Path:     "/" + service.DbName,
+		RawQuery: query.Encode(),
+	}
+
+	fmt.Fprintln(writer, mysqlUrl.String())
+}
+
+func writeJdbcUrl(writer io.Writer, service MysqlService, caCertPath string) {
+	query := url.Values{}
+	query.Set("user", service.Username)
+	query.Set("password", service.Password)
+	for name, value := range service.ClientOptions {
+		if property, ok := jdbcOptionNames[name]; ok {
+			query.Set(property, value)
+		}
+	}
+
+	jdbcUrl := url.URL{
+		Scheme:   "mysql",
+		Host:     net.JoinHostPort(service.Hostname, service.Port),
+		Path:     "/" + service.DbName,
+		RawQuery: query.Encode(),
+	}
+
+	fmt.Fprintln(writer, "jdbc:"+jdbcUrl.String())
+}
+
+// writeGoDsn prints a data source name for github.com/go-sql-driver/mysql, which takes the password unescaped. The
+// driver has no parameters for the TLS version and ciphers.
+func writeGoDsn(writer io.Writer, service MysqlService, caCertPath string) {
+	query := url.Values{}
+	if tls, ok := goDsnTlsValues[strings.ToUpper(service.ClientOptions["ssl-mode"])]; ok {
+		query.Set("tls", tls)
+	}
+	if charset := service.ClientOptions["default-character-set"]; charset != "" {
+		query.Set("charset", charset)
+	}
+
+	dsn := fmt.Sprintf("%s:%s@tcp(%s)/%s", service.Username, service.Password, net.JoinHostPort(service.Hostname, service.Port), service.DbName)
+	if len(query) > 0 {
+		dsn += "?" + query.Encode()
+	}
+
+	fmt.Fprintln(writer, dsn)
+}
@@ -0,0 +1,102 @@
+package cfmysql
+
+import (
+	"code.cloudfoundry.org/cli/plugin"
+	"context"
+	"flag"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"time"
+)
+
+// ExecTimeoutExitCode is the exit status of 'cf mysql-exec' when the statement timed out, like timeout(1)
+const ExecTimeoutExitCode = 124
+
+func (self *MysqlPlugin) execStatement(cliConnection plugin.CliConnection, args []string) {
+	flags := flag.NewFlagSet("mysql-exec", flag.ContinueOnError)
+	flags.SetOutput(ioutil.Discard)
+	format := flags.String("format", "tsv", "")
+	timeout := flags.Duration("timeout", 0, "")
+	sqlFile := flags.String("f", "", "")
+	ephemeral := flags.Bool("ephemeral", false, "")
+	app := addAppChoiceFlags(flags)
+	target := addSpaceTargetFlags(flags)
+
+	err := flags.Parse(args)
+	validArgs := *sqlFile == "" && flags.NArg() == 2 || *sqlFile != "" && flags.NArg() == 1
+	if err != nil || !validArgs || !isResultFormat(*format) || !app.valid() || !target.valid(*app) {
+		fmt.Fprint(self.Err, self.FormatUsage())
+		self.setErrorExit()
+		return
+	}
+
+	cliConnection, ok := self.targetConnection(cliConnection, *target)
+	if !ok {
+		return
+	}
+
+	dbName := flags.Arg(0)
+	mysqlArgs := []string{"--xml"}
+
+	var input io.Reader
+	if *sqlFile != "" {
+		file, err := self.OsWrapper.Open(*sqlFile)
+		if err != nil {
+			fmt.Fprintf(self.Err, "FAILED\nUnable to open SQL file: %s\n", err)
+			self.setErrorExit()
+			return
+		}
+		defer file.Close()
+		input = file
+	} else {
+		mysqlArgs = append(mysqlArgs, "--execute="+flags.Arg(1))
+	}
+
+	self.connectTo(cliConnection, dbName, *ephemeral, LocalAddress{Host: DefaultLocalHost}, *app, func(tunnelAddress LocalAddress, service MysqlService) error {
+		ctx := context.Background()
+		if *timeout > 0 {
+			var cancel context.CancelFunc
+			ctx, cancel = context.WithTimeout(ctx, *timeout)
+			defer cancel()
+		}
+
+		// rows are printed while the client is still running, so that large results are not held in memory
+		outputReader, outputWriter := io.Pipe()
+		written := make(chan error, 1)
+		go func() {
+			err := writeXmlResults(outputReader, newResultWriter(self.Out, *format))
+			// the client blocks until its output is read
+			io.Copy(ioutil.Discard, outputReader)
+			written <- err
+		}()
+
+		clientIo := ClientIo{
+			Stdin:  input,
+			Stdout: outputWriter,
+			Stderr: self.Err,
+		}
+
+		err := self.MysqlRunner.RunToolWithContext(ctx, MysqlTool, clientIo, tunnelAddress, service.DbName, service.Username, service.Password, service.CaCert, service.withClientArgs(mysqlArgs)...)
+		outputWriter.Close()
+		writeErr := <-written
+
+		// killing the client does not cancel the statement on the server
+		if ctx.Err() == context.DeadlineExceeded {
+			return &ClientExitError{
+				Message:  fmt.Sprintf("statement timed out after %s, it may still be running on the server", timeout.Round(time.Millisecond)),
+				ExitCode: ExecTimeoutExitCode,
+			}
+		}
+
+		// the results of statements before a failing one are printed as well
+		switch {
+		case err != nil:
+			return err
+		case writeErr != nil:
+			return fmt.Errorf("error printing mysql output: %s", writeErr)
+		default:
+			return nil
+		}
+	})
+}
@@ -0,0 +1,328 @@
+package cfmysql_test
+
+import (
+	"code.cloudfoundry.org/cli/plugin/models"
+	"context"
+	"errors"
+	. "github.com/andreasf/cf-mysql-plugin/cfmysql"
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+	"github.com/onsi/gomega/gbytes"
+	"io"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+)
+
+var _ = Describe("Exec", func() {
+	var serviceA MysqlService
+	var appList []plugin_models.GetAppsModel
+
+	xmlOutput := `<?xml version="1.0"?>
+
+<resultset statement="SELECT id, name, note FROM users" xmlns:xsi="http://www.w3.org/2001/XMLSchema-instance">
+  <row>
+	<field name="id">1</field>
+	<field name="name">Ann &amp; "Bob"</field>
+	<field name="note" xsi:nil="true" />
+  </row>
+  <row>
+	<field name="id">2</field>
+	<field name="name">tab	and
+newline</field>
+	<field name="note">NULL</field>
+  </row>
+</resultset>
+<?xml version="1.0"?>
+
+<resultset statement="SELECT 1 AS one" xmlns:xsi="http://www.w3.org/2001/XMLSchema-instance">
+  <row>
+	<field name="one">1</field>
+  </row>
+</resultset>
+`
+
+	writeXmlOutput := func(ctx context.Context, tool ClientTool, clientIo ClientIo, address LocalAddress, dbName string, username string, password string, caCert string, args ...string) error {
+		_, err := io.WriteString(clientIo.Stdout, xmlOutput)
+		return err
+	}
+
+	setUpMocks := func(mocks Mocks) {
+		mocks.CfService.GetServiceReturns(serviceA, nil)
+		mocks.CfService.GetStartedAppsReturns(appList, nil)
+		mocks.PortFinder.GetPortReturns(2342, nil)
+		mocks.MysqlRunner.RunToolWithContextStub = writeXmlOutput
+	}
+
+	BeforeEach(func() {
+		serviceA = MysqlService{
+			Name:     "database-a",
+			Hostname: "database-a.host",
+			Port:     "123",
+			DbName:   "dbname-a",
+			Username: "username",
+			Password: "password",
+			CaCert:   "ca-cert",
+		}
+
+		appList = []plugin_models.GetAppsModel{
+			{
+				Name: "app-name-1",
+			},
+		}
+	})
+
+	Context("When calling 'cf mysql-exec' without a statement", func() {
+		It("Prints usage information to STDERR and exits with 1", func() {
+			mysqlPlugin, mocks := NewPluginAndMocks()
+
+			mysqlPlugin.Run(mocks.CliConnection, []string{"mysql-exec", "database-a"})
+
+			Expect(string(mocks.Err.Contents())).To(Equal(mysqlPlugin.FormatUsage()))
+			Expect(mysqlPlugin.GetExitCode()).To(Equal(1))
+			Expect(mocks.CfService.GetServiceCallCount()).To(Equal(0))
+		})
+	})
+
+	Context("When calling 'cf mysql-exec' with an unknown format", func() {
+		It("Prints usage information to STDERR and exits with 1", func() {
+			mysqlPlugin, mocks := NewPluginAndMocks()
+
+			mysqlPlugin.Run(mocks.CliConnection, []string{"mysql-exec", "--format", "yaml", "database-a", "SELECT 1"})
+
+			Expect(string(mocks.Err.Contents())).To(Equal(mysqlPlugin.FormatUsage()))
+			Expect(mysqlPlugin.GetExitCode()).To(Equal(1))
+		})
+	})
+
+	Context("When calling 'cf mysql-exec db-name statement'", func() {
+		It("Runs the statement through the tunnel and prints TSV", func() {
+			mysqlPlugin, mocks := NewPluginAndMocks()
+			setUpMocks(mocks)
+
+			mysqlPlugin.Run(mocks.CliConnection, []string{"mysql-exec", "database-a", "SELECT id, name, note FROM users; SELECT 1 AS one"})
+
+			Expect(mocks.MysqlRunner.RunToolWithContextCallCount()).To(Equal(1))
+			_, tool, clientIo, address, dbName, username, password, caCert, args := mocks.MysqlRunner.RunToolWithContextArgsForCall(0)
+			Expect(tool.Name).To(Equal("mysql"))
+			Expect(clientIo.Stdin).To(BeNil())
+			Expect(address).To(Equal(LocalAddress{Host: "127.0.0.1", Port: 2342}))
+			Expect(dbName).To(Equal("dbname-a"))
+			Expect(username).To(Equal("username"))
+			Expect(password).To(Equal("password"))
+			Expect(caCert).To(Equal("ca-cert"))
+			Expect(args).To(Equal([]string{"--xml", "--execute=SELECT id, name, note FROM users; SELECT 1 AS one"}))
+
+			Expect(string(mocks.Out.Contents())).To(Equal("id\tname\tnote\n" +
+				"1\tAnn & \"Bob\"\t\\N\n" +
+				"2\ttab\\tand\\nnewline\tNULL\n" +
+				"\n" +
+				"one\n" +
+				"1\n"))
+			Expect(mysqlPlugin.GetExitCode()).To(Equal(0))
+		})
+
+		It("Prints CSV with --format csv", func() {
+			mysqlPlugin, mocks := NewPluginAndMocks()
+			setUpMocks(mocks)
+
+			mysqlPlugin.Run(mocks.CliConnection, []string{"mysql-exec", "--format", "csv", "database-a", "SELECT ..."})
+
+			Expect(string(mocks.Out.Contents())).To(Equal("id,name,note\n" +
+				"1,\"Ann & \"\"Bob\"\"\",\\N\n" +
+				"2,\"tab\tand\nnewline\",NULL\n" +
+				"\n" +
+				"one\n" +
+				"1\n"))
+		})
+
+		It("Prints one JSON array per result set with --format json", func() {
+			mysqlPlugin, mocks := NewPluginAndMocks()
+			setUpMocks(mocks)
+
+			mysqlPlugin.Run(mocks.CliConnection, []string{"mysql-exec", "--format=json", "database-a", "SELECT ..."})
+
+			Expect(string(mocks.Out.Contents())).To(Equal(`[{"id":"1","name":"Ann & \"Bob\"","note":null},{"id":"2","name":"tab\tand\nnewline","note":"NULL"}]` + "\n" +
+				`[{"one":"1"}]` + "\n"))
+		})
+	})
+
+	Context("When a statement returns no rows", func() {
+		emptyOutput := `<?xml version="1.0"?>
+
+<resultset statement="SELECT id FROM users WHERE 0" xmlns:xsi="http://www.w3.org/2001/XMLSchema-instance">
+</resultset>
+<?xml version="1.0"?>
+
+<resultset statement="SELECT 1 AS one" xmlns:xsi="http://www.w3.org/2001/XMLSchema-instance">
+  <row>
+	<field name="one"></field>
+  </row>
+</resultset>
+`
+
+		DescribeTable("Prints an empty result set, keeping the empty string apart from NULL",
+			func(format string, expectedOutput string) {
+				mysqlPlugin, mocks := NewPluginAndMocks()
+				setUpMocks(mocks)
+				mocks.MysqlRunner.RunToolWithContextStub = func(ctx context.Context, tool ClientTool, clientIo ClientIo, address LocalAddress, dbName string, username string, password string, caCert string, args ...string) error {
+					_, err := io.WriteString(clientIo.Stdout, emptyOutput)
+					return err
+				}
+
+				mysqlPlugin.Run(mocks.CliConnection, []string{"mysql-exec", "--format", format, "database-a", "SELECT ..."})
+
+				Expect(string(mocks.Out.Contents())).To(Equal(expectedOutput))
+				Expect(mysqlPlugin.GetExitCode()).To(Equal(0))
+			},
+			Entry("in TSV", "tsv", "\n\none\n\n"),
+			Entry("in CSV", "csv", "\n\none\n\n"),
+			Entry("in JSON", "json", "[]\n"+`[{"one":""}]`+"\n"),
+		)
+	})
+
+	Context("When the client is still running", func() {
+		It("Prints the rows it has returned so far", func() {
+			mysqlPlugin, mocks := NewPluginAndMocks()
+			setUpMocks(mocks)
+
+			release := make(chan bool)
+			mocks.MysqlRunner.RunToolWithContextStub = func(ctx context.Context, tool ClientTool, clientIo ClientIo, address LocalAddress, dbName string, username string, password string, caCert string, args ...string) error {
+				io.WriteString(clientIo.Stdout, `<?xml version="1.0"?>
+<resultset statement="SELECT 1 AS one" xmlns:xsi="http://www.w3.org/2001/XMLSchema-instance">
+  <row>
+	<field name="one">1</field>
+  </row>
+`)
+				<-release
+				_, err := io.WriteString(clientIo.Stdout, "</resultset>\n")
+				return err
+			}
+
+			done := make(chan bool)
+			go func() {
+				mysqlPlugin.Run(mocks.CliConnection, []string{"mysql-exec", "database-a", "SELECT 1 AS one"})
+				close(done)
+			}()
+
+			Eventually(mocks.Out).Should(gbytes.Say("^one\n1\n$"))
+			close(release)
+			Eventually(done).Should(BeClosed())
+			Expect(mysqlPlugin.GetExitCode()).To(Equal(0))
+		})
+	})
+
+	Context("When calling 'cf mysql-exec -f file db-name'", func() {
+		var tempDir string
+
+		BeforeEach(func() {
+			var err error
+			tempDir, err = ioutil.TempDir("", "cf-mysql-exec-test")
+			Expect(err).To(BeNil())
+		})
+
+		AfterEach(func() {
+			os.RemoveAll(tempDir)
+		})
+
+		It("Passes the file to mysql on stdin", func() {
+			mysqlPlugin, mocks := NewPluginAndMocks()
+			setUpMocks(mocks)
+
+			path := filepath.Join(tempDir, "statements.sql")
+			Expect(ioutil.WriteFile(path, []byte("SELECT 1 AS one;\n"), 0600)).To(Succeed())
+			mocks.OsWrapper.OpenStub = os.Open
+
+			var received []byte
+			mocks.MysqlRunner.RunToolWithContextStub = func(ctx context.Context, tool ClientTool, clientIo ClientIo, address LocalAddress, dbName string, username string, password string, caCert string, args ...string) error {
+				var err error
+				received, err = ioutil.ReadAll(clientIo.Stdin)
+				return err
+			}
+
+			mysqlPlugin.Run(mocks.CliConnection, []string{"mysql-exec", "-f", path, "database-a"})
+
+			Expect(mocks.OsWrapper.OpenArgsForCall(0)).To(Equal(path))
+			Expect(string(received)).To(Equal("SELECT 1 AS one;\n"))
+
+			_, _, _, _, _, _, _, _, args := mocks.MysqlRunner.RunToolWithContextArgsForCall(0)
+			Expect(args).To(Equal([]string{"--xml"}))
+			Expect(mysqlPlugin.GetExitCode()).To(Equal(0))
+		})
+
+		Context("When the file cannot be opened", func() {
+			It("Shows an error message and exits with 1", func() {
+				mysqlPlugin, mocks := NewPluginAndMocks()
+				mocks.OsWrapper.OpenReturns(nil, errors.New("no such file"))
+
+				mysqlPlugin.Run(mocks.CliConnection, []string{"mysql-exec", "-f", "missing.sql", "database-a"})
+
+				Expect(string(mocks.Err.Contents())).To(Equal("FAILED\nUnable to open SQL file: no such file\n"))
+				Expect(mysqlPlugin.GetExitCode()).To(Equal(1))
+				Expect(mocks.CfService.GetServiceCallCount()).To(Equal(0))
+			})
+		})
+	})
+
+	Context("When the statement fails", func() {
+		It("Prints the results so far and exits with the status of mysql", func() {
+			mysqlPlugin, mocks := NewPluginAndMocks()
+			setUpMocks(mocks)
+
+			mocks.MysqlRunner.RunToolWithContextStub = func(ctx context.Context, tool ClientTool, clientIo ClientIo, address LocalAddress, dbName string, username string, password string, caCert string, args ...string) error {
+				writeXmlOutput(ctx, tool, clientIo, address, dbName, username, password, caCert, args...)
+				io.WriteString(clientIo.Stderr, "ERROR 1146 (42S02) at line 1: Table 'dbname-a.nope' doesn't exist\n")
+				return &ClientExitError{Message: "error running mysql client: exit status 1", ExitCode: 1}
+			}
+
+			mysqlPlugin.Run(mocks.CliConnection, []string{"mysql-exec", "database-a", "SELECT ...; SELECT * FROM nope"})
+
+			Expect(string(mocks.Out.Contents())).To(ContainSubstring("one\n1\n"))
+			Expect(mocks.Err).To(gbytes.Say("ERROR 1146 \\(42S02\\) at line 1: Table 'dbname-a.nope' doesn't exist\n"))
+			Expect(mocks.Err).To(gbytes.Say("FAILED\nerror running mysql client: exit status 1"))
+			Expect(mysqlPlugin.GetExitCode()).To(Equal(1))
+		})
+	})
+
+	Context("When the statement takes longer than --timeout", func() {
+		It("Stops the client and exits with 124", func() {
+			mysqlPlugin, mocks := NewPluginAndMocks()
+			setUpMocks(mocks)
+
+			mocks.MysqlRunner.RunToolWithContextStub = func(ctx context.Context, tool ClientTool, clientIo ClientIo, address LocalAddress, dbName string, username string, password string, caCert string, args ...string) error {
+				<-ctx.Done()
+				return &ClientExitError{Message: "error running mysql client: signal: killed", ExitCode: -1}
+			}
+
+			mysqlPlugin.Run(mocks.CliConnection, []string{"mysql-exec", "--timeout", "10ms", "database-a", "SELECT SLEEP(60)"})
+
+			Expect(mocks.Err).To(gbytes.Say("FAILED\nstatement timed out after 10ms, it may still be running on the server"))
+			Expect(mysqlPlugin.GetExitCode()).To(Equal(ExecTimeoutExitCode))
+		})
+
+		It("Does not set a deadline without --timeout", func() {
+			mysqlPlugin, mocks := NewPluginAndMocks()
+			setUpMocks(mocks)
+
+			mysqlPlugin.Run(mocks.CliConnection, []string{"mysql-exec", "database-a", "SELECT 1"})
+
+			ctx, _, _, _, _, _, _, _, _ := mocks.MysqlRunner.RunToolWithContextArgsForCall(0)
+			_, hasDeadline := ctx.Deadline()
+			Expect(hasDeadline).To(BeFalse())
+		})
+	})
+
+	Context("When calling 'cf mysql-exec --ephemeral'", func() {
+		It("Uses an ephemeral service key", func() {
+			mysqlPlugin, mocks := NewPluginAndMocks()
+			setUpMocks(mocks)
+			serviceA.ServiceKeyGuid = "service-key-guid"
+			mocks.CfService.GetEphemeralServiceReturns(serviceA, nil)
+
+			mysqlPlugin.Run(mocks.CliConnection, []string{"mysql-exec", "--ephemeral", "database-a", "SELECT 1"})
+
+			Expect(mocks.CfService.GetServiceCallCount()).To(Equal(0))
+			Expect(mocks.CfService.DeleteServiceKeyCallCount()).To(Equal(1))
+		})
+	})
+})
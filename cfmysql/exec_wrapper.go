@@ -6,6 +6,7 @@ import "os/exec"
 type ExecWrapper interface {
 	LookPath(file string) (string, error)
 	Run(*exec.Cmd) error
+	Start(*exec.Cmd) error
 }
 
 func NewExecWrapper() ExecWrapper {
@@ -21,3 +22,7 @@ func (self *execWrapper) LookPath(file string) (string, error) {
 func (self *execWrapper) Run(cmd *exec.Cmd) error {
 	return cmd.Run()
 }
+
+func (self *execWrapper) Start(cmd *exec.Cmd) error {
+	return cmd.Start()
+}
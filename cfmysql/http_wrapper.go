@@ -12,6 +12,9 @@ import (
 type HttpWrapper interface {
 	Get(endpoint string, accessToken string, skipSsl bool) ([]byte, error)
 	Post(url string, body io.Reader, accessToken string, sslDisabled bool) ([]byte, error)
+	PostWithLocation(url string, body io.Reader, accessToken string, sslDisabled bool) ([]byte, string, error)
+	Delete(url string, accessToken string, sslDisabled bool) error
+	GetRedirect(url string, accessToken string, sslDisabled bool) (string, error)
 }
 
 func NewHttpWrapper(factory HttpClientFactory, requestDumper net.RequestDumperInterface) HttpWrapper {
@@ -36,17 +39,78 @@ func (self *httpWrapper) Get(url string, accessToken string, sslDisabled bool) (
 }
 
 func (self *httpWrapper) Post(url string, body io.Reader, accessToken string, sslDisabled bool) ([]byte, error) {
+	responseBody, _, err := self.PostWithLocation(url, body, accessToken, sslDisabled)
+
+	return responseBody, err
+}
+
+// PostWithLocation sends a POST request like Post, and also returns the Location header of the response. For
+// asynchronous operations, it points to the job that can be polled.
+func (self *httpWrapper) PostWithLocation(url string, body io.Reader, accessToken string, sslDisabled bool) ([]byte, string, error) {
 	request, err := http.NewRequest("POST", url, body)
 	if err != nil {
-		return nil, fmt.Errorf("error creating request: %s", err)
+		return nil, "", fmt.Errorf("error creating request: %s", err)
 	}
 
 	request.Header.Add("Content-Type", "application/json")
 
-	return self.do(request, accessToken, sslDisabled)
+	responseBody, header, err := self.doWithHeader(request, accessToken, sslDisabled)
+	if err != nil {
+		return nil, "", err
+	}
+
+	return responseBody, header.Get("Location"), nil
+}
+
+func (self *httpWrapper) Delete(url string, accessToken string, sslDisabled bool) error {
+	request, err := http.NewRequest("DELETE", url, nil)
+	if err != nil {
+		return fmt.Errorf("error creating request: %s", err)
+	}
+
+	_, err = self.do(request, accessToken, sslDisabled)
+	return err
+}
+
+// GetRedirect sends a GET request without following redirects, and returns the location the server redirects to
+func (self *httpWrapper) GetRedirect(url string, accessToken string, sslDisabled bool) (string, error) {
+	request, err := http.NewRequest("GET", url, nil)
+	if err != nil {
+		return "", fmt.Errorf("error creating request: %s", err)
+	}
+
+	request.Header.Add("Authorization", accessToken)
+
+	client := *self.httpClientFactory.NewClient(sslDisabled)
+	client.CheckRedirect = func(*http.Request, []*http.Request) error {
+		return http.ErrUseLastResponse
+	}
+
+	self.requestDumper.DumpRequest(request)
+
+	response, err := client.Do(request)
+	if err != nil {
+		return "", err
+	}
+	defer response.Body.Close()
+
+	self.requestDumper.DumpResponse(response)
+
+	location := response.Header.Get("Location")
+	if response.StatusCode/100 != 3 || location == "" {
+		return "", fmt.Errorf("HTTP status %d accessing %s, expected a redirect", response.StatusCode, request.URL.String())
+	}
+
+	return location, nil
 }
 
 func (self *httpWrapper) do(request *http.Request, accessToken string, sslDisabled bool) ([]byte, error) {
+	body, _, err := self.doWithHeader(request, accessToken, sslDisabled)
+
+	return body, err
+}
+
+func (self *httpWrapper) doWithHeader(request *http.Request, accessToken string, sslDisabled bool) ([]byte, http.Header, error) {
 	request.Header.Add("Authorization", accessToken)
 
 	client := self.httpClientFactory.NewClient(sslDisabled)
@@ -55,21 +119,22 @@ func (self *httpWrapper) do(request *http.Request, accessToken string, sslDisabl
 
 	response, err := client.Do(request)
 	if err != nil {
-		return nil, err
+		return nil, nil, err
 	}
+	defer response.Body.Close()
 
 	self.requestDumper.DumpResponse(response)
 
 	if !isSuccessCode(response.StatusCode) {
-		return nil, fmt.Errorf("HTTP status %d accessing %s", response.StatusCode, request.URL.String())
+		return nil, nil, fmt.Errorf("HTTP status %d accessing %s", response.StatusCode, request.URL.String())
 	}
 
 	body, err := ioutil.ReadAll(response.Body)
 	if err != nil {
-		return nil, err
+		return nil, nil, err
 	}
 
-	return body, nil
+	return body, response.Header, nil
 }
 
 func isSuccessCode(statusCode int) bool {
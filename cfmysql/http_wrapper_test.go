@@ -141,6 +141,27 @@ var _ = Describe("HttpWrapper", func() {
 			mockServer.Close()
 		})
 
+		It("Returns the Location header with PostWithLocation", func() {
+			locationHeader := make(http.Header)
+			locationHeader.Add("Location", "https://cf.api.url/v3/jobs/job-guid")
+
+			mockServer := ghttp.NewServer()
+			mockServer.AppendHandlers(ghttp.CombineHandlers(
+				ghttp.VerifyRequest("POST", "/path"),
+				ghttp.VerifyHeaderKV("Content-Type", "application/json"),
+				ghttp.RespondWith(http.StatusAccepted, "", locationHeader),
+			))
+			defer mockServer.Close()
+
+			httpWrapper := MakeHttp()
+
+			response, location, err := httpWrapper.PostWithLocation(mockServer.URL()+"/path", bytes.NewBufferString("{}"), "access-token", true)
+
+			Expect(err).To(BeNil())
+			Expect(response).To(BeEmpty())
+			Expect(location).To(Equal("https://cf.api.url/v3/jobs/job-guid"))
+		})
+
 		Context("When SSL is disabled", func() {
 			It("Configures an HTTP client without cert validation", func() {
 				mockFactory := new(cfmysqlfakes.FakeHttpClientFactory)
@@ -169,6 +190,76 @@ var _ = Describe("HttpWrapper", func() {
 			})
 		})
 	})
+
+	Describe("Delete", func() {
+		It("Sends a DELETE request", func() {
+			mockServer := ghttp.NewServer()
+			mockServer.AppendHandlers(ghttp.CombineHandlers(
+				ghttp.RespondWith(http.StatusNoContent, ""),
+				ghttp.VerifyRequest("DELETE", "/v2/service_keys/key-guid"),
+				ghttp.VerifyHeaderKV("Authorization", "access-token"),
+			))
+
+			err := MakeHttp().Delete(mockServer.URL()+"/v2/service_keys/key-guid", "access-token", true)
+
+			Expect(err).To(BeNil())
+			Expect(mockServer.ReceivedRequests()).To(HaveLen(1))
+
+			mockServer.Close()
+		})
+
+		Context("When the response returns a 4xx response", func() {
+			It("Returns an error", func() {
+				mockServer := ghttp.NewServer()
+				mockServer.AppendHandlers(ghttp.CombineHandlers(
+					ghttp.RespondWith(http.StatusNotFound, "not found"),
+					ghttp.VerifyRequest("DELETE", "/v2/service_keys/key-guid"),
+				))
+
+				err := MakeHttp().Delete(mockServer.URL()+"/v2/service_keys/key-guid", "access-token", true)
+
+				Expect(err).To(Equal(fmt.Errorf("HTTP status 404 accessing %s/v2/service_keys/key-guid", mockServer.URL())))
+
+				mockServer.Close()
+			})
+		})
+	})
+
+	Describe("GetRedirect", func() {
+		It("Returns the redirect location without following it", func() {
+			mockServer := ghttp.NewServer()
+			mockServer.AppendHandlers(ghttp.CombineHandlers(
+				ghttp.VerifyRequest("GET", "/oauth/authorize", "response_type=code&client_id=ssh-proxy"),
+				ghttp.VerifyHeaderKV("Authorization", "access-token"),
+				ghttp.RespondWith(http.StatusFound, "", http.Header{"Location": {"https://uaa.example.com/login?code=abc123"}}),
+			))
+
+			location, err := MakeHttp().GetRedirect(mockServer.URL()+"/oauth/authorize?response_type=code&client_id=ssh-proxy", "access-token", true)
+
+			Expect(err).To(BeNil())
+			Expect(location).To(Equal("https://uaa.example.com/login?code=abc123"))
+			Expect(mockServer.ReceivedRequests()).To(HaveLen(1))
+
+			mockServer.Close()
+		})
+
+		Context("When the server does not redirect", func() {
+			It("Returns an error", func() {
+				mockServer := ghttp.NewServer()
+				mockServer.AppendHandlers(ghttp.CombineHandlers(
+					ghttp.RespondWith(http.StatusUnauthorized, "go away"),
+					ghttp.VerifyRequest("GET", "/oauth/authorize"),
+				))
+
+				location, err := MakeHttp().GetRedirect(mockServer.URL()+"/oauth/authorize", "access-token", true)
+
+				Expect(err).To(Equal(fmt.Errorf("HTTP status 401 accessing %s/oauth/authorize, expected a redirect", mockServer.URL())))
+				Expect(location).To(Equal(""))
+
+				mockServer.Close()
+			})
+		})
+	})
 })
 
 func MakeHttp() cfmysql.HttpWrapper {
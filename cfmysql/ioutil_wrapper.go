@@ -8,6 +8,8 @@ import (
 //go:generate counterfeiter . IoUtilWrapper
 type IoUtilWrapper interface {
 	TempFile(dir, pattern string) (f *os.File, err error)
+	TempDir(dir, pattern string) (name string, err error)
+	WriteFile(filename string, data []byte, perm os.FileMode) error
 }
 
 func NewIoUtilWrapper() IoUtilWrapper {
@@ -19,3 +21,11 @@ type ioUtilWrapper struct{}
 func (self *ioUtilWrapper) TempFile(dir, pattern string) (f *os.File, err error) {
 	return ioutil.TempFile(dir, pattern)
 }
+
+func (self *ioUtilWrapper) TempDir(dir, pattern string) (name string, err error) {
+	return ioutil.TempDir(dir, pattern)
+}
+
+func (self *ioUtilWrapper) WriteFile(filename string, data []byte, perm os.FileMode) error {
+	return ioutil.WriteFile(filename, data, perm)
+}
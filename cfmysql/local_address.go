@@ -0,0 +1,58 @@
+package cfmysql
+
+import (
+	"flag"
+	"net"
+	"strconv"
+	"strings"
+)
+
+const DefaultLocalHost = "127.0.0.1"
+
+// LocalAddress is where the local end of an SSH tunnel listens. Port 0 stands for any free port. If Socket is set, the
+// tunnel listens on a Unix socket at that path instead, and Host and Port are not used.
+type LocalAddress struct {
+	Host   string
+	Port   int
+	Socket string
+}
+
+func (self LocalAddress) String() string {
+	if self.Socket != "" {
+		return self.Socket
+	}
+
+	return net.JoinHostPort(self.Host, strconv.Itoa(self.Port))
+}
+
+// Network returns the network to listen on or dial, as understood by package net
+func (self LocalAddress) Network() string {
+	if self.Socket != "" {
+		return "unix"
+	}
+
+	return "tcp"
+}
+
+// addLocalAddressFlags adds --local-host, --local-port and --local-socket to the flags of a command that opens a
+// tunnel
+func addLocalAddressFlags(flags *flag.FlagSet) *LocalAddress {
+	localAddress := new(LocalAddress)
+	flags.StringVar(&localAddress.Host, "local-host", DefaultLocalHost, "")
+	flags.IntVar(&localAddress.Port, "local-port", 0, "")
+	flags.StringVar(&localAddress.Socket, "local-socket", "", "")
+
+	return localAddress
+}
+
+// normalize strips the brackets from IPv6 addresses such as [::1], and reports whether the address is usable. A
+// socket cannot be combined with a port.
+func (self *LocalAddress) normalize() bool {
+	self.Host = strings.TrimSuffix(strings.TrimPrefix(self.Host, "["), "]")
+
+	if self.Socket != "" {
+		return self.Port == 0
+	}
+
+	return self.Host != "" && self.Port >= 0 && self.Port <= 65535
+}
@@ -1,12 +1,23 @@
 package models
 
 type ServiceInstance struct {
-	Name      string
-	Guid      string
-	SpaceGuid string
+	Name          string
+	Guid          string
+	SpaceGuid     string
+	PlanGuid      string
+	Plan          string
+	Label         string
+	Tags          []string
+	LastOperation string
+	UserProvided  bool
+	BoundAppGuids []string
+	// SharedFromSpace is the name of the space that shared the instance, it is empty for instances of the space
+	SharedFromSpace string
 }
 
 type ServiceKey struct {
+	Guid                string
+	Name                string
 	ServiceInstanceGuid string
 	Uri                 string
 	DbName              string
@@ -15,4 +26,24 @@ type ServiceKey struct {
 	Username            string
 	Password            string
 	CaCert              string
+	// CredhubRef names the credentials in CredHub, for brokers that store them there instead of in the key
+	CredhubRef string
+	// ClientOptions are options for the client programs found in connection URLs, such as ssl-mode
+	ClientOptions map[string]string
+	// Credentials are the credentials as published by the broker, to be read in the format of the service
+	Credentials map[string]interface{}
+}
+
+type Space struct {
+	Guid    string
+	Name    string
+	OrgGuid string
+	OrgName string
+}
+
+type SshInfo struct {
+	Endpoint           string
+	HostKeyFingerprint string
+	OauthClient        string
+	TokenEndpoint      string
 }
@@ -1,9 +1,10 @@
 package cfmysql
 
 import (
-	"code.cloudfoundry.org/cli/cf/errors"
+	"bytes"
+	"context"
 	"fmt"
-	"os"
+	"io"
 	"os/exec"
 	"strconv"
 	"strings"
@@ -11,8 +12,25 @@ import (
 
 //go:generate counterfeiter . MysqlRunner
 type MysqlRunner interface {
-	RunMysql(hostname string, port int, dbName string, username string, password string, caCert string, args ...string) error
-	RunMysqlDump(hostname string, port int, dbName string, username string, password string, caCert string, args ...string) error
+	RunTool(tool ClientTool, clientIo ClientIo, address LocalAddress, dbName string, username string, password string, caCert string, args ...string) error
+	RunToolWithContext(ctx context.Context, tool ClientTool, clientIo ClientIo, address LocalAddress, dbName string, username string, password string, caCert string, args ...string) error
+}
+
+// ClientIo holds the standard streams a client process is connected to
+type ClientIo struct {
+	Stdin  io.Reader
+	Stdout io.Writer
+	Stderr io.Writer
+}
+
+// ClientExitError is returned when a client process exits with a non-zero status
+type ClientExitError struct {
+	Message  string
+	ExitCode int
+}
+
+func (self *ClientExitError) Error() string {
+	return self.Message
 }
 
 func NewMysqlRunner(execWrapper ExecWrapper, ioUtilWrapper IoUtilWrapper, osWrapper OsWrapper) MysqlRunner {
@@ -29,10 +47,16 @@ type mysqlRunner struct {
 	osWrapper     OsWrapper
 }
 
-func (self *mysqlRunner) RunMysql(hostname string, port int, dbName string, username string, password string, caCert string, mysqlArgs ...string) error {
-	path, err := self.execWrapper.LookPath("mysql")
+func (self *mysqlRunner) RunTool(tool ClientTool, clientIo ClientIo, address LocalAddress, dbName string, username string, password string, caCert string, toolArgs ...string) error {
+	return self.RunToolWithContext(context.Background(), tool, clientIo, address, dbName, username, password, caCert, toolArgs...)
+}
+
+// RunToolWithContext runs any client program, placing the arguments as the tool requires. The client is connected to
+// the socket of the address if it has one, and killed when the context is done.
+func (self *mysqlRunner) RunToolWithContext(ctx context.Context, tool ClientTool, clientIo ClientIo, address LocalAddress, dbName string, username string, password string, caCert string, toolArgs ...string) error {
+	path, err := self.execWrapper.LookPath(tool.Name)
 	if err != nil {
-		return errors.New("'mysql' client not found in PATH")
+		return fmt.Errorf("'%s'%s not found in PATH", tool.Name, strings.TrimPrefix(tool.Label, tool.Name))
 	}
 
 	caCertArgs, caCertPath, err := self.storeCaCert(path, caCert)
@@ -41,65 +65,84 @@ func (self *mysqlRunner) RunMysql(hostname string, port int, dbName string, user
 		return fmt.Errorf("error preparing TLS arguments: %s", err)
 	}
 
-	args := []string{"-u", username, "-p" + password, "-h", hostname, "-P", strconv.Itoa(port)}
-	args = append(args, caCertArgs...)
-	args = append(args, mysqlArgs...)
-	args = append(args, dbName)
+	connectionArgs := []string{"-u", username, "-p" + password, "-h", address.Host, "-P", strconv.Itoa(address.Port)}
+	if address.Socket != "" {
+		connectionArgs = []string{"-u", username, "-p" + password, "--socket", address.Socket}
+	}
+	connectionArgs = append(connectionArgs, caCertArgs...)
+	toolArgs = self.adaptSslMode(path, toolArgs)
 
-	cmd := exec.Command(path, args...)
-	cmd.Stdin = os.Stdin
-	cmd.Stdout = os.Stdout
-	cmd.Stderr = os.Stderr
+	cmd := exec.CommandContext(ctx, path, tool.BuildArgs(connectionArgs, dbName, toolArgs)...)
+	cmd.Stdin = clientIo.Stdin
+	cmd.Stdout = clientIo.Stdout
+	cmd.Stderr = clientIo.Stderr
 
 	err = self.execWrapper.Run(cmd)
 	if err != nil {
-		return fmt.Errorf("error running mysql client: %s", err)
+		return clientError("error running "+tool.Label, err)
 	}
 
 	return nil
 }
 
-func (self *mysqlRunner) RunMysqlDump(hostname string, port int, dbName string, username string, password string, caCert string, mysqlDumpArgs ...string) error {
-	path, err := self.execWrapper.LookPath("mysqldump")
-	if err != nil {
-		return errors.New("'mysqldump' not found in PATH")
-	}
-
-	var tableArgs []string
-	nonTableArgs := mysqlDumpArgs[0:]
+// mariaDbSslArgs maps the ssl-mode option of the MySQL clients to the TLS options of the MariaDB clients, which reject
+// --ssl-mode. MariaDB verifies the hostname whenever it verifies the server certificate, so VERIFY_CA is as strict
+// as VERIFY_IDENTITY.
+var mariaDbSslArgs = map[string][]string{
+	"DISABLED":        {"--skip-ssl"},
+	"PREFERRED":       {"--ssl"},
+	"REQUIRED":        {"--ssl"},
+	"VERIFY_CA":       {"--ssl", "--ssl-verify-server-cert"},
+	"VERIFY_IDENTITY": {"--ssl", "--ssl-verify-server-cert"},
+}
 
-	for i, argument := range mysqlDumpArgs {
-		if strings.HasPrefix(argument, "-") {
+// adaptSslMode replaces --ssl-mode options with their MariaDB counterparts if the client is a MariaDB client. Unknown
+// modes are left out.
+func (self *mysqlRunner) adaptSslMode(path string, toolArgs []string) []string {
+	hasSslMode := false
+	for _, argument := range toolArgs {
+		if strings.HasPrefix(argument, "--ssl-mode=") {
+			hasSslMode = true
 			break
 		}
+	}
 
-		tableArgs = append(tableArgs, argument)
-		nonTableArgs = mysqlDumpArgs[i+1:]
+	if !hasSslMode || !self.isMariaDbClient(path) {
+		return toolArgs
 	}
 
-	caCertArgs, caCertPath, err := self.storeCaCert(path, caCert)
-	defer self.osWrapper.Remove(caCertPath)
-	if err != nil {
-		return fmt.Errorf("error preparing TLS arguments: %s", err)
+	var args []string
+	for _, argument := range toolArgs {
+		if strings.HasPrefix(argument, "--ssl-mode=") {
+			args = append(args, mariaDbSslArgs[strings.ToUpper(strings.TrimPrefix(argument, "--ssl-mode="))]...)
+			continue
+		}
+
+		args = append(args, argument)
 	}
 
-	args := []string{"-u", username, "-p" + password, "-h", hostname, "-P", strconv.Itoa(port)}
-	args = append(args, caCertArgs...)
-	args = append(args, nonTableArgs...)
-	args = append(args, dbName)
-	args = append(args, tableArgs...)
+	return args
+}
 
-	cmd := exec.Command(path, args...)
-	cmd.Stdin = os.Stdin
-	cmd.Stdout = os.Stdout
-	cmd.Stderr = os.Stderr
+// isMariaDbClient tells from the version output whether the client program comes with MariaDB
+func (self *mysqlRunner) isMariaDbClient(path string) bool {
+	var version bytes.Buffer
+	cmd := exec.Command(path, "--version")
+	cmd.Stdout = &version
 
-	err = self.execWrapper.Run(cmd)
-	if err != nil {
-		return fmt.Errorf("error running mysqldump: %s", err)
+	err := self.execWrapper.Run(cmd)
+	return err == nil && strings.Contains(version.String(), "MariaDB")
+}
+
+func clientError(message string, err error) error {
+	if exitErr, ok := err.(*exec.ExitError); ok {
+		return &ClientExitError{
+			Message:  fmt.Sprintf("%s: %s", message, err),
+			ExitCode: exitErr.ExitCode(),
+		}
 	}
 
-	return nil
+	return fmt.Errorf("%s: %s", message, err)
 }
 
 func (self *mysqlRunner) storeCaCert(mysqlPath string, caCert string) ([]string, string, error) {
@@ -107,17 +150,26 @@ func (self *mysqlRunner) storeCaCert(mysqlPath string, caCert string) ([]string,
 		return []string{}, "", nil
 	}
 
-	caCertFile, err := self.ioUtilWrapper.TempFile("", "mysql-ca-cert.pem")
+	caCertPath, err := writeCaCert(self.ioUtilWrapper, self.osWrapper, caCert)
 	if err != nil {
-		return []string{}, "", fmt.Errorf("error creating temp file: %s", err)
+		return []string{}, caCertPath, err
 	}
 
-	caCertPath := self.osWrapper.Name(caCertFile)
+	return []string{"--ssl-ca=" + caCertPath}, caCertPath, nil
+}
 
-	_, err = self.osWrapper.WriteString(caCertFile, caCert)
+func writeCaCert(ioUtilWrapper IoUtilWrapper, osWrapper OsWrapper, caCert string) (string, error) {
+	caCertFile, err := ioUtilWrapper.TempFile("", "mysql-ca-cert.pem")
 	if err != nil {
-		return []string{}, "", fmt.Errorf("error writing CA certificate to temp file: %s", err)
+		return "", fmt.Errorf("error creating temp file: %s", err)
 	}
 
-	return []string{"--ssl-ca=" + caCertPath}, caCertPath, nil
+	caCertPath := osWrapper.Name(caCertFile)
+
+	_, err = osWrapper.WriteString(caCertFile, caCert)
+	if err != nil {
+		return caCertPath, fmt.Errorf("error writing CA certificate to temp file: %s", err)
+	}
+
+	return caCertPath, nil
 }
@@ -1,16 +1,21 @@
 package cfmysql_test
 
 import (
+	"bytes"
+	"context"
 	"errors"
 	. "github.com/andreasf/cf-mysql-plugin/cfmysql"
 	"github.com/andreasf/cf-mysql-plugin/cfmysql/cfmysqlfakes"
 	. "github.com/onsi/ginkgo/v2"
 	. "github.com/onsi/gomega"
 	"os"
+	osexec "os/exec"
 )
 
 var _ = Describe("MysqlRunner", func() {
-	Context("RunMysql", func() {
+	stdIo := ClientIo{Stdin: os.Stdin, Stdout: os.Stdout, Stderr: os.Stderr}
+
+	Context("RunTool with mysql", func() {
 		var exec *cfmysqlfakes.FakeExecWrapper
 		var ioutilWrapper *cfmysqlfakes.FakeIoUtilWrapper
 		var osWrapper *cfmysqlfakes.FakeOsWrapper
@@ -27,7 +32,7 @@ var _ = Describe("MysqlRunner", func() {
 			It("Returns an error", func() {
 				exec.LookPathReturns("", errors.New("PC LOAD LETTER"))
 
-				err := runner.RunMysql("hostname", 42, "dbname", "username", "password", "")
+				err := runner.RunTool(MysqlTool, stdIo, LocalAddress{Host: "hostname", Port: 42}, "dbname", "username", "password", "")
 
 				Expect(err).To(Equal(errors.New("'mysql' client not found in PATH")))
 				Expect(exec.LookPathArgsForCall(0)).To(Equal("mysql"))
@@ -39,17 +44,32 @@ var _ = Describe("MysqlRunner", func() {
 				exec.LookPathReturns("/path/to/mysql", nil)
 				exec.RunReturns(errors.New("PC LOAD LETTER"))
 
-				err := runner.RunMysql("hostname", 42, "dbname", "username", "password", "")
+				err := runner.RunTool(MysqlTool, stdIo, LocalAddress{Host: "hostname", Port: 42}, "dbname", "username", "password", "")
 
 				Expect(err).To(Equal(errors.New("error running mysql client: PC LOAD LETTER")))
 			})
 		})
 
+		Context("When the mysql client exits with a non-zero status", func() {
+			It("Returns the exit status", func() {
+				exitErr := osexec.Command("sh", "-c", "exit 3").Run()
+				exec.LookPathReturns("/path/to/mysql", nil)
+				exec.RunReturns(exitErr)
+
+				err := runner.RunTool(MysqlTool, stdIo, LocalAddress{Host: "hostname", Port: 42}, "dbname", "username", "password", "")
+
+				Expect(err).To(Equal(&ClientExitError{
+					Message:  "error running mysql client: exit status 3",
+					ExitCode: 3,
+				}))
+			})
+		})
+
 		Context("When mysql is in PATH", func() {
 			It("Calls mysql with the right arguments", func() {
 				exec.LookPathReturns("/path/to/mysql", nil)
 
-				err := runner.RunMysql("hostname", 42, "dbname", "username", "password", "")
+				err := runner.RunTool(MysqlTool, stdIo, LocalAddress{Host: "hostname", Port: 42}, "dbname", "username", "password", "")
 
 				Expect(err).To(BeNil())
 				Expect(exec.LookPathCallCount()).To(Equal(1))
@@ -64,11 +84,23 @@ var _ = Describe("MysqlRunner", func() {
 			})
 		})
 
+		Context("When connecting to a Unix socket", func() {
+			It("Passes the socket instead of host and port", func() {
+				exec.LookPathReturns("/path/to/mysql", nil)
+
+				err := runner.RunTool(MysqlTool, stdIo, LocalAddress{Host: "127.0.0.1", Socket: "/tmp/mysql.sock"}, "dbname", "username", "password", "")
+
+				Expect(err).To(BeNil())
+				cmd := exec.RunArgsForCall(0)
+				Expect(cmd.Args).To(Equal([]string{"/path/to/mysql", "-u", "username", "-ppassword", "--socket", "/tmp/mysql.sock", "dbname"}))
+			})
+		})
+
 		Context("When mysql is in PATH and additional arguments are passed", func() {
 			It("Calls mysql with the right arguments", func() {
 				exec.LookPathReturns("/path/to/mysql", nil)
 
-				err := runner.RunMysql("hostname", 42, "dbname", "username", "password", "", "--foo", "bar", "--baz")
+				err := runner.RunTool(MysqlTool, stdIo, LocalAddress{Host: "hostname", Port: 42}, "dbname", "username", "password", "", "--foo", "bar", "--baz")
 
 				Expect(err).To(BeNil())
 				Expect(exec.LookPathCallCount()).To(Equal(1))
@@ -83,6 +115,49 @@ var _ = Describe("MysqlRunner", func() {
 			})
 		})
 
+		Context("When an ssl-mode is passed", func() {
+			versionOutput := func(version string) func(cmd *osexec.Cmd) error {
+				return func(cmd *osexec.Cmd) error {
+					if len(cmd.Args) == 2 && cmd.Args[1] == "--version" {
+						cmd.Stdout.Write([]byte(version))
+					}
+					return nil
+				}
+			}
+
+			It("Keeps it for the MySQL client", func() {
+				exec.LookPathReturns("/path/to/mysql", nil)
+				exec.RunStub = versionOutput("mysql  Ver 8.0.35 for Linux on x86_64 (MySQL Community Server - GPL)\n")
+
+				err := runner.RunTool(MysqlTool, stdIo, LocalAddress{Host: "hostname", Port: 42}, "dbname", "username", "password", "", "--ssl-mode=VERIFY_CA")
+
+				Expect(err).To(BeNil())
+				Expect(exec.RunCallCount()).To(Equal(2))
+				Expect(exec.RunArgsForCall(0).Args).To(Equal([]string{"/path/to/mysql", "--version"}))
+				Expect(exec.RunArgsForCall(1).Args).To(Equal([]string{"/path/to/mysql", "-u", "username", "-ppassword", "-h", "hostname", "-P", "42", "--ssl-mode=VERIFY_CA", "dbname"}))
+			})
+
+			It("Passes the corresponding TLS options to the MariaDB client, which rejects --ssl-mode", func() {
+				exec.LookPathReturns("/path/to/mysql", nil)
+				exec.RunStub = versionOutput("mysql  Ver 15.1 Distrib 10.11.6-MariaDB, for debian-linux-gnu (x86_64) using  EditLine wrapper\n")
+
+				err := runner.RunTool(MysqlTool, stdIo, LocalAddress{Host: "hostname", Port: 42}, "dbname", "username", "password", "", "--ssl-mode=verify_ca", "--foo")
+
+				Expect(err).To(BeNil())
+				Expect(exec.RunArgsForCall(1).Args).To(Equal([]string{"/path/to/mysql", "-u", "username", "-ppassword", "-h", "hostname", "-P", "42", "--ssl", "--ssl-verify-server-cert", "--foo", "dbname"}))
+			})
+
+			It("Disables TLS for the MariaDB client with --skip-ssl", func() {
+				exec.LookPathReturns("/path/to/mysql", nil)
+				exec.RunStub = versionOutput("mysql from 11.4.2-MariaDB, client 15.2 for Linux (x86_64)\n")
+
+				err := runner.RunTool(MysqlTool, stdIo, LocalAddress{Host: "hostname", Port: 42}, "dbname", "username", "password", "", "--ssl-mode=DISABLED")
+
+				Expect(err).To(BeNil())
+				Expect(exec.RunArgsForCall(1).Args).To(Equal([]string{"/path/to/mysql", "-u", "username", "-ppassword", "-h", "hostname", "-P", "42", "--skip-ssl", "dbname"}))
+			})
+		})
+
 		Context("When mysql is in PATH and a TLS CA certificate is part of the service credentials", func() {
 			It("Stores the cert in a temp file and calls mysql with --ssl-ca=path", func() {
 				exec.LookPathReturns("/path/to/mysql", nil)
@@ -90,7 +165,7 @@ var _ = Describe("MysqlRunner", func() {
 				ioutilWrapper.TempFileReturns(tempFile, nil)
 				osWrapper.NameReturns("/path/to/cert.pem")
 
-				err := runner.RunMysql("hostname", 42, "dbname", "username", "password", "cert-content", "--foo", "bar", "--baz")
+				err := runner.RunTool(MysqlTool, stdIo, LocalAddress{Host: "hostname", Port: 42}, "dbname", "username", "password", "cert-content", "--foo", "bar", "--baz")
 
 				Expect(err).To(BeNil())
 				Expect(exec.LookPathCallCount()).To(Equal(1))
@@ -121,7 +196,41 @@ var _ = Describe("MysqlRunner", func() {
 		})
 	})
 
-	Context("RunMysqlDump", func() {
+	Context("RunTool with other streams", func() {
+		var exec *cfmysqlfakes.FakeExecWrapper
+		var ioutilWrapper *cfmysqlfakes.FakeIoUtilWrapper
+		var osWrapper *cfmysqlfakes.FakeOsWrapper
+		var runner MysqlRunner
+
+		BeforeEach(func() {
+			exec = new(cfmysqlfakes.FakeExecWrapper)
+			ioutilWrapper = new(cfmysqlfakes.FakeIoUtilWrapper)
+			osWrapper = new(cfmysqlfakes.FakeOsWrapper)
+			runner = NewMysqlRunner(exec, ioutilWrapper, osWrapper)
+		})
+
+		It("Connects mysql to the given streams", func() {
+			exec.LookPathReturns("/path/to/mysql", nil)
+			clientIo := ClientIo{
+				Stdin:  bytes.NewBufferString("select 1;"),
+				Stdout: new(bytes.Buffer),
+				Stderr: new(bytes.Buffer),
+			}
+
+			err := runner.RunTool(MysqlTool, clientIo, LocalAddress{Host: "hostname", Port: 42}, "dbname", "username", "password", "", "--foo")
+
+			Expect(err).To(BeNil())
+			Expect(exec.RunCallCount()).To(Equal(1))
+
+			cmd := exec.RunArgsForCall(0)
+			Expect(cmd.Args).To(Equal([]string{"/path/to/mysql", "-u", "username", "-ppassword", "-h", "hostname", "-P", "42", "--foo", "dbname"}))
+			Expect(cmd.Stdin).To(BeIdenticalTo(clientIo.Stdin))
+			Expect(cmd.Stdout).To(BeIdenticalTo(clientIo.Stdout))
+			Expect(cmd.Stderr).To(BeIdenticalTo(clientIo.Stderr))
+		})
+	})
+
+	Context("RunTool with mysqldump", func() {
 		var exec *cfmysqlfakes.FakeExecWrapper
 		var ioutil *cfmysqlfakes.FakeIoUtilWrapper
 		var osWrapper *cfmysqlfakes.FakeOsWrapper
@@ -138,7 +247,7 @@ var _ = Describe("MysqlRunner", func() {
 			It("Returns an error", func() {
 				exec.LookPathReturns("", errors.New("PC LOAD LETTER"))
 
-				err := runner.RunMysqlDump("hostname", 42, "dbname", "username", "password", "")
+				err := runner.RunTool(MysqlDumpTool, stdIo, LocalAddress{Host: "hostname", Port: 42}, "dbname", "username", "password", "")
 
 				Expect(err).To(Equal(errors.New("'mysqldump' not found in PATH")))
 				Expect(exec.LookPathArgsForCall(0)).To(Equal("mysqldump"))
@@ -150,7 +259,7 @@ var _ = Describe("MysqlRunner", func() {
 				exec.LookPathReturns("/path/to/mysqldump", nil)
 				exec.RunReturns(errors.New("PC LOAD LETTER"))
 
-				err := runner.RunMysqlDump("hostname", 42, "dbname", "username", "password", "")
+				err := runner.RunTool(MysqlDumpTool, stdIo, LocalAddress{Host: "hostname", Port: 42}, "dbname", "username", "password", "")
 
 				Expect(err).To(Equal(errors.New("error running mysqldump: PC LOAD LETTER")))
 			})
@@ -160,7 +269,7 @@ var _ = Describe("MysqlRunner", func() {
 			It("Calls mysqldump with the right arguments", func() {
 				exec.LookPathReturns("/path/to/mysqldump", nil)
 
-				err := runner.RunMysqlDump("hostname", 42, "dbname", "username", "password", "")
+				err := runner.RunTool(MysqlDumpTool, stdIo, LocalAddress{Host: "hostname", Port: 42}, "dbname", "username", "password", "")
 
 				Expect(err).To(BeNil())
 				Expect(exec.LookPathCallCount()).To(Equal(1))
@@ -179,7 +288,7 @@ var _ = Describe("MysqlRunner", func() {
 			It("Calls mysqldump with the right arguments", func() {
 				exec.LookPathReturns("/path/to/mysqldump", nil)
 
-				err := runner.RunMysqlDump("hostname", 42, "dbname", "username", "password", "", "table1", "table2", "--foo", "bar", "--baz")
+				err := runner.RunTool(MysqlDumpTool, stdIo, LocalAddress{Host: "hostname", Port: 42}, "dbname", "username", "password", "", "table1", "table2", "--foo", "bar", "--baz")
 
 				Expect(err).To(BeNil())
 				Expect(exec.LookPathCallCount()).To(Equal(1))
@@ -201,7 +310,7 @@ var _ = Describe("MysqlRunner", func() {
 				ioutil.TempFileReturns(tempFile, nil)
 				osWrapper.NameReturns("/path/to/cert.pem")
 
-				err := runner.RunMysqlDump("hostname", 42, "dbname", "username", "password", "cert-content", "table1", "table2", "--foo", "bar", "--baz")
+				err := runner.RunTool(MysqlDumpTool, stdIo, LocalAddress{Host: "hostname", Port: 42}, "dbname", "username", "password", "cert-content", "table1", "table2", "--foo", "bar", "--baz")
 
 				Expect(err).To(BeNil())
 				Expect(exec.LookPathCallCount()).To(Equal(1))
@@ -231,4 +340,117 @@ var _ = Describe("MysqlRunner", func() {
 			})
 		})
 	})
+
+	Context("RunTool with mysqldump and other streams", func() {
+		var exec *cfmysqlfakes.FakeExecWrapper
+		var ioutilWrapper *cfmysqlfakes.FakeIoUtilWrapper
+		var osWrapper *cfmysqlfakes.FakeOsWrapper
+		var runner MysqlRunner
+
+		BeforeEach(func() {
+			exec = new(cfmysqlfakes.FakeExecWrapper)
+			ioutilWrapper = new(cfmysqlfakes.FakeIoUtilWrapper)
+			osWrapper = new(cfmysqlfakes.FakeOsWrapper)
+			runner = NewMysqlRunner(exec, ioutilWrapper, osWrapper)
+		})
+
+		It("Connects mysqldump to the given streams", func() {
+			exec.LookPathReturns("/path/to/mysqldump", nil)
+			clientIo := ClientIo{
+				Stdout: new(bytes.Buffer),
+				Stderr: new(bytes.Buffer),
+			}
+
+			err := runner.RunTool(MysqlDumpTool, clientIo, LocalAddress{Host: "hostname", Port: 42}, "dbname", "username", "password", "", "table1", "--foo")
+
+			Expect(err).To(BeNil())
+			Expect(exec.RunCallCount()).To(Equal(1))
+
+			cmd := exec.RunArgsForCall(0)
+			Expect(cmd.Args).To(Equal([]string{"/path/to/mysqldump", "-u", "username", "-ppassword", "-h", "hostname", "-P", "42", "--foo", "dbname", "table1"}))
+			Expect(cmd.Stdin).To(BeNil())
+			Expect(cmd.Stdout).To(BeIdenticalTo(clientIo.Stdout))
+			Expect(cmd.Stderr).To(BeIdenticalTo(clientIo.Stderr))
+		})
+	})
+
+	Context("RunToolWithContext", func() {
+		var exec *cfmysqlfakes.FakeExecWrapper
+		var ioutilWrapper *cfmysqlfakes.FakeIoUtilWrapper
+		var osWrapper *cfmysqlfakes.FakeOsWrapper
+		var runner MysqlRunner
+		var clientIo ClientIo
+
+		BeforeEach(func() {
+			exec = new(cfmysqlfakes.FakeExecWrapper)
+			ioutilWrapper = new(cfmysqlfakes.FakeIoUtilWrapper)
+			osWrapper = new(cfmysqlfakes.FakeOsWrapper)
+			runner = NewMysqlRunner(exec, ioutilWrapper, osWrapper)
+			clientIo = ClientIo{Stdin: os.Stdin, Stdout: os.Stdout, Stderr: os.Stderr}
+		})
+
+		Context("When running mysqladmin", func() {
+			It("Leaves out the database name", func() {
+				exec.LookPathReturns("/path/to/mysqladmin", nil)
+				tempFile := new(os.File)
+				ioutilWrapper.TempFileReturns(tempFile, nil)
+				osWrapper.NameReturns("/path/to/cert.pem")
+
+				err := runner.RunToolWithContext(context.Background(), MysqlAdminTool, clientIo, LocalAddress{Host: "hostname", Port: 42}, "dbname", "username", "password", "cert-content", "--verbose", "processlist")
+
+				Expect(err).To(BeNil())
+				Expect(exec.LookPathArgsForCall(0)).To(Equal("mysqladmin"))
+
+				cmd := exec.RunArgsForCall(0)
+				Expect(cmd.Args).To(Equal([]string{"/path/to/mysqladmin", "-u", "username", "-ppassword", "-h", "hostname", "-P", "42", "--ssl-ca=/path/to/cert.pem", "--verbose", "processlist"}))
+				Expect(osWrapper.RemoveArgsForCall(0)).To(Equal("/path/to/cert.pem"))
+			})
+		})
+
+		Context("When running mysqlimport", func() {
+			It("Reads local files and passes them after the database name", func() {
+				exec.LookPathReturns("/path/to/mysqlimport", nil)
+
+				err := runner.RunToolWithContext(context.Background(), MysqlImportTool, clientIo, LocalAddress{Host: "hostname", Port: 42}, "dbname", "username", "password", "", "users.csv", "--fields-terminated-by=,", "orders.csv", "--ignore-lines=1")
+
+				Expect(err).To(BeNil())
+				Expect(exec.LookPathArgsForCall(0)).To(Equal("mysqlimport"))
+
+				cmd := exec.RunArgsForCall(0)
+				Expect(cmd.Args).To(Equal([]string{"/path/to/mysqlimport", "-u", "username", "-ppassword", "-h", "hostname", "-P", "42", "--local", "--fields-terminated-by=,", "--ignore-lines=1", "dbname", "users.csv", "orders.csv"}))
+			})
+
+			It("Keeps separate option values with their options", func() {
+				exec.LookPathReturns("/path/to/mysqlimport", nil)
+
+				err := runner.RunToolWithContext(context.Background(), MysqlImportTool, clientIo, LocalAddress{Host: "hostname", Port: 42}, "dbname", "username", "password", "", "--fields-terminated-by", ",", "users.csv", "-c", "id,name", "--replace", "orders.csv")
+
+				Expect(err).To(BeNil())
+
+				cmd := exec.RunArgsForCall(0)
+				Expect(cmd.Args).To(Equal([]string{"/path/to/mysqlimport", "-u", "username", "-ppassword", "-h", "hostname", "-P", "42", "--local", "--fields-terminated-by", ",", "-c", "id,name", "--replace", "dbname", "users.csv", "orders.csv"}))
+			})
+		})
+
+		Context("When the tool is not in PATH", func() {
+			It("Returns an error", func() {
+				exec.LookPathReturns("", errors.New("PC LOAD LETTER"))
+
+				err := runner.RunToolWithContext(context.Background(), MysqlAdminTool, clientIo, LocalAddress{Host: "hostname", Port: 42}, "dbname", "username", "password", "")
+
+				Expect(err).To(Equal(errors.New("'mysqladmin' not found in PATH")))
+			})
+		})
+
+		Context("When the tool fails", func() {
+			It("Names the tool in the error", func() {
+				exec.LookPathReturns("/path/to/mysqlimport", nil)
+				exec.RunReturns(errors.New("PC LOAD LETTER"))
+
+				err := runner.RunToolWithContext(context.Background(), MysqlImportTool, clientIo, LocalAddress{Host: "hostname", Port: 42}, "dbname", "username", "password", "")
+
+				Expect(err).To(Equal(errors.New("error running mysqlimport: PC LOAD LETTER")))
+			})
+		})
+	})
 })
@@ -6,7 +6,9 @@ import "os"
 type OsWrapper interface {
 	LookupEnv(key string) (string, bool)
 	Name(file *os.File) string
+	Open(name string) (*os.File, error)
 	Remove(name string) error
+	RemoveAll(path string) error
 	WriteString(file *os.File, s string) (n int, err error)
 }
 
@@ -24,10 +26,18 @@ func (self *osWrapper) Name(file *os.File) string {
 	return file.Name()
 }
 
+func (self *osWrapper) Open(name string) (*os.File, error) {
+	return os.Open(name)
+}
+
 func (self *osWrapper) Remove(name string) error {
 	return os.Remove(name)
 }
 
+func (self *osWrapper) RemoveAll(path string) error {
+	return os.RemoveAll(path)
+}
+
 func (self *osWrapper) WriteString(file *os.File, s string) (n int, err error) {
 	return file.WriteString(s)
 }
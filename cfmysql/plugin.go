@@ -3,28 +3,45 @@ package cfmysql
 import (
 	"code.cloudfoundry.org/cli/plugin"
 	"code.cloudfoundry.org/cli/plugin/models"
+	"flag"
 	"fmt"
 	"io"
+	"io/ioutil"
+	"os"
+	"strings"
+	"syscall"
 )
 
 type MysqlPlugin struct {
-	In          io.Reader
-	Out         io.Writer
-	Err         io.Writer
-	CfService   CfService
-	MysqlRunner MysqlRunner
-	PortFinder  PortFinder
-	exitCode    int
+	In               io.Reader
+	Out              io.Writer
+	Err              io.Writer
+	CfService        CfService
+	MysqlRunner      MysqlRunner
+	PortFinder       PortFinder
+	OsWrapper        OsWrapper
+	IoUtilWrapper    IoUtilWrapper
+	SignalWrapper    SignalWrapper
+	TunnelAppPusher  TunnelAppPusher
+	TunnelDaemon     TunnelDaemon
+	TunnelStateStore TunnelStateStore
+	exitCode         int
 }
 
 func NewMysqlPlugin(conf PluginConf) *MysqlPlugin {
 	return &MysqlPlugin{
-		In:          conf.In,
-		Out:         conf.Out,
-		Err:         conf.Err,
-		CfService:   conf.CfService,
-		PortFinder:  conf.PortFinder,
-		MysqlRunner: conf.MysqlRunner,
+		In:               conf.In,
+		Out:              conf.Out,
+		Err:              conf.Err,
+		CfService:        conf.CfService,
+		PortFinder:       conf.PortFinder,
+		MysqlRunner:      conf.MysqlRunner,
+		OsWrapper:        conf.OsWrapper,
+		IoUtilWrapper:    conf.IoUtilWrapper,
+		SignalWrapper:    conf.SignalWrapper,
+		TunnelAppPusher:  conf.TunnelAppPusher,
+		TunnelDaemon:     conf.TunnelDaemon,
+		TunnelStateStore: conf.TunnelStateStore,
 	}
 }
 
@@ -41,50 +58,125 @@ func (self *MysqlPlugin) GetMetadata() plugin.PluginMetadata {
 			Minor: 7,
 			Build: 0,
 		},
-		Commands: []plugin.Command{
+		Commands: append(clientToolCommands(), []plugin.Command{
 			{
-				Name:     "mysql",
-				HelpText: "Connect to a MySQL database service",
+				Name:     "mysql-tunnel",
+				HelpText: "Open an SSH tunnel to a MySQL database service",
 				UsageDetails: plugin.Usage{
-					Usage: "Open a mysql client to a database:\n   " +
-						"cf mysql <service-name> [mysql args...]",
+					Usage: "Open tunnels to one or more services and print the connection details, until Ctrl-C is pressed:\n   " +
+						"cf mysql-tunnel [--local-port <port>] [--local-host <host>] [--local-socket <path>] [--app <app-name> | --push-app] [--org <org>] [--space <space>] <service-name> [<service-name>...]\n   " +
+						"Open a tunnel in the background, list the background tunnels or stop them:\n   " +
+						"cf mysql-tunnel start [--local-port <port>] [--local-host <host>] [--local-socket <path>] [--app <app-name> | --push-app] [--org <org>] [--space <space>] <service-name>\n   " +
+						"cf mysql-tunnel list\n   " +
+						"cf mysql-tunnel stop <service-name> | --all",
 				},
 			},
 			{
-				Name:     "mysqldump",
-				HelpText: "Dump a MySQL database",
+				Name:     "mysql-restore",
+				HelpText: "Restore a MySQL database from a dump file",
 				UsageDetails: plugin.Usage{
-					Usage: "Dump all tables in a database:\n   " +
-						"cf mysqldump <service-name> [mysqldump args...]\n   " +
-						"Dump specific tables in a database:\n   " +
-						"cf mysqldump <service-name> [tables...] [mysqldump args...]",
+					Usage: "Restore a plain or gzip-compressed dump, stopping at the first failing statement:\n   " +
+						"cf mysql-restore [--ephemeral] [--app <app-name> | --push-app] [--org <org>] [--space <space>] <service-name> <dump-file> [mysql args...]",
 				},
 			},
-		},
+			{
+				Name:     "mysql-exec",
+				HelpText: "Run SQL statements and print the results",
+				UsageDetails: plugin.Usage{
+					Usage: "Run a statement, or the statements in a file, and print the results as tsv (default), csv or json.\n   --timeout stops the client, a statement that is still running on the server is not cancelled:\n   " +
+						"cf mysql-exec [--format <format>] [--timeout <duration>] [--ephemeral] [--app <app-name> | --push-app] [--org <org>] [--space <space>] <service-name> <statement>\n   " +
+						"cf mysql-exec [--format <format>] [--timeout <duration>] [--ephemeral] [--app <app-name> | --push-app] [--org <org>] [--space <space>] -f <sql-file> <service-name>",
+				},
+			},
+			{
+				Name:     "mysql-copy",
+				HelpText: "Copy a MySQL database into another database service",
+				UsageDetails: plugin.Usage{
+					Usage: "Copy all tables, or only the given tables, from the source into the target database:\n   " +
+						"cf mysql-copy [--ephemeral] [--app <app-name> | --push-app] [--org <org>] [--space <space>] <source-service-name> <target-service-name> [tables...]",
+				},
+			},
+			{
+				Name:     "mysql-env",
+				HelpText: "Print the connection details of a MySQL database service",
+				UsageDetails: plugin.Usage{
+					Usage: "Print the credentials as env (default), mycnf, url, jdbc or dsn, optionally for a tunnel on a local port.\n   The mysql client reads only MYSQL_HOST, MYSQL_TCP_PORT and MYSQL_PWD, pass the user and database to it as options:\n   " +
+						"cf mysql-env [--format <format>] [--local-port <port>] [--local-host <host>] [--org <org>] [--space <space>] <service-name>",
+				},
+			},
+			{
+				Name:     "mysql-services",
+				HelpText: "List the MySQL database services in the current space",
+				UsageDetails: plugin.Usage{
+					Usage: "List MySQL services, or all services with --all:\n   " +
+						"cf mysql-services [--all] [--org <org>] [--space <space>]",
+				},
+			},
+			{
+				Name:     "mysql-cleanup",
+				HelpText: "Delete the service keys and temporary apps created by this plugin",
+				UsageDetails: plugin.Usage{
+					Usage: "Delete the cf-mysql service keys in the current space, or in the whole org with --org:\n   " +
+						"cf mysql-cleanup [--org] [--service <service-name>] [-f]\n   " +
+						"Delete the temporary tunnel apps left behind in the current space:\n   " +
+						"cf mysql-cleanup --apps [-f]",
+				},
+			},
+		}...),
+	}
+}
+
+func clientToolCommands() []plugin.Command {
+	var commands []plugin.Command
+	for _, tool := range ClientTools {
+		commands = append(commands, plugin.Command{
+			Name:         tool.Name,
+			HelpText:     tool.HelpText,
+			UsageDetails: plugin.Usage{Usage: tool.Usage},
+		})
 	}
+
+	return commands
 }
 
 func (self *MysqlPlugin) Run(cliConnection plugin.CliConnection, args []string) {
 	command := args[0]
 
+	if tool, found := FindClientTool(command); found {
+		self.runClientTool(cliConnection, tool, args[1:])
+		return
+	}
+
 	switch command {
-	case "mysql":
-		fallthrough
+	case "mysql-tunnel":
+		switch {
+		case len(args) > 1 && args[1] == "start":
+			self.startBackgroundTunnel(args[2:])
+		case len(args) > 1 && args[1] == "list":
+			self.listBackgroundTunnels(args[2:])
+		case len(args) > 1 && args[1] == "stop":
+			self.stopBackgroundTunnels(args[2:])
+		default:
+			self.runTunnel(cliConnection, args[1:])
+		}
 
-	case "mysqldump":
-		if len(args) > 1 {
-			dbName := args[1]
+	case "mysql-restore":
+		self.restore(cliConnection, args[1:])
 
-			var mysqlArgs []string
-			if len(args) > 2 {
-				mysqlArgs = args[2:]
-			}
+	case "mysql-exec":
+		self.execStatement(cliConnection, args[1:])
 
-			self.connectTo(cliConnection, command, dbName, mysqlArgs)
-		} else {
-			fmt.Fprint(self.Err, self.FormatUsage())
-			self.setErrorExit()
-		}
+	case "mysql-copy":
+		self.copyDatabase(cliConnection, args[1:])
+
+	case "mysql-env":
+		self.printEnv(cliConnection, args[1:])
+
+	case "mysql-services":
+		self.listServices(cliConnection, args[1:])
+
+	case "mysql-cleanup":
+		self.cleanup(cliConnection, args[1:])
 
 	default:
 		// we don't handle "uninstall"
@@ -117,65 +209,287 @@ func (self *MysqlPlugin) setErrorExit() {
 	self.exitCode = 1
 }
 
+// setClientErrorExit passes on the exit status of a failed client, if there is one
+func (self *MysqlPlugin) setClientErrorExit(err error) {
+	if exitErr, ok := err.(*ClientExitError); ok && exitErr.ExitCode > 0 {
+		self.exitCode = exitErr.ExitCode
+		return
+	}
+
+	self.setErrorExit()
+}
+
 type StartedAppsResult struct {
 	Apps []plugin_models.GetAppsModel
 	Err  error
 }
 
-func (self *MysqlPlugin) connectTo(cliConnection plugin.CliConnection, command string, dbName string, mysqlArgs []string) {
-	appsChan := make(chan StartedAppsResult, 0)
+func (self *MysqlPlugin) runClientTool(cliConnection plugin.CliConnection, tool ClientTool, args []string) {
+	flags := flag.NewFlagSet(tool.Name, flag.ContinueOnError)
+	flags.SetOutput(ioutil.Discard)
+	ephemeral := flags.Bool("ephemeral", false, "")
+	localAddress := addLocalAddressFlags(flags)
+	app := addAppChoiceFlags(flags)
+	target := addSpaceTargetFlags(flags)
+
+	err := flags.Parse(args)
+	if err != nil || flags.NArg() == 0 || !localAddress.normalize() || !app.valid() || !target.valid(*app) {
+		fmt.Fprint(self.Err, self.FormatUsage())
+		self.setErrorExit()
+		return
+	}
+
+	cliConnection, ok := self.targetConnection(cliConnection, *target)
+	if !ok {
+		return
+	}
+
+	dbName := flags.Arg(0)
+	toolArgs := flags.Args()[1:]
+	clientIo := ClientIo{Stdin: self.In, Stdout: self.Out, Stderr: self.Err}
+
+	self.connectTo(cliConnection, dbName, *ephemeral, *localAddress, *app, func(tunnelAddress LocalAddress, service MysqlService) error {
+		return self.MysqlRunner.RunTool(tool, clientIo, tunnelAddress, service.DbName, service.Username, service.Password, service.CaCert, service.withClientArgs(toolArgs)...)
+	})
+}
+
+// clientFunc runs a client through the tunnel listening on tunnelAddress
+type clientFunc func(tunnelAddress LocalAddress, service MysqlService) error
+
+func (self *MysqlPlugin) connectTo(cliConnection plugin.CliConnection, dbName string, ephemeral bool, localAddress LocalAddress, app appChoice, runClient clientFunc) {
+	if ephemeral || app.Push {
+		defer self.ignoreInterrupts()()
+	}
+
+	service, tunnel, tunnelAddress, ok := self.openTunnel(cliConnection, dbName, ephemeral, localAddress, app)
+	if ephemeral && service.ServiceKeyGuid != "" {
+		defer self.deleteEphemeralKey(cliConnection, service)
+	}
+
+	if !ok {
+		return
+	}
+	defer tunnel.Close()
+
+	err := runClient(tunnelAddress, service)
+	if err != nil {
+		fmt.Fprintf(self.Err, "FAILED\n%s", err)
+		self.setClientErrorExit(err)
+	}
+}
+
+// ignoreInterrupts keeps the plugin running on Ctrl-C until the returned func is called, so that a service key or app
+// can be deleted after the client exits
+func (self *MysqlPlugin) ignoreInterrupts() func() {
+	interrupts := make(chan os.Signal, 1)
+	self.SignalWrapper.Notify(interrupts, os.Interrupt, syscall.SIGTERM)
+
+	return func() {
+		self.SignalWrapper.Stop(interrupts)
+	}
+}
+
+func (self *MysqlPlugin) deleteEphemeralKey(cliConnection plugin.CliConnection, service MysqlService) {
+	err := self.CfService.DeleteServiceKey(cliConnection, service.ServiceKeyGuid)
+	if err != nil {
+		fmt.Fprintf(self.Err, "FAILED\nUnable to delete service key %s for %s: %s\n", service.ServiceKeyName, service.Name, err)
+		fmt.Fprintf(self.Err, "Delete it with: cf delete-service-key -f %s %s\n", service.Name, service.ServiceKeyName)
+
+		if self.exitCode == 0 {
+			self.setErrorExit()
+		}
+	}
+}
+
+// openTunnel retrieves credentials for the service and opens an SSH tunnel to it. Errors are reported to the user.
+// With ephemeral set, a new service key is created. It is returned even if the tunnel fails, so it can be deleted.
+// The tunnel listens on a free port, unless localAddress asks for a specific one. If an app is named, the tunnel goes
+// through that app only. If the space has no started apps and pushing is allowed, a temporary app is pushed, which is
+// deleted when the tunnel is closed.
+func (self *MysqlPlugin) openTunnel(cliConnection plugin.CliConnection, dbName string, ephemeral bool, localAddress LocalAddress, app appChoice) (MysqlService, SshTunnel, LocalAddress, bool) {
+	services, tunnel, tunnelAddresses, ok := self.openTunnels(cliConnection, []string{dbName}, ephemeral, localAddress, app)
+	if !ok {
+		if len(services) == 1 {
+			return services[0], nil, LocalAddress{}, false
+		}
+		return MysqlService{}, nil, LocalAddress{}, false
+	}
+
+	return services[0], tunnel, tunnelAddresses[0], true
+}
+
+// openTunnels works like openTunnel, but forwards a local port to each of the services through a single SSH
+// connection. With a local port given, the services are forwarded to consecutive ports starting at that port. The
+// services retrieved so far are returned even if the tunnel fails, so ephemeral keys can be deleted.
+func (self *MysqlPlugin) openTunnels(cliConnection plugin.CliConnection, dbNames []string, ephemeral bool, localAddress LocalAddress, app appChoice) ([]MysqlService, SshTunnel, []LocalAddress, bool) {
+	localAddresses, ok := self.localAddressesFor(len(dbNames), localAddress)
+	if !ok {
+		return nil, nil, nil, false
+	}
+
+	// buffered, so that the lookup can finish when the services cannot be retrieved and nobody waits for the apps
+	appsChan := make(chan StartedAppsResult, 1)
 	go func() {
 		startedApps, err := self.CfService.GetStartedApps(cliConnection)
 		appsChan <- StartedAppsResult{Apps: startedApps, Err: err}
 	}()
 
-	service, err := self.CfService.GetService(cliConnection, dbName)
-	if err != nil {
-		fmt.Fprintf(self.Err, "FAILED\nUnable to retrieve service credentials: %s\n", err)
-		self.setErrorExit()
-		return
+	getService := self.CfService.GetService
+	if ephemeral {
+		getService = self.CfService.GetEphemeralService
+	}
+
+	var services []MysqlService
+	var forwards []TunnelForward
+	for i, dbName := range dbNames {
+		service, err := getService(cliConnection, dbName)
+		if err != nil {
+			fmt.Fprintf(self.Err, "FAILED\nUnable to retrieve service credentials: %s\n", err)
+			self.setErrorExit()
+			return services, nil, nil, false
+		}
+		services = append(services, service)
+		forwards = append(forwards, TunnelForward{Service: service, LocalAddress: localAddresses[i]})
 	}
 
+	names := strings.Join(dbNames, "', '")
+
 	appsResult := <-appsChan
 	if appsResult.Err != nil {
 		fmt.Fprintf(self.Err, "FAILED\nUnable to retrieve started apps: %s\n", appsResult.Err)
 		self.setErrorExit()
-		return
+		return services, nil, nil, false
+	}
+
+	apps := appsResult.Apps
+	var pushedApp *plugin_models.GetAppsModel
+
+	if len(apps) == 0 && app.Push {
+		tunnelApp, ok := self.pushTunnelApp(cliConnection)
+		if !ok {
+			return services, nil, nil, false
+		}
+		apps = []plugin_models.GetAppsModel{tunnelApp}
+		pushedApp = &tunnelApp
 	}
 
-	if len(appsResult.Apps) == 0 {
-		fmt.Fprintf(self.Err, "FAILED\nUnable to connect to '%s': no started apps in current space\n", dbName)
+	if len(apps) == 0 {
+		fmt.Fprintf(self.Err, "FAILED\nUnable to connect to '%s': no started apps in %s\n", names, spaceDescription(cliConnection))
 		self.setErrorExit()
-		return
+		return services, nil, nil, false
 	}
 
-	tunnelPort := self.PortFinder.GetPort()
-	self.CfService.OpenSshTunnel(cliConnection, service, appsResult.Apps, tunnelPort)
+	if app.Name != "" {
+		apps = findApp(apps, app.Name)
+		if len(apps) == 0 {
+			fmt.Fprintf(self.Err, "FAILED\nUnable to connect to '%s': app %s is not started or not in %s\n", names, app.Name, spaceDescription(cliConnection))
+			self.setErrorExit()
+			return services, nil, nil, false
+		}
+	}
 
-	err = self.runClient(command, "127.0.0.1", tunnelPort, service.DbName, service.Username, service.Password, service.CaCert, mysqlArgs...)
+	tunnel, err := self.CfService.OpenSshTunnel(cliConnection, forwards, apps)
 	if err != nil {
-		fmt.Fprintf(self.Err, "FAILED\n%s", err)
+		fmt.Fprintf(self.Err, "FAILED\nUnable to connect to '%s': %s\n", names, err)
 		self.setErrorExit()
+		if pushedApp != nil {
+			self.deleteTunnelApp(cliConnection, pushedApp.Name)
+		}
+		return services, nil, nil, false
+	}
+
+	if pushedApp != nil {
+		tunnel = &appDeletingTunnel{
+			SshTunnel: tunnel,
+			deleteApp: func() { self.deleteTunnelApp(cliConnection, pushedApp.Name) },
+		}
 	}
+
+	return services, tunnel, localAddresses, true
 }
 
-func (self *MysqlPlugin) runClient(command string, hostname string, port int, dbName string, username string, password string, caCert string, args ...string) error {
-	switch command {
-	case "mysql":
-		return self.MysqlRunner.RunMysql(hostname, port, dbName, username, password, caCert, args...)
+// localAddressesFor returns count local addresses on the host of localAddress. Without a port, free ports are picked.
+// Otherwise, consecutive ports starting at the given one are used, if they are available. A socket can only be used
+// for a single service.
+func (self *MysqlPlugin) localAddressesFor(count int, localAddress LocalAddress) ([]LocalAddress, bool) {
+	if localAddress.Socket != "" {
+		if count > 1 {
+			fmt.Fprintf(self.Err, "FAILED\nA local socket can only be used with a single service\n")
+			self.setErrorExit()
+			return nil, false
+		}
+
+		if err := self.PortFinder.CheckPort(localAddress); err != nil {
+			fmt.Fprintf(self.Err, "FAILED\nLocal socket %s is not available: %s\n", localAddress.Socket, err)
+			self.setErrorExit()
+			return nil, false
+		}
 
-	case "mysqldump":
-		return self.MysqlRunner.RunMysqlDump(hostname, port, dbName, username, password, caCert, args...)
+		return []LocalAddress{localAddress}, true
+	}
+
+	var addresses []LocalAddress
+	taken := make(map[int]bool)
+
+	for i := 0; i < count; i++ {
+		address := LocalAddress{Host: localAddress.Host}
+
+		if localAddress.Port == 0 {
+			for attempt := 0; attempt < MaxPortAttempts; attempt++ {
+				port, err := self.PortFinder.GetPort(address.Host)
+				if err != nil {
+					fmt.Fprintf(self.Err, "FAILED\nUnable to find a free local port on %s: %s\n", address.Host, err)
+					self.setErrorExit()
+					return nil, false
+				}
+
+				address.Port = port
+				if !taken[port] {
+					break
+				}
+			}
+			if taken[address.Port] {
+				fmt.Fprintf(self.Err, "FAILED\nUnable to find %d free local ports\n", count)
+				self.setErrorExit()
+				return nil, false
+			}
+		} else {
+			address.Port = localAddress.Port + i
+			if err := self.PortFinder.CheckPort(address); err != nil {
+				fmt.Fprintf(self.Err, "FAILED\nLocal port %d is not available: %s\n", address.Port, err)
+				self.setErrorExit()
+				return nil, false
+			}
+		}
+
+		taken[address.Port] = true
+		addresses = append(addresses, address)
+	}
+
+	return addresses, true
+}
+
+func findApp(apps []plugin_models.GetAppsModel, name string) []plugin_models.GetAppsModel {
+	for _, app := range apps {
+		if app.Name == name {
+			return []plugin_models.GetAppsModel{app}
+		}
 	}
 
-	panic(fmt.Errorf("command not implemented: %s", command))
+	return nil
 }
 
 type PluginConf struct {
-	In          io.Reader
-	Out         io.Writer
-	Err         io.Writer
-	CfService   CfService
-	MysqlRunner MysqlRunner
-	PortFinder  PortFinder
+	In               io.Reader
+	Out              io.Writer
+	Err              io.Writer
+	CfService        CfService
+	MysqlRunner      MysqlRunner
+	PortFinder       PortFinder
+	OsWrapper        OsWrapper
+	IoUtilWrapper    IoUtilWrapper
+	SignalWrapper    SignalWrapper
+	TunnelAppPusher  TunnelAppPusher
+	TunnelDaemon     TunnelDaemon
+	TunnelStateStore TunnelStateStore
 }
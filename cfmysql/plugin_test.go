@@ -11,11 +11,13 @@ import (
 	. "github.com/onsi/ginkgo/v2"
 	. "github.com/onsi/gomega"
 	"github.com/onsi/gomega/gbytes"
+	"os"
+	"syscall"
 )
 
 var _ = Describe("Plugin", func() {
 	var appList []plugin_models.GetAppsModel
-	usage := "cf mysql - Connect to a MySQL database service\n\nUSAGE:\n   Open a mysql client to a database:\n   cf mysql <service-name> [mysql args...]\n\n\ncf mysqldump - Dump a MySQL database\n\nUSAGE:\n   Dump all tables in a database:\n   cf mysqldump <service-name> [mysqldump args...]\n   Dump specific tables in a database:\n   cf mysqldump <service-name> [tables...] [mysqldump args...]\n"
+	usage := "cf mysql - Connect to a MySQL database service\n\nUSAGE:\n   Open a mysql client to a database:\n   cf mysql [--ephemeral] [--local-port <port>] [--local-host <host>] [--local-socket <path>] [--app <app-name> | --push-app] [--org <org>] [--space <space>] <service-name> [mysql args...]\n\n\ncf mysqldump - Dump a MySQL database\n\nUSAGE:\n   Dump all tables in a database:\n   cf mysqldump [--ephemeral] [--local-port <port>] [--local-host <host>] [--local-socket <path>] [--app <app-name> | --push-app] [--org <org>] [--space <space>] <service-name> [mysqldump args...]\n   Dump specific tables in a database:\n   cf mysqldump [--ephemeral] [--local-port <port>] [--local-host <host>] [--local-socket <path>] [--app <app-name> | --push-app] [--org <org>] [--space <space>] <service-name> [tables...] [mysqldump args...]\n\n\ncf mysqladmin - Run administrative commands against a MySQL database service\n\nUSAGE:\n   Run mysqladmin commands such as processlist, status or variables:\n   cf mysqladmin [--ephemeral] [--local-port <port>] [--local-host <host>] [--local-socket <path>] [--app <app-name> | --push-app] [--org <org>] [--space <space>] <service-name> [mysqladmin args...] <command...>\n\n\ncf mysqlimport - Load data files into a MySQL database\n\nUSAGE:\n   Load local text files into the tables named like the files:\n   cf mysqlimport [--ephemeral] [--local-port <port>] [--local-host <host>] [--local-socket <path>] [--app <app-name> | --push-app] [--org <org>] [--space <space>] <service-name> [mysqlimport args...] <files...>\n\n\ncf mysql-tunnel - Open an SSH tunnel to a MySQL database service\n\nUSAGE:\n   Open tunnels to one or more services and print the connection details, until Ctrl-C is pressed:\n   cf mysql-tunnel [--local-port <port>] [--local-host <host>] [--local-socket <path>] [--app <app-name> | --push-app] [--org <org>] [--space <space>] <service-name> [<service-name>...]\n   Open a tunnel in the background, list the background tunnels or stop them:\n   cf mysql-tunnel start [--local-port <port>] [--local-host <host>] [--local-socket <path>] [--app <app-name> | --push-app] [--org <org>] [--space <space>] <service-name>\n   cf mysql-tunnel list\n   cf mysql-tunnel stop <service-name> | --all\n\n\ncf mysql-restore - Restore a MySQL database from a dump file\n\nUSAGE:\n   Restore a plain or gzip-compressed dump, stopping at the first failing statement:\n   cf mysql-restore [--ephemeral] [--app <app-name> | --push-app] [--org <org>] [--space <space>] <service-name> <dump-file> [mysql args...]\n\n\ncf mysql-exec - Run SQL statements and print the results\n\nUSAGE:\n   Run a statement, or the statements in a file, and print the results as tsv (default), csv or json.\n   --timeout stops the client, a statement that is still running on the server is not cancelled:\n   cf mysql-exec [--format <format>] [--timeout <duration>] [--ephemeral] [--app <app-name> | --push-app] [--org <org>] [--space <space>] <service-name> <statement>\n   cf mysql-exec [--format <format>] [--timeout <duration>] [--ephemeral] [--app <app-name> | --push-app] [--org <org>] [--space <space>] -f <sql-file> <service-name>\n\n\ncf mysql-copy - Copy a MySQL database into another database service\n\nUSAGE:\n   Copy all tables, or only the given tables, from the source into the target database:\n   cf mysql-copy [--ephemeral] [--app <app-name> | --push-app] [--org <org>] [--space <space>] <source-service-name> <target-service-name> [tables...]\n\n\ncf mysql-env - Print the connection details of a MySQL database service\n\nUSAGE:\n   Print the credentials as env (default), mycnf, url, jdbc or dsn, optionally for a tunnel on a local port.\n   The mysql client reads only MYSQL_HOST, MYSQL_TCP_PORT and MYSQL_PWD, pass the user and database to it as options:\n   cf mysql-env [--format <format>] [--local-port <port>] [--local-host <host>] [--org <org>] [--space <space>] <service-name>\n\n\ncf mysql-services - List the MySQL database services in the current space\n\nUSAGE:\n   List MySQL services, or all services with --all:\n   cf mysql-services [--all] [--org <org>] [--space <space>]\n\n\ncf mysql-cleanup - Delete the service keys and temporary apps created by this plugin\n\nUSAGE:\n   Delete the cf-mysql service keys in the current space, or in the whole org with --org:\n   cf mysql-cleanup [--org] [--service <service-name>] [-f]\n   Delete the temporary tunnel apps left behind in the current space:\n   cf mysql-cleanup --apps [-f]\n"
 
 	BeforeEach(func() {
 		appList = []plugin_models.GetAppsModel{
@@ -45,7 +47,7 @@ var _ = Describe("Plugin", func() {
 		It("Shows instructions for 'cf mysql'", func() {
 			mysqlPlugin, _ := NewPluginAndMocks()
 
-			Expect(mysqlPlugin.GetMetadata().Commands).To(HaveLen(2))
+			Expect(mysqlPlugin.GetMetadata().Commands).To(HaveLen(11))
 			Expect(mysqlPlugin.GetMetadata().Commands[0].Name).To(Equal("mysql"))
 		})
 	})
@@ -83,7 +85,7 @@ var _ = Describe("Plugin", func() {
 
 				mocks.CfService.GetServiceReturns(serviceA, nil)
 				mocks.CfService.GetStartedAppsReturns(appList, nil)
-				mocks.PortFinder.GetPortReturns(2342)
+				mocks.PortFinder.GetPortReturns(2342, nil)
 
 				mysqlPlugin.Run(mocks.CliConnection, []string{"mysql", "database-a"})
 
@@ -96,11 +98,10 @@ var _ = Describe("Plugin", func() {
 				Expect(mocks.PortFinder.GetPortCallCount()).To(Equal(1))
 				Expect(mocks.CfService.OpenSshTunnelCallCount()).To(Equal(1))
 
-				calledCliConnection, calledService, calledAppList, localPort := mocks.CfService.OpenSshTunnelArgsForCall(0)
+				calledCliConnection, forwards, calledAppList := mocks.CfService.OpenSshTunnelArgsForCall(0)
 				Expect(calledCliConnection).To(Equal(mocks.CliConnection))
-				Expect(calledService).To(Equal(serviceA))
 				Expect(calledAppList).To(Equal(appList))
-				Expect(localPort).To(Equal(2342))
+				Expect(forwards).To(Equal([]TunnelForward{{Service: serviceA, LocalAddress: LocalAddress{Host: "127.0.0.1", Port: 2342}}}))
 			})
 
 			It("Opens a MySQL client connecting through the tunnel", func() {
@@ -108,16 +109,17 @@ var _ = Describe("Plugin", func() {
 
 				mocks.CfService.GetServiceReturns(serviceA, nil)
 				mocks.CfService.GetStartedAppsReturns(appList, nil)
-				mocks.PortFinder.GetPortReturns(2342)
+				mocks.PortFinder.GetPortReturns(2342, nil)
 
 				mysqlPlugin.Run(mocks.CliConnection, []string{"mysql", "database-a"})
 
 				Expect(mocks.PortFinder.GetPortCallCount()).To(Equal(1))
-				Expect(mocks.MysqlRunner.RunMysqlCallCount()).To(Equal(1))
+				Expect(mocks.MysqlRunner.RunToolCallCount()).To(Equal(1))
 
-				hostname, port, dbName, username, password, caCert, _ := mocks.MysqlRunner.RunMysqlArgsForCall(0)
-				Expect(hostname).To(Equal("127.0.0.1"))
-				Expect(port).To(Equal(2342))
+				tool, clientIo, address, dbName, username, password, caCert, _ := mocks.MysqlRunner.RunToolArgsForCall(0)
+				Expect(tool.Name).To(Equal("mysql"))
+				Expect(clientIo).To(Equal(ClientIo{Stdin: mocks.In, Stdout: mocks.Out, Stderr: mocks.Err}))
+				Expect(address).To(Equal(LocalAddress{Host: "127.0.0.1", Port: 2342}))
 				Expect(dbName).To(Equal(serviceA.DbName))
 				Expect(username).To(Equal(serviceA.Username))
 				Expect(password).To(Equal(serviceA.Password))
@@ -130,22 +132,302 @@ var _ = Describe("Plugin", func() {
 
 					mocks.CfService.GetServiceReturns(serviceA, nil)
 					mocks.CfService.GetStartedAppsReturns(appList, nil)
-					mocks.PortFinder.GetPortReturns(2342)
+					mocks.PortFinder.GetPortReturns(2342, nil)
 
 					mysqlPlugin.Run(mocks.CliConnection, []string{"mysql", "database-a", "--foo", "bar", "--baz"})
 
 					Expect(mocks.PortFinder.GetPortCallCount()).To(Equal(1))
-					Expect(mocks.MysqlRunner.RunMysqlCallCount()).To(Equal(1))
+					Expect(mocks.MysqlRunner.RunToolCallCount()).To(Equal(1))
 
-					hostname, port, dbName, username, password, _, args := mocks.MysqlRunner.RunMysqlArgsForCall(0)
-					Expect(hostname).To(Equal("127.0.0.1"))
-					Expect(port).To(Equal(2342))
+					_, _, address, dbName, username, password, _, args := mocks.MysqlRunner.RunToolArgsForCall(0)
+					Expect(address).To(Equal(LocalAddress{Host: "127.0.0.1", Port: 2342}))
 					Expect(dbName).To(Equal(serviceA.DbName))
 					Expect(username).To(Equal(serviceA.Username))
 					Expect(password).To(Equal(serviceA.Password))
 					Expect(args).To(Equal([]string{"--foo", "bar", "--baz"}))
 				})
 			})
+
+			Context("When passing a local port and host", func() {
+				It("Opens the tunnel on that address and connects to it", func() {
+					mysqlPlugin, mocks := NewPluginAndMocks()
+
+					mocks.CfService.GetServiceReturns(serviceA, nil)
+					mocks.CfService.GetStartedAppsReturns(appList, nil)
+
+					mysqlPlugin.Run(mocks.CliConnection, []string{"mysql", "--local-port", "3306", "--local-host", "[::1]", "database-a", "--table"})
+
+					Expect(mocks.PortFinder.GetPortCallCount()).To(Equal(0))
+					Expect(mocks.PortFinder.CheckPortCallCount()).To(Equal(1))
+					Expect(mocks.PortFinder.CheckPortArgsForCall(0)).To(Equal(LocalAddress{Host: "::1", Port: 3306}))
+
+					_, forwards, _ := mocks.CfService.OpenSshTunnelArgsForCall(0)
+					Expect(forwards[0].LocalAddress).To(Equal(LocalAddress{Host: "::1", Port: 3306}))
+
+					_, _, address, _, _, _, _, args := mocks.MysqlRunner.RunToolArgsForCall(0)
+					Expect(address).To(Equal(LocalAddress{Host: "::1", Port: 3306}))
+					Expect(args).To(Equal([]string{"--table"}))
+				})
+			})
+
+			Context("When passing only a local host", func() {
+				It("Picks a free port on that host", func() {
+					mysqlPlugin, mocks := NewPluginAndMocks()
+
+					mocks.CfService.GetServiceReturns(serviceA, nil)
+					mocks.CfService.GetStartedAppsReturns(appList, nil)
+					mocks.PortFinder.GetPortReturns(2342, nil)
+
+					mysqlPlugin.Run(mocks.CliConnection, []string{"mysql", "--local-host", "::1", "database-a"})
+
+					Expect(mocks.PortFinder.GetPortCallCount()).To(Equal(1))
+					Expect(mocks.PortFinder.GetPortArgsForCall(0)).To(Equal("::1"))
+					_, _, address, _, _, _, _, _ := mocks.MysqlRunner.RunToolArgsForCall(0)
+					Expect(address).To(Equal(LocalAddress{Host: "::1", Port: 2342}))
+				})
+
+				It("Shows an error if no port can be found on that host", func() {
+					mysqlPlugin, mocks := NewPluginAndMocks()
+
+					mocks.PortFinder.GetPortReturns(0, errors.New("listen tcp [::1]:0: bind: cannot assign requested address"))
+
+					mysqlPlugin.Run(mocks.CliConnection, []string{"mysql", "--local-host", "::1", "database-a"})
+
+					Expect(mocks.Err).To(gbytes.Say("^FAILED\nUnable to find a free local port on ::1: listen tcp \\[::1\\]:0: bind: cannot assign requested address\n$"))
+					Expect(mocks.CfService.OpenSshTunnelCallCount()).To(Equal(0))
+					Expect(mysqlPlugin.GetExitCode()).To(Equal(1))
+				})
+			})
+
+			Context("When passing a local socket", func() {
+				It("Opens the tunnel on the socket and connects to it", func() {
+					mysqlPlugin, mocks := NewPluginAndMocks()
+
+					mocks.CfService.GetServiceReturns(serviceA, nil)
+					mocks.CfService.GetStartedAppsReturns(appList, nil)
+
+					mysqlPlugin.Run(mocks.CliConnection, []string{"mysql", "--local-socket", "/tmp/mysql.sock", "database-a"})
+
+					Expect(mocks.PortFinder.GetPortCallCount()).To(Equal(0))
+					Expect(mocks.PortFinder.CheckPortArgsForCall(0).Socket).To(Equal("/tmp/mysql.sock"))
+
+					_, forwards, _ := mocks.CfService.OpenSshTunnelArgsForCall(0)
+					Expect(forwards[0].LocalAddress.Socket).To(Equal("/tmp/mysql.sock"))
+
+					_, _, address, _, _, _, _, _ := mocks.MysqlRunner.RunToolArgsForCall(0)
+					Expect(address.Socket).To(Equal("/tmp/mysql.sock"))
+				})
+
+				It("Shows an error if the socket is not available", func() {
+					mysqlPlugin, mocks := NewPluginAndMocks()
+
+					mocks.PortFinder.CheckPortReturns(errors.New("address already in use"))
+
+					mysqlPlugin.Run(mocks.CliConnection, []string{"mysql", "--local-socket", "/tmp/mysql.sock", "database-a"})
+
+					Expect(mocks.Err).To(gbytes.Say("^FAILED\nLocal socket /tmp/mysql.sock is not available: address already in use\n$"))
+					Expect(mocks.CfService.OpenSshTunnelCallCount()).To(Equal(0))
+					Expect(mysqlPlugin.GetExitCode()).To(Equal(1))
+				})
+
+				It("Rejects a local port as well", func() {
+					mysqlPlugin, mocks := NewPluginAndMocks()
+
+					mysqlPlugin.Run(mocks.CliConnection, []string{"mysql", "--local-socket", "/tmp/mysql.sock", "--local-port", "3306", "database-a"})
+
+					Expect(string(mocks.Err.Contents())).To(Equal(mysqlPlugin.FormatUsage()))
+					Expect(mysqlPlugin.GetExitCode()).To(Equal(1))
+				})
+			})
+
+			Context("When passing an app name", func() {
+				It("Opens the tunnel through that app only", func() {
+					mysqlPlugin, mocks := NewPluginAndMocks()
+
+					mocks.CfService.GetServiceReturns(serviceA, nil)
+					mocks.CfService.GetStartedAppsReturns(appList, nil)
+
+					mysqlPlugin.Run(mocks.CliConnection, []string{"mysql", "--app", "app-name-2", "database-a"})
+
+					Expect(mocks.CfService.OpenSshTunnelCallCount()).To(Equal(1))
+					_, _, apps := mocks.CfService.OpenSshTunnelArgsForCall(0)
+					Expect(apps).To(Equal([]plugin_models.GetAppsModel{appList[1]}))
+					Expect(mysqlPlugin.GetExitCode()).To(Equal(0))
+				})
+			})
+
+			Context("When passing both an app name and --push-app", func() {
+				It("Prints usage information and exits with 1", func() {
+					mysqlPlugin, mocks := NewPluginAndMocks()
+
+					mysqlPlugin.Run(mocks.CliConnection, []string{"mysql", "--app", "app-name-1", "--push-app", "database-a"})
+
+					Expect(mocks.CfService.GetServiceCallCount()).To(Equal(0))
+					Expect(mocks.Err).To(gbytes.Say("cf mysql - Connect to a MySQL database service"))
+					Expect(mysqlPlugin.GetExitCode()).To(Equal(1))
+				})
+			})
+
+			Context("When passing an org and a space", func() {
+				It("Looks up the service and apps in that space", func() {
+					mysqlPlugin, mocks := NewPluginAndMocks()
+
+					targetedConnection := new(pluginfakes.FakeCliConnection)
+					mocks.CfService.TargetSpaceReturns(targetedConnection, nil)
+					mocks.CfService.GetServiceReturns(serviceA, nil)
+					mocks.CfService.GetStartedAppsReturns(appList, nil)
+
+					mysqlPlugin.Run(mocks.CliConnection, []string{"mysql", "--org", "other-org", "--space", "staging", "database-a"})
+
+					Expect(mocks.CfService.TargetSpaceCallCount()).To(Equal(1))
+					calledCliConnection, calledOrg, calledSpace := mocks.CfService.TargetSpaceArgsForCall(0)
+					Expect(calledCliConnection).To(Equal(mocks.CliConnection))
+					Expect(calledOrg).To(Equal("other-org"))
+					Expect(calledSpace).To(Equal("staging"))
+
+					calledCliConnection, _ = mocks.CfService.GetServiceArgsForCall(0)
+					Expect(calledCliConnection).To(BeIdenticalTo(targetedConnection))
+					Expect(mocks.CfService.GetStartedAppsArgsForCall(0)).To(BeIdenticalTo(targetedConnection))
+					Expect(mysqlPlugin.GetExitCode()).To(Equal(0))
+				})
+
+				It("Names the org and space if they have no started apps", func() {
+					mysqlPlugin, mocks := NewPluginAndMocks()
+
+					targetedConnection := new(pluginfakes.FakeCliConnection)
+					targetedConnection.GetCurrentOrgReturns(plugin_models.Organization{
+						OrganizationFields: plugin_models.OrganizationFields{Name: "other-org"},
+					}, nil)
+					targetedConnection.GetCurrentSpaceReturns(plugin_models.Space{
+						SpaceFields: plugin_models.SpaceFields{Name: "staging"},
+					}, nil)
+					mocks.CfService.TargetSpaceReturns(targetedConnection, nil)
+					mocks.CfService.GetServiceReturns(serviceA, nil)
+					mocks.CfService.GetStartedAppsReturns([]plugin_models.GetAppsModel{}, nil)
+
+					mysqlPlugin.Run(mocks.CliConnection, []string{"mysql", "--org", "other-org", "--space", "staging", "database-a"})
+
+					Expect(mocks.Err).To(gbytes.Say("^FAILED\\nUnable to connect to 'database-a': no started apps in org other-org / space staging\\n$"))
+					Expect(mysqlPlugin.GetExitCode()).To(Equal(1))
+				})
+
+				It("Names the org and space if the app passed is not started there", func() {
+					mysqlPlugin, mocks := NewPluginAndMocks()
+
+					targetedConnection := new(pluginfakes.FakeCliConnection)
+					targetedConnection.GetCurrentOrgReturns(plugin_models.Organization{
+						OrganizationFields: plugin_models.OrganizationFields{Name: "other-org"},
+					}, nil)
+					targetedConnection.GetCurrentSpaceReturns(plugin_models.Space{
+						SpaceFields: plugin_models.SpaceFields{Name: "staging"},
+					}, nil)
+					mocks.CfService.TargetSpaceReturns(targetedConnection, nil)
+					mocks.CfService.GetServiceReturns(serviceA, nil)
+					mocks.CfService.GetStartedAppsReturns(appList, nil)
+
+					mysqlPlugin.Run(mocks.CliConnection, []string{"mysql", "--org", "other-org", "--space", "staging", "--app", "stopped-app", "database-a"})
+
+					Expect(mocks.Err).To(gbytes.Say("^FAILED\\nUnable to connect to 'database-a': app stopped-app is not started or not in org other-org / space staging\\n$"))
+					Expect(mysqlPlugin.GetExitCode()).To(Equal(1))
+				})
+
+				It("Shows an error message if the space cannot be found", func() {
+					mysqlPlugin, mocks := NewPluginAndMocks()
+
+					mocks.CfService.TargetSpaceReturns(nil, errors.New("space staging not found in organization other-org"))
+
+					mysqlPlugin.Run(mocks.CliConnection, []string{"mysql", "--org", "other-org", "--space", "staging", "database-a"})
+
+					Expect(mocks.CfService.GetServiceCallCount()).To(Equal(0))
+					Expect(mocks.Err).To(gbytes.Say("^FAILED\\nUnable to target space staging: space staging not found in organization other-org\\n$"))
+					Expect(mysqlPlugin.GetExitCode()).To(Equal(1))
+				})
+
+				It("Does not target another space without the flags", func() {
+					mysqlPlugin, mocks := NewPluginAndMocks()
+
+					mocks.CfService.GetServiceReturns(serviceA, nil)
+					mocks.CfService.GetStartedAppsReturns(appList, nil)
+
+					mysqlPlugin.Run(mocks.CliConnection, []string{"mysql", "database-a"})
+
+					Expect(mocks.CfService.TargetSpaceCallCount()).To(Equal(0))
+				})
+			})
+
+			Context("When passing an org without a space, or a space with --push-app", func() {
+				It("Prints usage information and exits with 1", func() {
+					for _, args := range [][]string{
+						{"mysql", "--org", "other-org", "database-a"},
+						{"mysql", "--space", "staging", "--push-app", "database-a"},
+					} {
+						mysqlPlugin, mocks := NewPluginAndMocks()
+
+						mysqlPlugin.Run(mocks.CliConnection, args)
+
+						Expect(mocks.CfService.TargetSpaceCallCount()).To(Equal(0))
+						Expect(mocks.Err).To(gbytes.Say("cf mysql - Connect to a MySQL database service"))
+						Expect(mysqlPlugin.GetExitCode()).To(Equal(1))
+					}
+				})
+			})
+
+			Context("When the app passed is not started", func() {
+				It("Shows an error message and exits with 1", func() {
+					mysqlPlugin, mocks := NewPluginAndMocks()
+
+					mocks.CfService.GetServiceReturns(serviceA, nil)
+					mocks.CfService.GetStartedAppsReturns(appList, nil)
+
+					mysqlPlugin.Run(mocks.CliConnection, []string{"mysql", "--app", "stopped-app", "database-a"})
+
+					Expect(mocks.CfService.OpenSshTunnelCallCount()).To(Equal(0))
+					Expect(mocks.Err).To(gbytes.Say("^FAILED\\nUnable to connect to 'database-a': app stopped-app is not started or not in current space\\n$"))
+					Expect(mysqlPlugin.GetExitCode()).To(Equal(1))
+				})
+			})
+
+			Context("When the requested local port is taken", func() {
+				It("Shows an error message and exits with 1", func() {
+					mysqlPlugin, mocks := NewPluginAndMocks()
+
+					mocks.PortFinder.CheckPortReturns(errors.New("listen tcp 127.0.0.1:3306: bind: address already in use"))
+
+					mysqlPlugin.Run(mocks.CliConnection, []string{"mysql", "--local-port", "3306", "database-a"})
+
+					Expect(mocks.CfService.GetServiceCallCount()).To(Equal(0))
+					Expect(mocks.CfService.OpenSshTunnelCallCount()).To(Equal(0))
+					Expect(mocks.Err).To(gbytes.Say("^FAILED\nLocal port 3306 is not available: listen tcp 127.0.0.1:3306: bind: address already in use\n$"))
+					Expect(mysqlPlugin.GetExitCode()).To(Equal(1))
+				})
+			})
+
+			Context("When the local port is out of range", func() {
+				It("Prints usage information and exits with 1", func() {
+					mysqlPlugin, mocks := NewPluginAndMocks()
+
+					mysqlPlugin.Run(mocks.CliConnection, []string{"mysql", "--local-port", "65536", "database-a"})
+
+					Expect(mocks.CfService.GetServiceCallCount()).To(Equal(0))
+					Expect(mocks.Err).To(gbytes.Say("cf mysql - Connect to a MySQL database service"))
+					Expect(mysqlPlugin.GetExitCode()).To(Equal(1))
+				})
+			})
+		})
+
+		Context("When the mysql client exits with a non-zero status", func() {
+			It("Exits with the same status", func() {
+				mysqlPlugin, mocks := NewPluginAndMocks()
+
+				mocks.CfService.GetServiceReturns(serviceA, nil)
+				mocks.CfService.GetStartedAppsReturns(appList, nil)
+				mocks.MysqlRunner.RunToolReturns(&ClientExitError{Message: "error running mysql client: exit status 2", ExitCode: 2})
+
+				mysqlPlugin.Run(mocks.CliConnection, []string{"mysql", "database-a"})
+
+				Expect(mocks.Err).To(gbytes.Say("^FAILED\nerror running mysql client: exit status 2"))
+				Expect(mysqlPlugin.GetExitCode()).To(Equal(2))
+			})
 		})
 
 		Context("When a service key cannot be retrieved", func() {
@@ -180,6 +462,161 @@ var _ = Describe("Plugin", func() {
 			})
 		})
 
+		Context("When there are no started apps and --push-app is passed", func() {
+			var tunnelApp plugin_models.GetAppsModel
+
+			BeforeEach(func() {
+				tunnelApp = plugin_models.GetAppsModel{Name: "cf-mysql-tunnel-0000abcd", Guid: "tunnel-app-guid"}
+			})
+
+			It("Pushes a temporary app, opens the tunnel through it and deletes it afterwards", func() {
+				mysqlPlugin, mocks := NewPluginAndMocks()
+
+				mocks.CfService.GetServiceReturns(serviceA, nil)
+				mocks.CfService.GetStartedAppsReturns([]plugin_models.GetAppsModel{}, nil)
+				mocks.TunnelAppPusher.PushTunnelAppReturns(tunnelApp, nil)
+				mocks.MysqlRunner.RunToolStub = func(ClientTool, ClientIo, LocalAddress, string, string, string, string, ...string) error {
+					Expect(mocks.TunnelAppPusher.DeleteTunnelAppCallCount()).To(Equal(0))
+					return nil
+				}
+
+				mysqlPlugin.Run(mocks.CliConnection, []string{"mysql", "--push-app", "database-a"})
+
+				Expect(mocks.Err).To(gbytes.Say("No started apps in current space, pushing a temporary app for the SSH tunnel...\n"))
+				Expect(mocks.TunnelAppPusher.PushTunnelAppCallCount()).To(Equal(1))
+				Expect(mocks.TunnelAppPusher.PushTunnelAppArgsForCall(0)).To(Equal(mocks.CliConnection))
+
+				_, _, apps := mocks.CfService.OpenSshTunnelArgsForCall(0)
+				Expect(apps).To(Equal([]plugin_models.GetAppsModel{tunnelApp}))
+				Expect(mocks.MysqlRunner.RunToolCallCount()).To(Equal(1))
+
+				Expect(mocks.SshTunnel.CloseCallCount()).To(Equal(1))
+				Expect(mocks.Err).To(gbytes.Say("Deleting temporary app cf-mysql-tunnel-0000abcd...\n"))
+				Expect(mocks.TunnelAppPusher.DeleteTunnelAppCallCount()).To(Equal(1))
+				_, deletedName := mocks.TunnelAppPusher.DeleteTunnelAppArgsForCall(0)
+				Expect(deletedName).To(Equal("cf-mysql-tunnel-0000abcd"))
+				Expect(mysqlPlugin.GetExitCode()).To(Equal(0))
+			})
+
+			It("Keeps running on Ctrl-C until the app is deleted", func() {
+				mysqlPlugin, mocks := NewPluginAndMocks()
+
+				mocks.CfService.GetServiceReturns(serviceA, nil)
+				mocks.CfService.GetStartedAppsReturns([]plugin_models.GetAppsModel{}, nil)
+				mocks.TunnelAppPusher.PushTunnelAppReturns(tunnelApp, nil)
+
+				mysqlPlugin.Run(mocks.CliConnection, []string{"mysql", "--push-app", "database-a"})
+
+				Expect(mocks.SignalWrapper.NotifyCallCount()).To(Equal(2))
+				Expect(mocks.SignalWrapper.StopCallCount()).To(Equal(2))
+			})
+
+			It("Deletes the app if the tunnel cannot be opened", func() {
+				mysqlPlugin, mocks := NewPluginAndMocks()
+
+				mocks.CfService.GetServiceReturns(serviceA, nil)
+				mocks.CfService.GetStartedAppsReturns([]plugin_models.GetAppsModel{}, nil)
+				mocks.TunnelAppPusher.PushTunnelAppReturns(tunnelApp, nil)
+				mocks.CfService.OpenSshTunnelReturns(nil, errors.New("SSH disabled"))
+
+				mysqlPlugin.Run(mocks.CliConnection, []string{"mysql", "--push-app", "database-a"})
+
+				Expect(mocks.MysqlRunner.RunToolCallCount()).To(Equal(0))
+				Expect(mocks.TunnelAppPusher.DeleteTunnelAppCallCount()).To(Equal(1))
+				Expect(mysqlPlugin.GetExitCode()).To(Equal(1))
+			})
+
+			It("Tells the user how to delete the app if deleting fails", func() {
+				mysqlPlugin, mocks := NewPluginAndMocks()
+
+				mocks.CfService.GetServiceReturns(serviceA, nil)
+				mocks.CfService.GetStartedAppsReturns([]plugin_models.GetAppsModel{}, nil)
+				mocks.TunnelAppPusher.PushTunnelAppReturns(tunnelApp, nil)
+				mocks.TunnelAppPusher.DeleteTunnelAppReturns(errors.New("PC LOAD LETTER"))
+
+				mysqlPlugin.Run(mocks.CliConnection, []string{"mysql", "--push-app", "database-a"})
+
+				Expect(mocks.Err).To(gbytes.Say("FAILED\nUnable to delete app cf-mysql-tunnel-0000abcd: PC LOAD LETTER\n"))
+				Expect(mocks.Err).To(gbytes.Say("Delete it with: cf delete -f -r cf-mysql-tunnel-0000abcd\n"))
+				Expect(mysqlPlugin.GetExitCode()).To(Equal(1))
+			})
+
+			It("Shows an error message if the app cannot be pushed", func() {
+				mysqlPlugin, mocks := NewPluginAndMocks()
+
+				mocks.CfService.GetServiceReturns(serviceA, nil)
+				mocks.CfService.GetStartedAppsReturns([]plugin_models.GetAppsModel{}, nil)
+				mocks.TunnelAppPusher.PushTunnelAppReturns(plugin_models.GetAppsModel{}, errors.New("quota exceeded"))
+
+				mysqlPlugin.Run(mocks.CliConnection, []string{"mysql", "--push-app", "database-a"})
+
+				Expect(mocks.Err).To(gbytes.Say("FAILED\nUnable to push tunnel app: quota exceeded\n"))
+				Expect(mocks.CfService.OpenSshTunnelCallCount()).To(Equal(0))
+				Expect(mysqlPlugin.GetExitCode()).To(Equal(1))
+			})
+
+			It("Deletes the app and stops if interrupted while pushing", func() {
+				mysqlPlugin, mocks := NewPluginAndMocks()
+
+				mocks.CfService.GetServiceReturns(serviceA, nil)
+				mocks.CfService.GetStartedAppsReturns([]plugin_models.GetAppsModel{}, nil)
+				mocks.TunnelAppPusher.PushTunnelAppStub = func(plugin.CliConnection) (plugin_models.GetAppsModel, error) {
+					channel, _ := mocks.SignalWrapper.NotifyArgsForCall(mocks.SignalWrapper.NotifyCallCount() - 1)
+					channel <- os.Interrupt
+					return tunnelApp, nil
+				}
+
+				mysqlPlugin.Run(mocks.CliConnection, []string{"mysql", "--push-app", "database-a"})
+
+				Expect(mocks.TunnelAppPusher.DeleteTunnelAppCallCount()).To(Equal(1))
+				Expect(mocks.CfService.OpenSshTunnelCallCount()).To(Equal(0))
+				Expect(mysqlPlugin.GetExitCode()).To(Equal(1))
+			})
+
+			It("Uses the started apps if there are any", func() {
+				mysqlPlugin, mocks := NewPluginAndMocks()
+
+				mocks.CfService.GetServiceReturns(serviceA, nil)
+				mocks.CfService.GetStartedAppsReturns(appList, nil)
+
+				mysqlPlugin.Run(mocks.CliConnection, []string{"mysql", "--push-app", "database-a"})
+
+				Expect(mocks.TunnelAppPusher.PushTunnelAppCallCount()).To(Equal(0))
+				_, _, apps := mocks.CfService.OpenSshTunnelArgsForCall(0)
+				Expect(apps).To(Equal(appList))
+			})
+		})
+
+		Context("When the SSH tunnel cannot be opened", func() {
+			It("Shows an error message, does not run the client and exits with 1", func() {
+				mysqlPlugin, mocks := NewPluginAndMocks()
+
+				mocks.CfService.GetServiceReturns(serviceA, nil)
+				mocks.CfService.GetStartedAppsReturns(appList, nil)
+				mocks.CfService.OpenSshTunnelReturns(nil, errors.New("unable to open SSH tunnel through app app-name-1: SSH disabled"))
+
+				mysqlPlugin.Run(mocks.CliConnection, []string{"mysql", "database-a"})
+
+				Expect(mocks.MysqlRunner.RunToolCallCount()).To(Equal(0))
+				Expect(mocks.Err).To(gbytes.Say("^FAILED\nUnable to connect to 'database-a': unable to open SSH tunnel through app app-name-1: SSH disabled\n$"))
+				Expect(mysqlPlugin.GetExitCode()).To(Equal(1))
+			})
+		})
+
+		Context("When the client exits", func() {
+			It("Closes the SSH tunnel", func() {
+				mysqlPlugin, mocks := NewPluginAndMocks()
+
+				mocks.CfService.GetServiceReturns(serviceA, nil)
+				mocks.CfService.GetStartedAppsReturns(appList, nil)
+
+				mysqlPlugin.Run(mocks.CliConnection, []string{"mysql", "database-a"})
+
+				Expect(mocks.MysqlRunner.RunToolCallCount()).To(Equal(1))
+				Expect(mocks.SshTunnel.CloseCallCount()).To(Equal(1))
+			})
+		})
+
 		Context("When GetStartedApps returns an error", func() {
 			It("Shows an error message and exits with 1", func() {
 				mysqlPlugin, mocks := NewPluginAndMocks()
@@ -197,13 +634,174 @@ var _ = Describe("Plugin", func() {
 			})
 		})
 
+		Context("When calling 'cf mysql --ephemeral db-name'", func() {
+			BeforeEach(func() {
+				serviceA.ServiceKeyGuid = "service-key-guid"
+				serviceA.ServiceKeyName = "cf-mysql-0000002a"
+			})
+
+			It("Uses a new service key and deletes it after the client exits", func() {
+				mysqlPlugin, mocks := NewPluginAndMocks()
+
+				mocks.CfService.GetEphemeralServiceReturns(serviceA, nil)
+				mocks.CfService.GetStartedAppsReturns(appList, nil)
+				mocks.PortFinder.GetPortReturns(2342, nil)
+				mocks.MysqlRunner.RunToolStub = func(ClientTool, ClientIo, LocalAddress, string, string, string, string, ...string) error {
+					Expect(mocks.CfService.DeleteServiceKeyCallCount()).To(Equal(0))
+					return nil
+				}
+
+				mysqlPlugin.Run(mocks.CliConnection, []string{"mysql", "--ephemeral", "database-a", "--foo"})
+
+				Expect(mocks.CfService.GetServiceCallCount()).To(Equal(0))
+				calledCliConnection, calledName := mocks.CfService.GetEphemeralServiceArgsForCall(0)
+				Expect(calledCliConnection).To(Equal(mocks.CliConnection))
+				Expect(calledName).To(Equal("database-a"))
+
+				_, _, _, _, _, _, _, args := mocks.MysqlRunner.RunToolArgsForCall(0)
+				Expect(args).To(Equal([]string{"--foo"}))
+
+				Expect(mocks.CfService.DeleteServiceKeyCallCount()).To(Equal(1))
+				calledCliConnection, calledGuid := mocks.CfService.DeleteServiceKeyArgsForCall(0)
+				Expect(calledCliConnection).To(Equal(mocks.CliConnection))
+				Expect(calledGuid).To(Equal("service-key-guid"))
+				Expect(mysqlPlugin.GetExitCode()).To(Equal(0))
+			})
+
+			It("Keeps running on Ctrl-C until the key is deleted", func() {
+				mysqlPlugin, mocks := NewPluginAndMocks()
+
+				mocks.CfService.GetEphemeralServiceReturns(serviceA, nil)
+				mocks.CfService.GetStartedAppsReturns(appList, nil)
+
+				mysqlPlugin.Run(mocks.CliConnection, []string{"mysql", "--ephemeral", "database-a"})
+
+				Expect(mocks.SignalWrapper.NotifyCallCount()).To(Equal(1))
+				_, signals := mocks.SignalWrapper.NotifyArgsForCall(0)
+				Expect(signals).To(Equal([]os.Signal{os.Interrupt, syscall.SIGTERM}))
+				Expect(mocks.SignalWrapper.StopCallCount()).To(Equal(1))
+			})
+
+			Context("When the client fails", func() {
+				It("Deletes the key and exits with the client's status", func() {
+					mysqlPlugin, mocks := NewPluginAndMocks()
+
+					mocks.CfService.GetEphemeralServiceReturns(serviceA, nil)
+					mocks.CfService.GetStartedAppsReturns(appList, nil)
+					mocks.MysqlRunner.RunToolReturns(&ClientExitError{Message: "error running mysql client: exit status 2", ExitCode: 2})
+
+					mysqlPlugin.Run(mocks.CliConnection, []string{"mysql", "--ephemeral", "database-a"})
+
+					Expect(mocks.CfService.DeleteServiceKeyCallCount()).To(Equal(1))
+					Expect(mysqlPlugin.GetExitCode()).To(Equal(2))
+				})
+			})
+
+			Context("When the tunnel cannot be opened", func() {
+				It("Deletes the key", func() {
+					mysqlPlugin, mocks := NewPluginAndMocks()
+
+					mocks.CfService.GetEphemeralServiceReturns(serviceA, nil)
+					mocks.CfService.GetStartedAppsReturns([]plugin_models.GetAppsModel{}, nil)
+
+					mysqlPlugin.Run(mocks.CliConnection, []string{"mysql", "--ephemeral", "database-a"})
+
+					Expect(mocks.MysqlRunner.RunToolCallCount()).To(Equal(0))
+					Expect(mocks.CfService.DeleteServiceKeyCallCount()).To(Equal(1))
+					Expect(mysqlPlugin.GetExitCode()).To(Equal(1))
+				})
+			})
+
+			Context("When the key cannot be deleted", func() {
+				It("Reports the key and exits with 1", func() {
+					mysqlPlugin, mocks := NewPluginAndMocks()
+
+					mocks.CfService.GetEphemeralServiceReturns(serviceA, nil)
+					mocks.CfService.GetStartedAppsReturns(appList, nil)
+					mocks.CfService.DeleteServiceKeyReturns(errors.New("PC LOAD LETTER"))
+
+					mysqlPlugin.Run(mocks.CliConnection, []string{"mysql", "--ephemeral", "database-a"})
+
+					Expect(string(mocks.Err.Contents())).To(Equal("FAILED\n" +
+						"Unable to delete service key cf-mysql-0000002a for database-a: PC LOAD LETTER\n" +
+						"Delete it with: cf delete-service-key -f database-a cf-mysql-0000002a\n"))
+					Expect(mysqlPlugin.GetExitCode()).To(Equal(1))
+				})
+			})
+		})
+	})
+
+	Context("When the service has client arguments", func() {
+		It("Passes them after leading arguments and before the user's options", func() {
+			mysqlPlugin, mocks := NewPluginAndMocks()
+
+			serviceA := MysqlService{
+				Name:       "database-a",
+				DbName:     "dbname-a",
+				ClientArgs: []string{"--ssl-mode=REQUIRED"},
+			}
+			mocks.CfService.GetServiceReturns(serviceA, nil)
+			mocks.CfService.GetStartedAppsReturns(appList, nil)
+			mocks.PortFinder.GetPortReturns(2342, nil)
+
+			mysqlPlugin.Run(mocks.CliConnection, []string{"mysqldump", "database-a", "table-a", "table-b", "--ssl-mode=DISABLED"})
+
+			Expect(mocks.MysqlRunner.RunToolCallCount()).To(Equal(1))
+			tool, _, _, _, _, _, _, args := mocks.MysqlRunner.RunToolArgsForCall(0)
+			Expect(tool.Name).To(Equal("mysqldump"))
+			Expect(args).To(Equal([]string{"table-a", "table-b", "--ssl-mode=REQUIRED", "--ssl-mode=DISABLED"}))
+			Expect(mysqlPlugin.GetExitCode()).To(Equal(0))
+		})
+	})
+
+	Context("When calling 'cf mysqladmin db-name processlist'", func() {
+		It("Runs mysqladmin through the tunnel", func() {
+			mysqlPlugin, mocks := NewPluginAndMocks()
+
+			serviceA := MysqlService{
+				Name:     "database-a",
+				DbName:   "dbname-a",
+				Username: "username",
+				Password: "password",
+				CaCert:   "ca-cert",
+			}
+			mocks.CfService.GetServiceReturns(serviceA, nil)
+			mocks.CfService.GetStartedAppsReturns(appList, nil)
+			mocks.PortFinder.GetPortReturns(2342, nil)
+
+			mysqlPlugin.Run(mocks.CliConnection, []string{"mysqladmin", "database-a", "--verbose", "processlist"})
+
+			Expect(mocks.MysqlRunner.RunToolCallCount()).To(Equal(1))
+			tool, clientIo, address, dbName, username, password, caCert, args := mocks.MysqlRunner.RunToolArgsForCall(0)
+			Expect(tool.Name).To(Equal("mysqladmin"))
+			Expect(clientIo).To(Equal(ClientIo{Stdin: mocks.In, Stdout: mocks.Out, Stderr: mocks.Err}))
+			Expect(address).To(Equal(LocalAddress{Host: "127.0.0.1", Port: 2342}))
+			Expect(dbName).To(Equal("dbname-a"))
+			Expect(username).To(Equal("username"))
+			Expect(password).To(Equal("password"))
+			Expect(caCert).To(Equal("ca-cert"))
+			Expect(args).To(Equal([]string{"--verbose", "processlist"}))
+			Expect(mysqlPlugin.GetExitCode()).To(Equal(0))
+		})
+	})
+
+	Context("When calling 'cf mysqlimport' without arguments", func() {
+		It("Prints usage information to STDERR and exits with 1", func() {
+			mysqlPlugin, mocks := NewPluginAndMocks()
+
+			mysqlPlugin.Run(mocks.CliConnection, []string{"mysqlimport"})
+
+			Expect(string(mocks.Err.Contents())).To(Equal(usage))
+			Expect(mysqlPlugin.GetExitCode()).To(Equal(1))
+			Expect(mocks.CfService.GetServiceCallCount()).To(Equal(0))
+		})
 	})
 
 	Context("When calling 'cf mysqldump -h'", func() {
 		It("Shows instructions for 'cf mysqldump'", func() {
 			mysqlPlugin, _ := NewPluginAndMocks()
 
-			Expect(mysqlPlugin.GetMetadata().Commands).To(HaveLen(2))
+			Expect(mysqlPlugin.GetMetadata().Commands).To(HaveLen(11))
 			Expect(mysqlPlugin.GetMetadata().Commands[1].Name).To(Equal("mysqldump"))
 		})
 	})
@@ -253,7 +851,7 @@ var _ = Describe("Plugin", func() {
 
 				mocks.CfService.GetServiceReturns(serviceA, nil)
 				mocks.CfService.GetStartedAppsReturns([]plugin_models.GetAppsModel{app1, app2}, nil)
-				mocks.PortFinder.GetPortReturns(2342)
+				mocks.PortFinder.GetPortReturns(2342, nil)
 
 				mysqlPlugin.Run(mocks.CliConnection, []string{"mysqldump", "database-a"})
 
@@ -262,11 +860,10 @@ var _ = Describe("Plugin", func() {
 				Expect(mocks.PortFinder.GetPortCallCount()).To(Equal(1))
 				Expect(mocks.CfService.OpenSshTunnelCallCount()).To(Equal(1))
 
-				calledCliConnection, calledService, calledAppList, localPort := mocks.CfService.OpenSshTunnelArgsForCall(0)
+				calledCliConnection, forwards, calledAppList := mocks.CfService.OpenSshTunnelArgsForCall(0)
 				Expect(calledCliConnection).To(Equal(mocks.CliConnection))
-				Expect(calledService).To(Equal(serviceA))
 				Expect(calledAppList).To(Equal(appList))
-				Expect(localPort).To(Equal(2342))
+				Expect(forwards).To(Equal([]TunnelForward{{Service: serviceA, LocalAddress: LocalAddress{Host: "127.0.0.1", Port: 2342}}}))
 			})
 
 			It("Opens mysqldump connecting through the tunnel", func() {
@@ -274,16 +871,16 @@ var _ = Describe("Plugin", func() {
 
 				mocks.CfService.GetServiceReturns(serviceA, nil)
 				mocks.CfService.GetStartedAppsReturns([]plugin_models.GetAppsModel{app1}, nil)
-				mocks.PortFinder.GetPortReturns(2342)
+				mocks.PortFinder.GetPortReturns(2342, nil)
 
 				mysqlPlugin.Run(mocks.CliConnection, []string{"mysqldump", "database-a"})
 
 				Expect(mocks.PortFinder.GetPortCallCount()).To(Equal(1))
-				Expect(mocks.MysqlRunner.RunMysqlDumpCallCount()).To(Equal(1))
+				Expect(mocks.MysqlRunner.RunToolCallCount()).To(Equal(1))
 
-				hostname, port, dbName, username, password, caCert, _ := mocks.MysqlRunner.RunMysqlDumpArgsForCall(0)
-				Expect(hostname).To(Equal("127.0.0.1"))
-				Expect(port).To(Equal(2342))
+				tool, _, address, dbName, username, password, caCert, _ := mocks.MysqlRunner.RunToolArgsForCall(0)
+				Expect(tool.Name).To(Equal("mysqldump"))
+				Expect(address).To(Equal(LocalAddress{Host: "127.0.0.1", Port: 2342}))
 				Expect(dbName).To(Equal(serviceA.DbName))
 				Expect(username).To(Equal(serviceA.Username))
 				Expect(password).To(Equal(serviceA.Password))
@@ -306,34 +903,81 @@ var _ = Describe("Plugin", func() {
 })
 
 type Mocks struct {
-	In            *gbytes.Buffer
-	Out           *gbytes.Buffer
-	Err           *gbytes.Buffer
-	CfService     *cfmysqlfakes.FakeCfService
-	PortFinder    *cfmysqlfakes.FakePortFinder
-	CliConnection *pluginfakes.FakeCliConnection
-	MysqlRunner   *cfmysqlfakes.FakeMysqlRunner
+	In               *gbytes.Buffer
+	Out              *gbytes.Buffer
+	Err              *gbytes.Buffer
+	CfService        *cfmysqlfakes.FakeCfService
+	PortFinder       *cfmysqlfakes.FakePortFinder
+	CliConnection    *pluginfakes.FakeCliConnection
+	MysqlRunner      *cfmysqlfakes.FakeMysqlRunner
+	OsWrapper        *cfmysqlfakes.FakeOsWrapper
+	IoUtilWrapper    *cfmysqlfakes.FakeIoUtilWrapper
+	SignalWrapper    *cfmysqlfakes.FakeSignalWrapper
+	TunnelAppPusher  *cfmysqlfakes.FakeTunnelAppPusher
+	TunnelDaemon     *cfmysqlfakes.FakeTunnelDaemon
+	TunnelStateStore *cfmysqlfakes.FakeTunnelStateStore
+	SshTunnel        *cfmysqlfakes.FakeSshTunnel
 }
 
 func NewPluginAndMocks() (*MysqlPlugin, Mocks) {
 	mocks := Mocks{
-		In:            gbytes.NewBuffer(),
-		Out:           gbytes.NewBuffer(),
-		Err:           gbytes.NewBuffer(),
-		CfService:     new(cfmysqlfakes.FakeCfService),
-		CliConnection: new(pluginfakes.FakeCliConnection),
-		MysqlRunner:   new(cfmysqlfakes.FakeMysqlRunner),
-		PortFinder:    new(cfmysqlfakes.FakePortFinder),
+		In:               gbytes.NewBuffer(),
+		Out:              gbytes.NewBuffer(),
+		Err:              gbytes.NewBuffer(),
+		CfService:        new(cfmysqlfakes.FakeCfService),
+		CliConnection:    new(pluginfakes.FakeCliConnection),
+		MysqlRunner:      new(cfmysqlfakes.FakeMysqlRunner),
+		PortFinder:       new(cfmysqlfakes.FakePortFinder),
+		OsWrapper:        new(cfmysqlfakes.FakeOsWrapper),
+		IoUtilWrapper:    new(cfmysqlfakes.FakeIoUtilWrapper),
+		SignalWrapper:    new(cfmysqlfakes.FakeSignalWrapper),
+		TunnelAppPusher:  new(cfmysqlfakes.FakeTunnelAppPusher),
+		TunnelDaemon:     new(cfmysqlfakes.FakeTunnelDaemon),
+		TunnelStateStore: new(cfmysqlfakes.FakeTunnelStateStore),
 	}
+	mocks.SshTunnel, _ = NewFakeSshTunnel()
+
+	mocks.CfService.OpenSshTunnelReturns(mocks.SshTunnel, nil)
+	mocks.TunnelStateStore.LockReturns(func() {}, nil)
 
 	mysqlPlugin := NewMysqlPlugin(PluginConf{
-		In:          mocks.In,
-		Out:         mocks.Out,
-		Err:         mocks.Err,
-		CfService:   mocks.CfService,
-		MysqlRunner: mocks.MysqlRunner,
-		PortFinder:  mocks.PortFinder,
+		In:               mocks.In,
+		Out:              mocks.Out,
+		Err:              mocks.Err,
+		CfService:        mocks.CfService,
+		MysqlRunner:      mocks.MysqlRunner,
+		PortFinder:       mocks.PortFinder,
+		OsWrapper:        mocks.OsWrapper,
+		IoUtilWrapper:    mocks.IoUtilWrapper,
+		SignalWrapper:    mocks.SignalWrapper,
+		TunnelAppPusher:  mocks.TunnelAppPusher,
+		TunnelDaemon:     mocks.TunnelDaemon,
+		TunnelStateStore: mocks.TunnelStateStore,
 	})
 
 	return mysqlPlugin, mocks
 }
+
+// NewFakeSshTunnel returns a tunnel that stays open until it is closed, or until drop is called
+func NewFakeSshTunnel() (tunnel *cfmysqlfakes.FakeSshTunnel, drop func(err error)) {
+	result := make(chan error, 1)
+	stop := func(err error) {
+		select {
+		case result <- err:
+		default:
+		}
+	}
+
+	tunnel = new(cfmysqlfakes.FakeSshTunnel)
+	tunnel.WaitStub = func() error {
+		err := <-result
+		stop(err)
+		return err
+	}
+	tunnel.CloseStub = func() error {
+		stop(nil)
+		return nil
+	}
+
+	return tunnel, stop
+}
@@ -1,10 +1,16 @@
 package cfmysql
 
-import "github.com/phayes/freeport"
+import (
+	"net"
+)
+
+// MaxPortAttempts limits how often GetPort is asked for another port when it returns one already in use
+const MaxPortAttempts = 10
 
 //go:generate counterfeiter . PortFinder
 type PortFinder interface {
-	GetPort() int
+	GetPort(host string) (int, error)
+	CheckPort(localAddress LocalAddress) error
 }
 
 func NewPortFinder() PortFinder {
@@ -13,6 +19,25 @@ func NewPortFinder() PortFinder {
 
 type portFinder struct{}
 
-func (self *portFinder) GetPort() int {
-	return freeport.GetPort()
+// GetPort asks the system for a free port on the host, so that it is free in the address family the tunnel will
+// listen on, such as IPv6 for ::1
+func (self *portFinder) GetPort(host string) (int, error) {
+	listener, err := net.Listen("tcp", net.JoinHostPort(host, "0"))
+	if err != nil {
+		return 0, err
+	}
+	defer listener.Close()
+
+	return listener.Addr().(*net.TCPAddr).Port, nil
+}
+
+// CheckPort returns an error if nothing can listen on the address, for example because the port is taken or the
+// socket file exists
+func (self *portFinder) CheckPort(localAddress LocalAddress) error {
+	listener, err := net.Listen(localAddress.Network(), localAddress.String())
+	if err != nil {
+		return err
+	}
+
+	return listener.Close()
 }
@@ -0,0 +1,53 @@
+package cfmysql_test
+
+import (
+	. "github.com/andreasf/cf-mysql-plugin/cfmysql"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+	"net"
+)
+
+// freePort returns a free port on the IPv4 loopback address
+func freePort() int {
+	port, err := NewPortFinder().GetPort("127.0.0.1")
+	Expect(err).To(BeNil())
+
+	return port
+}
+
+var _ = Describe("PortFinder", func() {
+	var portFinder PortFinder
+
+	BeforeEach(func() {
+		portFinder = NewPortFinder()
+	})
+
+	Context("GetPort", func() {
+		It("Returns a port that is free on the host", func() {
+			port, err := portFinder.GetPort("127.0.0.1")
+
+			Expect(err).To(BeNil())
+			Expect(portFinder.CheckPort(LocalAddress{Host: "127.0.0.1", Port: port})).To(Succeed())
+		})
+
+		It("Looks for the port in the address family of the host", func() {
+			listener, err := net.Listen("tcp", "[::1]:0")
+			if err != nil {
+				Skip("IPv6 loopback is not available: " + err.Error())
+			}
+			listener.Close()
+
+			port, err := portFinder.GetPort("::1")
+
+			Expect(err).To(BeNil())
+			Expect(portFinder.CheckPort(LocalAddress{Host: "::1", Port: port})).To(Succeed())
+		})
+
+		It("Returns an error if nothing can listen on the host", func() {
+			_, err := portFinder.GetPort("192.0.2.1")
+
+			Expect(err).NotTo(BeNil())
+		})
+	})
+})
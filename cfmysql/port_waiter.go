@@ -1,15 +1,14 @@
 package cfmysql
 
 import (
-	"errors"
-	"net"
-	"strconv"
+	"context"
+	"fmt"
 	"time"
 )
 
 //go:generate counterfeiter . PortWaiter
 type PortWaiter interface {
-	WaitUntilOpen(localPort int)
+	WaitUntilOpen(ctx context.Context, localAddress LocalAddress) error
 }
 
 func NewPortWaiter(netWrapper NetWrapper) PortWaiter {
@@ -24,14 +23,19 @@ type portWaiter struct {
 	NetWrapper NetWrapper
 }
 
-func (self *portWaiter) WaitUntilOpen(localPort int) {
-	address := "127.0.0.1:" + strconv.Itoa(localPort)
+// WaitUntilOpen tries to connect to the port or socket until it succeeds, or until the context is done
+func (self *portWaiter) WaitUntilOpen(ctx context.Context, localAddress LocalAddress) error {
+	for {
+		select {
+		case <-ctx.Done():
+			return fmt.Errorf("%s did not open: %s", localAddress, ctx.Err())
+		case <-time.After(SleepTime * time.Millisecond):
+		}
 
-	var conn net.Conn
-	err := errors.New("")
-	for err != nil {
-		time.Sleep(SleepTime * time.Millisecond)
-		conn, err = self.NetWrapper.Dial("tcp", address)
+		conn, err := self.NetWrapper.Dial(localAddress.Network(), localAddress.String())
+		if err == nil {
+			self.NetWrapper.Close(conn)
+			return nil
+		}
 	}
-	self.NetWrapper.Close(conn)
 }
@@ -3,17 +3,20 @@ package cfmysql_test
 import (
 	. "github.com/andreasf/cf-mysql-plugin/cfmysql"
 
+	"context"
 	"errors"
 	"github.com/andreasf/cf-mysql-plugin/cfmysql/cfmysqlfakes"
 	"github.com/andreasf/cf-mysql-plugin/cfmysql/netfakes"
 	. "github.com/onsi/ginkgo/v2"
 	. "github.com/onsi/gomega"
 	"net"
+	"time"
 )
 
 var _ = Describe("PortWaiter", func() {
 	var netWrapper *cfmysqlfakes.FakeNetWrapper
 	var portWaiter PortWaiter
+	localAddress := LocalAddress{Host: "127.0.0.1", Port: 523}
 
 	const SucceedAfterTries = 5
 	dialCount := 0
@@ -34,19 +37,59 @@ var _ = Describe("PortWaiter", func() {
 	It("Waits until the port is open", func() {
 		netWrapper.DialStub = mockDial
 
-		portWaiter.WaitUntilOpen(523)
+		err := portWaiter.WaitUntilOpen(context.Background(), localAddress)
 
+		Expect(err).To(BeNil())
 		Expect(netWrapper.DialCallCount()).To(Equal(SucceedAfterTries))
+		network, address := netWrapper.DialArgsForCall(0)
+		Expect(network).To(Equal("tcp"))
+		Expect(address).To(Equal("127.0.0.1:523"))
 	})
 
 	It("Closes the connection", func() {
 		mockConn := new(netfakes.FakeConn)
 		netWrapper.DialReturns(mockConn, nil)
 
-		portWaiter.WaitUntilOpen(523)
+		portWaiter.WaitUntilOpen(context.Background(), localAddress)
 
 		Expect(netWrapper.CloseCallCount()).To(Equal(1))
 		Expect(netWrapper.CloseArgsForCall(0)).To(Equal(mockConn))
 		Expect(netWrapper.DialCallCount()).To(Equal(1))
 	})
+
+	It("Dials Unix sockets", func() {
+		netWrapper.DialReturns(new(netfakes.FakeConn), nil)
+
+		err := portWaiter.WaitUntilOpen(context.Background(), LocalAddress{Socket: "/tmp/mysql.sock"})
+
+		Expect(err).To(BeNil())
+		network, address := netWrapper.DialArgsForCall(0)
+		Expect(network).To(Equal("unix"))
+		Expect(address).To(Equal("/tmp/mysql.sock"))
+	})
+
+	Context("When the port does not open before the deadline", func() {
+		It("Returns an error", func() {
+			netWrapper.DialReturns(nil, errors.New("connection refused"))
+			ctx, cancel := context.WithTimeout(context.Background(), 350*time.Millisecond)
+			defer cancel()
+
+			err := portWaiter.WaitUntilOpen(ctx, localAddress)
+
+			Expect(err).To(MatchError("127.0.0.1:523 did not open: context deadline exceeded"))
+			Expect(netWrapper.DialCallCount()).To(BeNumerically(">=", 2))
+		})
+	})
+
+	Context("When waiting is cancelled", func() {
+		It("Returns an error without dialing", func() {
+			ctx, cancel := context.WithCancel(context.Background())
+			cancel()
+
+			err := portWaiter.WaitUntilOpen(ctx, localAddress)
+
+			Expect(err).To(MatchError("127.0.0.1:523 did not open: context canceled"))
+			Expect(netWrapper.DialCallCount()).To(Equal(0))
+		})
+	})
 })
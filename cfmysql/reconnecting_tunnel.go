@@ -0,0 +1,157 @@
+package cfmysql
+
+import (
+	"code.cloudfoundry.org/cli/plugin"
+	sdkModels "code.cloudfoundry.org/cli/plugin/models"
+	"fmt"
+	"io"
+	"strings"
+	"sync"
+	"time"
+)
+
+const TunnelReconnectAttempts = 3
+const TunnelReconnectDelay = 500 * time.Millisecond
+
+// reconnectingTunnel reopens a dropped SSH tunnel on the same local addresses. Each attempt moves on to the next
+// started app, in case the one in use has gone away.
+type reconnectingTunnel struct {
+	cliConnection plugin.CliConnection
+	sshRunner     SshRunner
+	forwards      []TunnelForward
+	apps          []sdkModels.GetAppsModel
+	appIndex      int
+	logWriter     io.Writer
+	done          chan bool
+
+	mutex   sync.Mutex
+	current SshTunnel
+	closing bool
+	err     error
+}
+
+func newReconnectingTunnel(cliConnection plugin.CliConnection, sshRunner SshRunner, forwards []TunnelForward, apps []sdkModels.GetAppsModel, appIndex int, logWriter io.Writer, tunnel SshTunnel) *reconnectingTunnel {
+	reconnecting := &reconnectingTunnel{
+		cliConnection: cliConnection,
+		sshRunner:     sshRunner,
+		forwards:      forwards,
+		apps:          apps,
+		appIndex:      appIndex,
+		logWriter:     logWriter,
+		done:          make(chan bool),
+		current:       tunnel,
+	}
+	go reconnecting.monitor()
+
+	return reconnecting
+}
+
+func (self *reconnectingTunnel) Close() error {
+	self.mutex.Lock()
+	self.closing = true
+	current := self.current
+	self.mutex.Unlock()
+
+	err := current.Close()
+	<-self.done
+
+	return err
+}
+
+// Wait blocks until the tunnel is closed, or until it dropped and could not be reopened
+func (self *reconnectingTunnel) Wait() error {
+	<-self.done
+
+	self.mutex.Lock()
+	defer self.mutex.Unlock()
+
+	return self.err
+}
+
+func (self *reconnectingTunnel) monitor() {
+	defer close(self.done)
+
+	for {
+		self.mutex.Lock()
+		current := self.current
+		self.mutex.Unlock()
+
+		err := current.Wait()
+		if err == nil || self.isClosing() {
+			return
+		}
+
+		fmt.Fprintf(self.logWriter, "SSH tunnel to %s dropped: %s\n", forwardedServiceNames(self.forwards), err)
+
+		err = self.reconnect()
+		if err != nil {
+			self.mutex.Lock()
+			self.err = err
+			self.mutex.Unlock()
+			return
+		}
+	}
+}
+
+func (self *reconnectingTunnel) reconnect() error {
+	var err error
+
+	for attempt := 0; attempt < TunnelReconnectAttempts; attempt++ {
+		time.Sleep(time.Duration(attempt) * TunnelReconnectDelay)
+		if self.isClosing() {
+			return nil
+		}
+
+		appIndex := (self.appIndex + attempt) % len(self.apps)
+		app := self.apps[appIndex]
+		fmt.Fprintf(self.logWriter, "Reconnecting SSH tunnel to %s through app %s...\n", forwardedServiceNames(self.forwards), app.Name)
+
+		var tunnel SshTunnel
+		tunnel, err = self.sshRunner.OpenSshTunnel(self.cliConnection, self.forwards, app)
+		if err != nil {
+			fmt.Fprintf(self.logWriter, "Unable to reconnect: %s\n", err)
+			continue
+		}
+
+		self.mutex.Lock()
+		if self.closing {
+			self.mutex.Unlock()
+			tunnel.Close()
+			return nil
+		}
+		self.current = tunnel
+		self.appIndex = appIndex
+		self.mutex.Unlock()
+
+		fmt.Fprintf(self.logWriter, "Reconnected SSH tunnel to %s on %s\n", forwardedServiceNames(self.forwards), forwardedAddresses(self.forwards))
+		return nil
+	}
+
+	fmt.Fprintf(self.logWriter, "Giving up on SSH tunnel to %s after %d attempts\n", forwardedServiceNames(self.forwards), TunnelReconnectAttempts)
+	return fmt.Errorf("SSH tunnel dropped and could not be reopened: %s", err)
+}
+
+func (self *reconnectingTunnel) isClosing() bool {
+	self.mutex.Lock()
+	defer self.mutex.Unlock()
+
+	return self.closing
+}
+
+func forwardedServiceNames(forwards []TunnelForward) string {
+	names := make([]string, 0, len(forwards))
+	for _, forward := range forwards {
+		names = append(names, forward.Service.Name)
+	}
+
+	return strings.Join(names, ", ")
+}
+
+func forwardedAddresses(forwards []TunnelForward) string {
+	addresses := make([]string, 0, len(forwards))
+	for _, forward := range forwards {
+		addresses = append(addresses, forward.LocalAddress.String())
+	}
+
+	return strings.Join(addresses, ", ")
+}
@@ -0,0 +1,155 @@
+package resources
+
+import (
+	"errors"
+	"fmt"
+	"github.com/andreasf/cf-mysql-plugin/cfmysql/models"
+	"net/url"
+	"strings"
+)
+
+const DefaultMysqlPort = "3306"
+
+// clientOptionNames maps query parameters of connection URLs to the options of the MySQL client programs. JDBC
+// properties are included under their own names.
+var clientOptionNames = map[string]string{
+	"ssl-mode":              "ssl-mode",
+	"sslMode":               "ssl-mode",
+	"ssl-cipher":            "ssl-cipher",
+	"tls-version":           "tls-version",
+	"enabledTLSProtocols":   "tls-version",
+	"default-character-set": "default-character-set",
+}
+
+// connectionUrl is what a mysql:// or jdbc:mysql:// URL says about a connection
+type connectionUrl struct {
+	Hostname      string
+	Port          string
+	DbName        string
+	Username      string
+	Password      string
+	ClientOptions map[string]string
+}
+
+func parseConnectionUrl(rawUrl string) (connectionUrl, error) {
+	jdbc := strings.HasPrefix(rawUrl, "jdbc:")
+
+	parsedUrl, err := url.Parse(strings.TrimPrefix(rawUrl, "jdbc:"))
+	if err != nil {
+		// the URL itself is left out, as it may contain the password
+		if urlErr, ok := err.(*url.Error); ok {
+			return connectionUrl{}, urlErr.Err
+		}
+		return connectionUrl{}, errors.New("invalid URL")
+	}
+
+	switch parsedUrl.Scheme {
+	case "mysql", "mysql2", "mariadb":
+	default:
+		return connectionUrl{}, fmt.Errorf("unsupported scheme '%s'", parsedUrl.Scheme)
+	}
+
+	if parsedUrl.Hostname() == "" {
+		return connectionUrl{}, errors.New("missing hostname")
+	}
+
+	query := parsedUrl.Query()
+	result := connectionUrl{
+		Hostname: parsedUrl.Hostname(),
+		Port:     parsedUrl.Port(),
+		DbName:   strings.TrimPrefix(parsedUrl.Path, "/"),
+	}
+
+	if result.Port == "" {
+		result.Port = DefaultMysqlPort
+	}
+
+	if parsedUrl.User != nil {
+		result.Username = parsedUrl.User.Username()
+		result.Password, _ = parsedUrl.User.Password()
+	} else if jdbc {
+		result.Username = query.Get("user")
+		result.Password = query.Get("password")
+	}
+
+	for parameter, values := range query {
+		optionName, ok := clientOptionNames[parameter]
+		if !ok || len(values) == 0 {
+			continue
+		}
+		result.setClientOption(optionName, values[0])
+	}
+
+	if useSsl := query.Get("useSSL"); jdbc && useSsl != "" && result.ClientOptions["ssl-mode"] == "" {
+		if useSsl == "true" {
+			result.setClientOption("ssl-mode", "REQUIRED")
+		} else {
+			result.setClientOption("ssl-mode", "DISABLED")
+		}
+	}
+
+	return result, nil
+}
+
+func (self *connectionUrl) setClientOption(name string, value string) {
+	if self.ClientOptions == nil {
+		self.ClientOptions = make(map[string]string)
+	}
+	self.ClientOptions[name] = value
+}
+
+// fillFromConnectionUrls completes the service key with the 'uri' and 'jdbcUrl' credentials. Fields that are set in
+// the key are kept, as are options taken from the first URL. An unparseable URL is only an error if fields are
+// still missing.
+func fillFromConnectionUrls(serviceKey *models.ServiceKey, uri string, jdbcUrl string) error {
+	urls := []struct {
+		name  string
+		value string
+	}{
+		{"uri", uri},
+		{"jdbcUrl", jdbcUrl},
+	}
+
+	for _, rawUrl := range urls {
+		if rawUrl.value == "" {
+			continue
+		}
+
+		parsedUrl, err := parseConnectionUrl(rawUrl.value)
+		if err != nil {
+			if hasConnectionFields(*serviceKey) {
+				continue
+			}
+			return fmt.Errorf("unable to parse %s in service key: %s", rawUrl.name, err)
+		}
+
+		fillIfEmpty(&serviceKey.Hostname, parsedUrl.Hostname)
+		fillIfEmpty(&serviceKey.Port, parsedUrl.Port)
+		fillIfEmpty(&serviceKey.DbName, parsedUrl.DbName)
+		fillIfEmpty(&serviceKey.Username, parsedUrl.Username)
+		fillIfEmpty(&serviceKey.Password, parsedUrl.Password)
+
+		for name, value := range parsedUrl.ClientOptions {
+			if _, ok := serviceKey.ClientOptions[name]; ok {
+				continue
+			}
+			if serviceKey.ClientOptions == nil {
+				serviceKey.ClientOptions = make(map[string]string)
+			}
+			serviceKey.ClientOptions[name] = value
+		}
+	}
+
+	return nil
+}
+
+func hasConnectionFields(serviceKey models.ServiceKey) bool {
+	return serviceKey.Hostname != "" && serviceKey.Port != "" && serviceKey.DbName != "" &&
+		serviceKey.Username != "" && serviceKey.Password != ""
+}
+
+func fillIfEmpty(field *string, value string) {
+	if *field == "" {
+		*field = value
+	}
+}
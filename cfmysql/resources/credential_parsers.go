@@ -0,0 +1,255 @@
+package resources
+
+import (
+	"fmt"
+	"github.com/andreasf/cf-mysql-plugin/cfmysql/models"
+	"strconv"
+	"strings"
+	"unicode"
+)
+
+// CredentialParser reads the credentials of one broker format. Each field lists the credential keys holding it, in
+// order of preference. Nested keys are separated by dots.
+type CredentialParser struct {
+	Name string
+	// Keywords select the parser if the service label or one of the tags contains them as whole words, such as "rds"
+	// in "aws-rds" but not in "cards-db". A keyword of several words, such as "p-mysql", matches them in sequence.
+	Keywords []string
+
+	Hostname []string
+	Port     []string
+	DbName   []string
+	Username []string
+	Password []string
+	CaCert   []string
+
+	// DefaultPort is used if the format does not include the port
+	DefaultPort string
+}
+
+// CredentialParsers are the known broker formats. Other formats can be added by appending to the list.
+var CredentialParsers = []CredentialParser{
+	{
+		Name:     "p-mysql",
+		Keywords: []string{"p-mysql", "p.mysql"},
+		Hostname: []string{"hostname"},
+		Port:     []string{"port"},
+		DbName:   []string{"name"},
+		Username: []string{"username"},
+		Password: []string{"password"},
+		CaCert:   []string{"tls.cert.ca"},
+	},
+	{
+		Name:     "aws-rds",
+		Keywords: []string{"aws", "rds"},
+		Hostname: []string{"host", "hostname", "ENDPOINT_ADDRESS"},
+		Port:     []string{"port", "PORT"},
+		DbName:   []string{"db_name", "name", "DB_NAME"},
+		Username: []string{"username", "MASTER_USERNAME"},
+		Password: []string{"password", "MASTER_PASSWORD"},
+	},
+	{
+		Name:     "azure-mysql",
+		Keywords: []string{"azure"},
+		Hostname: []string{"host"},
+		Port:     []string{"port"},
+		DbName:   []string{"database"},
+		Username: []string{"username"},
+		Password: []string{"password"},
+	},
+	{
+		Name:        "google-cloudsql-mysql",
+		Keywords:    []string{"google", "cloudsql", "gcp"},
+		Hostname:    []string{"host"},
+		DbName:      []string{"database_name"},
+		Username:    []string{"Username", "username"},
+		Password:    []string{"Password", "password"},
+		CaCert:      []string{"CaCert"},
+		DefaultPort: DefaultMysqlPort,
+	},
+	{
+		Name:     "mariadb",
+		Keywords: []string{"mariadb"},
+		Hostname: []string{"host", "hostname"},
+		Port:     []string{"port"},
+		DbName:   []string{"database", "name"},
+		Username: []string{"user", "username"},
+		Password: []string{"password"},
+		CaCert:   []string{"sslca"},
+	},
+}
+
+// ParseCredentials tries each parser in turn, starting with those selected by the service label and tags. Fields
+// missing from the credentials are taken from the 'uri' or 'jdbcUrl' credentials, which can complete the fields of a
+// parser for another format, so a CA certificate missing from the result is looked up with the keys of all parsers.
+// If no parser finds all fields, the error lists the fields the first parser expected.
+func ParseCredentials(credentials map[string]interface{}, label string, tags []string) (models.ServiceKey, error) {
+	parsers := orderParsers(CredentialParsers, label, tags)
+	if len(parsers) == 0 {
+		return models.ServiceKey{}, fmt.Errorf("no credential parsers configured")
+	}
+
+	var firstMissing []string
+	var firstErr error
+
+	for i, parser := range parsers {
+		serviceKey, missing, err := parser.Parse(credentials)
+		if len(missing) == 0 {
+			if serviceKey.CaCert == "" {
+				serviceKey.CaCert = lookupCaCert(credentials, parsers)
+			}
+			return serviceKey, nil
+		}
+
+		if i == 0 {
+			firstMissing = missing
+			firstErr = err
+		}
+	}
+
+	err := fmt.Errorf("credentials do not match the %s format, missing %s", parsers[0].Name, strings.Join(firstMissing, ", "))
+	if firstErr != nil {
+		err = fmt.Errorf("%s (%s)", err, firstErr)
+	}
+
+	return models.ServiceKey{}, err
+}
+
+// Parse reads the fields of the format, returning the fields it expected but could not find, and the error of an
+// unparseable connection URL
+func (self CredentialParser) Parse(credentials map[string]interface{}) (models.ServiceKey, []string, error) {
+	serviceKey := models.ServiceKey{
+		Uri:      lookupCredential(credentials, []string{"uri"}),
+		Hostname: lookupCredential(credentials, self.Hostname),
+		Port:     lookupCredential(credentials, self.Port),
+		DbName:   lookupCredential(credentials, self.DbName),
+		Username: lookupCredential(credentials, self.Username),
+		Password: lookupCredential(credentials, self.Password),
+		CaCert:   lookupCredential(credentials, self.CaCert),
+	}
+
+	urlErr := fillFromConnectionUrls(&serviceKey, serviceKey.Uri, lookupCredential(credentials, []string{"jdbcUrl"}))
+
+	if serviceKey.Port == "" {
+		serviceKey.Port = self.DefaultPort
+	}
+
+	var missing []string
+	fields := []struct {
+		value string
+		keys  []string
+	}{
+		{serviceKey.Hostname, self.Hostname},
+		{serviceKey.Port, self.Port},
+		{serviceKey.DbName, self.DbName},
+		{serviceKey.Username, self.Username},
+		{serviceKey.Password, self.Password},
+	}
+	for _, field := range fields {
+		if field.value == "" {
+			missing = append(missing, strings.Join(field.keys, " or "))
+		}
+	}
+
+	return serviceKey, missing, urlErr
+}
+
+// lookupCaCert returns the CA certificate under the keys of the first parser that has one
+func lookupCaCert(credentials map[string]interface{}, parsers []CredentialParser) string {
+	for _, parser := range parsers {
+		caCert := lookupCredential(credentials, parser.CaCert)
+		if caCert != "" {
+			return caCert
+		}
+	}
+
+	return ""
+}
+
+// orderParsers moves the parsers whose keywords appear in the label or tags to the front
+func orderParsers(parsers []CredentialParser, label string, tags []string) []CredentialParser {
+	candidates := append([]string{label}, tags...)
+	var selected []CredentialParser
+	var others []CredentialParser
+
+	for _, parser := range parsers {
+		if parser.matches(candidates) {
+			selected = append(selected, parser)
+		} else {
+			others = append(others, parser)
+		}
+	}
+
+	return append(selected, others...)
+}
+
+func (self CredentialParser) matches(candidates []string) bool {
+	for _, candidate := range candidates {
+		words := splitWords(candidate)
+
+		for _, keyword := range self.Keywords {
+			if containsWords(words, splitWords(keyword)) {
+				return true
+			}
+		}
+	}
+
+	return false
+}
+
+// splitWords splits a label or tag into lowercase words at anything but letters and digits
+func splitWords(value string) []string {
+	return strings.FieldsFunc(strings.ToLower(value), func(char rune) bool {
+		return !unicode.IsLetter(char) && !unicode.IsDigit(char)
+	})
+}
+
+// containsWords tells whether the sequence appears in the words without gaps
+func containsWords(words []string, sequence []string) bool {
+	if len(sequence) == 0 {
+		return false
+	}
+
+	for start := 0; start+len(sequence) <= len(words); start++ {
+		found := true
+		for i, word := range sequence {
+			if words[start+i] != word {
+				found = false
+				break
+			}
+		}
+
+		if found {
+			return true
+		}
+	}
+
+	return false
+}
+
+// lookupCredential returns the first of the keys that is set to a string or a number
+func lookupCredential(credentials map[string]interface{}, keys []string) string {
+	for _, key := range keys {
+		var value interface{} = credentials
+
+		for _, part := range strings.Split(key, ".") {
+			object, ok := value.(map[string]interface{})
+			if !ok {
+				value = nil
+				break
+			}
+			value = object[part]
+		}
+
+		switch typedValue := value.(type) {
+		case string:
+			if typedValue != "" {
+				return typedValue
+			}
+		case float64:
+			return strconv.FormatFloat(typedValue, 'f', -1, 64)
+		}
+	}
+
+	return ""
+}
@@ -2,10 +2,9 @@ package resources
 
 import (
 	"code.cloudfoundry.org/cli/cf/api/resources"
+	sdkModels "code.cloudfoundry.org/cli/plugin/models"
 	"encoding/json"
-	"fmt"
 	"github.com/andreasf/cf-mysql-plugin/cfmysql/models"
-	"strconv"
 	"strings"
 )
 
@@ -20,23 +19,23 @@ type ServiceBindingEntity struct {
 	Credentials         MysqlCredentials `json:"credentials"`
 }
 
+// MysqlCredentials are the credentials of a service key, read by the CredentialParsers
 type MysqlCredentials struct {
-	Uri      string `json:"uri"`
-	DbName   string `json:"name"`
-	Hostname string `json:"hostname"`
-	Port     string
-	RawPort  json.RawMessage `json:"port"`
-	Username string          `json:"username"`
-	Password string          `json:"password"`
-	Tls      TlsResource     `json:"tls"`
+	// CredhubRef is set instead of the other credentials by brokers that store them in CredHub
+	CredhubRef string `json:"credhub-ref"`
+	// Raw holds all credentials
+	Raw map[string]interface{} `json:"-"`
 }
 
-type TlsResource struct {
-	Cert TlsCertResource `json:"cert"`
-}
+func (self *MysqlCredentials) UnmarshalJSON(data []byte) error {
+	type plainCredentials MysqlCredentials
+
+	err := json.Unmarshal(data, (*plainCredentials)(self))
+	if err != nil {
+		return err
+	}
 
-type TlsCertResource struct {
-	Ca string `json:"ca"`
+	return json.Unmarshal(data, &self.Raw)
 }
 
 type PaginatedServiceInstanceResources struct {
@@ -62,15 +61,17 @@ type ServiceKeyResource struct {
 }
 
 type ServiceKeyEntity struct {
-	ServiceInstanceName string `json:"name"`
+	Name                string `json:"name"`
 	ServiceInstanceGuid string `json:"service_instance_guid"`
 	Credentials         MysqlCredentials
 }
 
 type ServiceInstanceEntity struct {
 	Name            string                         `json:"name"`
+	Type            string                         `json:"type"`
 	DashboardURL    string                         `json:"dashboard_url"`
 	Tags            []string                       `json:"tags"`
+	ServicePlanGuid string                         `json:"service_plan_guid"`
 	ServiceBindings []ServiceBindingResource       `json:"service_bindings"`
 	ServiceKeys     []resources.ServiceKeyResource `json:"service_keys"`
 	ServicePlan     resources.ServicePlanResource  `json:"service_plan"`
@@ -78,6 +79,100 @@ type ServiceInstanceEntity struct {
 	SpaceUrl        string                         `json:"space_url"`
 }
 
+type ServicePlanResource struct {
+	resources.Resource
+	Entity ServicePlanEntity
+}
+
+type ServicePlanEntity struct {
+	Name        string `json:"name"`
+	ServiceGuid string `json:"service_guid"`
+}
+
+type ServiceResource struct {
+	resources.Resource
+	Entity ServiceEntity
+}
+
+type ServiceEntity struct {
+	Label string   `json:"label"`
+	Tags  []string `json:"tags"`
+}
+
+type AppResource struct {
+	resources.Resource
+	Entity AppEntity
+}
+
+type AppEntity struct {
+	Name      string `json:"name"`
+	State     string `json:"state"`
+	EnableSsh bool   `json:"enable_ssh"`
+}
+
+type PaginatedAppResources struct {
+	NextUrl   string `json:"next_url"`
+	Resources []AppResource
+}
+
+func (self *PaginatedAppResources) ToModel() []sdkModels.GetAppsModel {
+	var apps []sdkModels.GetAppsModel
+	for _, resource := range self.Resources {
+		apps = append(apps, sdkModels.GetAppsModel{
+			Name:  resource.Entity.Name,
+			Guid:  resource.Metadata.GUID,
+			State: strings.ToLower(resource.Entity.State),
+		})
+	}
+
+	return apps
+}
+
+// PaginatedNamedResources is a list of organizations or spaces
+type PaginatedNamedResources struct {
+	NextUrl   string `json:"next_url"`
+	Resources []NamedResource
+}
+
+type NamedResource struct {
+	resources.Resource
+	Entity struct {
+		Name string `json:"name"`
+	}
+}
+
+// SharedFromResource describes the space a service instance was shared from. The API returns no content for
+// instances that are not shared.
+type SharedFromResource struct {
+	SpaceGuid        string `json:"space_guid"`
+	SpaceName        string `json:"space_name"`
+	OrganizationName string `json:"organization_name"`
+}
+
+// CredhubDataResource is the response of CredHub to a request for the current version of a credential
+type CredhubDataResource struct {
+	Data []struct {
+		Type  string           `json:"type"`
+		Value MysqlCredentials `json:"value"`
+	} `json:"data"`
+}
+
+type InfoResource struct {
+	AppSshEndpoint           string `json:"app_ssh_endpoint"`
+	AppSshHostKeyFingerprint string `json:"app_ssh_host_key_fingerprint"`
+	AppSshOauthClient        string `json:"app_ssh_oauth_client"`
+	TokenEndpoint            string `json:"token_endpoint"`
+}
+
+func (self *InfoResource) ToSshInfo() models.SshInfo {
+	return models.SshInfo{
+		Endpoint:           self.AppSshEndpoint,
+		HostKeyFingerprint: self.AppSshHostKeyFingerprint,
+		OauthClient:        self.AppSshOauthClient,
+		TokenEndpoint:      self.TokenEndpoint,
+	}
+}
+
 func (self *PaginatedServiceInstanceResources) ToModel() []models.ServiceInstance {
 	var convertedModels []models.ServiceInstance
 
@@ -85,57 +180,51 @@ func (self *PaginatedServiceInstanceResources) ToModel() []models.ServiceInstanc
 		model := models.ServiceInstance{}
 		model.Guid = resource.Metadata.GUID
 		model.Name = resource.Entity.Name
+		model.PlanGuid = resource.Entity.ServicePlanGuid
+		model.Tags = resource.Entity.Tags
+		model.UserProvided = resource.Entity.Type == "user_provided_service_instance"
+
+		lastOperation := resource.Entity.LastOperation
+		model.LastOperation = strings.TrimSpace(lastOperation.Type + " " + lastOperation.State)
 
 		pathParts := strings.Split(resource.Entity.SpaceUrl, "/")
 		model.SpaceGuid = pathParts[len(pathParts)-1]
 
+		for _, binding := range resource.Entity.ServiceBindings {
+			model.BoundAppGuids = append(model.BoundAppGuids, binding.Entity.AppGUID)
+		}
+
 		convertedModels = append(convertedModels, model)
 	}
 
 	return convertedModels
 }
 
-func (self *PaginatedServiceKeyResources) ToModel() ([]models.ServiceKey, error) {
+func (self *PaginatedServiceKeyResources) ToModel() []models.ServiceKey {
 	var convertedModels []models.ServiceKey
 
 	for _, resource := range self.Resources {
-		model, err := resource.ToModel()
-		if err != nil {
-			return nil, err
-		}
-
-		convertedModels = append(convertedModels, model)
+		convertedModels = append(convertedModels, resource.ToModel())
 	}
 
-	return convertedModels, nil
+	return convertedModels
 }
 
-func (self *ServiceKeyResource) ToModel() (models.ServiceKey, error) {
-	var port string
-
-	if len(self.Entity.Credentials.RawPort) > 0 {
-		var portInt int
-		var portString string
+func (self *ServiceKeyResource) ToModel() models.ServiceKey {
+	serviceKey := self.Entity.Credentials.ToModel()
+	serviceKey.Guid = self.Metadata.GUID
+	serviceKey.Name = self.Entity.Name
+	serviceKey.ServiceInstanceGuid = self.Entity.ServiceInstanceGuid
 
-		err := json.Unmarshal(self.Entity.Credentials.RawPort, &portString)
-		if err != nil {
-			err = json.Unmarshal(self.Entity.Credentials.RawPort, &portInt)
-			if err != nil {
-				return models.ServiceKey{}, fmt.Errorf("unable to deserialize port in service key: '%s'", string(self.Entity.Credentials.RawPort))
-			}
-			portString = strconv.Itoa(portInt)
-		}
-		port = portString
-	}
+	return serviceKey
+}
 
+// ToModel converts the credentials to a service key model, without the fields identifying the key. The credentials are
+// only read by ParseCredentials, once the service they belong to is known, so that keys of other services can be
+// listed.
+func (self *MysqlCredentials) ToModel() models.ServiceKey {
 	return models.ServiceKey{
-		ServiceInstanceGuid: self.Entity.ServiceInstanceGuid,
-		Uri:                 self.Entity.Credentials.Uri,
-		DbName:              self.Entity.Credentials.DbName,
-		Hostname:            self.Entity.Credentials.Hostname,
-		Port:                port,
-		Username:            self.Entity.Credentials.Username,
-		Password:            self.Entity.Credentials.Password,
-		CaCert:              self.Entity.Credentials.Tls.Cert.Ca,
-	}, nil
+		CredhubRef:  self.CredhubRef,
+		Credentials: self.Raw,
+	}
 }
@@ -0,0 +1,228 @@
+package resources
+
+import (
+	sdkModels "code.cloudfoundry.org/cli/plugin/models"
+	"github.com/andreasf/cf-mysql-plugin/cfmysql/models"
+	"strings"
+)
+
+// RootResource is the API root, which links to the API versions and other components of the foundation
+type RootResource struct {
+	Links RootLinks `json:"links"`
+}
+
+type RootLinks struct {
+	CloudControllerV2 *LinkResource      `json:"cloud_controller_v2"`
+	CloudControllerV3 *LinkResource      `json:"cloud_controller_v3"`
+	AppSsh            AppSshLinkResource `json:"app_ssh"`
+	Uaa               LinkResource       `json:"uaa"`
+	Credhub           LinkResource       `json:"credhub"`
+}
+
+type LinkResource struct {
+	Href string `json:"href"`
+}
+
+type AppSshLinkResource struct {
+	Href string `json:"href"`
+	Meta struct {
+		HostKeyFingerprint string `json:"host_key_fingerprint"`
+		OauthClient        string `json:"oauth_client"`
+	} `json:"meta"`
+}
+
+func (self *RootResource) ToSshInfo() models.SshInfo {
+	return models.SshInfo{
+		Endpoint:           self.Links.AppSsh.Href,
+		HostKeyFingerprint: self.Links.AppSsh.Meta.HostKeyFingerprint,
+		OauthClient:        self.Links.AppSsh.Meta.OauthClient,
+		TokenEndpoint:      self.Links.Uaa.Href,
+	}
+}
+
+type PaginationV3 struct {
+	TotalResults int           `json:"total_results"`
+	Next         *LinkResource `json:"next"`
+}
+
+// NextUrl returns the URL of the next page, or an empty string on the last page
+func (self *PaginationV3) NextUrl() string {
+	if self.Next == nil {
+		return ""
+	}
+
+	return self.Next.Href
+}
+
+type RelationshipV3 struct {
+	Data struct {
+		Guid string `json:"guid"`
+	} `json:"data"`
+}
+
+type LastOperationV3 struct {
+	Type  string `json:"type"`
+	State string `json:"state"`
+}
+
+type PaginatedServiceInstanceResourcesV3 struct {
+	Pagination PaginationV3                `json:"pagination"`
+	Resources  []ServiceInstanceResourceV3 `json:"resources"`
+	Included   struct {
+		Spaces []NamedResourceV3 `json:"spaces"`
+	} `json:"included"`
+}
+
+type NamedResourceV3 struct {
+	Guid string `json:"guid"`
+	Name string `json:"name"`
+}
+
+type ServiceInstanceResourceV3 struct {
+	Guid          string          `json:"guid"`
+	Name          string          `json:"name"`
+	Type          string          `json:"type"`
+	Tags          []string        `json:"tags"`
+	LastOperation LastOperationV3 `json:"last_operation"`
+	Relationships struct {
+		Space       RelationshipV3 `json:"space"`
+		ServicePlan RelationshipV3 `json:"service_plan"`
+	} `json:"relationships"`
+}
+
+type ServicePlanResourceV3 struct {
+	Guid          string `json:"guid"`
+	Name          string `json:"name"`
+	Relationships struct {
+		ServiceOffering RelationshipV3 `json:"service_offering"`
+	} `json:"relationships"`
+}
+
+type ServiceOfferingResourceV3 struct {
+	Guid string   `json:"guid"`
+	Name string   `json:"name"`
+	Tags []string `json:"tags"`
+}
+
+type PaginatedCredentialBindingResourcesV3 struct {
+	Pagination PaginationV3                  `json:"pagination"`
+	Resources  []CredentialBindingResourceV3 `json:"resources"`
+}
+
+// CredentialBindingResourceV3 is a service key or an app binding. The credentials are retrieved separately.
+type CredentialBindingResourceV3 struct {
+	Guid          string          `json:"guid"`
+	Name          string          `json:"name"`
+	Type          string          `json:"type"`
+	LastOperation LastOperationV3 `json:"last_operation"`
+	Relationships struct {
+		App             RelationshipV3 `json:"app"`
+		ServiceInstance RelationshipV3 `json:"service_instance"`
+	} `json:"relationships"`
+}
+
+type CredentialBindingDetailsResourceV3 struct {
+	Credentials MysqlCredentials `json:"credentials"`
+}
+
+type PaginatedAppResourcesV3 struct {
+	Pagination PaginationV3    `json:"pagination"`
+	Resources  []AppResourceV3 `json:"resources"`
+}
+
+type AppResourceV3 struct {
+	Guid  string `json:"guid"`
+	Name  string `json:"name"`
+	State string `json:"state"`
+}
+
+func (self *PaginatedAppResourcesV3) ToModel() []sdkModels.GetAppsModel {
+	var apps []sdkModels.GetAppsModel
+	for _, resource := range self.Resources {
+		apps = append(apps, sdkModels.GetAppsModel{
+			Name:  resource.Name,
+			Guid:  resource.Guid,
+			State: strings.ToLower(resource.State),
+		})
+	}
+
+	return apps
+}
+
+// PaginatedNamedResourcesV3 is a list of organizations or spaces
+type PaginatedNamedResourcesV3 struct {
+	Pagination PaginationV3      `json:"pagination"`
+	Resources  []NamedResourceV3 `json:"resources"`
+}
+
+type AppSshEnabledResourceV3 struct {
+	Enabled bool   `json:"enabled"`
+	Reason  string `json:"reason"`
+}
+
+type JobResourceV3 struct {
+	Guid   string `json:"guid"`
+	State  string `json:"state"`
+	Errors []struct {
+		Detail string `json:"detail"`
+	} `json:"errors"`
+}
+
+// Error returns the details of the errors of a failed job
+func (self *JobResourceV3) Error() string {
+	var details []string
+	for _, jobError := range self.Errors {
+		details = append(details, jobError.Detail)
+	}
+
+	return strings.Join(details, ", ")
+}
+
+func (self *PaginatedServiceInstanceResourcesV3) ToModel() []models.ServiceInstance {
+	var convertedModels []models.ServiceInstance
+
+	for _, resource := range self.Resources {
+		lastOperation := resource.LastOperation
+
+		convertedModels = append(convertedModels, models.ServiceInstance{
+			Name:          resource.Name,
+			Guid:          resource.Guid,
+			SpaceGuid:     resource.Relationships.Space.Data.Guid,
+			PlanGuid:      resource.Relationships.ServicePlan.Data.Guid,
+			Tags:          resource.Tags,
+			LastOperation: strings.TrimSpace(lastOperation.Type + " " + lastOperation.State),
+			UserProvided:  resource.Type == "user-provided",
+		})
+	}
+
+	return convertedModels
+}
+
+// SpaceNames maps the guids of the spaces included in the response to their names
+func (self *PaginatedServiceInstanceResourcesV3) SpaceNames() map[string]string {
+	spaceNames := make(map[string]string)
+	for _, space := range self.Included.Spaces {
+		spaceNames[space.Guid] = space.Name
+	}
+
+	return spaceNames
+}
+
+// ToModel converts a service key without its credentials
+func (self *CredentialBindingResourceV3) ToModel() models.ServiceKey {
+	return models.ServiceKey{
+		Guid:                self.Guid,
+		Name:                self.Name,
+		ServiceInstanceGuid: self.Relationships.ServiceInstance.Data.Guid,
+	}
+}
+
+// ToModel converts a service key with the credentials from its details
+func (self *CredentialBindingDetailsResourceV3) ToModel(binding CredentialBindingResourceV3) models.ServiceKey {
+	serviceKey := self.Credentials.ToModel()
+	serviceKey.Guid = binding.Guid
+	serviceKey.Name = binding.Name
+	serviceKey.ServiceInstanceGuid = binding.Relationships.ServiceInstance.Data.Guid
+
+	return serviceKey
+}
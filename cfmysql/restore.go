@@ -0,0 +1,497 @@
+package cfmysql
+
+import (
+	"bufio"
+	"bytes"
+	"code.cloudfoundry.org/cli/plugin"
+	"compress/gzip"
+	"flag"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"regexp"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+const RestoreProgressInterval = 500 * time.Millisecond
+
+const scannedWordLength = 8
+
+var failedLinePattern = regexp.MustCompile(`ERROR \d+ \([0-9A-Z]+\) at line (\d+)`)
+
+func (self *MysqlPlugin) restore(cliConnection plugin.CliConnection, args []string) {
+	flags := flag.NewFlagSet("mysql-restore", flag.ContinueOnError)
+	flags.SetOutput(ioutil.Discard)
+	ephemeral := flags.Bool("ephemeral", false, "")
+	app := addAppChoiceFlags(flags)
+	target := addSpaceTargetFlags(flags)
+
+	err := flags.Parse(args)
+	if err != nil || flags.NArg() < 2 || !app.valid() || !target.valid(*app) {
+		fmt.Fprint(self.Err, self.FormatUsage())
+		self.setErrorExit()
+		return
+	}
+
+	cliConnection, ok := self.targetConnection(cliConnection, *target)
+	if !ok {
+		return
+	}
+
+	dbName := flags.Arg(0)
+	dumpPath := flags.Arg(1)
+	mysqlArgs := flags.Args()[2:]
+
+	dumpFile, err := self.OsWrapper.Open(dumpPath)
+	if err != nil {
+		fmt.Fprintf(self.Err, "FAILED\nUnable to open dump file: %s\n", err)
+		self.setErrorExit()
+		return
+	}
+	defer dumpFile.Close()
+
+	var dumpSize int64
+	fileInfo, err := dumpFile.Stat()
+	if err == nil {
+		dumpSize = fileInfo.Size()
+	}
+
+	progress, err := newRestoreProgress(dumpFile, dumpSize)
+	if err != nil {
+		fmt.Fprintf(self.Err, "FAILED\nUnable to read dump file: %s\n", err)
+		self.setErrorExit()
+		return
+	}
+
+	if *ephemeral || app.Push {
+		defer self.ignoreInterrupts()()
+	}
+
+	service, tunnel, tunnelAddress, ok := self.openTunnel(cliConnection, dbName, *ephemeral, LocalAddress{Host: DefaultLocalHost}, *app)
+	if *ephemeral && service.ServiceKeyGuid != "" {
+		defer self.deleteEphemeralKey(cliConnection, service)
+	}
+	if !ok {
+		return
+	}
+	defer tunnel.Close()
+
+	fmt.Fprintf(self.Err, "Restoring %s into %s...\n", dumpPath, dbName)
+
+	clientErrors := new(bytes.Buffer)
+	clientIo := ClientIo{
+		Stdin:  progress,
+		Stdout: self.Out,
+		Stderr: clientErrors,
+	}
+
+	stopReporting := reportProgress(self.Err, progress, RestoreProgressInterval)
+	err = self.MysqlRunner.RunTool(MysqlTool, clientIo, tunnelAddress, service.DbName, service.Username, service.Password, service.CaCert, service.withClientArgs(mysqlArgs)...)
+	stopReporting()
+
+	if err == nil {
+		fmt.Fprintf(self.Err, "\r%s\n", progress)
+		fmt.Fprint(self.Err, "OK\n")
+		return
+	}
+
+	// mysql reads ahead of the statement it runs, so the statements read are more than those applied
+	match := failedLinePattern.FindSubmatch(clientErrors.Bytes())
+	var failedLine int
+	if match != nil {
+		failedLine, _ = strconv.Atoi(string(match[1]))
+	}
+	if failedLine > 0 {
+		applied, countErr := countStatementsBefore(dumpFile, failedLine)
+		if countErr == nil {
+			progress.setStatements(applied)
+		}
+	}
+
+	fmt.Fprintf(self.Err, "\r%s\n", progress)
+	self.Err.Write(clientErrors.Bytes())
+	fmt.Fprint(self.Err, "FAILED\n")
+	if failedLine > 0 {
+		fmt.Fprintf(self.Err, "Restore stopped at line %d of %s\n", failedLine, dumpPath)
+	} else {
+		fmt.Fprintf(self.Err, "%s\n", err)
+	}
+	self.setClientErrorExit(err)
+}
+
+// countStatementsBefore reads the dump again from the start and counts the statements that end before the given line.
+// mysql reports the failing statement by its line, so these are the statements it applied. Statements ending on the
+// same line as the failing statement starts are not counted.
+func countStatementsBefore(dumpFile io.ReadSeeker, line int) (int, error) {
+	_, err := dumpFile.Seek(0, io.SeekStart)
+	if err != nil {
+		return 0, err
+	}
+
+	progress, err := newRestoreProgress(dumpFile, 0)
+	if err != nil {
+		return 0, err
+	}
+	progress.scanner.stopLine = line
+
+	buffer := make([]byte, 32*1024)
+	for progress.scanner.line < line {
+		_, err := progress.Read(buffer)
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return 0, err
+		}
+	}
+
+	return progress.scanner.statements, nil
+}
+
+// restoreProgress passes a dump through to the client and keeps track of how much of it has been read
+type restoreProgress struct {
+	file     *countingReader
+	fileSize int64
+	dump     io.Reader
+	mutex    sync.Mutex
+	bytes    int64
+	scanner  statementScanner
+}
+
+func newRestoreProgress(dumpFile io.Reader, dumpSize int64) (*restoreProgress, error) {
+	file := &countingReader{reader: dumpFile}
+	buffered := bufio.NewReader(file)
+
+	var dump io.Reader = buffered
+	magic, _ := buffered.Peek(2)
+	if bytes.Equal(magic, []byte{0x1f, 0x8b}) {
+		gzipReader, err := gzip.NewReader(buffered)
+		if err != nil {
+			return nil, fmt.Errorf("error reading gzip header: %s", err)
+		}
+		dump = gzipReader
+	}
+
+	return &restoreProgress{
+		file:     file,
+		fileSize: dumpSize,
+		dump:     dump,
+		scanner:  newStatementScanner(),
+	}, nil
+}
+
+func (self *restoreProgress) Read(p []byte) (int, error) {
+	n, err := self.dump.Read(p)
+
+	self.mutex.Lock()
+	self.bytes += int64(n)
+	self.scanner.scan(p[:n])
+	self.mutex.Unlock()
+
+	return n, err
+}
+
+func (self *restoreProgress) setStatements(statements int) {
+	self.mutex.Lock()
+	self.scanner.statements = statements
+	self.mutex.Unlock()
+}
+
+func (self *restoreProgress) String() string {
+	self.mutex.Lock()
+	defer self.mutex.Unlock()
+
+	status := fmt.Sprintf("%s, %d statements", formatBytes(self.bytes), self.scanner.statements)
+	if self.fileSize > 0 {
+		percent := self.file.count() * 100 / self.fileSize
+		status = fmt.Sprintf("%3d%% (%s)", percent, status)
+	}
+
+	return status
+}
+
+// reportProgress periodically overwrites the current line of writer with the progress, until the returned func is
+// called
+func reportProgress(writer io.Writer, progress fmt.Stringer, interval time.Duration) func() {
+	done := make(chan struct{})
+	stopped := make(chan struct{})
+
+	go func() {
+		defer close(stopped)
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+
+		for {
+			select {
+			case <-ticker.C:
+				fmt.Fprintf(writer, "\r%s", progress)
+			case <-done:
+				return
+			}
+		}
+	}()
+
+	return func() {
+		close(done)
+		<-stopped
+	}
+}
+
+type countingReader struct {
+	reader io.Reader
+	mutex  sync.Mutex
+	read   int64
+}
+
+func (self *countingReader) Read(p []byte) (int, error) {
+	n, err := self.reader.Read(p)
+
+	self.mutex.Lock()
+	self.read += int64(n)
+	self.mutex.Unlock()
+
+	return n, err
+}
+
+func (self *countingReader) count() int64 {
+	self.mutex.Lock()
+	defer self.mutex.Unlock()
+
+	return self.read
+}
+
+const (
+	scanCode = iota
+	scanSingleQuote
+	scanDoubleQuote
+	scanBacktick
+	scanLineComment
+	scanBlockComment
+)
+
+const delimiterCommand = "DELIMITER "
+
+// statementScanner counts SQL statements in a stream, skipping quoted strings and comments. It understands the
+// DELIMITER command used by mysqldump for stored routines and triggers. Rows are counted from the value lists of
+// INSERT and REPLACE statements. If stopLine is set, scanning stops at the beginning of that line.
+type statementScanner struct {
+	statements int
+	rows       int
+	line       int
+	stopLine   int
+	state      int
+	escaped    bool
+	dashes     bool
+	previous   byte
+	delimiter  string
+	matched    int
+	lineStart  bool
+	pending    []byte
+	word       []byte
+	firstWord  bool
+	insert     bool
+	values     bool
+	depth      int
+}
+
+func newStatementScanner() statementScanner {
+	return statementScanner{
+		delimiter: ";",
+		line:      1,
+		lineStart: true,
+		firstWord: true,
+	}
+}
+
+func (self *statementScanner) scan(data []byte) {
+	for _, c := range data {
+		if self.stopLine > 0 && self.line >= self.stopLine {
+			return
+		}
+		if c == '\n' {
+			self.line++
+		}
+
+		if self.lineStart && self.state == scanCode {
+			self.scanLineStart(c)
+		} else {
+			self.scanByte(c)
+		}
+	}
+}
+
+// scanLineStart holds back the beginning of a line until it is clear whether it is a DELIMITER command
+func (self *statementScanner) scanLineStart(c byte) {
+	self.pending = append(self.pending, c)
+
+	prefixLength := len(self.pending)
+	if prefixLength > len(delimiterCommand) {
+		prefixLength = len(delimiterCommand)
+	}
+
+	if !strings.EqualFold(string(self.pending[:prefixLength]), delimiterCommand[:prefixLength]) {
+		pending := self.pending
+		self.pending = nil
+		for _, pendingByte := range pending {
+			self.scanByte(pendingByte)
+		}
+		return
+	}
+
+	if c == '\n' {
+		delimiter := strings.TrimSpace(string(self.pending[len(delimiterCommand):]))
+		if delimiter != "" {
+			self.delimiter = delimiter
+			self.matched = 0
+		}
+		self.pending = nil
+		self.previous = c
+	}
+}
+
+func (self *statementScanner) scanByte(c byte) {
+	switch self.state {
+	case scanSingleQuote, scanDoubleQuote, scanBacktick:
+		self.scanQuoted(c)
+
+	case scanLineComment:
+		if c == '\n' {
+			self.state = scanCode
+		}
+
+	case scanBlockComment:
+		if self.previous == '*' && c == '/' {
+			self.state = scanCode
+			c = 0
+		}
+
+	default:
+		self.scanCode(c)
+	}
+
+	self.previous = c
+	self.lineStart = c == '\n'
+}
+
+func (self *statementScanner) scanQuoted(c byte) {
+	if self.escaped {
+		self.escaped = false
+		return
+	}
+
+	switch {
+	case c == '\\' && self.state != scanBacktick:
+		self.escaped = true
+	case c == '\'' && self.state == scanSingleQuote,
+		c == '"' && self.state == scanDoubleQuote,
+		c == '`' && self.state == scanBacktick:
+		self.state = scanCode
+	}
+}
+
+func (self *statementScanner) scanCode(c byte) {
+	// "--" only starts a comment if it is followed by whitespace or a control character, "1--1" is a subtraction
+	if self.dashes {
+		self.dashes = false
+		if c <= ' ' {
+			if c != '\n' {
+				self.state = scanLineComment
+			}
+			return
+		}
+	}
+
+	if c == self.delimiter[self.matched] {
+		self.matched++
+		if self.matched == len(self.delimiter) {
+			self.endStatement()
+		}
+		return
+	}
+	self.matched = 0
+
+	self.scanRows(c)
+
+	switch {
+	case c == '\'':
+		self.state = scanSingleQuote
+	case c == '"':
+		self.state = scanDoubleQuote
+	case c == '`':
+		self.state = scanBacktick
+	case c == '#':
+		self.state = scanLineComment
+	case c == '-' && self.previous == '-':
+		self.dashes = true
+	case c == '*' && self.previous == '/':
+		self.state = scanBlockComment
+	}
+}
+
+func (self *statementScanner) endStatement() {
+	self.statements++
+	self.matched = 0
+	self.word = self.word[:0]
+	self.firstWord = true
+	self.insert = false
+	self.values = false
+	self.depth = 0
+}
+
+// scanRows counts the parenthesized rows following the VALUES keyword of INSERT and REPLACE statements
+func (self *statementScanner) scanRows(c byte) {
+	if isWordByte(c) {
+		if len(self.word) < scannedWordLength {
+			self.word = append(self.word, c)
+		}
+		return
+	}
+
+	if len(self.word) > 0 {
+		word := strings.ToUpper(string(self.word))
+		if self.firstWord {
+			self.insert = word == "INSERT" || word == "REPLACE"
+			self.firstWord = false
+		}
+		if word == "VALUES" || word == "VALUE" {
+			self.values = self.insert
+		}
+		self.word = self.word[:0]
+	}
+
+	switch c {
+	case '(':
+		if self.values && self.depth == 0 {
+			self.rows++
+		}
+		self.depth++
+	case ')':
+		if self.depth > 0 {
+			self.depth--
+		}
+	}
+}
+
+func isWordByte(c byte) bool {
+	return c >= 'a' && c <= 'z' || c >= 'A' && c <= 'Z' || c >= '0' && c <= '9' || c == '_'
+}
+
+func formatBytes(count int64) string {
+	units := []string{"B", "KiB", "MiB", "GiB", "TiB"}
+	value := float64(count)
+	unit := 0
+
+	for value >= 1024 && unit < len(units)-1 {
+		value /= 1024
+		unit++
+	}
+
+	if unit == 0 {
+		return strconv.FormatInt(count, 10) + " B"
+	}
+
+	return fmt.Sprintf("%.1f %s", value, units[unit])
+}
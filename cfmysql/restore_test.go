@@ -0,0 +1,277 @@
+package cfmysql_test
+
+import (
+	"code.cloudfoundry.org/cli/plugin/models"
+	"compress/gzip"
+	"fmt"
+	. "github.com/andreasf/cf-mysql-plugin/cfmysql"
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+	"github.com/onsi/gomega/gbytes"
+	"io"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing/iotest"
+)
+
+var _ = Describe("Restore", func() {
+	var serviceA MysqlService
+	var appList []plugin_models.GetAppsModel
+	var tempDir string
+
+	dump := "-- MySQL dump\n" +
+		"/*!40101 SET NAMES utf8 */;\n" +
+		"CREATE TABLE `t` (`a` text);\n" +
+		"INSERT INTO `t` VALUES ('semi;colon'),('it\\'s'),(\"--\");\n" +
+		"DELIMITER ;;\n" +
+		"CREATE TRIGGER `trg` BEFORE INSERT ON `t` FOR EACH ROW BEGIN SET NEW.a = 'x'; END ;;\n" +
+		"DELIMITER ;\n" +
+		"# comment; with a semicolon\n" +
+		"DROP TABLE `t`;\n"
+
+	writeDump := func(name string, contents string, compress bool) string {
+		path := filepath.Join(tempDir, name)
+		file, err := os.Create(path)
+		Expect(err).To(BeNil())
+		defer file.Close()
+
+		var writer io.Writer = file
+		if compress {
+			gzipWriter := gzip.NewWriter(file)
+			defer gzipWriter.Close()
+			writer = gzipWriter
+		}
+
+		_, err = io.WriteString(writer, contents)
+		Expect(err).To(BeNil())
+
+		return path
+	}
+
+	openFile := func(name string) (*os.File, error) {
+		return os.Open(name)
+	}
+
+	BeforeEach(func() {
+		var err error
+		tempDir, err = ioutil.TempDir("", "cf-mysql-restore-test")
+		Expect(err).To(BeNil())
+
+		serviceA = MysqlService{
+			Name:     "database-a",
+			Hostname: "database-a.host",
+			Port:     "123",
+			DbName:   "dbname-a",
+			Username: "username",
+			Password: "password",
+			CaCert:   "ca-cert",
+		}
+
+		appList = []plugin_models.GetAppsModel{
+			{
+				Name: "app-name-1",
+			},
+		}
+	})
+
+	AfterEach(func() {
+		os.RemoveAll(tempDir)
+	})
+
+	Context("When calling 'cf mysql-restore' without a dump file", func() {
+		It("Prints usage information to STDERR and exits with 1", func() {
+			mysqlPlugin, mocks := NewPluginAndMocks()
+
+			mysqlPlugin.Run(mocks.CliConnection, []string{"mysql-restore", "database-a"})
+
+			Expect(string(mocks.Err.Contents())).To(Equal(mysqlPlugin.FormatUsage()))
+			Expect(mysqlPlugin.GetExitCode()).To(Equal(1))
+		})
+	})
+
+	Context("When the dump file cannot be opened", func() {
+		It("Shows an error message and exits with 1", func() {
+			mysqlPlugin, mocks := NewPluginAndMocks()
+			mocks.OsWrapper.OpenStub = openFile
+
+			mysqlPlugin.Run(mocks.CliConnection, []string{"mysql-restore", "database-a", filepath.Join(tempDir, "missing.sql")})
+
+			Expect(mocks.Err).To(gbytes.Say("^FAILED\nUnable to open dump file: open .*missing.sql: no such file or directory\n$"))
+			Expect(mocks.CfService.GetServiceCallCount()).To(Equal(0))
+			Expect(mysqlPlugin.GetExitCode()).To(Equal(1))
+		})
+	})
+
+	for _, compressed := range []bool{false, true} {
+		compressed := compressed
+
+		Context(fmt.Sprintf("When restoring a dump file (compressed: %t)", compressed), func() {
+			It("Streams the dump into mysql through the tunnel and reports progress", func() {
+				mysqlPlugin, mocks := NewPluginAndMocks()
+				dumpPath := writeDump("dump.sql", dump, compressed)
+
+				mocks.OsWrapper.OpenStub = openFile
+				mocks.CfService.GetServiceReturns(serviceA, nil)
+				mocks.CfService.GetStartedAppsReturns(appList, nil)
+				mocks.PortFinder.GetPortReturns(2342, nil)
+
+				var restored []byte
+				mocks.MysqlRunner.RunToolStub = func(tool ClientTool, clientIo ClientIo, address LocalAddress, dbName string, username string, password string, caCert string, args ...string) error {
+					var err error
+					restored, err = ioutil.ReadAll(clientIo.Stdin)
+					return err
+				}
+
+				mysqlPlugin.Run(mocks.CliConnection, []string{"mysql-restore", "database-a", dumpPath, "--foo"})
+
+				Expect(string(restored)).To(Equal(dump))
+				Expect(mocks.MysqlRunner.RunToolCallCount()).To(Equal(1))
+				tool, clientIo, address, dbName, username, password, caCert, args := mocks.MysqlRunner.RunToolArgsForCall(0)
+				Expect(tool.Name).To(Equal("mysql"))
+				Expect(clientIo.Stdout).To(Equal(mocks.Out))
+				Expect(address).To(Equal(LocalAddress{Host: "127.0.0.1", Port: 2342}))
+				Expect(dbName).To(Equal("dbname-a"))
+				Expect(username).To(Equal("username"))
+				Expect(password).To(Equal("password"))
+				Expect(caCert).To(Equal("ca-cert"))
+				Expect(args).To(Equal([]string{"--foo"}))
+
+				Expect(mocks.Err).To(gbytes.Say("Restoring .*dump.sql into database-a...\n"))
+				Expect(mocks.Err).To(gbytes.Say(fmt.Sprintf("\r100%% \\(%d B, 5 statements\\)\n", len(dump))))
+				Expect(mocks.Err).To(gbytes.Say("OK\n"))
+				Expect(mysqlPlugin.GetExitCode()).To(Equal(0))
+			})
+		})
+	}
+
+	Context("When calling 'cf mysql-restore --ephemeral --app app-name-1'", func() {
+		It("Restores through the app with an ephemeral key and deletes the key afterwards", func() {
+			mysqlPlugin, mocks := NewPluginAndMocks()
+			dumpPath := writeDump("dump.sql", dump, false)
+			serviceA.ServiceKeyGuid = "service-key-guid"
+
+			mocks.OsWrapper.OpenStub = openFile
+			mocks.CfService.GetEphemeralServiceReturns(serviceA, nil)
+			mocks.CfService.GetStartedAppsReturns(append(appList, plugin_models.GetAppsModel{Name: "app-name-2"}), nil)
+			mocks.MysqlRunner.RunToolStub = func(tool ClientTool, clientIo ClientIo, address LocalAddress, dbName string, username string, password string, caCert string, args ...string) error {
+				Expect(mocks.CfService.DeleteServiceKeyCallCount()).To(Equal(0))
+				_, err := ioutil.ReadAll(clientIo.Stdin)
+				return err
+			}
+
+			mysqlPlugin.Run(mocks.CliConnection, []string{"mysql-restore", "--ephemeral", "--app", "app-name-1", "database-a", dumpPath, "--foo"})
+
+			Expect(mocks.CfService.GetServiceCallCount()).To(Equal(0))
+			_, _, calledApps := mocks.CfService.OpenSshTunnelArgsForCall(0)
+			Expect(calledApps).To(Equal(appList))
+			_, _, _, _, _, _, _, args := mocks.MysqlRunner.RunToolArgsForCall(0)
+			Expect(args).To(Equal([]string{"--foo"}))
+
+			Expect(mocks.CfService.DeleteServiceKeyCallCount()).To(Equal(1))
+			_, calledGuid := mocks.CfService.DeleteServiceKeyArgsForCall(0)
+			Expect(calledGuid).To(Equal("service-key-guid"))
+			Expect(mocks.SignalWrapper.StopCallCount()).To(Equal(1))
+			Expect(mocks.Err).To(gbytes.Say("OK\n"))
+			Expect(mysqlPlugin.GetExitCode()).To(Equal(0))
+		})
+	})
+
+	Context("When a statement fails", func() {
+		It("Shows the line of the failing statement and exits with the status of mysql", func() {
+			mysqlPlugin, mocks := NewPluginAndMocks()
+			dumpPath := writeDump("dump.sql", dump, false)
+
+			mocks.OsWrapper.OpenStub = openFile
+			mocks.CfService.GetServiceReturns(serviceA, nil)
+			mocks.CfService.GetStartedAppsReturns(appList, nil)
+			mocks.MysqlRunner.RunToolStub = func(tool ClientTool, clientIo ClientIo, address LocalAddress, dbName string, username string, password string, caCert string, args ...string) error {
+				ioutil.ReadAll(clientIo.Stdin)
+				io.WriteString(clientIo.Stderr, "ERROR 1050 (42S01) at line 3: Table 't' already exists\n")
+				return &ClientExitError{Message: "error running mysql client: exit status 1", ExitCode: 1}
+			}
+
+			mysqlPlugin.Run(mocks.CliConnection, []string{"mysql-restore", "database-a", dumpPath})
+
+			Expect(mocks.Err).To(gbytes.Say(fmt.Sprintf("\r100%% \\(%d B, 1 statements\\)\n", len(dump))))
+			Expect(mocks.Err).To(gbytes.Say("ERROR 1050 \\(42S01\\) at line 3: Table 't' already exists\n"))
+			Expect(mocks.Err).To(gbytes.Say("FAILED\nRestore stopped at line 3 of .*dump.sql\n$"))
+			Expect(mysqlPlugin.GetExitCode()).To(Equal(1))
+		})
+
+		It("Counts only the statements before the failing one for a compressed dump", func() {
+			mysqlPlugin, mocks := NewPluginAndMocks()
+			dumpPath := writeDump("dump.sql.gz", dump, true)
+
+			mocks.OsWrapper.OpenStub = openFile
+			mocks.CfService.GetServiceReturns(serviceA, nil)
+			mocks.CfService.GetStartedAppsReturns(appList, nil)
+			mocks.MysqlRunner.RunToolStub = func(tool ClientTool, clientIo ClientIo, address LocalAddress, dbName string, username string, password string, caCert string, args ...string) error {
+				ioutil.ReadAll(clientIo.Stdin)
+				io.WriteString(clientIo.Stderr, "ERROR 1146 (42S02) at line 9: Unknown table 't'\n")
+				return &ClientExitError{Message: "error running mysql client: exit status 1", ExitCode: 1}
+			}
+
+			mysqlPlugin.Run(mocks.CliConnection, []string{"mysql-restore", "database-a", dumpPath})
+
+			Expect(mocks.Err).To(gbytes.Say("\r100% \\(.*, 4 statements\\)\n"))
+			Expect(mocks.Err).To(gbytes.Say("FAILED\nRestore stopped at line 9 of .*dump.sql.gz\n$"))
+		})
+	})
+
+	Context("When counting statements", func() {
+		restore := func(contents string, read func(io.Reader) ([]byte, error)) string {
+			mysqlPlugin, mocks := NewPluginAndMocks()
+			dumpPath := writeDump("dump.sql", contents, false)
+
+			mocks.OsWrapper.OpenStub = openFile
+			mocks.CfService.GetServiceReturns(serviceA, nil)
+			mocks.CfService.GetStartedAppsReturns(appList, nil)
+			mocks.MysqlRunner.RunToolStub = func(tool ClientTool, clientIo ClientIo, address LocalAddress, dbName string, username string, password string, caCert string, args ...string) error {
+				_, err := read(clientIo.Stdin)
+				return err
+			}
+
+			mysqlPlugin.Run(mocks.CliConnection, []string{"mysql-restore", "database-a", dumpPath})
+
+			Expect(mysqlPlugin.GetExitCode()).To(Equal(0))
+			return string(mocks.Err.Contents())
+		}
+
+		It("Finds the same statements however the client splits its reads", func() {
+			oneByteAtATime := func(reader io.Reader) ([]byte, error) {
+				return ioutil.ReadAll(iotest.OneByteReader(reader))
+			}
+
+			Expect(restore(dump, oneByteAtATime)).To(ContainSubstring(", 5 statements)\n"))
+		})
+
+		It("Only treats -- followed by whitespace as a comment", func() {
+			contents := "SELECT 1--1;\n" +
+				"SELECT 2 --;\n" +
+				"--\n" +
+				"-- SELECT 3;\n" +
+				"--\tSELECT 4;\n" +
+				"SELECT 5;\n"
+
+			Expect(restore(contents, ioutil.ReadAll)).To(ContainSubstring(", 3 statements)\n"))
+		})
+	})
+
+	Context("When mysql fails without reporting a line", func() {
+		It("Shows the error and exits with the status of mysql", func() {
+			mysqlPlugin, mocks := NewPluginAndMocks()
+			dumpPath := writeDump("dump.sql", dump, false)
+
+			mocks.OsWrapper.OpenStub = openFile
+			mocks.CfService.GetServiceReturns(serviceA, nil)
+			mocks.CfService.GetStartedAppsReturns(appList, nil)
+			mocks.MysqlRunner.RunToolReturns(&ClientExitError{Message: "error running mysql client: exit status 2", ExitCode: 2})
+
+			mysqlPlugin.Run(mocks.CliConnection, []string{"mysql-restore", "database-a", dumpPath})
+
+			Expect(mocks.Err).To(gbytes.Say("FAILED\nerror running mysql client: exit status 2\n$"))
+			Expect(mysqlPlugin.GetExitCode()).To(Equal(2))
+		})
+	})
+})
@@ -0,0 +1,269 @@
+package cfmysql
+
+import (
+	"bytes"
+	"database/sql"
+	"encoding/csv"
+	"encoding/json"
+	"encoding/xml"
+	"fmt"
+	"io"
+	"strings"
+)
+
+var ResultFormats = []string{"tsv", "csv", "json"}
+
+// resultWriter prints the result sets in one format, row by row as the client returns them
+type resultWriter interface {
+	// StartResult begins a result set. The columns are missing if the result set is empty, as the client only names
+	// them in the rows.
+	StartResult(columns []string) error
+	WriteRow(values []sql.NullString) error
+	EndResult() error
+}
+
+type xmlRow struct {
+	Fields []xmlField `xml:"field"`
+}
+
+type xmlField struct {
+	Name  string `xml:"name,attr"`
+	Nil   string `xml:"http://www.w3.org/2001/XMLSchema-instance nil,attr"`
+	Value string `xml:",chardata"`
+}
+
+// writeXmlResults reads the output of 'mysql --xml', which unlike the tab-separated batch output keeps NULL apart
+// from the string 'NULL', and prints each row as soon as it has been read. There is one <resultset> element per
+// statement that returned rows.
+func writeXmlResults(output io.Reader, results resultWriter) error {
+	decoder := xml.NewDecoder(output)
+	started := false
+
+	for {
+		token, err := decoder.Token()
+		if err == io.EOF {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+
+		switch element := token.(type) {
+		case xml.StartElement:
+			switch element.Name.Local {
+			case "resultset":
+				started = false
+			case "row":
+				var row xmlRow
+				err = decoder.DecodeElement(&row, &element)
+				if err != nil {
+					return err
+				}
+
+				if !started {
+					started = true
+					err = results.StartResult(columnNames(row))
+					if err != nil {
+						return err
+					}
+				}
+
+				err = results.WriteRow(fieldValues(row))
+				if err != nil {
+					return err
+				}
+			}
+		case xml.EndElement:
+			if element.Name.Local != "resultset" {
+				continue
+			}
+
+			if !started {
+				err = results.StartResult(nil)
+				if err != nil {
+					return err
+				}
+			}
+			err = results.EndResult()
+			if err != nil {
+				return err
+			}
+		}
+	}
+}
+
+func columnNames(row xmlRow) []string {
+	columns := make([]string, len(row.Fields))
+	for i, field := range row.Fields {
+		columns[i] = field.Name
+	}
+
+	return columns
+}
+
+func fieldValues(row xmlRow) []sql.NullString {
+	values := make([]sql.NullString, len(row.Fields))
+	for i, field := range row.Fields {
+		values[i] = sql.NullString{String: field.Value, Valid: field.Nil != "true"}
+	}
+
+	return values
+}
+
+func isResultFormat(format string) bool {
+	for _, known := range ResultFormats {
+		if format == known {
+			return true
+		}
+	}
+
+	return false
+}
+
+// newResultWriter returns the writer of the format. TSV and CSV result sets are separated by an empty line, and JSON
+// result sets are printed as one array per line.
+func newResultWriter(writer io.Writer, format string) resultWriter {
+	switch format {
+	case "csv":
+		return &csvResultWriter{writer: writer, csvWriter: csv.NewWriter(writer)}
+	case "json":
+		return &jsonResultWriter{writer: writer}
+	default:
+		return &tsvResultWriter{writer: writer}
+	}
+}
+
+var tsvEscaper = strings.NewReplacer("\\", "\\\\", "\t", "\\t", "\n", "\\n", "\r", "\\r")
+
+// tsvResultWriter prints NULL as \N, like the batch output of mysql
+type tsvResultWriter struct {
+	writer  io.Writer
+	results int
+}
+
+func (self *tsvResultWriter) StartResult(columns []string) error {
+	self.results++
+	if self.results > 1 {
+		fmt.Fprintln(self.writer)
+	}
+
+	header := make([]string, len(columns))
+	for i, column := range columns {
+		header[i] = tsvEscaper.Replace(column)
+	}
+
+	_, err := io.WriteString(self.writer, strings.Join(header, "\t")+"\n")
+	return err
+}
+
+func (self *tsvResultWriter) WriteRow(row []sql.NullString) error {
+	values := make([]string, len(row))
+	for i, value := range row {
+		if value.Valid {
+			values[i] = tsvEscaper.Replace(value.String)
+		} else {
+			values[i] = `\N`
+		}
+	}
+
+	_, err := io.WriteString(self.writer, strings.Join(values, "\t")+"\n")
+	return err
+}
+
+func (self *tsvResultWriter) EndResult() error {
+	return nil
+}
+
+// csvResultWriter prints NULL as an unquoted \N, as LOAD DATA reads it, to keep it apart from the empty string
+type csvResultWriter struct {
+	writer    io.Writer
+	csvWriter *csv.Writer
+	results   int
+}
+
+func (self *csvResultWriter) StartResult(columns []string) error {
+	self.results++
+	if self.results > 1 {
+		fmt.Fprintln(self.writer)
+	}
+
+	self.csvWriter.Write(columns)
+	return self.csvWriter.Error()
+}
+
+func (self *csvResultWriter) WriteRow(row []sql.NullString) error {
+	values := make([]string, len(row))
+	for i, value := range row {
+		if value.Valid {
+			values[i] = value.String
+		} else {
+			values[i] = `\N`
+		}
+	}
+
+	self.csvWriter.Write(values)
+	return self.csvWriter.Error()
+}
+
+func (self *csvResultWriter) EndResult() error {
+	self.csvWriter.Flush()
+	return self.csvWriter.Error()
+}
+
+// jsonResultWriter prints an array of objects per result set, keeping the column order
+type jsonResultWriter struct {
+	writer  io.Writer
+	columns []string
+	rows    int
+}
+
+func (self *jsonResultWriter) StartResult(columns []string) error {
+	self.columns = columns
+	self.rows = 0
+
+	_, err := io.WriteString(self.writer, "[")
+	return err
+}
+
+func (self *jsonResultWriter) WriteRow(row []sql.NullString) error {
+	buffer := new(bytes.Buffer)
+	if self.rows > 0 {
+		buffer.WriteString(",")
+	}
+	self.rows++
+	buffer.WriteString("{")
+
+	for i, value := range row {
+		if i > 0 {
+			buffer.WriteString(",")
+		}
+
+		writeJsonString(buffer, self.columns[i])
+		buffer.WriteString(":")
+
+		if value.Valid {
+			writeJsonString(buffer, value.String)
+		} else {
+			buffer.WriteString("null")
+		}
+	}
+
+	buffer.WriteString("}")
+
+	_, err := buffer.WriteTo(self.writer)
+	return err
+}
+
+func (self *jsonResultWriter) EndResult() error {
+	_, err := io.WriteString(self.writer, "]\n")
+	return err
+}
+
+func writeJsonString(buffer *bytes.Buffer, value string) {
+	encoded := new(bytes.Buffer)
+	encoder := json.NewEncoder(encoded)
+	encoder.SetEscapeHTML(false)
+	encoder.Encode(value)
+
+	buffer.Write(bytes.TrimSuffix(encoded.Bytes(), []byte("\n")))
+}
@@ -0,0 +1,59 @@
+package cfmysql
+
+import (
+	"code.cloudfoundry.org/cli/plugin"
+	"flag"
+	"fmt"
+	"io/ioutil"
+	"text/tabwriter"
+)
+
+func (self *MysqlPlugin) listServices(cliConnection plugin.CliConnection, args []string) {
+	flags := flag.NewFlagSet("mysql-services", flag.ContinueOnError)
+	flags.SetOutput(ioutil.Discard)
+	all := flags.Bool("all", false, "")
+	target := addSpaceTargetFlags(flags)
+
+	err := flags.Parse(args)
+	if err != nil || flags.NArg() > 0 || !target.valid(appChoice{}) {
+		fmt.Fprint(self.Err, self.FormatUsage())
+		self.setErrorExit()
+		return
+	}
+
+	cliConnection, ok := self.targetConnection(cliConnection, *target)
+	if !ok {
+		return
+	}
+
+	services, err := self.CfService.GetServices(cliConnection)
+	if err != nil {
+		fmt.Fprintf(self.Err, "FAILED\nUnable to list services: %s\n", err)
+		self.setErrorExit()
+		return
+	}
+
+	var shown []ServiceSummary
+	for _, service := range services {
+		if *all || service.MysqlCompatible {
+			shown = append(shown, service)
+		}
+	}
+
+	if len(shown) == 0 {
+		fmt.Fprintf(self.Out, "No MySQL services found in the %s.\n", spaceDescription(cliConnection))
+		return
+	}
+
+	table := tabwriter.NewWriter(self.Out, 0, 0, 3, ' ', 0)
+	fmt.Fprintln(table, "name\tservice\tplan\tlast operation\tcf-mysql key")
+	for _, service := range shown {
+		hasKey := "no"
+		if service.HasServiceKey {
+			hasKey = "yes"
+		}
+
+		fmt.Fprintf(table, "%s\t%s\t%s\t%s\t%s\n", service.Name, service.Service, service.Plan, service.LastOperation, hasKey)
+	}
+	table.Flush()
+}
@@ -0,0 +1,140 @@
+package cfmysql_test
+
+import (
+	"code.cloudfoundry.org/cli/plugin/models"
+	"code.cloudfoundry.org/cli/plugin/pluginfakes"
+	"errors"
+	. "github.com/andreasf/cf-mysql-plugin/cfmysql"
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("Services", func() {
+	var services []ServiceSummary
+
+	BeforeEach(func() {
+		services = []ServiceSummary{
+			{
+				Name:            "database-a",
+				Service:         "p-mysql",
+				Plan:            "db-small",
+				LastOperation:   "create succeeded",
+				HasServiceKey:   true,
+				MysqlCompatible: true,
+			},
+			{
+				Name:            "redis-b",
+				Service:         "p-redis",
+				Plan:            "shared-vm",
+				LastOperation:   "create succeeded",
+				MysqlCompatible: false,
+			},
+			{
+				Name:            "database-c",
+				Service:         "cleardb",
+				Plan:            "spark",
+				LastOperation:   "update in progress",
+				HasServiceKey:   false,
+				MysqlCompatible: true,
+			},
+		}
+	})
+
+	Context("When calling 'cf mysql-services'", func() {
+		It("Prints a table of the MySQL services", func() {
+			mysqlPlugin, mocks := NewPluginAndMocks()
+			mocks.CfService.GetServicesReturns(services, nil)
+
+			mysqlPlugin.Run(mocks.CliConnection, []string{"mysql-services"})
+
+			Expect(string(mocks.Out.Contents())).To(Equal(
+				"name         service   plan       last operation       cf-mysql key\n" +
+					"database-a   p-mysql   db-small   create succeeded     yes\n" +
+					"database-c   cleardb   spark      update in progress   no\n"))
+			Expect(mysqlPlugin.GetExitCode()).To(Equal(0))
+			Expect(mocks.CfService.GetServicesArgsForCall(0)).To(Equal(mocks.CliConnection))
+		})
+	})
+
+	Context("When calling 'cf mysql-services --all'", func() {
+		It("Prints a table of all services", func() {
+			mysqlPlugin, mocks := NewPluginAndMocks()
+			mocks.CfService.GetServicesReturns(services, nil)
+
+			mysqlPlugin.Run(mocks.CliConnection, []string{"mysql-services", "--all"})
+
+			Expect(string(mocks.Out.Contents())).To(ContainSubstring("redis-b      p-redis   shared-vm"))
+			Expect(mysqlPlugin.GetExitCode()).To(Equal(0))
+		})
+	})
+
+	Context("When no MySQL services exist", func() {
+		It("Says so", func() {
+			mysqlPlugin, mocks := NewPluginAndMocks()
+			mocks.CfService.GetServicesReturns(services[1:2], nil)
+
+			mysqlPlugin.Run(mocks.CliConnection, []string{"mysql-services"})
+
+			Expect(string(mocks.Out.Contents())).To(Equal("No MySQL services found in the current space.\n"))
+			Expect(mysqlPlugin.GetExitCode()).To(Equal(0))
+		})
+	})
+
+	Context("When passing an org and a space", func() {
+		It("Lists the services of that space", func() {
+			mysqlPlugin, mocks := NewPluginAndMocks()
+			targetedConnection := new(pluginfakes.FakeCliConnection)
+			mocks.CfService.TargetSpaceReturns(targetedConnection, nil)
+			mocks.CfService.GetServicesReturns(services, nil)
+
+			mysqlPlugin.Run(mocks.CliConnection, []string{"mysql-services", "--org", "other-org", "--space", "staging"})
+
+			_, calledOrg, calledSpace := mocks.CfService.TargetSpaceArgsForCall(0)
+			Expect(calledOrg).To(Equal("other-org"))
+			Expect(calledSpace).To(Equal("staging"))
+			Expect(mocks.CfService.GetServicesArgsForCall(0)).To(BeIdenticalTo(targetedConnection))
+			Expect(string(mocks.Out.Contents())).To(ContainSubstring("database-a"))
+			Expect(mysqlPlugin.GetExitCode()).To(Equal(0))
+		})
+
+		It("Names the org and space if it has no MySQL services", func() {
+			mysqlPlugin, mocks := NewPluginAndMocks()
+			targetedConnection := new(pluginfakes.FakeCliConnection)
+			targetedConnection.GetCurrentOrgReturns(plugin_models.Organization{
+				OrganizationFields: plugin_models.OrganizationFields{Name: "other-org"},
+			}, nil)
+			targetedConnection.GetCurrentSpaceReturns(plugin_models.Space{
+				SpaceFields: plugin_models.SpaceFields{Name: "staging"},
+			}, nil)
+			mocks.CfService.TargetSpaceReturns(targetedConnection, nil)
+
+			mysqlPlugin.Run(mocks.CliConnection, []string{"mysql-services", "--org", "other-org", "--space", "staging"})
+
+			Expect(string(mocks.Out.Contents())).To(Equal("No MySQL services found in the org other-org / space staging.\n"))
+		})
+	})
+
+	Context("When the services cannot be retrieved", func() {
+		It("Shows an error message and exits with 1", func() {
+			mysqlPlugin, mocks := NewPluginAndMocks()
+			mocks.CfService.GetServicesReturns(nil, errors.New("PC LOAD LETTER"))
+
+			mysqlPlugin.Run(mocks.CliConnection, []string{"mysql-services"})
+
+			Expect(string(mocks.Err.Contents())).To(Equal("FAILED\nUnable to list services: PC LOAD LETTER\n"))
+			Expect(mysqlPlugin.GetExitCode()).To(Equal(1))
+		})
+	})
+
+	Context("When calling 'cf mysql-services' with an unknown argument", func() {
+		It("Prints usage information to STDERR and exits with 1", func() {
+			mysqlPlugin, mocks := NewPluginAndMocks()
+
+			mysqlPlugin.Run(mocks.CliConnection, []string{"mysql-services", "--bogus"})
+
+			Expect(string(mocks.Err.Contents())).To(Equal(mysqlPlugin.FormatUsage()))
+			Expect(mysqlPlugin.GetExitCode()).To(Equal(1))
+			Expect(mocks.CfService.GetServicesCallCount()).To(Equal(0))
+		})
+	})
+})
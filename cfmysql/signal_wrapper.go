@@ -0,0 +1,26 @@
+package cfmysql
+
+import (
+	"os"
+	"os/signal"
+)
+
+//go:generate counterfeiter . SignalWrapper
+type SignalWrapper interface {
+	Notify(c chan<- os.Signal, sig ...os.Signal)
+	Stop(c chan<- os.Signal)
+}
+
+func NewSignalWrapper() SignalWrapper {
+	return new(signalWrapper)
+}
+
+type signalWrapper struct{}
+
+func (self *signalWrapper) Notify(c chan<- os.Signal, sig ...os.Signal) {
+	signal.Notify(c, sig...)
+}
+
+func (self *signalWrapper) Stop(c chan<- os.Signal) {
+	signal.Stop(c)
+}
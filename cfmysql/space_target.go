@@ -0,0 +1,117 @@
+package cfmysql
+
+import (
+	"code.cloudfoundry.org/cli/plugin"
+	sdkModels "code.cloudfoundry.org/cli/plugin/models"
+	"flag"
+	"fmt"
+	pluginModels "github.com/andreasf/cf-mysql-plugin/cfmysql/models"
+)
+
+// spaceTarget holds the --org and --space flags, which select the space of the services instead of the CLI's target
+type spaceTarget struct {
+	Org   string
+	Space string
+}
+
+func addSpaceTargetFlags(flags *flag.FlagSet) *spaceTarget {
+	target := new(spaceTarget)
+	flags.StringVar(&target.Org, "org", "", "")
+	flags.StringVar(&target.Space, "space", "", "")
+
+	return target
+}
+
+// valid requires a space whenever an org is given. A tunnel app can only be pushed to the CLI's target.
+func (self *spaceTarget) valid(app appChoice) bool {
+	if self.Space == "" {
+		return self.Org == ""
+	}
+
+	return !app.Push
+}
+
+// targetConnection returns a connection for the space chosen with --org and --space, or the connection as it is if
+// none was chosen. Errors are reported to the user.
+func (self *MysqlPlugin) targetConnection(cliConnection plugin.CliConnection, target spaceTarget) (plugin.CliConnection, bool) {
+	if target.Space == "" {
+		return cliConnection, true
+	}
+
+	connection, err := self.CfService.TargetSpace(cliConnection, target.Org, target.Space)
+	if err != nil {
+		fmt.Fprintf(self.Err, "FAILED\nUnable to target space %s: %s\n", target.Space, err)
+		self.setErrorExit()
+		return nil, false
+	}
+
+	return connection, true
+}
+
+// spaceDescription names the org and space the connection reports as the current ones, for messages that need to say
+// which space was searched
+func spaceDescription(cliConnection plugin.CliConnection) string {
+	space, err := cliConnection.GetCurrentSpace()
+	if err != nil || space.Name == "" {
+		return "current space"
+	}
+
+	org, err := cliConnection.GetCurrentOrg()
+	if err != nil || org.Name == "" {
+		return "space " + space.Name
+	}
+
+	return fmt.Sprintf("org %s / space %s", org.Name, space.Name)
+}
+
+// TargetSpace returns a connection on which the space is the current one, so that services and apps are looked up
+// there. The CLI's target is not changed. Without an org, the space is looked up in the current org.
+func (self *cfService) TargetSpace(connection plugin.CliConnection, orgName string, spaceName string) (plugin.CliConnection, error) {
+	if orgName == "" {
+		org, err := connection.GetCurrentOrg()
+		if err != nil {
+			return nil, fmt.Errorf("unable to retrieve current org: %s", err)
+		}
+		orgName = org.Name
+	}
+
+	space, err := self.apiClient.GetSpace(connection, orgName, spaceName)
+	if err != nil {
+		return nil, err
+	}
+
+	return &targetedConnection{
+		CliConnection: connection,
+		space:         space,
+		apiClient:     self.apiClient,
+	}, nil
+}
+
+// targetedConnection reports a space other than the CLI's target as the current one, and lists the apps of that space
+type targetedConnection struct {
+	plugin.CliConnection
+	space     pluginModels.Space
+	apiClient ApiClient
+}
+
+func (self *targetedConnection) GetCurrentOrg() (sdkModels.Organization, error) {
+	return sdkModels.Organization{
+		OrganizationFields: sdkModels.OrganizationFields{
+			Guid: self.space.OrgGuid,
+			Name: self.space.OrgName,
+		},
+	}, nil
+}
+
+func (self *targetedConnection) GetCurrentSpace() (sdkModels.Space, error) {
+	return sdkModels.Space{
+		SpaceFields: sdkModels.SpaceFields{
+			Guid: self.space.Guid,
+			Name: self.space.Name,
+		},
+	}, nil
+}
+
+func (self *targetedConnection) GetApps() ([]sdkModels.GetAppsModel, error) {
+	return self.apiClient.GetSpaceApps(self.CliConnection, self.space.Guid)
+}
@@ -2,26 +2,328 @@ package cfmysql
 
 import (
 	"code.cloudfoundry.org/cli/plugin"
+	sdkModels "code.cloudfoundry.org/cli/plugin/models"
+	"crypto/md5"
+	"crypto/sha1"
+	"crypto/sha256"
+	"encoding/base64"
+	"errors"
 	"fmt"
-	"strconv"
+	"golang.org/x/crypto/ssh"
+	"io"
+	"net"
+	"os"
+	"strings"
+	"sync"
+	"time"
 )
 
 //go:generate counterfeiter . SshRunner
 type SshRunner interface {
-	OpenSshTunnel(cliConnection plugin.CliConnection, toService MysqlService, throughApp string, localPort int)
+	OpenSshTunnel(cliConnection plugin.CliConnection, forwards []TunnelForward, throughApp sdkModels.GetAppsModel) (SshTunnel, error)
 }
 
-func NewSshRunner() SshRunner {
-	return new(sshRunner)
+// TunnelForward is a local address forwarded to a service through the tunnel
+type TunnelForward struct {
+	Service      MysqlService
+	LocalAddress LocalAddress
 }
 
-type sshRunner struct{}
+//go:generate counterfeiter . SshTunnel
+type SshTunnel interface {
+	Close() error
+	Wait() error
+}
+
+func NewSshRunner(apiClient ApiClient, logWriter io.Writer, probeTimeout time.Duration) SshRunner {
+	return &sshRunner{
+		apiClient:    apiClient,
+		logWriter:    logWriter,
+		probeTimeout: probeTimeout,
+	}
+}
+
+const SshDialTimeout = 30 * time.Second
+
+// TunnelProbeTimeout bounds the wait for the app to reach a service, as security groups may drop the packets silently
+const TunnelProbeTimeout = 10 * time.Second
+const SshKeepAliveInterval = 15 * time.Second
+
+const (
+	md5FingerprintLength          = 47
+	hexSha1FingerprintLength      = 59
+	base64Sha256FingerprintLength = 43
+)
+
+type sshRunner struct {
+	apiClient    ApiClient
+	logWriter    io.Writer
+	probeTimeout time.Duration
+}
+
+// OpenSshTunnel logs in to the SSH proxy with a one-time code, in the same way as 'cf ssh', and forwards connections
+// to each local address through the first instance of the app. All forwards share the SSH connection. The tunnel is
+// ready when it is returned: each service has been reached from the app once.
+func (self *sshRunner) OpenSshTunnel(cliConnection plugin.CliConnection, forwards []TunnelForward, throughApp sdkModels.GetAppsModel) (SshTunnel, error) {
+	sshInfo, err := self.apiClient.GetSshInfo(cliConnection)
+	if err != nil {
+		return nil, fmt.Errorf("unable to retrieve SSH settings: %s", err)
+	}
+
+	code, err := self.apiClient.GetSshCode(cliConnection, sshInfo)
+	if err != nil {
+		return nil, fmt.Errorf("unable to get one-time SSH code: %s", err)
+	}
+
+	clientConfig := &ssh.ClientConfig{
+		User:            fmt.Sprintf("cf:%s/0", throughApp.Guid),
+		Auth:            []ssh.AuthMethod{ssh.Password(code)},
+		HostKeyCallback: fingerprintCallback(sshInfo.HostKeyFingerprint),
+		Timeout:         SshDialTimeout,
+	}
+
+	client, err := ssh.Dial("tcp", sshInfo.Endpoint, clientConfig)
+	if err != nil {
+		return nil, fmt.Errorf("unable to connect to SSH proxy at %s: %s", sshInfo.Endpoint, err)
+	}
+
+	for _, forward := range forwards {
+		err = probe(client, forward.Service, self.probeTimeout)
+		if err != nil {
+			client.Close()
+			return nil, err
+		}
+	}
+
+	tunnel := &sshTunnel{
+		client:    client,
+		logWriter: self.logWriter,
+		done:      make(chan bool),
+	}
+
+	for _, forward := range forwards {
+		listener, err := listen(forward.LocalAddress)
+		if err != nil {
+			tunnel.closeListeners()
+			client.Close()
+			return nil, fmt.Errorf("unable to listen on %s: %s", forward.LocalAddress, err)
+		}
+		tunnel.listeners = append(tunnel.listeners, listener)
+	}
+
+	for i, forward := range forwards {
+		go tunnel.serve(tunnel.listeners[i], net.JoinHostPort(forward.Service.Hostname, forward.Service.Port))
+	}
+	go tunnel.watch()
+	go tunnel.keepAlive(SshKeepAliveInterval)
+
+	return tunnel, nil
+}
+
+// probe checks that the app can reach the service, as the forward only dials it once a client connects. Security
+// groups may keep the app from reaching it. The SSH connection is closed if the app does not get through in time, as
+// that is the only way to abandon the dial.
+func probe(client *ssh.Client, service MysqlService, timeout time.Duration) error {
+	remoteAddress := net.JoinHostPort(service.Hostname, service.Port)
+
+	result := make(chan error, 1)
+	go func() {
+		conn, err := client.Dial("tcp", remoteAddress)
+		if err == nil {
+			conn.Close()
+		}
+		result <- err
+	}()
 
-func (self *sshRunner) OpenSshTunnel(cliConnection plugin.CliConnection, toService MysqlService, throughApp string, localPort int) {
-	tunnelSpec := strconv.Itoa(localPort) + ":" + toService.Hostname + ":" + toService.Port
-	_, err := cliConnection.CliCommand("ssh", throughApp, "-N", "-L", tunnelSpec)
+	select {
+	case err := <-result:
+		if err != nil {
+			return fmt.Errorf("unable to reach %s at %s: %s", service.Name, remoteAddress, err)
+		}
+		return nil
+	case <-time.After(timeout):
+		client.Close()
+		return fmt.Errorf("unable to reach %s at %s (timed out after %s)", service.Name, remoteAddress, timeout)
+	}
+}
+
+// listen opens the local end of a forward. Socket files are only accessible to the current user, and are removed
+// when the listener is closed.
+func listen(localAddress LocalAddress) (net.Listener, error) {
+	listener, err := net.Listen(localAddress.Network(), localAddress.String())
+	if err != nil || localAddress.Socket == "" {
+		return listener, err
+	}
 
+	err = os.Chmod(localAddress.Socket, 0600)
 	if err != nil {
-		panic(fmt.Errorf("SSH tunnel failed: %s", err))
+		listener.Close()
+		return nil, err
 	}
+
+	return listener, nil
+}
+
+type sshTunnel struct {
+	client    *ssh.Client
+	listeners []net.Listener
+	logWriter io.Writer
+	done      chan bool
+
+	mutex   sync.Mutex
+	closing bool
+	err     error
+}
+
+// Close stops the tunnel. Connections that are still open are cut off.
+func (self *sshTunnel) Close() error {
+	self.mutex.Lock()
+	self.closing = true
+	self.mutex.Unlock()
+
+	self.client.Close()
+	<-self.done
+
+	return nil
+}
+
+// Wait blocks until the tunnel is closed or the SSH connection is lost. The error is nil if Close was called.
+func (self *sshTunnel) Wait() error {
+	<-self.done
+
+	self.mutex.Lock()
+	defer self.mutex.Unlock()
+
+	return self.err
+}
+
+func (self *sshTunnel) watch() {
+	err := self.client.Wait()
+	if err == nil {
+		err = errors.New("connection closed by the SSH proxy")
+	}
+
+	self.stop(err)
+	self.closeListeners()
+	close(self.done)
+}
+
+func (self *sshTunnel) closeListeners() {
+	for _, listener := range self.listeners {
+		listener.Close()
+	}
+}
+
+// stop closes the SSH connection, recording the first cause unless the tunnel is being closed on purpose
+func (self *sshTunnel) stop(cause error) {
+	self.mutex.Lock()
+	if !self.closing && self.err == nil {
+		self.err = cause
+	}
+	self.mutex.Unlock()
+
+	self.client.Close()
+}
+
+// keepAlive sends requests at every interval, in the same way as 'cf ssh'. A connection that does not respond within
+// the interval is considered dead, as the TCP connection might not notice.
+func (self *sshTunnel) keepAlive(interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-self.done:
+			return
+		case <-ticker.C:
+			err := self.sendKeepAlive(interval)
+			if err != nil {
+				self.stop(fmt.Errorf("keepalive failed: %s", err))
+				return
+			}
+		}
+	}
+}
+
+func (self *sshTunnel) sendKeepAlive(timeout time.Duration) error {
+	result := make(chan error, 1)
+	go func() {
+		_, _, err := self.client.SendRequest("keepalive@cloudfoundry.org", true, nil)
+		result <- err
+	}()
+
+	select {
+	case err := <-result:
+		return err
+	case <-time.After(timeout):
+		return fmt.Errorf("no response within %s", timeout)
+	}
+}
+
+func (self *sshTunnel) serve(listener net.Listener, remoteAddress string) {
+	for {
+		localConn, err := listener.Accept()
+		if err != nil {
+			return
+		}
+
+		go self.forward(localConn, remoteAddress)
+	}
+}
+
+func (self *sshTunnel) forward(localConn net.Conn, remoteAddress string) {
+	defer localConn.Close()
+
+	remoteConn, err := self.client.Dial("tcp", remoteAddress)
+	if err != nil {
+		fmt.Fprintf(self.logWriter, "Unable to reach %s through the SSH tunnel: %s\n", remoteAddress, err)
+		return
+	}
+	defer remoteConn.Close()
+
+	done := make(chan bool, 2)
+	go func() {
+		io.Copy(remoteConn, localConn)
+		done <- true
+	}()
+	go func() {
+		io.Copy(localConn, remoteConn)
+		done <- true
+	}()
+
+	<-done
+}
+
+// fingerprintCallback verifies the host key of the SSH proxy against the fingerprint advertised by the API, which
+// may be an MD5, SHA-1 or SHA-256 fingerprint
+func fingerprintCallback(expectedFingerprint string) ssh.HostKeyCallback {
+	return func(hostname string, remote net.Addr, key ssh.PublicKey) error {
+		var fingerprint string
+
+		switch len(expectedFingerprint) {
+		case base64Sha256FingerprintLength:
+			sum := sha256.Sum256(key.Marshal())
+			fingerprint = base64.RawStdEncoding.EncodeToString(sum[:])
+		case hexSha1FingerprintLength:
+			sum := sha1.Sum(key.Marshal())
+			fingerprint = hexFingerprint(sum[:])
+		case md5FingerprintLength:
+			sum := md5.Sum(key.Marshal())
+			fingerprint = hexFingerprint(sum[:])
+		case 0:
+			return errors.New("unable to verify SSH host key: the API does not advertise a fingerprint")
+		default:
+			return fmt.Errorf("unsupported SSH host key fingerprint format: '%s'", expectedFingerprint)
+		}
+
+		if fingerprint != expectedFingerprint {
+			return fmt.Errorf("SSH host key verification failed: the fingerprint of the received key was '%s'", fingerprint)
+		}
+
+		return nil
+	}
+}
+
+func hexFingerprint(sum []byte) string {
+	return strings.Replace(fmt.Sprintf("% x", sum), " ", ":", -1)
 }
@@ -1,53 +1,511 @@
 package cfmysql_test
 
 import (
+	"bufio"
+	"code.cloudfoundry.org/cli/plugin/models"
 	"code.cloudfoundry.org/cli/plugin/pluginfakes"
+	"crypto/ed25519"
+	"crypto/rand"
+	"crypto/sha1"
 	"errors"
+	"fmt"
 	. "github.com/andreasf/cf-mysql-plugin/cfmysql"
+	"github.com/andreasf/cf-mysql-plugin/cfmysql/cfmysqlfakes"
+	"github.com/andreasf/cf-mysql-plugin/cfmysql/models"
 	. "github.com/onsi/ginkgo/v2"
 	. "github.com/onsi/gomega"
+	"github.com/onsi/gomega/gbytes"
+	"golang.org/x/crypto/ssh"
+	"io"
+	"io/ioutil"
+	"net"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
 )
 
 var _ = Describe("SshRunner", func() {
 	var cliConnection *pluginfakes.FakeCliConnection
+	var apiClient *cfmysqlfakes.FakeApiClient
+	var logWriter *gbytes.Buffer
 	var sshRunner SshRunner
-	service := MysqlService{
-		Name:     "database-a",
-		Hostname: "database-a.host",
-		Port:     "3306",
-		DbName:   "dbname-a",
-		Username: "username-a",
-		Password: "password-a",
+	var proxy *testSshProxy
+	var database net.Listener
+	var localAddress LocalAddress
+	var service MysqlService
+	var forwards []TunnelForward
+	var sshInfo models.SshInfo
+
+	app := plugin_models.GetAppsModel{
+		Name: "app-name",
+		Guid: "app-guid",
 	}
 
 	BeforeEach(func() {
+		var err error
+		database, err = net.Listen("tcp", "127.0.0.1:0")
+		Expect(err).To(BeNil())
+		go serveGreeting(database, "hello from database-a\n")
+
+		proxy = startSshProxy("one-time-code", "cf:app-guid/0")
+
+		_, databasePort, _ := net.SplitHostPort(database.Addr().String())
+		service = MysqlService{
+			Name:     "database-a",
+			Hostname: "127.0.0.1",
+			Port:     databasePort,
+		}
+
 		cliConnection = new(pluginfakes.FakeCliConnection)
-		sshRunner = NewSshRunner()
+		apiClient = new(cfmysqlfakes.FakeApiClient)
+		sshInfo = models.SshInfo{
+			Endpoint:           proxy.Address(),
+			HostKeyFingerprint: proxy.Sha256Fingerprint(),
+			OauthClient:        "ssh-proxy",
+			TokenEndpoint:      "https://uaa.example.com",
+		}
+		apiClient.GetSshInfoReturns(sshInfo, nil)
+		apiClient.GetSshCodeReturns("one-time-code", nil)
+
+		logWriter = gbytes.NewBuffer()
+		sshRunner = NewSshRunner(apiClient, logWriter, TunnelProbeTimeout)
+		localAddress = LocalAddress{Host: "127.0.0.1", Port: freePort()}
+		forwards = []TunnelForward{{Service: service, LocalAddress: localAddress}}
+	})
+
+	AfterEach(func() {
+		database.Close()
+		proxy.Close()
 	})
 
 	Context("When opening the tunnel", func() {
-		It("Runs 'cf ssh'", func() {
-			sshRunner.OpenSshTunnel(cliConnection, service, "app-name", 4242)
+		It("Logs in to the SSH proxy with a one-time code and forwards the local port to the service", func() {
+			tunnel, err := sshRunner.OpenSshTunnel(cliConnection, forwards, app)
+			Expect(err).To(BeNil())
+			defer tunnel.Close()
+
+			Expect(readGreeting(localAddress)).To(Equal("hello from database-a\n"))
+
+			Expect(apiClient.GetSshInfoCallCount()).To(Equal(1))
+			Expect(apiClient.GetSshInfoArgsForCall(0)).To(Equal(cliConnection))
+			Expect(apiClient.GetSshCodeCallCount()).To(Equal(1))
+			_, calledInfo := apiClient.GetSshCodeArgsForCall(0)
+			Expect(calledInfo.OauthClient).To(Equal("ssh-proxy"))
+		})
+
+		It("Forwards several services through a single SSH connection", func() {
+			otherDatabase, err := net.Listen("tcp", "127.0.0.1:0")
+			Expect(err).To(BeNil())
+			defer otherDatabase.Close()
+			go serveGreeting(otherDatabase, "hello from database-b\n")
+
+			_, otherPort, _ := net.SplitHostPort(otherDatabase.Addr().String())
+			otherAddress := LocalAddress{Host: "127.0.0.1", Port: freePort()}
+			forwards = append(forwards, TunnelForward{
+				Service:      MysqlService{Name: "database-b", Hostname: "127.0.0.1", Port: otherPort},
+				LocalAddress: otherAddress,
+			})
+
+			tunnel, err := sshRunner.OpenSshTunnel(cliConnection, forwards, app)
+			Expect(err).To(BeNil())
+			defer tunnel.Close()
+
+			Expect(readGreeting(localAddress)).To(Equal("hello from database-a\n"))
+			Expect(readGreeting(otherAddress)).To(Equal("hello from database-b\n"))
+			Expect(proxy.ConnectionCount()).To(Equal(1))
+			Expect(apiClient.GetSshCodeCallCount()).To(Equal(1))
+		})
 
-			Expect(cliConnection.CliCommandCallCount()).To(Equal(1))
-			calledArgs := cliConnection.CliCommandArgsForCall(0)
-			Expect(calledArgs).To(Equal([]string{"ssh", "app-name", "-N", "-L", "4242:database-a.host:3306"}))
+		It("Listens on a Unix socket that only the user can access, and removes it when closed", func() {
+			socketDir, err := ioutil.TempDir("", "ssh-runner-test")
+			Expect(err).To(BeNil())
+			defer os.RemoveAll(socketDir)
+			socketAddress := LocalAddress{Socket: filepath.Join(socketDir, "mysql.sock")}
+			forwards[0].LocalAddress = socketAddress
+
+			tunnel, err := sshRunner.OpenSshTunnel(cliConnection, forwards, app)
+			Expect(err).To(BeNil())
+
+			Expect(readGreeting(socketAddress)).To(Equal("hello from database-a\n"))
+			info, err := os.Stat(socketAddress.Socket)
+			Expect(err).To(BeNil())
+			Expect(info.Mode().Perm()).To(Equal(os.FileMode(0600)))
+
+			Expect(tunnel.Close()).To(Succeed())
+			Expect(tunnel.Wait()).To(Succeed())
+			_, err = os.Stat(socketAddress.Socket)
+			Expect(os.IsNotExist(err)).To(BeTrue())
+		})
+
+		It("Stops listening when the tunnel is closed", func() {
+			tunnel, err := sshRunner.OpenSshTunnel(cliConnection, forwards, app)
+			Expect(err).To(BeNil())
+
+			Expect(tunnel.Close()).To(Succeed())
+			Expect(tunnel.Wait()).To(Succeed())
+
+			_, err = net.Dial("tcp", localAddress.String())
+			Expect(err).NotTo(BeNil())
 		})
 	})
 
-	Context("When 'cf ssh' returns an error", func() {
-		It("panics", func() {
-			defer func() {
-				if thePanic := recover(); thePanic != nil {
-					Expect(thePanic).To(Equal(errors.New("SSH tunnel failed: PC LOAD LETTER")))
-				} else {
-					Fail("Expected function to panic")
-				}
-			}()
-			cliConnection.CliCommandWithoutTerminalOutputStub = nil
-			cliConnection.CliCommandReturns(nil, errors.New("PC LOAD LETTER"))
+	Context("When the SSH connection is lost", func() {
+		It("Stops listening and returns the cause from Wait", func() {
+			tunnel, err := sshRunner.OpenSshTunnel(cliConnection, forwards, app)
+			Expect(err).To(BeNil())
+			defer tunnel.Close()
 
-			sshRunner.OpenSshTunnel(cliConnection, service, "app-name", 4242)
+			proxy.DropConnections()
+
+			Expect(tunnel.Wait()).NotTo(Succeed())
+			_, err = net.Dial("tcp", localAddress.String())
+			Expect(err).NotTo(BeNil())
 		})
 	})
+
+	Context("When the service cannot be reached from the app", func() {
+		It("Returns an error without listening", func() {
+			database.Close()
+
+			tunnel, err := sshRunner.OpenSshTunnel(cliConnection, forwards, app)
+
+			Expect(tunnel).To(BeNil())
+			Expect(err).To(MatchError(HavePrefix("unable to reach database-a at 127.0.0.1:%s: ", service.Port)))
+			_, err = net.Dial("tcp", localAddress.String())
+			Expect(err).NotTo(BeNil())
+			Expect(proxy.ConnectionCount()).To(Equal(1))
+			Eventually(proxy.OpenConnectionCount).Should(Equal(0))
+		})
+	})
+
+	Context("When the app does not get through to the service", func() {
+		It("Gives up after the timeout and closes the SSH connection", func() {
+			proxy.StallForwarding("cf:app-guid/0")
+			sshRunner = NewSshRunner(apiClient, logWriter, 100*time.Millisecond)
+
+			tunnel, err := sshRunner.OpenSshTunnel(cliConnection, forwards, app)
+
+			Expect(tunnel).To(BeNil())
+			Expect(err).To(MatchError(fmt.Sprintf("unable to reach database-a at 127.0.0.1:%s (timed out after 100ms)", service.Port)))
+			Eventually(proxy.OpenConnectionCount).Should(Equal(0))
+		})
+	})
+
+	Context("When the service becomes unreachable after the tunnel opened", func() {
+		It("Logs the error and closes the local connection", func() {
+			tunnel, err := sshRunner.OpenSshTunnel(cliConnection, forwards, app)
+			Expect(err).To(BeNil())
+			defer tunnel.Close()
+
+			database.Close()
+
+			Expect(readGreeting(localAddress)).To(Equal(""))
+			Eventually(logWriter).Should(gbytes.Say("Unable to reach 127.0.0.1:%s through the SSH tunnel", service.Port))
+		})
+	})
+
+	Context("When the first app cannot reach the service", func() {
+		It("Opens the tunnel through the next app", func() {
+			proxy.Close()
+			proxy = startSshProxy("one-time-code", "cf:app-guid-1/0", "cf:app-guid-2/0")
+			proxy.BlockForwarding("cf:app-guid-1/0")
+			sshInfo.Endpoint = proxy.Address()
+			sshInfo.HostKeyFingerprint = proxy.Sha256Fingerprint()
+			apiClient.GetSshInfoReturns(sshInfo, nil)
+			apiClient.IsSshEnabledReturns(true, nil)
+			apps := []plugin_models.GetAppsModel{
+				{Name: "app-name-1", Guid: "app-guid-1"},
+				{Name: "app-name-2", Guid: "app-guid-2"},
+			}
+			cfService := NewCfService(apiClient, sshRunner, new(cfmysqlfakes.FakeHttpWrapper), new(cfmysqlfakes.FakeRandWrapper), logWriter)
+
+			tunnel, err := cfService.OpenSshTunnel(cliConnection, forwards, apps)
+			Expect(err).To(BeNil())
+			defer tunnel.Close()
+
+			Expect(logWriter).To(gbytes.Say("unable to open SSH tunnel through app app-name-1: unable to reach database-a at 127.0.0.1:%s: .*, trying the next app\n", service.Port))
+			Expect(readGreeting(localAddress)).To(Equal("hello from database-a\n"))
+		})
+	})
+
+	DescribeTable("Verifying the host key",
+		func(fingerprint func(proxy *testSshProxy) string, expectedError string) {
+			sshInfo.HostKeyFingerprint = fingerprint(proxy)
+			apiClient.GetSshInfoReturns(sshInfo, nil)
+
+			tunnel, err := sshRunner.OpenSshTunnel(cliConnection, forwards, app)
+
+			if expectedError == "" {
+				Expect(err).To(BeNil())
+				tunnel.Close()
+			} else {
+				Expect(tunnel).To(BeNil())
+				Expect(err).To(MatchError(ContainSubstring(expectedError)))
+			}
+		},
+		Entry("accepts a SHA-256 fingerprint", (*testSshProxy).Sha256Fingerprint, ""),
+		Entry("accepts a SHA-1 fingerprint", (*testSshProxy).Sha1Fingerprint, ""),
+		Entry("accepts an MD5 fingerprint", (*testSshProxy).Md5Fingerprint, ""),
+		Entry("rejects a different key",
+			func(*testSshProxy) string { return "a6:d1:08:0b:b0:cb:9b:5f:c4:ba:44:2a:97:26:19:8a" },
+			"SSH host key verification failed"),
+		Entry("rejects a missing fingerprint",
+			func(*testSshProxy) string { return "" },
+			"the API does not advertise a fingerprint"),
+	)
+
+	Context("When the SSH settings cannot be retrieved", func() {
+		It("Returns an error", func() {
+			apiClient.GetSshInfoReturns(models.SshInfo{}, errors.New("PC LOAD LETTER"))
+
+			tunnel, err := sshRunner.OpenSshTunnel(cliConnection, forwards, app)
+
+			Expect(tunnel).To(BeNil())
+			Expect(err).To(MatchError("unable to retrieve SSH settings: PC LOAD LETTER"))
+		})
+	})
+
+	Context("When no one-time code can be obtained", func() {
+		It("Returns an error", func() {
+			apiClient.GetSshCodeReturns("", errors.New("HTTP status 401"))
+
+			tunnel, err := sshRunner.OpenSshTunnel(cliConnection, forwards, app)
+
+			Expect(tunnel).To(BeNil())
+			Expect(err).To(MatchError("unable to get one-time SSH code: HTTP status 401"))
+		})
+	})
+
+	Context("When the SSH proxy rejects the login", func() {
+		It("Returns an error", func() {
+			apiClient.GetSshCodeReturns("expired-code", nil)
+
+			tunnel, err := sshRunner.OpenSshTunnel(cliConnection, forwards, app)
+
+			Expect(tunnel).To(BeNil())
+			Expect(err).To(MatchError(HavePrefix("unable to connect to SSH proxy at " + proxy.Address())))
+		})
+	})
+
+	Context("When the local port is in use", func() {
+		It("Returns an error", func() {
+			listener, err := net.Listen("tcp", localAddress.String())
+			Expect(err).To(BeNil())
+			defer listener.Close()
+
+			tunnel, err := sshRunner.OpenSshTunnel(cliConnection, forwards, app)
+
+			Expect(tunnel).To(BeNil())
+			Expect(err).To(MatchError(HavePrefix(fmt.Sprintf("unable to listen on %s", localAddress))))
+		})
+	})
+	Context("When one of the local ports is in use", func() {
+		It("Releases the other ports and returns an error", func() {
+			otherAddress := LocalAddress{Host: "127.0.0.1", Port: freePort()}
+			forwards = append(forwards, TunnelForward{Service: service, LocalAddress: otherAddress})
+			listener, err := net.Listen("tcp", otherAddress.String())
+			Expect(err).To(BeNil())
+			defer listener.Close()
+
+			tunnel, err := sshRunner.OpenSshTunnel(cliConnection, forwards, app)
+
+			Expect(tunnel).To(BeNil())
+			Expect(err).To(MatchError(HavePrefix(fmt.Sprintf("unable to listen on %s", otherAddress))))
+			_, err = net.Dial("tcp", localAddress.String())
+			Expect(err).NotTo(BeNil())
+		})
+	})
+
 })
+
+// testSshProxy accepts a single user and forwards direct-tcpip channels, like the Diego SSH proxy
+type testSshProxy struct {
+	listener    net.Listener
+	key         ssh.PublicKey
+	mutex       sync.Mutex
+	connections []*ssh.ServerConn
+	blocked     map[string]bool
+	stalled     map[string]bool
+	open        int
+}
+
+func startSshProxy(password string, users ...string) *testSshProxy {
+	_, privateKey, err := ed25519.GenerateKey(rand.Reader)
+	Expect(err).To(BeNil())
+	signer, err := ssh.NewSignerFromKey(privateKey)
+	Expect(err).To(BeNil())
+
+	config := &ssh.ServerConfig{
+		PasswordCallback: func(conn ssh.ConnMetadata, givenPassword []byte) (*ssh.Permissions, error) {
+			for _, user := range users {
+				if conn.User() == user && string(givenPassword) == password {
+					return nil, nil
+				}
+			}
+			return nil, errors.New("access denied")
+		},
+	}
+	config.AddHostKey(signer)
+
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	Expect(err).To(BeNil())
+
+	proxy := &testSshProxy{listener: listener, key: signer.PublicKey(), blocked: make(map[string]bool), stalled: make(map[string]bool)}
+	go proxy.serve(config)
+
+	return proxy
+}
+
+func (self *testSshProxy) Address() string {
+	return self.listener.Addr().String()
+}
+
+func (self *testSshProxy) Close() {
+	self.listener.Close()
+}
+
+func (self *testSshProxy) DropConnections() {
+	self.mutex.Lock()
+	defer self.mutex.Unlock()
+
+	for _, connection := range self.connections {
+		connection.Close()
+	}
+}
+
+// BlockForwarding rejects the forwards of the user, like an app whose security groups do not allow the target
+func (self *testSshProxy) BlockForwarding(user string) {
+	self.mutex.Lock()
+	defer self.mutex.Unlock()
+
+	self.blocked[user] = true
+}
+
+// StallForwarding leaves the forwards of the user unanswered, like an app whose security groups drop the packets
+func (self *testSshProxy) StallForwarding(user string) {
+	self.mutex.Lock()
+	defer self.mutex.Unlock()
+
+	self.stalled[user] = true
+}
+
+// OpenConnectionCount is the number of connections that have not been closed
+func (self *testSshProxy) OpenConnectionCount() int {
+	self.mutex.Lock()
+	defer self.mutex.Unlock()
+
+	return self.open
+}
+
+func (self *testSshProxy) ConnectionCount() int {
+	self.mutex.Lock()
+	defer self.mutex.Unlock()
+
+	return len(self.connections)
+}
+
+func (self *testSshProxy) Sha256Fingerprint() string {
+	return strings.TrimPrefix(ssh.FingerprintSHA256(self.key), "SHA256:")
+}
+
+func (self *testSshProxy) Sha1Fingerprint() string {
+	sum := sha1.Sum(self.key.Marshal())
+	return strings.Replace(fmt.Sprintf("% x", sum), " ", ":", -1)
+}
+
+func (self *testSshProxy) Md5Fingerprint() string {
+	return ssh.FingerprintLegacyMD5(self.key)
+}
+
+func (self *testSshProxy) serve(config *ssh.ServerConfig) {
+	for {
+		conn, err := self.listener.Accept()
+		if err != nil {
+			return
+		}
+
+		go func() {
+			serverConn, channels, requests, err := ssh.NewServerConn(conn, config)
+			if err != nil {
+				return
+			}
+
+			self.mutex.Lock()
+			self.connections = append(self.connections, serverConn)
+			self.open++
+			blocked := self.blocked[serverConn.User()]
+			stalled := self.stalled[serverConn.User()]
+			self.mutex.Unlock()
+			go ssh.DiscardRequests(requests)
+
+			for newChannel := range channels {
+				if blocked {
+					newChannel.Reject(ssh.ConnectionFailed, "connection timed out")
+					continue
+				}
+				if stalled {
+					continue
+				}
+				go forwardChannel(newChannel)
+			}
+
+			self.mutex.Lock()
+			self.open--
+			self.mutex.Unlock()
+		}()
+	}
+}
+
+func forwardChannel(newChannel ssh.NewChannel) {
+	var target struct {
+		Host       string
+		Port       uint32
+		OriginHost string
+		OriginPort uint32
+	}
+	if newChannel.ChannelType() != "direct-tcpip" || ssh.Unmarshal(newChannel.ExtraData(), &target) != nil {
+		newChannel.Reject(ssh.UnknownChannelType, "unsupported channel")
+		return
+	}
+
+	targetConn, err := net.Dial("tcp", net.JoinHostPort(target.Host, strconv.Itoa(int(target.Port))))
+	if err != nil {
+		newChannel.Reject(ssh.ConnectionFailed, err.Error())
+		return
+	}
+	defer targetConn.Close()
+
+	channel, requests, err := newChannel.Accept()
+	if err != nil {
+		return
+	}
+	defer channel.Close()
+	go ssh.DiscardRequests(requests)
+
+	go io.Copy(targetConn, channel)
+	io.Copy(channel, targetConn)
+}
+
+func serveGreeting(listener net.Listener, greeting string) {
+	for {
+		conn, err := listener.Accept()
+		if err != nil {
+			return
+		}
+
+		io.WriteString(conn, greeting)
+		conn.Close()
+	}
+}
+
+func readGreeting(localAddress LocalAddress) string {
+	conn, err := net.Dial(localAddress.Network(), localAddress.String())
+	Expect(err).To(BeNil())
+	defer conn.Close()
+
+	greeting, _ := bufio.NewReader(conn).ReadString('\n')
+	return greeting
+}
@@ -0,0 +1,123 @@
+package cfmysql
+
+import (
+	"code.cloudfoundry.org/cli/plugin"
+	"flag"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"strings"
+	"syscall"
+	"text/tabwriter"
+)
+
+func (self *MysqlPlugin) runTunnel(cliConnection plugin.CliConnection, args []string) {
+	flags := flag.NewFlagSet("mysql-tunnel", flag.ContinueOnError)
+	flags.SetOutput(ioutil.Discard)
+	localAddress := addLocalAddressFlags(flags)
+	app := addAppChoiceFlags(flags)
+	target := addSpaceTargetFlags(flags)
+
+	err := flags.Parse(args)
+	if err != nil || flags.NArg() < 1 || !localAddress.normalize() || !app.valid() || !target.valid(*app) {
+		fmt.Fprint(self.Err, self.FormatUsage())
+		self.setErrorExit()
+		return
+	}
+
+	cliConnection, ok := self.targetConnection(cliConnection, *target)
+	if !ok {
+		return
+	}
+
+	dbNames := flags.Args()
+	services, tunnel, tunnelAddresses, ok := self.openTunnels(cliConnection, dbNames, false, *localAddress, *app)
+	if !ok {
+		return
+	}
+	defer tunnel.Close()
+
+	caCertPaths := make([]string, len(services))
+	for i, service := range services {
+		if service.CaCert == "" {
+			continue
+		}
+
+		caCertPaths[i], err = writeCaCert(self.IoUtilWrapper, self.OsWrapper, service.CaCert)
+		if caCertPaths[i] != "" {
+			defer self.OsWrapper.Remove(caCertPaths[i])
+		}
+		if err != nil {
+			fmt.Fprintf(self.Err, "FAILED\nUnable to store CA certificate: %s\n", err)
+			self.setErrorExit()
+			return
+		}
+	}
+
+	interrupts := make(chan os.Signal, 1)
+	self.SignalWrapper.Notify(interrupts, os.Interrupt, syscall.SIGTERM)
+	defer self.SignalWrapper.Stop(interrupts)
+
+	names := strings.Join(dbNames, ", ")
+
+	if len(services) == 1 {
+		fmt.Fprintf(self.Out, "SSH tunnel to %s is open. Press Ctrl-C to close it.\n\n", names)
+		self.printTunnelDetails(services[0], tunnelAddresses[0], caCertPaths[0])
+	} else {
+		fmt.Fprintf(self.Out, "SSH tunnels to %s are open. Press Ctrl-C to close them.\n\n", names)
+		self.printTunnelTable(dbNames, services, tunnelAddresses, caCertPaths)
+	}
+
+	tunnelClosed := make(chan error, 1)
+	go func() {
+		tunnelClosed <- tunnel.Wait()
+	}()
+
+	select {
+	case <-interrupts:
+		fmt.Fprintf(self.Out, "\nClosing SSH tunnel to %s\n", names)
+	case err := <-tunnelClosed:
+		fmt.Fprintf(self.Err, "FAILED\nSSH tunnel to %s closed: %s\n", names, err)
+		self.setErrorExit()
+	}
+}
+
+func (self *MysqlPlugin) printTunnelDetails(service MysqlService, tunnelAddress LocalAddress, caCertPath string) {
+	if tunnelAddress.Socket != "" {
+		fmt.Fprintf(self.Out, "Socket:   %s\n", tunnelAddress.Socket)
+	} else {
+		fmt.Fprintf(self.Out, "Host:     %s\n", tunnelAddress.Host)
+		fmt.Fprintf(self.Out, "Port:     %d\n", tunnelAddress.Port)
+	}
+	fmt.Fprintf(self.Out, "Database: %s\n", service.DbName)
+	fmt.Fprintf(self.Out, "Username: %s\n", service.Username)
+	fmt.Fprintf(self.Out, "Password: %s\n", service.Password)
+	if caCertPath != "" {
+		fmt.Fprintf(self.Out, "CA cert:  %s\n", caCertPath)
+	}
+}
+
+// printTunnelTable prints one line per service. The CA cert column is only shown if any service has a certificate.
+func (self *MysqlPlugin) printTunnelTable(dbNames []string, services []MysqlService, tunnelAddresses []LocalAddress, caCertPaths []string) {
+	withCaCert := false
+	for _, caCertPath := range caCertPaths {
+		withCaCert = withCaCert || caCertPath != ""
+	}
+
+	table := tabwriter.NewWriter(self.Out, 0, 0, 3, ' ', 0)
+	if withCaCert {
+		fmt.Fprintln(table, "service\thost\tport\tdatabase\tusername\tpassword\tca cert")
+	} else {
+		fmt.Fprintln(table, "service\thost\tport\tdatabase\tusername\tpassword")
+	}
+
+	for i, service := range services {
+		fmt.Fprintf(table, "%s\t%s\t%d\t%s\t%s\t%s", dbNames[i], tunnelAddresses[i].Host, tunnelAddresses[i].Port, service.DbName, service.Username, service.Password)
+		if withCaCert {
+			fmt.Fprintf(table, "\t%s", caCertPaths[i])
+		}
+		fmt.Fprintln(table)
+	}
+
+	table.Flush()
+}
@@ -0,0 +1,190 @@
+package cfmysql
+
+import (
+	"code.cloudfoundry.org/cli/plugin"
+	sdkModels "code.cloudfoundry.org/cli/plugin/models"
+	"flag"
+	"fmt"
+	"math"
+	"os"
+	"path/filepath"
+	"strings"
+	"syscall"
+	"time"
+)
+
+//go:generate counterfeiter . TunnelAppPusher
+type TunnelAppPusher interface {
+	PushTunnelApp(cliConnection plugin.CliConnection) (sdkModels.GetAppsModel, error)
+	DeleteTunnelApp(cliConnection plugin.CliConnection, name string) error
+	FindTunnelApps(cliConnection plugin.CliConnection) ([]sdkModels.GetAppsModel, error)
+}
+
+func NewTunnelAppPusher(ioUtilWrapper IoUtilWrapper, osWrapper OsWrapper, randWrapper RandWrapper) TunnelAppPusher {
+	return &tunnelAppPusher{
+		ioUtilWrapper: ioUtilWrapper,
+		osWrapper:     osWrapper,
+		randWrapper:   randWrapper,
+	}
+}
+
+const TunnelAppPrefix = "cf-mysql-tunnel"
+const TunnelAppMemory = "128M"
+const TunnelAppStartTimeout = 2 * time.Minute
+const TunnelAppPollInterval = 2 * time.Second
+
+type tunnelAppPusher struct {
+	ioUtilWrapper IoUtilWrapper
+	osWrapper     OsWrapper
+	randWrapper   RandWrapper
+}
+
+// PushTunnelApp pushes an empty static site with a unique name and waits for it to start. The static site is served
+// by nginx, which is small and available on every foundation. If the app does not start, it is deleted.
+func (self *tunnelAppPusher) PushTunnelApp(cliConnection plugin.CliConnection) (sdkModels.GetAppsModel, error) {
+	name := fmt.Sprintf("%s-%08x", TunnelAppPrefix, self.randWrapper.Intn(math.MaxInt32))
+
+	appDir, err := self.ioUtilWrapper.TempDir("", TunnelAppPrefix)
+	if err != nil {
+		return sdkModels.GetAppsModel{}, fmt.Errorf("unable to create app directory: %s", err)
+	}
+	defer self.osWrapper.RemoveAll(appDir)
+
+	err = self.ioUtilWrapper.WriteFile(filepath.Join(appDir, "Staticfile"), []byte{}, 0644)
+	if err != nil {
+		return sdkModels.GetAppsModel{}, fmt.Errorf("unable to create Staticfile: %s", err)
+	}
+
+	_, err = cliConnection.CliCommandWithoutTerminalOutput("push", name, "-p", appDir, "-m", TunnelAppMemory, "--no-route")
+	if err != nil {
+		self.DeleteTunnelApp(cliConnection, name)
+		return sdkModels.GetAppsModel{}, fmt.Errorf("unable to push app %s: %s", name, err)
+	}
+
+	app, err := self.waitUntilStarted(cliConnection, name)
+	if err != nil {
+		self.DeleteTunnelApp(cliConnection, name)
+		return sdkModels.GetAppsModel{}, err
+	}
+
+	return app, nil
+}
+
+func (self *tunnelAppPusher) waitUntilStarted(cliConnection plugin.CliConnection, name string) (sdkModels.GetAppsModel, error) {
+	deadline := time.Now().Add(TunnelAppStartTimeout)
+
+	for {
+		app, err := cliConnection.GetApp(name)
+		if err != nil {
+			return sdkModels.GetAppsModel{}, fmt.Errorf("unable to retrieve app %s: %s", name, err)
+		}
+
+		if app.State == "started" && app.RunningInstances > 0 {
+			return sdkModels.GetAppsModel{
+				Name:             app.Name,
+				Guid:             app.Guid,
+				State:            app.State,
+				RunningInstances: app.RunningInstances,
+			}, nil
+		}
+
+		if time.Now().After(deadline) {
+			return sdkModels.GetAppsModel{}, fmt.Errorf("app %s did not start within %s", name, TunnelAppStartTimeout)
+		}
+
+		time.Sleep(TunnelAppPollInterval)
+	}
+}
+
+func (self *tunnelAppPusher) DeleteTunnelApp(cliConnection plugin.CliConnection, name string) error {
+	_, err := cliConnection.CliCommandWithoutTerminalOutput("delete", name, "-f", "-r")
+	return err
+}
+
+// FindTunnelApps returns the tunnel apps in the current space, which may have been left behind by an interrupted session
+func (self *tunnelAppPusher) FindTunnelApps(cliConnection plugin.CliConnection) ([]sdkModels.GetAppsModel, error) {
+	apps, err := cliConnection.GetApps()
+	if err != nil {
+		return nil, fmt.Errorf("unable to retrieve apps: %s", err)
+	}
+
+	var tunnelApps []sdkModels.GetAppsModel
+	for _, app := range apps {
+		if strings.HasPrefix(app.Name, TunnelAppPrefix+"-") {
+			tunnelApps = append(tunnelApps, app)
+		}
+	}
+
+	return tunnelApps, nil
+}
+
+// appChoice holds the --app and --push-app flags, which control the app the tunnel goes through
+type appChoice struct {
+	Name string
+	Push bool
+}
+
+func addAppChoiceFlags(flags *flag.FlagSet) *appChoice {
+	choice := new(appChoice)
+	flags.StringVar(&choice.Name, "app", "", "")
+	flags.BoolVar(&choice.Push, "push-app", false, "")
+
+	return choice
+}
+
+func (self *appChoice) valid() bool {
+	return self.Name == "" || !self.Push
+}
+
+// pushTunnelApp pushes a temporary app for the tunnel. Interrupts are held back in the meantime, so that the app is
+// deleted again if the user gives up while it is starting.
+func (self *MysqlPlugin) pushTunnelApp(cliConnection plugin.CliConnection) (sdkModels.GetAppsModel, bool) {
+	interrupts := make(chan os.Signal, 1)
+	self.SignalWrapper.Notify(interrupts, os.Interrupt, syscall.SIGTERM)
+	defer self.SignalWrapper.Stop(interrupts)
+
+	fmt.Fprintln(self.Err, "No started apps in current space, pushing a temporary app for the SSH tunnel...")
+	app, err := self.TunnelAppPusher.PushTunnelApp(cliConnection)
+	if err != nil {
+		fmt.Fprintf(self.Err, "FAILED\nUnable to push tunnel app: %s\n", err)
+		self.setErrorExit()
+		return sdkModels.GetAppsModel{}, false
+	}
+
+	select {
+	case <-interrupts:
+		self.deleteTunnelApp(cliConnection, app.Name)
+		self.setErrorExit()
+		return sdkModels.GetAppsModel{}, false
+	default:
+	}
+
+	return app, true
+}
+
+func (self *MysqlPlugin) deleteTunnelApp(cliConnection plugin.CliConnection, name string) {
+	fmt.Fprintf(self.Err, "Deleting temporary app %s...\n", name)
+
+	err := self.TunnelAppPusher.DeleteTunnelApp(cliConnection, name)
+	if err != nil {
+		fmt.Fprintf(self.Err, "FAILED\nUnable to delete app %s: %s\n", name, err)
+		fmt.Fprintf(self.Err, "Delete it with: cf delete -f -r %s\n", name)
+
+		if self.exitCode == 0 {
+			self.setErrorExit()
+		}
+	}
+}
+
+// appDeletingTunnel deletes the app it goes through when it is closed
+type appDeletingTunnel struct {
+	SshTunnel
+	deleteApp func()
+}
+
+func (self *appDeletingTunnel) Close() error {
+	err := self.SshTunnel.Close()
+	self.deleteApp()
+
+	return err
+}
@@ -0,0 +1,134 @@
+package cfmysql_test
+
+import (
+	"code.cloudfoundry.org/cli/plugin/models"
+	"code.cloudfoundry.org/cli/plugin/pluginfakes"
+	"errors"
+	. "github.com/andreasf/cf-mysql-plugin/cfmysql"
+	"github.com/andreasf/cf-mysql-plugin/cfmysql/cfmysqlfakes"
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+	"math"
+	"os"
+)
+
+var _ = Describe("TunnelAppPusher", func() {
+	var cliConnection *pluginfakes.FakeCliConnection
+	var ioUtilWrapper *cfmysqlfakes.FakeIoUtilWrapper
+	var osWrapper *cfmysqlfakes.FakeOsWrapper
+	var randWrapper *cfmysqlfakes.FakeRandWrapper
+	var pusher TunnelAppPusher
+
+	BeforeEach(func() {
+		cliConnection = new(pluginfakes.FakeCliConnection)
+		ioUtilWrapper = new(cfmysqlfakes.FakeIoUtilWrapper)
+		osWrapper = new(cfmysqlfakes.FakeOsWrapper)
+		randWrapper = new(cfmysqlfakes.FakeRandWrapper)
+		pusher = NewTunnelAppPusher(ioUtilWrapper, osWrapper, randWrapper)
+
+		ioUtilWrapper.TempDirReturns("/tmp/cf-mysql-tunnel123", nil)
+		randWrapper.IntnReturns(0xabcd)
+		cliConnection.GetAppReturns(plugin_models.GetAppModel{
+			Name:             "cf-mysql-tunnel-0000abcd",
+			Guid:             "tunnel-app-guid",
+			State:            "started",
+			RunningInstances: 1,
+		}, nil)
+	})
+
+	Context("PushTunnelApp", func() {
+		It("Pushes a static app from a temporary directory and returns it once started", func() {
+			app, err := pusher.PushTunnelApp(cliConnection)
+
+			Expect(err).To(BeNil())
+			Expect(app).To(Equal(plugin_models.GetAppsModel{
+				Name:             "cf-mysql-tunnel-0000abcd",
+				Guid:             "tunnel-app-guid",
+				State:            "started",
+				RunningInstances: 1,
+			}))
+
+			Expect(randWrapper.IntnArgsForCall(0)).To(Equal(math.MaxInt32))
+
+			dir, pattern := ioUtilWrapper.TempDirArgsForCall(0)
+			Expect(dir).To(Equal(""))
+			Expect(pattern).To(Equal("cf-mysql-tunnel"))
+
+			filename, data, perm := ioUtilWrapper.WriteFileArgsForCall(0)
+			Expect(filename).To(Equal("/tmp/cf-mysql-tunnel123/Staticfile"))
+			Expect(data).To(BeEmpty())
+			Expect(perm).To(Equal(os.FileMode(0644)))
+
+			Expect(cliConnection.CliCommandWithoutTerminalOutputCallCount()).To(Equal(1))
+			Expect(cliConnection.CliCommandWithoutTerminalOutputArgsForCall(0)).To(Equal([]string{
+				"push", "cf-mysql-tunnel-0000abcd", "-p", "/tmp/cf-mysql-tunnel123", "-m", "128M", "--no-route",
+			}))
+			Expect(cliConnection.GetAppArgsForCall(0)).To(Equal("cf-mysql-tunnel-0000abcd"))
+
+			Expect(osWrapper.RemoveAllCallCount()).To(Equal(1))
+			Expect(osWrapper.RemoveAllArgsForCall(0)).To(Equal("/tmp/cf-mysql-tunnel123"))
+		})
+
+		Context("When the push fails", func() {
+			It("Deletes the app and returns an error", func() {
+				cliConnection.CliCommandWithoutTerminalOutputReturnsOnCall(0, nil, errors.New("staging failed"))
+
+				_, err := pusher.PushTunnelApp(cliConnection)
+
+				Expect(err).To(MatchError("unable to push app cf-mysql-tunnel-0000abcd: staging failed"))
+				Expect(cliConnection.CliCommandWithoutTerminalOutputCallCount()).To(Equal(2))
+				Expect(cliConnection.CliCommandWithoutTerminalOutputArgsForCall(1)).To(Equal([]string{
+					"delete", "cf-mysql-tunnel-0000abcd", "-f", "-r",
+				}))
+				Expect(osWrapper.RemoveAllCallCount()).To(Equal(1))
+			})
+		})
+
+		Context("When the app cannot be retrieved after pushing", func() {
+			It("Deletes the app and returns an error", func() {
+				cliConnection.GetAppReturns(plugin_models.GetAppModel{}, errors.New("PC LOAD LETTER"))
+
+				_, err := pusher.PushTunnelApp(cliConnection)
+
+				Expect(err).To(MatchError("unable to retrieve app cf-mysql-tunnel-0000abcd: PC LOAD LETTER"))
+				Expect(cliConnection.CliCommandWithoutTerminalOutputArgsForCall(1)).To(Equal([]string{
+					"delete", "cf-mysql-tunnel-0000abcd", "-f", "-r",
+				}))
+			})
+		})
+
+		Context("When the directory cannot be created", func() {
+			It("Returns an error without pushing", func() {
+				ioUtilWrapper.TempDirReturns("", errors.New("disk full"))
+
+				_, err := pusher.PushTunnelApp(cliConnection)
+
+				Expect(err).To(MatchError("unable to create app directory: disk full"))
+				Expect(cliConnection.CliCommandWithoutTerminalOutputCallCount()).To(Equal(0))
+			})
+		})
+	})
+
+	Context("FindTunnelApps", func() {
+		It("Returns the apps named like tunnel apps", func() {
+			cliConnection.GetAppsReturns([]plugin_models.GetAppsModel{
+				{Name: "my-app"},
+				{Name: "cf-mysql-tunnel-0000abcd"},
+				{Name: "cf-mysql-tunnel"},
+			}, nil)
+
+			apps, err := pusher.FindTunnelApps(cliConnection)
+
+			Expect(err).To(BeNil())
+			Expect(apps).To(Equal([]plugin_models.GetAppsModel{{Name: "cf-mysql-tunnel-0000abcd"}}))
+		})
+
+		It("Returns an error if the apps cannot be retrieved", func() {
+			cliConnection.GetAppsReturns(nil, errors.New("PC LOAD LETTER"))
+
+			_, err := pusher.FindTunnelApps(cliConnection)
+
+			Expect(err).To(MatchError("unable to retrieve apps: PC LOAD LETTER"))
+		})
+	})
+})
@@ -0,0 +1,240 @@
+package cfmysql
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"regexp"
+	"time"
+)
+
+//go:generate counterfeiter . TunnelDaemon
+type TunnelDaemon interface {
+	Start(serviceName string, localAddress LocalAddress, cfArgs []string) (BackgroundTunnel, error)
+	IsRunning(tunnel BackgroundTunnel) bool
+	Stop(pid int) error
+}
+
+//go:generate counterfeiter . TunnelStateStore
+type TunnelStateStore interface {
+	Lock() (unlock func(), err error)
+	Load() ([]BackgroundTunnel, error)
+	Save(tunnels []BackgroundTunnel) error
+}
+
+// BackgroundTunnel is a tunnel started with 'cf mysql-tunnel start', kept in the state file
+type BackgroundTunnel struct {
+	Service   string    `json:"service"`
+	Host      string    `json:"host"`
+	Port      int       `json:"port"`
+	Socket    string    `json:"socket,omitempty"`
+	Pid       int       `json:"pid"`
+	StartedAt time.Time `json:"started_at"`
+	LogFile   string    `json:"log_file"`
+}
+
+// LocalAddress returns where the tunnel listens
+func (self BackgroundTunnel) LocalAddress() LocalAddress {
+	return LocalAddress{Host: self.Host, Port: self.Port, Socket: self.Socket}
+}
+
+const BackgroundTunnelStartTimeout = 3 * time.Minute
+
+var unsafeFileNameChars = regexp.MustCompile(`[^A-Za-z0-9._-]`)
+
+// TunnelStateDir returns the directory for the state file and the logs of background tunnels, next to the CLI config
+func TunnelStateDir() string {
+	home, found := os.LookupEnv("CF_HOME")
+	if !found {
+		var err error
+		home, err = os.UserHomeDir()
+		if err != nil {
+			home = os.TempDir()
+		}
+	}
+
+	return filepath.Join(home, ".cf", "mysql-plugin")
+}
+
+func NewTunnelDaemon(stateDir string, execWrapper ExecWrapper, portWaiter PortWaiter) TunnelDaemon {
+	return &tunnelDaemon{
+		stateDir:    stateDir,
+		execWrapper: execWrapper,
+		portWaiter:  portWaiter,
+	}
+}
+
+type tunnelDaemon struct {
+	stateDir    string
+	execWrapper ExecWrapper
+	portWaiter  PortWaiter
+}
+
+// Start runs 'cf' with the given arguments in a detached process, which writes its output to a log file in the state
+// directory. It returns once the tunnel accepts connections on localAddress.
+func (self *tunnelDaemon) Start(serviceName string, localAddress LocalAddress, cfArgs []string) (BackgroundTunnel, error) {
+	cfPath, err := self.execWrapper.LookPath("cf")
+	if err != nil {
+		return BackgroundTunnel{}, fmt.Errorf("unable to find the cf CLI: %s", err)
+	}
+
+	err = os.MkdirAll(self.stateDir, 0700)
+	if err != nil {
+		return BackgroundTunnel{}, fmt.Errorf("unable to create state directory: %s", err)
+	}
+
+	logName := fmt.Sprintf("tunnel-%s-%d.log", unsafeFileNameChars.ReplaceAllString(serviceName, "_"), localAddress.Port)
+	if localAddress.Socket != "" {
+		logName = fmt.Sprintf("tunnel-%s-%s.log", unsafeFileNameChars.ReplaceAllString(serviceName, "_"), unsafeFileNameChars.ReplaceAllString(filepath.Base(localAddress.Socket), "_"))
+	}
+	logPath := filepath.Join(self.stateDir, logName)
+	logFile, err := os.OpenFile(logPath, os.O_CREATE|os.O_WRONLY|os.O_TRUNC, 0600)
+	if err != nil {
+		return BackgroundTunnel{}, fmt.Errorf("unable to create log file: %s", err)
+	}
+	defer logFile.Close()
+
+	cmd := exec.Command(cfPath, cfArgs...)
+	cmd.Stdout = logFile
+	cmd.Stderr = logFile
+	cmd.SysProcAttr = detachedProcAttr()
+
+	err = self.execWrapper.Start(cmd)
+	if err != nil {
+		return BackgroundTunnel{}, fmt.Errorf("unable to start cf: %s", err)
+	}
+
+	err = self.waitUntilOpen(cmd, localAddress)
+	if err != nil {
+		return BackgroundTunnel{}, fmt.Errorf("%s, see %s", err, logPath)
+	}
+
+	return BackgroundTunnel{
+		Service:   serviceName,
+		Host:      localAddress.Host,
+		Port:      localAddress.Port,
+		Socket:    localAddress.Socket,
+		Pid:       cmd.Process.Pid,
+		StartedAt: time.Now(),
+		LogFile:   logPath,
+	}, nil
+}
+
+// waitUntilOpen waits for the port to open, giving up after BackgroundTunnelStartTimeout or when the process exits.
+// A process that is still running after the timeout is stopped.
+func (self *tunnelDaemon) waitUntilOpen(cmd *exec.Cmd, localAddress LocalAddress) error {
+	ctx, cancel := context.WithTimeout(context.Background(), BackgroundTunnelStartTimeout)
+	defer cancel()
+
+	exited := make(chan bool)
+	go func() {
+		cmd.Wait()
+		close(exited)
+		cancel()
+	}()
+
+	err := self.portWaiter.WaitUntilOpen(ctx, localAddress)
+	if err == nil {
+		return nil
+	}
+
+	select {
+	case <-exited:
+		return fmt.Errorf("the tunnel process exited")
+	default:
+		stopProcess(cmd.Process.Pid)
+		return err
+	}
+}
+
+// IsRunning checks that the process of the tunnel is still running. It does not connect through the tunnel, as that
+// would open a session on the database.
+func (self *tunnelDaemon) IsRunning(tunnel BackgroundTunnel) bool {
+	return isProcessRunning(tunnel.Pid)
+}
+
+// Stop asks the process to close its tunnel. On Windows, the process is killed instead.
+func (self *tunnelDaemon) Stop(pid int) error {
+	return stopProcess(pid)
+}
+
+func NewTunnelStateStore(path string) TunnelStateStore {
+	return &tunnelStateStore{
+		path: path,
+	}
+}
+
+type tunnelStateStore struct {
+	path string
+}
+
+// Lock takes an exclusive lock on a file next to the state file, waiting for other commands to release it, so that
+// their changes to the state file don't overwrite each other. Call unlock once the state file has been saved.
+func (self *tunnelStateStore) Lock() (func(), error) {
+	err := os.MkdirAll(filepath.Dir(self.path), 0700)
+	if err != nil {
+		return nil, fmt.Errorf("unable to create state directory: %s", err)
+	}
+
+	file, err := os.OpenFile(self.path+".lock", os.O_CREATE|os.O_RDWR, 0600)
+	if err != nil {
+		return nil, fmt.Errorf("unable to open lock file: %s", err)
+	}
+
+	err = lockFile(file)
+	if err != nil {
+		file.Close()
+		return nil, fmt.Errorf("unable to lock state file: %s", err)
+	}
+
+	return func() {
+		unlockFile(file)
+		file.Close()
+	}, nil
+}
+
+// Load returns the tunnels in the state file, or none if there is no state file yet
+func (self *tunnelStateStore) Load() ([]BackgroundTunnel, error) {
+	content, err := ioutil.ReadFile(self.path)
+	if os.IsNotExist(err) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("unable to read state file: %s", err)
+	}
+
+	var tunnels []BackgroundTunnel
+	err = json.Unmarshal(content, &tunnels)
+	if err != nil {
+		return nil, fmt.Errorf("unable to deserialize state file %s: %s", self.path, err)
+	}
+
+	return tunnels, nil
+}
+
+func (self *tunnelStateStore) Save(tunnels []BackgroundTunnel) error {
+	if tunnels == nil {
+		tunnels = []BackgroundTunnel{}
+	}
+
+	content, err := json.MarshalIndent(tunnels, "", "  ")
+	if err != nil {
+		return fmt.Errorf("unable to serialize state: %s", err)
+	}
+
+	err = os.MkdirAll(filepath.Dir(self.path), 0700)
+	if err != nil {
+		return fmt.Errorf("unable to create state directory: %s", err)
+	}
+
+	err = ioutil.WriteFile(self.path, content, 0600)
+	if err != nil {
+		return fmt.Errorf("unable to write state file: %s", err)
+	}
+
+	return nil
+}
@@ -0,0 +1,224 @@
+package cfmysql_test
+
+import (
+	"context"
+	. "github.com/andreasf/cf-mysql-plugin/cfmysql"
+	"github.com/andreasf/cf-mysql-plugin/cfmysql/cfmysqlfakes"
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+	"io/ioutil"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"time"
+)
+
+var _ = Describe("TunnelDaemon", func() {
+	var stateDir string
+	var execWrapper *cfmysqlfakes.FakeExecWrapper
+	var portWaiter *cfmysqlfakes.FakePortWaiter
+	var daemon TunnelDaemon
+	var localAddress LocalAddress
+
+	writeCfScript := func(script string) {
+		path := filepath.Join(stateDir, "cf")
+		Expect(ioutil.WriteFile(path, []byte("#!/bin/sh\n"+script+"\n"), 0755)).To(Succeed())
+		execWrapper.LookPathReturns(path, nil)
+	}
+
+	BeforeEach(func() {
+		var err error
+		stateDir, err = ioutil.TempDir("", "tunnel-daemon-test")
+		Expect(err).To(BeNil())
+
+		execWrapper = new(cfmysqlfakes.FakeExecWrapper)
+		execWrapper.StartStub = func(cmd *exec.Cmd) error {
+			return cmd.Start()
+		}
+		portWaiter = new(cfmysqlfakes.FakePortWaiter)
+		daemon = NewTunnelDaemon(stateDir, execWrapper, portWaiter)
+		localAddress = LocalAddress{Host: "127.0.0.1", Port: 13306}
+	})
+
+	AfterEach(func() {
+		os.RemoveAll(stateDir)
+	})
+
+	Context("When the tunnel opens", func() {
+		It("Keeps the process running in the background and returns its details", func() {
+			writeCfScript(`echo "$@"; exec sleep 30`)
+
+			tunnel, err := daemon.Start("database/a", localAddress, []string{"mysql-tunnel", "database/a"})
+
+			Expect(err).To(BeNil())
+			Expect(execWrapper.LookPathArgsForCall(0)).To(Equal("cf"))
+			Expect(tunnel.Service).To(Equal("database/a"))
+			Expect(tunnel.Host).To(Equal("127.0.0.1"))
+			Expect(tunnel.Port).To(Equal(13306))
+			Expect(tunnel.StartedAt).To(BeTemporally("~", time.Now(), time.Second))
+			Expect(tunnel.LogFile).To(Equal(filepath.Join(stateDir, "tunnel-database_a-13306.log")))
+			Expect(daemon.IsRunning(tunnel)).To(BeTrue())
+
+			Eventually(func() string {
+				content, _ := ioutil.ReadFile(tunnel.LogFile)
+				return string(content)
+			}).Should(Equal("mysql-tunnel database/a\n"))
+
+			_, calledAddress := portWaiter.WaitUntilOpenArgsForCall(0)
+			Expect(calledAddress).To(Equal(localAddress))
+
+			Expect(daemon.Stop(tunnel.Pid)).To(Succeed())
+			Eventually(func() bool { return daemon.IsRunning(tunnel) }).Should(BeFalse())
+		})
+	})
+
+	Context("When stopping the tunnel", func() {
+		It("Signals the child processes of the cf CLI as well", func() {
+			childScript := `trap "echo stopped > '` + stateDir + `/child-stopped'; exit 0" TERM; ` +
+				`touch '` + stateDir + `/child-ready'; sleep 30 & wait`
+			writeCfScript(`sh -c "` + strings.Replace(childScript, `"`, `\"`, -1) + `" & wait`)
+
+			tunnel, err := daemon.Start("database-a", localAddress, []string{"mysql-tunnel", "database-a"})
+			Expect(err).To(BeNil())
+			Eventually(filepath.Join(stateDir, "child-ready")).Should(BeAnExistingFile())
+
+			Expect(daemon.Stop(tunnel.Pid)).To(Succeed())
+
+			Eventually(func() string {
+				content, _ := ioutil.ReadFile(filepath.Join(stateDir, "child-stopped"))
+				return string(content)
+			}).Should(Equal("stopped\n"))
+			Eventually(func() bool { return daemon.IsRunning(tunnel) }).Should(BeFalse())
+		})
+	})
+
+	Context("When checking a recorded tunnel", func() {
+		It("Only checks that its process is running, without connecting through it", func() {
+			tunnel := BackgroundTunnel{Service: "database-a", Host: "127.0.0.1", Port: 13306, Pid: os.Getpid()}
+
+			Expect(daemon.IsRunning(tunnel)).To(BeTrue())
+			Expect(portWaiter.WaitUntilOpenCallCount()).To(Equal(0))
+		})
+
+		It("Does not consider the tunnel running once its process has exited", func() {
+			cmd := exec.Command("true")
+			Expect(cmd.Run()).To(Succeed())
+			tunnel := BackgroundTunnel{Service: "database-a", Host: "127.0.0.1", Port: 13306, Pid: cmd.Process.Pid}
+
+			Expect(daemon.IsRunning(tunnel)).To(BeFalse())
+		})
+	})
+
+	Context("When the tunnel listens on a Unix socket", func() {
+		It("Names the log after the socket and records it", func() {
+			writeCfScript(`exec sleep 30`)
+			localAddress = LocalAddress{Socket: "/tmp/my sockets/mysql.sock"}
+
+			tunnel, err := daemon.Start("database-a", localAddress, []string{"mysql-tunnel", "database-a"})
+
+			Expect(err).To(BeNil())
+			defer daemon.Stop(tunnel.Pid)
+			Expect(tunnel.Socket).To(Equal("/tmp/my sockets/mysql.sock"))
+			Expect(tunnel.LocalAddress()).To(Equal(localAddress))
+			Expect(tunnel.LogFile).To(Equal(filepath.Join(stateDir, "tunnel-database-a-mysql.sock.log")))
+		})
+	})
+
+	Context("When the process exits before the tunnel opens", func() {
+		It("Returns an error pointing to the log", func() {
+			writeCfScript(`echo "FAILED"; exit 1`)
+			portWaiter.WaitUntilOpenStub = func(ctx context.Context, localAddress LocalAddress) error {
+				<-ctx.Done()
+				return ctx.Err()
+			}
+
+			_, err := daemon.Start("database-a", localAddress, []string{"mysql-tunnel", "database-a"})
+
+			logFile := filepath.Join(stateDir, "tunnel-database-a-13306.log")
+			Expect(err).To(MatchError("the tunnel process exited, see " + logFile))
+			Expect(ioutil.ReadFile(logFile)).To(Equal([]byte("FAILED\n")))
+		})
+	})
+
+	Context("When the cf CLI cannot be found", func() {
+		It("Returns an error", func() {
+			execWrapper.LookPathReturns("", &exec.Error{Name: "cf", Err: exec.ErrNotFound})
+
+			_, err := daemon.Start("database-a", localAddress, []string{"mysql-tunnel", "database-a"})
+
+			Expect(err).To(MatchError(HavePrefix("unable to find the cf CLI: ")))
+			Expect(execWrapper.StartCallCount()).To(Equal(0))
+		})
+	})
+})
+
+var _ = Describe("TunnelStateStore", func() {
+	var stateDir string
+	var store TunnelStateStore
+
+	BeforeEach(func() {
+		var err error
+		stateDir, err = ioutil.TempDir("", "tunnel-state-test")
+		Expect(err).To(BeNil())
+
+		store = NewTunnelStateStore(filepath.Join(stateDir, "state", "tunnels.json"))
+	})
+
+	AfterEach(func() {
+		os.RemoveAll(stateDir)
+	})
+
+	It("Returns no tunnels before the state file exists", func() {
+		tunnels, err := store.Load()
+
+		Expect(err).To(BeNil())
+		Expect(tunnels).To(BeEmpty())
+	})
+
+	It("Loads the tunnels it saved", func() {
+		tunnels := []BackgroundTunnel{
+			{
+				Service:   "database-a",
+				Host:      "127.0.0.1",
+				Port:      13306,
+				Pid:       4242,
+				StartedAt: time.Date(2026, 10, 18, 9, 30, 0, 0, time.UTC),
+				LogFile:   "/tmp/tunnel-database-a-13306.log",
+			},
+		}
+
+		Expect(store.Save(tunnels)).To(Succeed())
+		loaded, err := store.Load()
+
+		Expect(err).To(BeNil())
+		Expect(loaded).To(Equal(tunnels))
+	})
+
+	It("Makes a second lock wait until the first is released", func() {
+		unlock, err := store.Lock()
+		Expect(err).To(BeNil())
+
+		locked := make(chan bool)
+		go func() {
+			defer GinkgoRecover()
+			unlockSecond, err := NewTunnelStateStore(filepath.Join(stateDir, "state", "tunnels.json")).Lock()
+			Expect(err).To(BeNil())
+			close(locked)
+			unlockSecond()
+		}()
+
+		Consistently(locked, 200*time.Millisecond).ShouldNot(BeClosed())
+		unlock()
+		Eventually(locked).Should(BeClosed())
+	})
+
+	It("Returns an error if the state file is corrupt", func() {
+		path := filepath.Join(stateDir, "tunnels.json")
+		Expect(ioutil.WriteFile(path, []byte("{"), 0600)).To(Succeed())
+
+		_, err := NewTunnelStateStore(path).Load()
+
+		Expect(err).To(MatchError(HavePrefix("unable to deserialize state file " + path)))
+	})
+})
@@ -0,0 +1,31 @@
+//go:build !windows
+
+package cfmysql
+
+import (
+	"os"
+	"syscall"
+)
+
+// detachedProcAttr starts the process in a new session, so that it keeps running when the terminal is closed
+func detachedProcAttr() *syscall.SysProcAttr {
+	return &syscall.SysProcAttr{Setsid: true}
+}
+
+func isProcessRunning(pid int) bool {
+	return syscall.Kill(pid, 0) == nil
+}
+
+// stopProcess signals the process group the detached process leads, as the tunnel is held by the plugin process
+// that the cf CLI started
+func stopProcess(pid int) error {
+	return syscall.Kill(-pid, syscall.SIGTERM)
+}
+
+func lockFile(file *os.File) error {
+	return syscall.Flock(int(file.Fd()), syscall.LOCK_EX)
+}
+
+func unlockFile(file *os.File) error {
+	return syscall.Flock(int(file.Fd()), syscall.LOCK_UN)
+}
@@ -0,0 +1,355 @@
+package cfmysql_test
+
+import (
+	"code.cloudfoundry.org/cli/plugin/models"
+	"errors"
+	. "github.com/andreasf/cf-mysql-plugin/cfmysql"
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+	"github.com/onsi/gomega/gbytes"
+	"os"
+	"syscall"
+)
+
+var _ = Describe("Tunnel", func() {
+	var serviceA MysqlService
+	var appList []plugin_models.GetAppsModel
+
+	interruptImmediately := func(c chan<- os.Signal, sig ...os.Signal) {
+		c <- os.Interrupt
+	}
+
+	BeforeEach(func() {
+		serviceA = MysqlService{
+			Name:     "database-a",
+			Hostname: "database-a.host",
+			Port:     "123",
+			DbName:   "dbname-a",
+			Username: "username",
+			Password: "password",
+			CaCert:   "ca-cert",
+		}
+
+		appList = []plugin_models.GetAppsModel{
+			{
+				Name: "app-name-1",
+			},
+		}
+	})
+
+	Context("When calling 'cf mysql-tunnel' without arguments", func() {
+		It("Prints usage information to STDERR and exits with 1", func() {
+			mysqlPlugin, mocks := NewPluginAndMocks()
+
+			mysqlPlugin.Run(mocks.CliConnection, []string{"mysql-tunnel"})
+
+			Expect(string(mocks.Err.Contents())).To(Equal(mysqlPlugin.FormatUsage()))
+			Expect(mysqlPlugin.GetExitCode()).To(Equal(1))
+			Expect(mocks.CfService.GetServiceCallCount()).To(Equal(0))
+		})
+	})
+
+	Context("When calling 'cf mysql-tunnel db-name'", func() {
+		It("Opens an SSH tunnel through a started app", func() {
+			mysqlPlugin, mocks := NewPluginAndMocks()
+
+			mocks.CfService.GetServiceReturns(serviceA, nil)
+			mocks.CfService.GetStartedAppsReturns(appList, nil)
+			mocks.PortFinder.GetPortReturns(2342, nil)
+			mocks.SignalWrapper.NotifyStub = interruptImmediately
+
+			mysqlPlugin.Run(mocks.CliConnection, []string{"mysql-tunnel", "database-a"})
+
+			_, calledName := mocks.CfService.GetServiceArgsForCall(0)
+			Expect(calledName).To(Equal("database-a"))
+
+			Expect(mocks.CfService.OpenSshTunnelCallCount()).To(Equal(1))
+			_, forwards, calledAppList := mocks.CfService.OpenSshTunnelArgsForCall(0)
+			Expect(calledAppList).To(Equal(appList))
+			Expect(forwards).To(Equal([]TunnelForward{{Service: serviceA, LocalAddress: LocalAddress{Host: "127.0.0.1", Port: 2342}}}))
+			Expect(mocks.MysqlRunner.RunToolCallCount()).To(Equal(0))
+		})
+
+		It("Prints the connection details and keeps the tunnel open until interrupted", func() {
+			mysqlPlugin, mocks := NewPluginAndMocks()
+
+			mocks.CfService.GetServiceReturns(serviceA, nil)
+			mocks.CfService.GetStartedAppsReturns(appList, nil)
+			mocks.PortFinder.GetPortReturns(2342, nil)
+			mocks.OsWrapper.NameReturns("/path/to/cert.pem")
+			mocks.SignalWrapper.NotifyStub = interruptImmediately
+
+			mysqlPlugin.Run(mocks.CliConnection, []string{"mysql-tunnel", "database-a"})
+
+			Expect(mocks.Out).To(gbytes.Say("SSH tunnel to database-a is open. Press Ctrl-C to close it.\n\n"))
+			Expect(mocks.Out).To(gbytes.Say("Host:     127.0.0.1\n"))
+			Expect(mocks.Out).To(gbytes.Say("Port:     2342\n"))
+			Expect(mocks.Out).To(gbytes.Say("Database: dbname-a\n"))
+			Expect(mocks.Out).To(gbytes.Say("Username: username\n"))
+			Expect(mocks.Out).To(gbytes.Say("Password: password\n"))
+			Expect(mocks.Out).To(gbytes.Say("CA cert:  /path/to/cert.pem\n"))
+			Expect(mocks.Out).To(gbytes.Say("\nClosing SSH tunnel to database-a\n"))
+			Expect(mysqlPlugin.GetExitCode()).To(Equal(0))
+
+			Expect(mocks.SignalWrapper.NotifyCallCount()).To(Equal(1))
+			_, signals := mocks.SignalWrapper.NotifyArgsForCall(0)
+			Expect(signals).To(Equal([]os.Signal{os.Interrupt, syscall.SIGTERM}))
+			Expect(mocks.SignalWrapper.StopCallCount()).To(Equal(1))
+		})
+
+		It("Listens on the local port and host that were asked for", func() {
+			mysqlPlugin, mocks := NewPluginAndMocks()
+
+			mocks.CfService.GetServiceReturns(serviceA, nil)
+			mocks.CfService.GetStartedAppsReturns(appList, nil)
+			mocks.SignalWrapper.NotifyStub = interruptImmediately
+
+			mysqlPlugin.Run(mocks.CliConnection, []string{"mysql-tunnel", "--local-port", "13306", "--local-host", "::1", "database-a"})
+
+			Expect(mocks.PortFinder.GetPortCallCount()).To(Equal(0))
+			_, forwards, _ := mocks.CfService.OpenSshTunnelArgsForCall(0)
+			Expect(forwards[0].LocalAddress).To(Equal(LocalAddress{Host: "::1", Port: 13306}))
+			Expect(mocks.Out).To(gbytes.Say("Host:     ::1\n"))
+			Expect(mocks.Out).To(gbytes.Say("Port:     13306\n"))
+		})
+
+		It("Prints the socket when listening on a Unix socket", func() {
+			mysqlPlugin, mocks := NewPluginAndMocks()
+
+			serviceA.CaCert = ""
+			mocks.CfService.GetServiceReturns(serviceA, nil)
+			mocks.CfService.GetStartedAppsReturns(appList, nil)
+			mocks.SignalWrapper.NotifyStub = interruptImmediately
+
+			mysqlPlugin.Run(mocks.CliConnection, []string{"mysql-tunnel", "--local-socket", "/tmp/mysql.sock", "database-a"})
+
+			_, forwards, _ := mocks.CfService.OpenSshTunnelArgsForCall(0)
+			Expect(forwards[0].LocalAddress.Socket).To(Equal("/tmp/mysql.sock"))
+			Expect(mocks.Out).To(gbytes.Say("SSH tunnel to database-a is open. Press Ctrl-C to close it.\n\n"))
+			Expect(mocks.Out).To(gbytes.Say("Socket:   /tmp/mysql.sock\n"))
+			Expect(mocks.Out).To(gbytes.Say("Database: dbname-a\n"))
+			Expect(string(mocks.Out.Contents())).NotTo(ContainSubstring("Port:"))
+		})
+
+		It("Closes the tunnel when interrupted", func() {
+			mysqlPlugin, mocks := NewPluginAndMocks()
+
+			mocks.CfService.GetServiceReturns(serviceA, nil)
+			mocks.CfService.GetStartedAppsReturns(appList, nil)
+			mocks.SignalWrapper.NotifyStub = interruptImmediately
+
+			mysqlPlugin.Run(mocks.CliConnection, []string{"mysql-tunnel", "database-a"})
+
+			Expect(mocks.SshTunnel.CloseCallCount()).To(Equal(1))
+		})
+
+		Context("When the tunnel drops and cannot be reopened", func() {
+			It("Shows an error message and exits with 1", func() {
+				mysqlPlugin, mocks := NewPluginAndMocks()
+
+				tunnel, drop := NewFakeSshTunnel()
+				drop(errors.New("SSH tunnel dropped and could not be reopened: SSH disabled"))
+				mocks.CfService.OpenSshTunnelReturns(tunnel, nil)
+				mocks.CfService.GetServiceReturns(serviceA, nil)
+				mocks.CfService.GetStartedAppsReturns(appList, nil)
+
+				mysqlPlugin.Run(mocks.CliConnection, []string{"mysql-tunnel", "database-a"})
+
+				Expect(mocks.Err).To(gbytes.Say("FAILED\nSSH tunnel to database-a closed: SSH tunnel dropped and could not be reopened: SSH disabled\n"))
+				Expect(mysqlPlugin.GetExitCode()).To(Equal(1))
+			})
+		})
+
+		It("Stores the CA certificate in a temp file and removes it on exit", func() {
+			mysqlPlugin, mocks := NewPluginAndMocks()
+
+			mocks.CfService.GetServiceReturns(serviceA, nil)
+			mocks.CfService.GetStartedAppsReturns(appList, nil)
+			mocks.OsWrapper.NameReturns("/path/to/cert.pem")
+			mocks.SignalWrapper.NotifyStub = interruptImmediately
+
+			mysqlPlugin.Run(mocks.CliConnection, []string{"mysql-tunnel", "database-a"})
+
+			Expect(mocks.IoUtilWrapper.TempFileCallCount()).To(Equal(1))
+			_, writtenCert := mocks.OsWrapper.WriteStringArgsForCall(0)
+			Expect(writtenCert).To(Equal("ca-cert"))
+			Expect(mocks.OsWrapper.RemoveCallCount()).To(Equal(1))
+			Expect(mocks.OsWrapper.RemoveArgsForCall(0)).To(Equal("/path/to/cert.pem"))
+		})
+
+		Context("When the service has no CA certificate", func() {
+			It("Does not print a CA cert path", func() {
+				mysqlPlugin, mocks := NewPluginAndMocks()
+
+				serviceA.CaCert = ""
+				mocks.CfService.GetServiceReturns(serviceA, nil)
+				mocks.CfService.GetStartedAppsReturns(appList, nil)
+				mocks.SignalWrapper.NotifyStub = interruptImmediately
+
+				mysqlPlugin.Run(mocks.CliConnection, []string{"mysql-tunnel", "database-a"})
+
+				Expect(mocks.Out).ToNot(gbytes.Say("CA cert:"))
+				Expect(mocks.IoUtilWrapper.TempFileCallCount()).To(Equal(0))
+				Expect(mocks.OsWrapper.RemoveCallCount()).To(Equal(0))
+			})
+		})
+
+		Context("When the CA certificate cannot be stored", func() {
+			It("Shows an error message and exits with 1", func() {
+				mysqlPlugin, mocks := NewPluginAndMocks()
+
+				mocks.CfService.GetServiceReturns(serviceA, nil)
+				mocks.CfService.GetStartedAppsReturns(appList, nil)
+				mocks.IoUtilWrapper.TempFileReturns(nil, errors.New("PC LOAD LETTER"))
+
+				mysqlPlugin.Run(mocks.CliConnection, []string{"mysql-tunnel", "database-a"})
+
+				Expect(mocks.Err).To(gbytes.Say("^FAILED\nUnable to store CA certificate: error creating temp file: PC LOAD LETTER\n$"))
+				Expect(mocks.SignalWrapper.NotifyCallCount()).To(Equal(0))
+				Expect(mysqlPlugin.GetExitCode()).To(Equal(1))
+			})
+		})
+
+		Context("When there are no started apps", func() {
+			It("Shows an error message and exits with 1", func() {
+				mysqlPlugin, mocks := NewPluginAndMocks()
+
+				mocks.CfService.GetServiceReturns(serviceA, nil)
+				mocks.CfService.GetStartedAppsReturns([]plugin_models.GetAppsModel{}, nil)
+
+				mysqlPlugin.Run(mocks.CliConnection, []string{"mysql-tunnel", "database-a"})
+
+				Expect(mocks.Err).To(gbytes.Say("^FAILED\nUnable to connect to 'database-a': no started apps in current space\n$"))
+				Expect(mocks.CfService.OpenSshTunnelCallCount()).To(Equal(0))
+				Expect(mysqlPlugin.GetExitCode()).To(Equal(1))
+			})
+		})
+	})
+
+	Context("When calling 'cf mysql-tunnel' with several services", func() {
+		var serviceB MysqlService
+
+		BeforeEach(func() {
+			serviceA.CaCert = ""
+			serviceB = MysqlService{
+				Name:     "database-b",
+				Hostname: "database-b.host",
+				Port:     "456",
+				DbName:   "dbname-b",
+				Username: "username-b",
+				Password: "password-b",
+			}
+		})
+
+		It("Forwards all services through one tunnel and prints a table of local ports", func() {
+			mysqlPlugin, mocks := NewPluginAndMocks()
+
+			mocks.CfService.GetServiceReturnsOnCall(0, serviceA, nil)
+			mocks.CfService.GetServiceReturnsOnCall(1, serviceB, nil)
+			mocks.CfService.GetStartedAppsReturns(appList, nil)
+			mocks.PortFinder.GetPortReturnsOnCall(0, 2342, nil)
+			mocks.PortFinder.GetPortReturnsOnCall(1, 2343, nil)
+			mocks.SignalWrapper.NotifyStub = interruptImmediately
+
+			mysqlPlugin.Run(mocks.CliConnection, []string{"mysql-tunnel", "database-a", "database-b"})
+
+			Expect(mocks.CfService.GetServiceCallCount()).To(Equal(2))
+			_, calledName := mocks.CfService.GetServiceArgsForCall(1)
+			Expect(calledName).To(Equal("database-b"))
+
+			Expect(mocks.CfService.OpenSshTunnelCallCount()).To(Equal(1))
+			_, forwards, _ := mocks.CfService.OpenSshTunnelArgsForCall(0)
+			Expect(forwards).To(Equal([]TunnelForward{
+				{Service: serviceA, LocalAddress: LocalAddress{Host: "127.0.0.1", Port: 2342}},
+				{Service: serviceB, LocalAddress: LocalAddress{Host: "127.0.0.1", Port: 2343}},
+			}))
+
+			Expect(mocks.Out).To(gbytes.Say("SSH tunnels to database-a, database-b are open. Press Ctrl-C to close them.\n\n"))
+			Expect(mocks.Out).To(gbytes.Say(`service      host        port   database   username     password\n`))
+			Expect(mocks.Out).To(gbytes.Say(`database-a   127.0.0.1   2342   dbname-a   username     password\n`))
+			Expect(mocks.Out).To(gbytes.Say(`database-b   127.0.0.1   2343   dbname-b   username-b   password-b\n`))
+			Expect(mocks.Out).To(gbytes.Say("\nClosing SSH tunnel to database-a, database-b\n"))
+			Expect(mocks.SshTunnel.CloseCallCount()).To(Equal(1))
+			Expect(mysqlPlugin.GetExitCode()).To(Equal(0))
+		})
+
+		It("Uses consecutive ports from --local-port", func() {
+			mysqlPlugin, mocks := NewPluginAndMocks()
+
+			mocks.CfService.GetServiceReturnsOnCall(0, serviceA, nil)
+			mocks.CfService.GetServiceReturnsOnCall(1, serviceB, nil)
+			mocks.CfService.GetStartedAppsReturns(appList, nil)
+			mocks.SignalWrapper.NotifyStub = interruptImmediately
+
+			mysqlPlugin.Run(mocks.CliConnection, []string{"mysql-tunnel", "--local-port", "13306", "database-a", "database-b"})
+
+			Expect(mocks.PortFinder.GetPortCallCount()).To(Equal(0))
+			Expect(mocks.PortFinder.CheckPortCallCount()).To(Equal(2))
+			Expect(mocks.PortFinder.CheckPortArgsForCall(1)).To(Equal(LocalAddress{Host: "127.0.0.1", Port: 13307}))
+			_, forwards, _ := mocks.CfService.OpenSshTunnelArgsForCall(0)
+			Expect(forwards[0].LocalAddress).To(Equal(LocalAddress{Host: "127.0.0.1", Port: 13306}))
+			Expect(forwards[1].LocalAddress).To(Equal(LocalAddress{Host: "127.0.0.1", Port: 13307}))
+		})
+
+		It("Does not forward two services to the same free port", func() {
+			mysqlPlugin, mocks := NewPluginAndMocks()
+
+			mocks.CfService.GetServiceReturnsOnCall(0, serviceA, nil)
+			mocks.CfService.GetServiceReturnsOnCall(1, serviceB, nil)
+			mocks.CfService.GetStartedAppsReturns(appList, nil)
+			mocks.PortFinder.GetPortReturnsOnCall(0, 2342, nil)
+			mocks.PortFinder.GetPortReturnsOnCall(1, 2342, nil)
+			mocks.PortFinder.GetPortReturnsOnCall(2, 2343, nil)
+			mocks.SignalWrapper.NotifyStub = interruptImmediately
+
+			mysqlPlugin.Run(mocks.CliConnection, []string{"mysql-tunnel", "database-a", "database-b"})
+
+			_, forwards, _ := mocks.CfService.OpenSshTunnelArgsForCall(0)
+			Expect(forwards[1].LocalAddress).To(Equal(LocalAddress{Host: "127.0.0.1", Port: 2343}))
+		})
+
+		Context("When one of the services cannot be retrieved", func() {
+			It("Shows an error message and does not open the tunnel", func() {
+				mysqlPlugin, mocks := NewPluginAndMocks()
+
+				mocks.CfService.GetServiceReturnsOnCall(0, serviceA, nil)
+				mocks.CfService.GetServiceReturnsOnCall(1, MysqlService{}, errors.New("service 'database-b' is not a MySQL service"))
+				mocks.CfService.GetStartedAppsReturns(appList, nil)
+				mocks.PortFinder.GetPortReturnsOnCall(0, 2342, nil)
+				mocks.PortFinder.GetPortReturnsOnCall(1, 2343, nil)
+
+				mysqlPlugin.Run(mocks.CliConnection, []string{"mysql-tunnel", "database-a", "database-b"})
+
+				Expect(mocks.Err).To(gbytes.Say("^FAILED\nUnable to retrieve service credentials: service 'database-b' is not a MySQL service\n$"))
+				Expect(mocks.CfService.OpenSshTunnelCallCount()).To(Equal(0))
+				Expect(mysqlPlugin.GetExitCode()).To(Equal(1))
+			})
+		})
+
+		Context("When a local socket is given", func() {
+			It("Shows an error message and exits with 1", func() {
+				mysqlPlugin, mocks := NewPluginAndMocks()
+
+				mysqlPlugin.Run(mocks.CliConnection, []string{"mysql-tunnel", "--local-socket", "/tmp/mysql.sock", "database-a", "database-b"})
+
+				Expect(mocks.Err).To(gbytes.Say("^FAILED\nA local socket can only be used with a single service\n$"))
+				Expect(mocks.CfService.GetServiceCallCount()).To(Equal(0))
+				Expect(mysqlPlugin.GetExitCode()).To(Equal(1))
+			})
+		})
+
+		Context("When one of the local ports is not available", func() {
+			It("Shows an error message and exits with 1", func() {
+				mysqlPlugin, mocks := NewPluginAndMocks()
+
+				mocks.PortFinder.CheckPortReturnsOnCall(1, errors.New("address already in use"))
+
+				mysqlPlugin.Run(mocks.CliConnection, []string{"mysql-tunnel", "--local-port", "13306", "database-a", "database-b"})
+
+				Expect(mocks.Err).To(gbytes.Say("^FAILED\nLocal port 13307 is not available: address already in use\n$"))
+				Expect(mocks.CfService.GetServiceCallCount()).To(Equal(0))
+				Expect(mysqlPlugin.GetExitCode()).To(Equal(1))
+			})
+		})
+	})
+})
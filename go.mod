@@ -6,7 +6,8 @@ require (
 	code.cloudfoundry.org/cli v7.1.0+incompatible
 	github.com/onsi/ginkgo/v2 v2.11.0
 	github.com/onsi/gomega v1.27.8
-	github.com/phayes/freeport v0.0.0-20220201140144-74d24b5ae9f5
+	golang.org/x/crypto v0.10.0
+	golang.org/x/sys v0.9.0
 )
 
 require (
@@ -53,9 +54,7 @@ require (
 	github.com/sirupsen/logrus v1.9.3 // indirect
 	github.com/tedsuo/rata v1.0.0 // indirect
 	github.com/vito/go-interact v1.0.0 // indirect
-	golang.org/x/crypto v0.10.0 // indirect
 	golang.org/x/net v0.11.0 // indirect
-	golang.org/x/sys v0.9.0 // indirect
 	golang.org/x/term v0.9.0 // indirect
 	golang.org/x/text v0.10.0 // indirect
 	golang.org/x/tools v0.10.0 // indirect
@@ -5,6 +5,7 @@ import (
 	"fmt"
 	"github.com/andreasf/cf-mysql-plugin/cfmysql"
 	"os"
+	"path/filepath"
 )
 
 func main() {
@@ -27,26 +28,37 @@ func newPlugin() *cfmysql.MysqlPlugin {
 	osWrapper := cfmysql.NewOsWrapper()
 	requestDumper := cfmysql.NewRequestDumper(osWrapper, os.Stderr)
 	http := cfmysql.NewHttpWrapper(httpClientFactory, requestDumper)
-	apiClient := cfmysql.NewApiClient(http)
+	apiClient := cfmysql.NewVersionedApiClient(http)
 
-	sshRunner := cfmysql.NewSshRunner()
+	sshRunner := cfmysql.NewSshRunner(apiClient, os.Stderr, cfmysql.TunnelProbeTimeout)
 	netWrapper := cfmysql.NewNetWrapper()
 	waiter := cfmysql.NewPortWaiter(netWrapper)
 	randWrapper := cfmysql.NewRandWrapper()
-	cfService := cfmysql.NewCfService(apiClient, sshRunner, waiter, http, randWrapper, os.Stderr)
+	cfService := cfmysql.NewCfService(apiClient, sshRunner, http, randWrapper, os.Stderr)
 
 	execWrapper := cfmysql.NewExecWrapper()
 	ioUtilWrapper := cfmysql.NewIoUtilWrapper()
 	runner := cfmysql.NewMysqlRunner(execWrapper, ioUtilWrapper, osWrapper)
 
 	portFinder := cfmysql.NewPortFinder()
+	signalWrapper := cfmysql.NewSignalWrapper()
+	tunnelAppPusher := cfmysql.NewTunnelAppPusher(ioUtilWrapper, osWrapper, randWrapper)
+	stateDir := cfmysql.TunnelStateDir()
+	tunnelDaemon := cfmysql.NewTunnelDaemon(stateDir, execWrapper, waiter)
+	tunnelStateStore := cfmysql.NewTunnelStateStore(filepath.Join(stateDir, "tunnels.json"))
 
 	return cfmysql.NewMysqlPlugin(cfmysql.PluginConf{
-		In:          os.Stdin,
-		Out:         os.Stdout,
-		Err:         os.Stderr,
-		CfService:   cfService,
-		PortFinder:  portFinder,
-		MysqlRunner: runner,
+		In:               os.Stdin,
+		Out:              os.Stdout,
+		Err:              os.Stderr,
+		CfService:        cfService,
+		PortFinder:       portFinder,
+		MysqlRunner:      runner,
+		OsWrapper:        osWrapper,
+		IoUtilWrapper:    ioUtilWrapper,
+		SignalWrapper:    signalWrapper,
+		TunnelAppPusher:  tunnelAppPusher,
+		TunnelDaemon:     tunnelDaemon,
+		TunnelStateStore: tunnelStateStore,
 	})
 }